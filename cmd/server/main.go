@@ -9,6 +9,8 @@ import (
 	"github.com/omniful/go_commons/log"
 	"github.com/si/internal/config"
 	"github.com/si/internal/http/handlers"
+	"github.com/si/internal/http/middleware"
+	"github.com/si/internal/notifier"
 	"github.com/si/internal/setup"
 	"github.com/si/internal/storage/postgres"
 	"github.com/si/internal/storage/service"
@@ -57,20 +59,85 @@ func main() {
 	userRepo := postgres.NewUserRepo(cluster)
 	productRepo := postgres.NewProductRepo(cluster)
 	orderRepo := postgres.NewOrderRepo(cluster)
+	returnRepo := postgres.NewReturnRepo(cluster)
+	shipmentRepo := postgres.NewShipmentRepo(cluster)
+	idempotencyRepo := postgres.NewIdempotencyRepo(cluster)
+	exchangeRateRepo := postgres.NewExchangeRateRepo(cluster)
+	couponRepo := postgres.NewCouponRepo(cluster)
+	taxRuleRepo := postgres.NewTaxRuleRepo(cluster)
+	addressRepo := postgres.NewAddressRepo(cluster)
+	reservationRepo := postgres.NewReservationRepo(cluster)
+	stockMovementRepo := postgres.NewStockMovementRepo(cluster)
+	locationRepo := postgres.NewLocationRepo(cluster)
+	stockAlertRepo := postgres.NewStockAlertRepo(cluster)
+	supplierRepo := postgres.NewSupplierRepo(cluster)
+	purchaseOrderRepo := postgres.NewPurchaseOrderRepo(cluster)
+	stockTakeRepo := postgres.NewStockTakeRepo(cluster)
+	stockLotRepo := postgres.NewStockLotRepo(cluster)
+	serialNumberRepo := postgres.NewSerialNumberRepo(cluster)
+	categoryRepo := postgres.NewCategoryRepo(cluster)
+	productImportRepo := postgres.NewProductImportRepo(cluster)
+	priceChangeRepo := postgres.NewPriceChangeRepo(cluster)
+
+	// notifiers
+	var alertNotifier notifier.Notifier = notifier.NewLogNotifier()
+	if config.AppConf.Inventory.Alerts.Notifier == "webhook" {
+		alertNotifier = notifier.NewWebhookNotifier(config.AppConf.Inventory.Alerts.WebhookURL, config.AppConf.Inventory.Alerts.WebhookTimeout)
+	}
 
 	// services
 	userService := service.NewUserService(userRepo)
-	productService := service.NewProductService(productRepo)
-	orderService := service.NewOrderService(orderRepo, userRepo, productRepo)
+	addressService := service.NewAddressService(addressRepo, userRepo)
+	productService := service.NewProductService(productRepo, locationRepo, stockLotRepo, categoryRepo, priceChangeRepo, config.AppConf.Money.DefaultCurrency, config.AppConf.Search.PriceBands, config.AppConf.Search.FuzzyThreshold)
+	taxService := service.NewTaxService(taxRuleRepo, categoryRepo, config.AppConf.Tax.Source, config.AppConf.Tax.Rules)
+	reservationService := service.NewReservationService(reservationRepo, productRepo, stockLotRepo, orderRepo, config.AppConf.Inventory.ReservationTTL, config.AppConf.Inventory.AllocationStrategy)
+	orderService := service.NewOrderService(orderRepo, userRepo, productRepo, exchangeRateRepo, couponRepo, taxService, addressRepo, reservationService, shipmentRepo, config.AppConf.Money.DefaultCurrency)
+	returnService := service.NewReturnService(returnRepo, orderRepo, productRepo, serialNumberRepo)
+	shipmentService := service.NewShipmentService(shipmentRepo, orderRepo, serialNumberRepo, reservationService)
+	exchangeRateService := service.NewExchangeRateService(exchangeRateRepo)
+	couponService := service.NewCouponService(couponRepo, categoryRepo)
+	inventoryService := service.NewInventoryService(stockMovementRepo, productRepo, locationRepo, stockAlertRepo, stockLotRepo, alertNotifier)
+	locationService := service.NewLocationService(locationRepo)
+	supplierService := service.NewSupplierService(supplierRepo)
+	purchaseOrderService := service.NewPurchaseOrderService(purchaseOrderRepo, supplierRepo, productRepo, locationRepo, stockLotRepo, serialNumberRepo, config.AppConf.Money.DefaultCurrency)
+	stockTakeService := service.NewStockTakeService(stockTakeRepo, productRepo, locationRepo)
+	serialService := service.NewSerialService(serialNumberRepo, productRepo, locationRepo, orderRepo)
+	categoryService := service.NewCategoryService(categoryRepo)
+	catalogService := service.NewCatalogService(productImportRepo, productRepo, productService)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, config.AppConf.Idempotency.TTL, config.AppConf.Idempotency.Lease)
 
 	// handlers
 	userHandler := handlers.NewUserHandler(userService)
+	addressHandler := handlers.NewAddressHandler(addressService)
 	producthandler := handlers.NewProductHandler(productService)
 	orderHandler := handlers.NewOrderHandler(orderService)
+	returnHandler := handlers.NewReturnHandler(returnService)
+	shipmentHandler := handlers.NewShipmentHandler(shipmentService)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateService)
+	couponHandler := handlers.NewCouponHandler(couponService)
+	taxRuleHandler := handlers.NewTaxRuleHandler(taxService)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	locationHandler := handlers.NewLocationHandler(locationService)
+	supplierHandler := handlers.NewSupplierHandler(supplierService)
+	purchaseOrderHandler := handlers.NewPurchaseOrderHandler(purchaseOrderService)
+	stockTakeHandler := handlers.NewStockTakeHandler(stockTakeService)
+	serialHandler := handlers.NewSerialHandler(serialService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	catalogHandler := handlers.NewCatalogHandler(catalogService)
+
+	// background workers
+	go reservationService.RunSweeper(types.WithActor(ctx, "reservation-sweeper"), config.AppConf.Inventory.SweepInterval)
+	go inventoryService.RunAlertNotifier(ctx, config.AppConf.Inventory.Alerts.Interval)
+	go catalogService.RunImportWorker(ctx, config.AppConf.Catalog.ImportInterval)
+	go productService.RunPriceScheduler(types.WithActor(ctx, "price-scheduler"), config.AppConf.Catalog.PriceInterval)
+
+	// middlewares
+	idempotent := middleware.Idempotency(idempotencyService)
 
 	server := http.InitializeServer(
-		":3000", 0, 0, 0, true,
+		":3000", config.AppConf.Server.ReadTimeout, config.AppConf.Server.WriteTimeout, config.AppConf.Server.IdleTimeout, true,
 	)
+	server.Use(middleware.Actor())
 
 	server.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK.Code(), types.APIResponse{
@@ -80,7 +147,7 @@ func main() {
 	})
 
 
-	setup.SetupRoutes(server, userHandler, producthandler, orderHandler)
+	setup.SetupRoutes(server, userHandler, addressHandler, producthandler, orderHandler, returnHandler, shipmentHandler, exchangeRateHandler, couponHandler, taxRuleHandler, inventoryHandler, locationHandler, supplierHandler, purchaseOrderHandler, stockTakeHandler, serialHandler, categoryHandler, catalogHandler, idempotent)
 
 	log.Info("server starting on port 3000")
 	if err := server.StartServer("oms-service"); err != nil {
@@ -5,6 +5,8 @@ go 1.25.1
 require (
 	github.com/gin-gonic/gin v1.11.0
 	github.com/omniful/go_commons v0.6.79
+	golang.org/x/crypto v0.42.0
+	gorm.io/gorm v1.31.0
 )
 
 require (
@@ -67,7 +69,6 @@ require (
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.23.0 // indirect
 	golang.org/x/arch v0.21.0 // indirect
-	golang.org/x/crypto v0.42.0 // indirect
 	golang.org/x/exp v0.0.0-20240318143956-a85f2c67cd81 // indirect
 	golang.org/x/mod v0.28.0 // indirect
 	golang.org/x/net v0.44.0 // indirect
@@ -80,5 +81,4 @@ require (
 	google.golang.org/protobuf v1.36.9 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gorm.io/driver/postgres v1.6.0 // indirect
-	gorm.io/gorm v1.31.0 // indirect
 )
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/omniful/go_commons/config"
 	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
 )
 
 type AppConfig struct {
@@ -15,6 +18,58 @@ type AppConfig struct {
 	Server      ServerConfig
 	Database    DatabaseConfig
 	Slaves      []DatabaseConfig
+	Idempotency IdempotencyConfig
+	Money       MoneyConfig
+	Tax         TaxConfig
+	Inventory   InventoryConfig
+	Search      SearchConfig
+	Catalog     CatalogConfig
+}
+
+type IdempotencyConfig struct {
+	TTL   time.Duration
+	Lease time.Duration
+}
+
+type MoneyConfig struct {
+	DefaultCurrency string
+}
+
+// rules are read from config.yaml when source is "config" and from the tax_rules table when it is "db"
+type TaxConfig struct {
+	Source string
+	Rules  []types.TaxRule
+}
+
+// unpaid orders hold their stock for ReservationTTL, the sweeper looks for expired holds every SweepInterval.
+// AllocationStrategy picks the location each order line is fulfilled from
+type InventoryConfig struct {
+	ReservationTTL     time.Duration
+	SweepInterval      time.Duration
+	AllocationStrategy types.AllocationStrategy
+	Alerts             AlertConfig
+}
+
+// low stock alerts are pushed to Notifier ("log" or "webhook") every Interval, WebhookURL is required for webhooks
+type AlertConfig struct {
+	Notifier       string
+	WebhookURL     string
+	WebhookTimeout time.Duration
+	Interval       time.Duration
+}
+
+// a query without a full text match falls back to names with at least FuzzyThreshold word similarity,
+// PriceBands are the ascending upper bounds of the price facet bands
+type SearchConfig struct {
+	FuzzyThreshold float64
+	PriceBands     []types.Amount
+}
+
+// queued catalog imports are picked up every ImportInterval, scheduled price changes that came due are
+// applied every PriceInterval
+type CatalogConfig struct {
+	ImportInterval time.Duration
+	PriceInterval  time.Duration
 }
 
 type ServerConfig struct {
@@ -68,6 +123,18 @@ func Init(ctx context.Context) error {
 
     slaves := loadSlavesConfig(ctx)
 
+    taxRules, err := loadTaxRulesConfig(ctx)
+    if err != nil {
+        log.ErrorfWithContext(ctx, "failed to load tax rules", err)
+        return err
+    }
+
+    search, err := loadSearchConfig(ctx)
+    if err != nil {
+        log.ErrorfWithContext(ctx, "failed to load search config", err)
+        return err
+    }
+
 	AppConf = &AppConfig{
         Environment: config.GetString(ctx, "env"),
         Server: ServerConfig{
@@ -79,6 +146,33 @@ func Init(ctx context.Context) error {
         },
         Database: masterDB,
         Slaves:   slaves,
+        Idempotency: IdempotencyConfig{
+            TTL:   config.GetDuration(ctx, "idempotency.ttl"),
+            Lease: config.GetDuration(ctx, "idempotency.lease"),
+        },
+        Money: MoneyConfig{
+            DefaultCurrency: config.GetString(ctx, "money.default_currency"),
+        },
+        Tax: TaxConfig{
+            Source: config.GetString(ctx, "tax.source"),
+            Rules:  taxRules,
+        },
+        Inventory: InventoryConfig{
+            ReservationTTL: config.GetDuration(ctx, "inventory.reservation_ttl"),
+            SweepInterval:  config.GetDuration(ctx, "inventory.sweep_interval"),
+            AllocationStrategy: types.AllocationStrategy(config.GetString(ctx, "inventory.allocation_strategy")),
+            Alerts: AlertConfig{
+                Notifier:       config.GetString(ctx, "inventory.alerts.notifier"),
+                WebhookURL:     config.GetString(ctx, "inventory.alerts.webhook_url"),
+                WebhookTimeout: config.GetDuration(ctx, "inventory.alerts.webhook_timeout"),
+                Interval:       config.GetDuration(ctx, "inventory.alerts.interval"),
+            },
+        },
+        Search: search,
+        Catalog: CatalogConfig{
+            ImportInterval: config.GetDuration(ctx, "catalog.import_interval"),
+            PriceInterval:  config.GetDuration(ctx, "catalog.price_interval"),
+        },
     }
 
 	if err := validate(); err != nil {
@@ -132,6 +226,67 @@ func loadSlavesConfig(ctx context.Context) []DatabaseConfig {
     return slaves
 }
 
+func loadTaxRulesConfig(ctx context.Context) ([]types.TaxRule, error) {
+    rules := make([]types.TaxRule, 0)
+
+    ruleCount := config.GetInt(ctx, "tax.rules.count")
+
+    for i := 0; i < ruleCount; i++ {
+        rulePrefix := fmt.Sprintf("tax.rules.rule_%d", i+1)
+
+        rate, err := types.ParseRate(config.GetString(ctx, rulePrefix+".rate"))
+        if err != nil {
+            return nil, fmt.Errorf("%s.rate: %w", rulePrefix, err)
+        }
+        if rate < 0 || rate >= types.Rate(100000000) {
+            return nil, fmt.Errorf("%s.rate - tax rate must be a fraction between 0 and 1", rulePrefix)
+        }
+
+        rule := types.TaxRule{
+            Name:      config.GetString(ctx, rulePrefix+".name"),
+            Region:    config.GetString(ctx, rulePrefix+".region"),
+            Rate:      rate,
+            Inclusive: config.GetBool(ctx, rulePrefix+".inclusive"),
+            IsActive:  true,
+        }
+        if rule.Name == "" {
+            return nil, fmt.Errorf("%s.name - tax rule name is required", rulePrefix)
+        }
+        categoryID := int64(config.GetInt(ctx, rulePrefix+".category_id"))
+        if categoryID < 0 {
+            return nil, fmt.Errorf("%s.category_id - category id cannot be negative", rulePrefix)
+        }
+        if categoryID != 0 {
+            rule.CategoryID = &categoryID
+        }
+
+        rules = append(rules, rule)
+    }
+
+    return rules, nil
+}
+
+func loadSearchConfig(ctx context.Context) (SearchConfig, error) {
+    threshold, err := strconv.ParseFloat(config.GetString(ctx, "search.fuzzy_threshold"), 64)
+    if err != nil {
+        return SearchConfig{}, fmt.Errorf("search.fuzzy_threshold: %w", err)
+    }
+
+    var bands []types.Amount
+    for _, bound := range strings.Split(config.GetString(ctx, "search.price_bands"), ",") {
+        if strings.TrimSpace(bound) == "" {
+            continue
+        }
+        amount, err := types.ParseAmount(strings.TrimSpace(bound))
+        if err != nil {
+            return SearchConfig{}, fmt.Errorf("search.price_bands: %w", err)
+        }
+        bands = append(bands, amount)
+    }
+
+    return SearchConfig{FuzzyThreshold: threshold, PriceBands: bands}, nil
+}
+
 func validate() error {
     if AppConf.Database.Host == "" {
         return errors.New("postgres.host - database host is required")
@@ -145,6 +300,54 @@ func validate() error {
     if AppConf.Database.Database == "" {
         return errors.New("postgres.db - database name is required")
     }
+    if AppConf.Idempotency.TTL <= 0 {
+        return errors.New("idempotency.ttl - idempotency key ttl must be positive")
+    }
+    if AppConf.Idempotency.Lease <= 0 || AppConf.Idempotency.Lease > AppConf.Idempotency.TTL {
+        return errors.New("idempotency.lease - idempotency key lease must be positive and at most the ttl")
+    }
+    // a retry may only take a key over once the request holding it can no longer be running
+    if AppConf.Idempotency.Lease <= AppConf.Server.WriteTimeout {
+        return errors.New("idempotency.lease - idempotency key lease must be longer than http_server.write_timeout")
+    }
+    if len(AppConf.Money.DefaultCurrency) != 3 {
+        return errors.New("money.default_currency - a 3 letter ISO 4217 currency code is required")
+    }
+    if AppConf.Tax.Source != "config" && AppConf.Tax.Source != "db" {
+        return errors.New("tax.source - must be either config or db")
+    }
+    if AppConf.Inventory.ReservationTTL <= 0 {
+        return errors.New("inventory.reservation_ttl - stock reservation ttl must be positive")
+    }
+    if AppConf.Inventory.SweepInterval <= 0 {
+        return errors.New("inventory.sweep_interval - reservation sweep interval must be positive")
+    }
+    if !AppConf.Inventory.AllocationStrategy.IsValid() {
+        return errors.New("inventory.allocation_strategy - must be one of nearest, highest_stock or priority")
+    }
+    if AppConf.Inventory.Alerts.Notifier != "log" && AppConf.Inventory.Alerts.Notifier != "webhook" {
+        return errors.New("inventory.alerts.notifier - must be either log or webhook")
+    }
+    if AppConf.Inventory.Alerts.Notifier == "webhook" && AppConf.Inventory.Alerts.WebhookURL == "" {
+        return errors.New("inventory.alerts.webhook_url - a url is required for the webhook notifier")
+    }
+    if AppConf.Inventory.Alerts.Interval <= 0 {
+        return errors.New("inventory.alerts.interval - alert notification interval must be positive")
+    }
+    if AppConf.Search.FuzzyThreshold <= 0 || AppConf.Search.FuzzyThreshold > 1 {
+        return errors.New("search.fuzzy_threshold - must be above 0 and at most 1")
+    }
+    for i, bound := range AppConf.Search.PriceBands {
+        if bound <= 0 || (i > 0 && bound <= AppConf.Search.PriceBands[i-1]) {
+            return errors.New("search.price_bands - bounds must be positive and ascending")
+        }
+    }
+    if AppConf.Catalog.ImportInterval <= 0 {
+        return errors.New("catalog.import_interval - catalog import interval must be positive")
+    }
+    if AppConf.Catalog.PriceInterval <= 0 {
+        return errors.New("catalog.price_interval - price scheduler interval must be positive")
+    }
 
     return nil
 }
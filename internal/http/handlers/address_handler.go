@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type AddressHandler struct {
+	AddressService *service.AddressService
+}
+
+func NewAddressHandler(addressService *service.AddressService) *AddressHandler {
+	return &AddressHandler{
+		AddressService: addressService,
+	}
+}
+
+// request body shared by create and update
+type addressRequest struct {
+	Label             string `json:"label" validate:"omitempty,max=50"`
+	RecipientName     string `json:"recipient_name" validate:"required,max=255"`
+	Phone             string `json:"phone" validate:"omitempty,numeric,max=20"`
+	Line1             string `json:"line1" validate:"required,max=255"`
+	Line2             string `json:"line2" validate:"omitempty,max=255"`
+	City              string `json:"city" validate:"required,max=100"`
+	State             string `json:"state" validate:"omitempty,max=100"`
+	PostalCode        string `json:"postal_code" validate:"omitempty,max=20"`
+	Country           string `json:"country" validate:"required,len=2,uppercase"`
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+}
+
+func (r addressRequest) toAddress() *types.UserAddress {
+	return &types.UserAddress{
+		Label:             r.Label,
+		RecipientName:     r.RecipientName,
+		Phone:             r.Phone,
+		Line1:             r.Line1,
+		Line2:             r.Line2,
+		City:              r.City,
+		State:             r.State,
+		PostalCode:        r.PostalCode,
+		Country:           r.Country,
+		IsDefaultShipping: r.IsDefaultShipping,
+		IsDefaultBilling:  r.IsDefaultBilling,
+	}
+}
+
+func (h *AddressHandler) CreateAddressHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[AddressHandler][CreateAddressHandler]"
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid user ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid user ID format", err.Error()))
+		return
+	}
+
+	var body addressRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	address, err := h.AddressService.CreateAddress(ctx, userID, body.toAddress())
+	if err != nil {
+		writeAddressError(c, logTag, "error when creating address", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message": "address created successfully",
+		"address": address,
+	})
+}
+
+func (h *AddressHandler) GetAddressesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[AddressHandler][GetAddressesHandler]"
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid user ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid user ID format", err.Error()))
+		return
+	}
+
+	addresses, err := h.AddressService.GetAddresses(ctx, userID)
+	if err != nil {
+		writeAddressError(c, logTag, "error when getting addresses", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":   "addresses fetched successfully",
+		"addresses": addresses,
+	})
+}
+
+func (h *AddressHandler) GetAddressHandler(c *gin.Context) {
+	logTag := "[AddressHandler][GetAddressHandler]"
+
+	userID, addressID, ok := parseAddressPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	address, err := h.AddressService.GetAddress(c.Request.Context(), userID, addressID)
+	if err != nil {
+		writeAddressError(c, logTag, "error when getting address", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "address fetched successfully",
+		"address": address,
+	})
+}
+
+func (h *AddressHandler) UpdateAddressHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[AddressHandler][UpdateAddressHandler]"
+
+	userID, addressID, ok := parseAddressPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	var body addressRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	address, err := h.AddressService.UpdateAddress(ctx, userID, addressID, body.toAddress())
+	if err != nil {
+		writeAddressError(c, logTag, "error when updating address", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "address updated successfully",
+		"address": address,
+	})
+}
+
+func (h *AddressHandler) DeleteAddressHandler(c *gin.Context) {
+	logTag := "[AddressHandler][DeleteAddressHandler]"
+
+	userID, addressID, ok := parseAddressPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	if err := h.AddressService.DeleteAddress(c.Request.Context(), userID, addressID); err != nil {
+		writeAddressError(c, logTag, "error when deleting address", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "address deleted successfully",
+	})
+}
+
+func parseAddressPath(c *gin.Context, logTag string) (int64, int64, bool) {
+	ctx := c.Request.Context()
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid user ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid user ID format", err.Error()))
+		return 0, 0, false
+	}
+
+	addressID, err := strconv.ParseInt(c.Param("address_id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid address ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid address ID format", err.Error()))
+		return 0, 0, false
+	}
+
+	return userID, addressID, true
+}
+
+func writeAddressError(c *gin.Context, logTag, message string, err error) {
+	switch {
+	case err.Error() == "address not found":
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("address not found", err.Error()))
+	case strings.HasPrefix(err.Error(), "user not found"):
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("user not found", err.Error()))
+	default:
+		log.ErrorfWithContext(c.Request.Context(), logTag+" "+message, err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse(message, err.Error()))
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+// largest catalog file taken in one import
+const maxImportFileSize = 20 << 20
+
+type CatalogHandler struct {
+	CatalogService *service.CatalogService
+}
+
+func NewCatalogHandler(catalogService *service.CatalogService) *CatalogHandler {
+	return &CatalogHandler{
+		CatalogService: catalogService,
+	}
+}
+
+// takes a multipart upload with the catalog in "file", the format comes from the "format" field or else
+// from the file extension. The rows are applied in the background, the import returned tracks them
+func (h *CatalogHandler) CreateImportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CatalogHandler][CreateImportHandler]"
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when reading the upload", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("a catalog file is required", err.Error()))
+		return
+	}
+	if header.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("file too large", fmt.Sprintf("a catalog file can be at most %d MB", maxImportFileSize>>20)))
+		return
+	}
+
+	format := types.ProductFileFormat(strings.ToLower(c.PostForm("format")))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			format = types.ProductFileFormatCSV
+		case ".jsonl", ".ndjson":
+			format = types.ProductFileFormatJSONL
+		}
+	}
+	if !format.IsValid() {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid format", "format must be csv or jsonl"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when opening the upload", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("error when reading the file", err.Error()))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when reading the upload", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("error when reading the file", err.Error()))
+		return
+	}
+
+	job, err := h.CatalogService.SubmitImport(ctx, format, filepath.Base(header.Filename), data)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidImport) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid catalog file", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when submitting import", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when submitting import", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusAccepted.Code(), gin.H{
+		"message": "product import queued",
+		"import":  job,
+	})
+}
+
+func (h *CatalogHandler) GetImportsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CatalogHandler][GetImportsHandler]"
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	jobs, total, err := h.CatalogService.GetImports(ctx, limit, (page-1)*limit)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting imports", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching imports", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "product imports fetched successfully",
+		"imports": jobs,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func (h *CatalogHandler) GetImportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CatalogHandler][GetImportHandler]"
+
+	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid import ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid import ID format", err.Error()))
+		return
+	}
+
+	job, err := h.CatalogService.GetImport(ctx, importID)
+	if err != nil {
+		if err.Error() == "product import not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product import not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting import", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching import", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "product import fetched successfully",
+		"import":  job,
+	})
+}
+
+// the rows of an import that could not be applied, in file order
+func (h *CatalogHandler) GetImportErrorsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CatalogHandler][GetImportErrorsHandler]"
+
+	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid import ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid import ID format", err.Error()))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	rowErrors, total, err := h.CatalogService.GetImportErrors(ctx, importID, limit, (page-1)*limit)
+	if err != nil {
+		if err.Error() == "product import not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product import not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting import errors", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching import errors", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "product import errors fetched successfully",
+		"errors":  rowErrors,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// streams the products matching the same filters as the search as a csv or json lines file, the rows can be
+// imported again as they are
+func (h *CatalogHandler) ExportProductsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CatalogHandler][ExportProductsHandler]"
+
+	var body struct {
+		Format             types.ProductFileFormat `json:"format" validate:"omitempty,oneof=csv jsonl"`
+		Query              string                  `json:"query" validate:"omitempty,max=200"`
+		CategoryID         int64                   `json:"category_id" validate:"omitempty,min=1"`
+		IncludeDescendants bool                    `json:"include_descendants"`
+		Attributes         []types.AttributeFilter `json:"attributes" validate:"omitempty,max=20"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	if body.Format == "" {
+		body.Format = types.ProductFileFormatCSV
+	}
+
+	params, err := h.CatalogService.ProductService.BuildSearchParams(ctx, body.Query, body.CategoryID, body.IncludeDescendants, body.Attributes)
+	if err != nil {
+		if err.Error() == "category not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("category not found", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidAttributes) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid attribute filter", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when preparing export", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when exporting products", err.Error()))
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if body.Format == types.ProductFileFormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, body.Format))
+	c.Status(http.StatusOK.Code())
+
+	// the status is sent with the first rows, a failure after that can only cut the file short
+	if err := h.CatalogService.ExportProducts(ctx, params, body.Format, c.Writer); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when exporting products", err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type CategoryHandler struct {
+	CategoryService *service.CategoryService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		CategoryService: categoryService,
+	}
+}
+
+func (h *CategoryHandler) CreateCategoryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CategoryHandler][CreateCategoryHandler]"
+
+	var body struct {
+		Name            string                `json:"name" validate:"required,max=100"`
+		Description     string                `json:"description" validate:"omitempty,max=1000"`
+		ParentID        *int64                `json:"parent_id" validate:"omitempty,min=1"`
+		AttributeSchema types.AttributeSchema `json:"attribute_schema" validate:"omitempty,max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	category, err := h.CategoryService.CreateCategory(ctx, body.Name, body.Description, body.ParentID, body.AttributeSchema)
+	if err != nil {
+		h.writeCategoryError(c, logTag, "error when creating category", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":  "category created successfully",
+		"category": category,
+	})
+}
+
+func (h *CategoryHandler) GetCategoriesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CategoryHandler][GetCategoriesHandler]"
+
+	tree, err := h.CategoryService.GetCategoryTree(ctx)
+	if err != nil {
+		h.writeCategoryError(c, logTag, "error when fetching categories", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":    "categories fetched successfully",
+		"categories": tree,
+	})
+}
+
+func (h *CategoryHandler) GetCategoryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CategoryHandler][GetCategoryHandler]"
+
+	categoryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid category ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category ID format", err.Error()))
+		return
+	}
+
+	category, err := h.CategoryService.GetCategory(ctx, categoryID)
+	if err != nil {
+		h.writeCategoryError(c, logTag, "error when fetching category", err)
+		return
+	}
+
+	// the category's own schema plus what it inherits
+	schema, err := h.CategoryService.GetAttributeSchema(ctx, categoryID)
+	if err != nil {
+		h.writeCategoryError(c, logTag, "error when fetching attribute schema", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":    "category fetched successfully",
+		"category":   category,
+		"attributes": schema,
+	})
+}
+
+func (h *CategoryHandler) UpdateCategoryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CategoryHandler][UpdateCategoryHandler]"
+
+	categoryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid category ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category ID format", err.Error()))
+		return
+	}
+
+	// a parent_id of 0 moves the category to the root
+	var body struct {
+		Name            *string                `json:"name" validate:"omitempty,min=1,max=100"`
+		Description     *string                `json:"description" validate:"omitempty,max=1000"`
+		ParentID        *int64                 `json:"parent_id" validate:"omitempty,min=0"`
+		AttributeSchema *types.AttributeSchema `json:"attribute_schema" validate:"omitempty,max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	category, err := h.CategoryService.UpdateCategory(ctx, categoryID, service.CategoryUpdate{
+		Name:            body.Name,
+		Description:     body.Description,
+		ParentID:        body.ParentID,
+		AttributeSchema: body.AttributeSchema,
+	})
+	if err != nil {
+		h.writeCategoryError(c, logTag, "error when updating category", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":  "category updated successfully",
+		"category": category,
+	})
+}
+
+func (h *CategoryHandler) DeleteCategoryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CategoryHandler][DeleteCategoryHandler]"
+
+	categoryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid category ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category ID format", err.Error()))
+		return
+	}
+
+	if err := h.CategoryService.DeleteCategory(ctx, categoryID); err != nil {
+		h.writeCategoryError(c, logTag, "error when deleting category", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "category deleted successfully",
+	})
+}
+
+func (h *CategoryHandler) writeCategoryError(c *gin.Context, logTag, message string, err error) {
+	switch {
+	case err.Error() == "category not found":
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("category not found", err.Error()))
+	case errors.Is(err, service.ErrInvalidCategory):
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category", err.Error()))
+	case errors.Is(err, service.ErrCategoryInUse):
+		c.JSON(http.StatusConflict.Code(), response.ErrorResponse("category is in use", err.Error()))
+	default:
+		log.ErrorfWithContext(c.Request.Context(), logTag+" "+message, err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse(message, err.Error()))
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type CouponHandler struct {
+	CouponService *service.CouponService
+}
+
+func NewCouponHandler(couponService *service.CouponService) *CouponHandler {
+	return &CouponHandler{
+		CouponService: couponService,
+	}
+}
+
+func (h *CouponHandler) CreateCouponHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CouponHandler][CreateCouponHandler]"
+
+	var body struct {
+		Code           string       `json:"code" validate:"required,min=3,max=64"`
+		Type           string       `json:"type" validate:"required,oneof=coupon.percentage coupon.fixed coupon.buy_x_get_y"`
+		Description    string       `json:"description" validate:"omitempty,max=1000"`
+		PercentOff     int32        `json:"percent_off" validate:"omitempty,min=1,max=100"`
+		AmountOff      types.Amount `json:"amount_off" validate:"omitempty,min=0"`
+		Currency       string       `json:"currency" validate:"omitempty,len=3,uppercase"`
+		BuyQuantity    int32        `json:"buy_quantity" validate:"omitempty,min=1"`
+		GetQuantity    int32        `json:"get_quantity" validate:"omitempty,min=1"`
+		CategoryID     *int64       `json:"category_id" validate:"omitempty,min=1"`
+		MaxUses        int32        `json:"max_uses" validate:"omitempty,min=1"`
+		MaxUsesPerUser int32        `json:"max_uses_per_user" validate:"omitempty,min=1"`
+		StartsAt       *time.Time   `json:"starts_at"`
+		EndsAt         *time.Time   `json:"ends_at"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	coupon, err := h.CouponService.CreateCoupon(ctx, &types.Coupon{
+		Code:           body.Code,
+		Type:           types.CouponType(body.Type),
+		Description:    body.Description,
+		PercentOff:     body.PercentOff,
+		AmountOff:      body.AmountOff,
+		Currency:       body.Currency,
+		BuyQuantity:    body.BuyQuantity,
+		GetQuantity:    body.GetQuantity,
+		CategoryID:     body.CategoryID,
+		MaxUses:        body.MaxUses,
+		MaxUsesPerUser: body.MaxUsesPerUser,
+		StartsAt:       body.StartsAt,
+		EndsAt:         body.EndsAt,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCoupon) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid coupon", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCategory) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when creating coupon", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creating coupon", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message": "coupon created successfully",
+		"coupon":  coupon,
+	})
+}
+
+func (h *CouponHandler) GetCouponsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CouponHandler][GetCouponsHandler]"
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	coupons, total, err := h.CouponService.GetCoupons(ctx, limit, (page-1)*limit)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting coupons", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching coupons", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "coupons fetched successfully",
+		"coupons": coupons,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func (h *CouponHandler) GetCouponHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CouponHandler][GetCouponHandler]"
+
+	couponID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid coupon ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid coupon ID format", err.Error()))
+		return
+	}
+
+	coupon, err := h.CouponService.GetCoupon(ctx, couponID)
+	if err != nil {
+		if err.Error() == "coupon not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("coupon not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting coupon", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching coupon", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "coupon fetched successfully",
+		"coupon":  coupon,
+	})
+}
+
+func (h *CouponHandler) UpdateCouponHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[CouponHandler][UpdateCouponHandler]"
+
+	couponID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid coupon ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid coupon ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		IsActive       *bool      `json:"is_active"`
+		StartsAt       *time.Time `json:"starts_at"`
+		EndsAt         *time.Time `json:"ends_at"`
+		MaxUses        *int32     `json:"max_uses" validate:"omitempty,min=0"`
+		MaxUsesPerUser *int32     `json:"max_uses_per_user" validate:"omitempty,min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	coupon, err := h.CouponService.UpdateCouponAvailability(ctx, couponID, service.CouponAvailability{
+		IsActive:       body.IsActive,
+		StartsAt:       body.StartsAt,
+		EndsAt:         body.EndsAt,
+		MaxUses:        body.MaxUses,
+		MaxUsesPerUser: body.MaxUsesPerUser,
+	})
+	if err != nil {
+		if err.Error() == "coupon not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("coupon not found", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCoupon) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid coupon", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when updating coupon", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when updating coupon", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "coupon updated successfully",
+		"coupon":  coupon,
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type ExchangeRateHandler struct {
+	ExchangeRateService *service.ExchangeRateService
+}
+
+func NewExchangeRateHandler(exchangeRateService *service.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		ExchangeRateService: exchangeRateService,
+	}
+}
+
+func (h *ExchangeRateHandler) SetExchangeRateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ExchangeRateHandler][SetExchangeRateHandler]"
+
+	var body struct {
+		BaseCurrency  string     `json:"base_currency" validate:"required,len=3,uppercase"`
+		QuoteCurrency string     `json:"quote_currency" validate:"required,len=3,uppercase"`
+		Rate          types.Rate `json:"rate" validate:"required,gt=0"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	rate, err := h.ExchangeRateService.SetRate(ctx, body.BaseCurrency, body.QuoteCurrency, body.Rate)
+	if err != nil {
+		if err.Error() == "base and quote currency must differ" {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse(err.Error(), err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when setting exchange rate", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when setting exchange rate", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":       "exchange rate saved successfully",
+		"exchange_rate": rate,
+	})
+}
+
+func (h *ExchangeRateHandler) GetExchangeRatesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ExchangeRateHandler][GetExchangeRatesHandler]"
+
+	rates, err := h.ExchangeRateService.GetRates(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting exchange rates", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting exchange rates", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "exchange rates fetched successfully",
+		"exchange_rates": rates,
+	})
+}
+
+func (h *ExchangeRateHandler) DeleteExchangeRateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ExchangeRateHandler][DeleteExchangeRateHandler]"
+
+	base := strings.ToUpper(c.Param("base"))
+	quote := strings.ToUpper(c.Param("quote"))
+	if len(base) != 3 || len(quote) != 3 {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid currency pair", base+"/"+quote))
+		return
+	}
+
+	if err := h.ExchangeRateService.DeleteRate(ctx, base, quote); err != nil {
+		if err.Error() == "exchange rate not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("exchange rate not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when deleting exchange rate", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when deleting exchange rate", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "exchange rate deleted successfully",
+	})
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type InventoryHandler struct {
+	InventoryService *service.InventoryService
+}
+
+func NewInventoryHandler(inventoryService *service.InventoryService) *InventoryHandler {
+	return &InventoryHandler{
+		InventoryService: inventoryService,
+	}
+}
+
+func (h *InventoryHandler) GetStockMovementsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[InventoryHandler][GetStockMovementsHandler]"
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	movements, total, err := h.InventoryService.GetStockMovements(ctx, productID, limit, (page-1)*limit)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "product not found") {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting stock movements", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching stock movements", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":   "stock movements fetched successfully",
+		"movements": movements,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+func (h *InventoryHandler) ReconcileStockHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[InventoryHandler][ReconcileStockHandler]"
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	reconciliation, err := h.InventoryService.ReconcileStock(ctx, productID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "product not found") {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when reconciling stock", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when reconciling stock", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "stock reconciled successfully",
+		"reconciliation": reconciliation,
+	})
+}
+
+func (h *InventoryHandler) TransferStockHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[InventoryHandler][TransferStockHandler]"
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		FromLocationID int64  `json:"from_location_id" validate:"required,min=1"`
+		ToLocationID   int64  `json:"to_location_id" validate:"required,min=1"`
+		Quantity       int64  `json:"quantity" validate:"required,min=1"`
+		Note           string `json:"note" validate:"omitempty,max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	transfer, err := h.InventoryService.TransferStock(ctx, productID, body.FromLocationID, body.ToLocationID, body.Quantity, body.Note)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "product not found") {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+			return
+		}
+		if err.Error() == "location not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("location not found", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTransfer) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid stock transfer", err.Error()))
+			return
+		}
+		if err.Error() == "insufficient stock" {
+			c.JSON(http.StatusConflict.Code(), response.ErrorResponse("insufficient stock", "the source location does not have that much available stock"))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when transferring stock", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when transferring stock", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":  "stock transferred successfully",
+		"transfer": transfer,
+	})
+}
+
+func (h *InventoryHandler) GetStockTransfersHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[InventoryHandler][GetStockTransfersHandler]"
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	transfers, total, err := h.InventoryService.GetStockTransfers(ctx, productID, limit, (page-1)*limit)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "product not found") {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting stock transfers", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching stock transfers", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":   "stock transfers fetched successfully",
+		"transfers": transfers,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+func (h *InventoryHandler) GetStockAlertsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[InventoryHandler][GetStockAlertsHandler]"
+
+	status := types.StockAlertStatus(c.Query("status"))
+	if status != "" && status != types.StockAlertStatusOpen && status != types.StockAlertStatusResolved {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid status", "status must be alert.open or alert.resolved"))
+		return
+	}
+
+	var productID int64
+	if raw := c.Query("product_id"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+			return
+		}
+		productID = parsed
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	alerts, total, err := h.InventoryService.GetStockAlerts(ctx, status, productID, limit, (page-1)*limit)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting stock alerts", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching stock alerts", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "stock alerts fetched successfully",
+		"alerts":  alerts,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func (h *InventoryHandler) GetProductLotsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[InventoryHandler][GetProductLotsHandler]"
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	lots, err := h.InventoryService.GetProductLots(ctx, productID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "product not found") {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting product lots", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching product lots", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "product lots fetched successfully",
+		"lots":    lots,
+	})
+}
+
+func (h *InventoryHandler) GetExpiringLotsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[InventoryHandler][GetExpiringLotsHandler]"
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid days", "days must be a non-negative number"))
+		return
+	}
+
+	var locationID int64
+	if raw := c.Query("location_id"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" invalid location ID format", err)
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid location ID format", err.Error()))
+			return
+		}
+		locationID = parsed
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	lots, total, err := h.InventoryService.GetExpiringLots(ctx, days, locationID, limit, (page-1)*limit)
+	if err != nil {
+		if err.Error() == "location not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("location not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting expiring lots", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching expiring lots", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "expiring lots fetched successfully",
+		"lots":    lots,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
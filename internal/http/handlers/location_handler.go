@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type LocationHandler struct {
+	LocationService *service.LocationService
+}
+
+func NewLocationHandler(locationService *service.LocationService) *LocationHandler {
+	return &LocationHandler{
+		LocationService: locationService,
+	}
+}
+
+func (h *LocationHandler) CreateLocationHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[LocationHandler][CreateLocationHandler]"
+
+	var body struct {
+		Code       string `json:"code" validate:"required,max=50"`
+		Name       string `json:"name" validate:"required,max=255"`
+		Country    string `json:"country" validate:"omitempty,len=2,alpha"`
+		State      string `json:"state" validate:"omitempty,max=100"`
+		PostalCode string `json:"postal_code" validate:"omitempty,max=20"`
+		Priority   int32  `json:"priority" validate:"min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	location, err := h.LocationService.CreateLocation(ctx, &types.Location{
+		Code:       body.Code,
+		Name:       body.Name,
+		Country:    body.Country,
+		State:      body.State,
+		PostalCode: body.PostalCode,
+		Priority:   body.Priority,
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating location", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creating location", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":  "location created successfully",
+		"location": location,
+	})
+}
+
+func (h *LocationHandler) GetLocationsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[LocationHandler][GetLocationsHandler]"
+
+	locations, err := h.LocationService.GetLocations(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting locations", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching locations", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":   "locations fetched successfully",
+		"locations": locations,
+	})
+}
+
+func (h *LocationHandler) GetLocationHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[LocationHandler][GetLocationHandler]"
+
+	locationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid location ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid location ID format", err.Error()))
+		return
+	}
+
+	location, err := h.LocationService.GetLocation(ctx, locationID)
+	if err != nil {
+		if err.Error() == "location not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("location not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting location", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching location", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":  "location fetched successfully",
+		"location": location,
+	})
+}
+
+func (h *LocationHandler) UpdateLocationHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[LocationHandler][UpdateLocationHandler]"
+
+	locationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid location ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid location ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		Name       *string `json:"name" validate:"omitempty,min=1,max=255"`
+		Country    *string `json:"country" validate:"omitempty,len=2,alpha"`
+		State      *string `json:"state" validate:"omitempty,max=100"`
+		PostalCode *string `json:"postal_code" validate:"omitempty,max=20"`
+		Priority   *int32  `json:"priority" validate:"omitempty,min=0"`
+		IsActive   *bool   `json:"is_active"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	location, err := h.LocationService.UpdateLocation(ctx, locationID, service.LocationUpdate{
+		Name:       body.Name,
+		Country:    body.Country,
+		State:      body.State,
+		PostalCode: body.PostalCode,
+		Priority:   body.Priority,
+		IsActive:   body.IsActive,
+	})
+	if err != nil {
+		if err.Error() == "location not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("location not found", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidLocation) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid location", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when updating location", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when updating location", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":  "location updated successfully",
+		"location": location,
+	})
+}
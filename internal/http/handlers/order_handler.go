@@ -1,7 +1,9 @@
 package handlers
 
 import (
+    "errors"
     "strconv"
+    "strings"
 
     "github.com/gin-gonic/gin"
     "github.com/omniful/go_commons/http"
@@ -28,8 +30,13 @@ func (h *OrderHandler) CreateOrderHandler(c *gin.Context) {
     log.InfofWithContext(ctx, logTag+" creating order")
 
     var body struct {
-        UserID int64 `json:"user_id" validate:"required,numeric"`
-        Items  []struct {
+        UserID   int64  `json:"user_id" validate:"required,numeric"`
+        Currency  string   `json:"currency" validate:"omitempty,len=3,uppercase"`
+        TaxRegion string   `json:"tax_region" validate:"omitempty,min=2,max=10,uppercase"`
+        Coupons   []string `json:"coupon_codes" validate:"omitempty,max=5,dive,required,max=64"`
+        ShippingAddressID int64 `json:"shipping_address_id" validate:"omitempty,numeric"`
+        BillingAddressID  int64 `json:"billing_address_id" validate:"omitempty,numeric"`
+        Items    []struct {
             ProductID int64 `json:"product_id" validate:"required,numeric"`
             Quantity  int32 `json:"quantity" validate:"required,numeric,min=1"`
         } `json:"items" validate:"required,min=1"`
@@ -55,8 +62,46 @@ func (h *OrderHandler) CreateOrderHandler(c *gin.Context) {
         })
     }
 
-    order, err := h.OrderService.CreateOrder(ctx, body.UserID, orderItems)
+    order, err := h.OrderService.CreateOrder(ctx, body.UserID, orderItems, types.CreateOrderOptions{
+        Currency:    body.Currency,
+        TaxRegion:   body.TaxRegion,
+        CouponCodes: body.Coupons,
+        ShippingAddressID: body.ShippingAddressID,
+        BillingAddressID:  body.BillingAddressID,
+    })
     if err != nil {
+        if errors.Is(err, service.ErrPriceUnavailable) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("product not available in requested currency", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrProductNotOrderable) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("product cannot be ordered", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrCouponNotRedeemable) || errors.Is(err, service.ErrCouponNotApplicable) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("coupon cannot be applied", err.Error()))
+            return
+        }
+        if err.Error() == "address not found" {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("address does not belong to the user", err.Error()))
+            return
+        }
+        if errors.Is(err, types.ErrAmountOverflow) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("order amount out of range", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrCouponUsageExceeded) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("coupon usage limit reached", err.Error()))
+            return
+        }
+        if err.Error() == "insufficient stock" {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("insufficient stock", err.Error()))
+            return
+        }
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when creating order", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creating order", err.Error()))
         return
@@ -114,7 +159,8 @@ func (h *OrderHandler) SearchOrdersHandler(c *gin.Context) {
         OrderID      int64               `json:"order_id" validate:"omitempty,numeric"`
         CustomerName string              `json:"customer_name" validate:"omitempty"`
         ItemName     string              `json:"item_name" validate:"omitempty"`
-        Status       types.OrderStatus   `json:"status" validate:"omitempty,oneof=order.pending order.shipped order.cancelled order.delivered"`
+        Status       types.OrderStatus   `json:"status" validate:"omitempty,oneof=order.pending order.partially_shipped order.shipped order.cancelled order.delivered"`
+        ReturnStatus types.ReturnStatus  `json:"return_status" validate:"omitempty,oneof=return.requested return.approved return.rejected return.received"`
         Page         int                 `json:"page" validate:"omitempty,numeric"`
         Limit        int                 `json:"limit" validate:"omitempty,numeric"`
     }
@@ -147,6 +193,7 @@ func (h *OrderHandler) SearchOrdersHandler(c *gin.Context) {
         CustomerName: body.CustomerName,
         ItemName:     body.ItemName,
         Status:       body.Status,
+        ReturnStatus: body.ReturnStatus,
         Limit:        body.Limit,
         Offset:       offset,
     }
@@ -185,7 +232,7 @@ func (h *OrderHandler) UpdateOrderStatusHandler(c *gin.Context) {
     }
 
     var body struct {
-        Status types.OrderStatus `json:"status" validate:"required,oneof=order.pending order.shipped order.cancelled order.delivered"`
+        Status types.OrderStatus `json:"status" validate:"required,oneof=order.pending order.partially_shipped order.shipped order.cancelled order.delivered"`
     }
 
     if err := c.ShouldBindJSON(&body); err != nil {
@@ -206,6 +253,10 @@ func (h *OrderHandler) UpdateOrderStatusHandler(c *gin.Context) {
             c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
             return
         }
+        if errors.Is(err, service.ErrInvalidStatusTransition) || errors.Is(err, service.ErrOrderStatusChanged) || errors.Is(err, service.ErrStatusDerivedFromShipments) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order status transition not allowed", err.Error()))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when updating order status", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when updating order status", err.Error()))
         return
@@ -217,6 +268,232 @@ func (h *OrderHandler) UpdateOrderStatusHandler(c *gin.Context) {
     })
 }
 
+func (h *OrderHandler) CancelOrderHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[OrderHandler][CancelOrderHandler]"
+
+    id := c.Param("id")
+    if id == "" {
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("order ID is required", ""))
+        return
+    }
+
+    orderID, err := strconv.ParseInt(id, 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+        return
+    }
+
+    var body struct {
+        Reason types.CancellationReason `json:"reason" validate:"required,oneof=customer_request out_of_stock payment_failed fraud_suspected duplicate_order other"`
+        Note   string                   `json:"note" validate:"omitempty,max=1000"`
+    }
+
+    if err := c.ShouldBindJSON(&body); err != nil {
+        log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+        return
+    }
+
+    if err := validator.ValidateStruct(ctx, body); err.Exists() {
+        log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+        return
+    }
+
+    cancelledOrder, err := h.OrderService.CancelOrder(ctx, orderID, body.Reason, body.Note)
+    if err != nil {
+        if err.Error() == "order not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidStatusTransition) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order cannot be cancelled", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when cancelling order", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when cancelling order", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "order cancelled successfully",
+        "order":   cancelledOrder,
+    })
+}
+
+func (h *OrderHandler) CancelOrderRemainderHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[OrderHandler][CancelOrderRemainderHandler]"
+
+    orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+        return
+    }
+
+    var body struct {
+        Reason types.CancellationReason `json:"reason" validate:"required,oneof=customer_request out_of_stock payment_failed fraud_suspected duplicate_order other"`
+        Note   string                   `json:"note" validate:"omitempty,max=1000"`
+    }
+
+    if err := c.ShouldBindJSON(&body); err != nil {
+        log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+        return
+    }
+
+    if err := validator.ValidateStruct(ctx, body); err.Exists() {
+        log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+        return
+    }
+
+    order, err := h.OrderService.CancelOrderRemainder(ctx, orderID, body.Reason, body.Note)
+    if err != nil {
+        if err.Error() == "order not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidStatusTransition) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order has no unshipped remainder to cancel", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when cancelling unshipped remainder", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when cancelling unshipped remainder", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "unshipped remainder cancelled successfully",
+        "order":   order,
+    })
+}
+
+func (h *OrderHandler) MarkOrderPaidHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[OrderHandler][MarkOrderPaidHandler]"
+
+    orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+        return
+    }
+
+    paidOrder, err := h.OrderService.MarkOrderPaid(ctx, orderID)
+    if err != nil {
+        if err.Error() == "order not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrOrderAlreadyPaid) || errors.Is(err, service.ErrInvalidStatusTransition) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order cannot be paid", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when marking order paid", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when marking order paid", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "order marked paid successfully",
+        "order":   paidOrder,
+    })
+}
+
+func (h *OrderHandler) GetOrderReservationsHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[OrderHandler][GetOrderReservationsHandler]"
+
+    orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+        return
+    }
+
+    reservations, err := h.OrderService.GetOrderReservations(ctx, orderID)
+    if err != nil {
+        if err.Error() == "order not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when getting reservations", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting reservations", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message":      "reservations fetched successfully",
+        "reservations": reservations,
+    })
+}
+
+func (h *OrderHandler) GetOrderLotsHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[OrderHandler][GetOrderLotsHandler]"
+
+    orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+        return
+    }
+
+    lots, err := h.OrderService.GetOrderLots(ctx, orderID)
+    if err != nil {
+        if err.Error() == "order not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when getting order lots", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting order lots", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "order lots fetched successfully",
+        "lots":    lots,
+    })
+}
+
+func (h *OrderHandler) GetOrderTransitionsHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[OrderHandler][GetOrderTransitionsHandler]"
+
+    id := c.Param("id")
+    if id == "" {
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("order ID is required", ""))
+        return
+    }
+
+    orderID, err := strconv.ParseInt(id, 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+        return
+    }
+
+    transitions, err := h.OrderService.GetOrderTransitions(ctx, orderID)
+    if err != nil {
+        if err.Error() == "order not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when getting order transitions", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting order transitions", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message":     "order transitions fetched successfully",
+        "transitions": transitions,
+    })
+}
+
 func (h *OrderHandler) AddOrderItemHandler(c *gin.Context) {
     ctx := c.Request.Context()
     logTag := "[OrderHandler][AddOrderItemHandler]"
@@ -261,6 +538,22 @@ func (h *OrderHandler) AddOrderItemHandler(c *gin.Context) {
             c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("insufficient stock", err.Error()))
             return
         }
+        if errors.Is(err, service.ErrPriceUnavailable) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("product not available in order currency", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrProductNotOrderable) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("product cannot be ordered", err.Error()))
+            return
+        }
+        if errors.Is(err, types.ErrAmountOverflow) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("order amount out of range", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrOrderNotEditable) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order cannot be changed", err.Error()))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when adding order item", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when adding order item", err.Error()))
         return
@@ -324,6 +617,18 @@ func (h *OrderHandler) UpdateOrderItemHandler(c *gin.Context) {
             c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("insufficient stock", err.Error()))
             return
         }
+        if errors.Is(err, service.ErrItemPartlyFulfilled) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order item cannot be reduced below the shipped quantity", err.Error()))
+            return
+        }
+        if errors.Is(err, types.ErrAmountOverflow) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("order amount out of range", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrOrderNotEditable) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order cannot be changed", err.Error()))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when updating order item", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when updating order item", err.Error()))
         return
@@ -367,6 +672,14 @@ func (h *OrderHandler) RemoveOrderItemHandler(c *gin.Context) {
             c.JSON(http.StatusNotFound.Code(), response.ErrorResponse(err.Error(), err.Error()))
             return
         }
+        if errors.Is(err, service.ErrItemPartlyFulfilled) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order item cannot be removed after shipping", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrOrderNotEditable) {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order cannot be changed", err.Error()))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when removing order item", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when removing order item", err.Error()))
         return
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/omniful/go_commons/http"
 	"github.com/omniful/go_commons/log"
 	"github.com/omniful/go_commons/validator"
 	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
 	"github.com/si/internal/utils/response"
 )
 
@@ -30,11 +34,16 @@ func (h *ProductHandler) CreateProductHandler(c *gin.Context) {
 	log.InfofWithContext(ctx, logTag+" creating product ")
 
 	var body struct {
-		Name          string  `json:"name" validate:"required"`
-		SKU           string  `json:"sku" validate:"required,alphanum"`
-		Price         float64 `json:"price" validate:"required,numeric"`
-		Category      string  `json:"category" validate:"required,alpha"`
-		StockQuantity int64   `json:"stock_quantity" validate:"required,numeric"`
+		Name            string                  `json:"name" validate:"required"`
+		SKU             string                  `json:"sku" validate:"required,alphanum"`
+		Price           types.Amount            `json:"price" validate:"required,min=0"`
+		CategoryID      int64                   `json:"category_id" validate:"required,min=1"`
+		StockQuantity   int64                   `json:"stock_quantity" validate:"min=0"`
+		ReorderPoint    int64                   `json:"reorder_point" validate:"min=0"`
+		ReorderQuantity int64                   `json:"reorder_quantity" validate:"min=0"`
+		IsSerialized    bool                    `json:"is_serialized"`
+		OptionAxes      []string                `json:"option_axes" validate:"omitempty,max=5,dive,required,max=50"`
+		Attributes      types.ProductAttributes `json:"attributes" validate:"omitempty,max=50"`
 	}
 
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -49,8 +58,24 @@ func (h *ProductHandler) CreateProductHandler(c *gin.Context) {
 		return
 	}
 
-	prod, err := h.ProductService.CreateProduct(ctx, body.Name, body.SKU, body.Price, body.Category, body.StockQuantity)
+	prod, err := h.ProductService.CreateProduct(ctx, body.Name, body.SKU, body.Price, body.CategoryID, body.StockQuantity, body.ReorderPoint, body.ReorderQuantity, body.IsSerialized, body.OptionAxes, body.Attributes)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidVariant) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product variant", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCategory) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidAttributes) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product attributes", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSerialNumbers) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid serial numbers", err.Error()))
+			return
+		}
 		log.ErrorfWithContext(ctx, logTag+" error when creating product")
 		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creaitng product", err.Error()))
 		return
@@ -62,6 +87,103 @@ func (h *ProductHandler) CreateProductHandler(c *gin.Context) {
 	})
 }
 
+func (h *ProductHandler) CreateVariantHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ProductHandler][CreateVariantHandler]"
+
+	parentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		SKU             string                  `json:"sku" validate:"required,alphanum"`
+		Options         types.ProductOptions    `json:"options" validate:"required,min=1"`
+		PriceOverride   *types.Amount           `json:"price_override" validate:"omitempty,min=0"`
+		StockQuantity   int64                   `json:"stock_quantity" validate:"min=0"`
+		ReorderPoint    int64                   `json:"reorder_point" validate:"min=0"`
+		ReorderQuantity int64                   `json:"reorder_quantity" validate:"min=0"`
+		Attributes      types.ProductAttributes `json:"attributes" validate:"omitempty,max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	variant, err := h.ProductService.CreateVariant(ctx, parentID, body.SKU, body.Options, body.PriceOverride, body.StockQuantity, body.ReorderPoint, body.ReorderQuantity, body.Attributes)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "product not found") {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidVariant) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product variant", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidAttributes) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product attributes", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSerialNumbers) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid serial numbers", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when creating variant", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creating variant", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message": "variant created successfully",
+		"product": variant,
+	})
+}
+
+func (h *ProductHandler) GetProductVariantsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ProductHandler][GetProductVariantsHandler]"
+
+	parentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	group, err := h.ProductService.GetProductVariants(ctx, parentID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "product not found") {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidVariant) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product variant", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting variants", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching variants", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":            "variants fetched successfully",
+		"product":            group.Product,
+		"variants":           group.Variants,
+		"available_quantity": group.AvailableQuantity,
+		"in_stock_variants":  group.InStockVariants,
+	})
+}
+
 func (h *ProductHandler) GetProductByIdHandler(c *gin.Context){
 	ctx := c.Request.Context()
 	logTag := "[ProductHandler][GetProductByIdHandler]"
@@ -130,10 +252,14 @@ func (h *ProductHandler) SearchProductsHandler(c *gin.Context) {
     logTag := "[ProductHandler][SearchProductsHandler]"
 
     var body struct {
-        Name     string `json:"name" validate:"omitempty"`
-        Category string `json:"category" validate:"omitempty"`
-        Page     int    `json:"page" validate:"omitempty,numeric"`
-        Limit    int    `json:"limit" validate:"omitempty,numeric"`
+        Query              string                  `json:"query" validate:"omitempty,max=200"`
+        Name               string                  `json:"name" validate:"omitempty"`
+        CategoryID         int64                   `json:"category_id" validate:"omitempty,min=1"`
+        IncludeDescendants bool                    `json:"include_descendants"`
+        Attributes         []types.AttributeFilter `json:"attributes" validate:"omitempty,max=20"`
+        Currency           string                  `json:"currency" validate:"omitempty,len=3,uppercase"`
+        Page               int                     `json:"page" validate:"omitempty,numeric"`
+        Limit              int                     `json:"limit" validate:"omitempty,numeric"`
     }
 
     if err := c.ShouldBindJSON(&body); err != nil {
@@ -157,8 +283,23 @@ func (h *ProductHandler) SearchProductsHandler(c *gin.Context) {
 
     offset := (body.Page - 1) * body.Limit
 
-    products, total, err := h.ProductService.SearchProduct(ctx, body.Name, body.Category, body.Limit, offset)
+    // name is the older spelling of query
+    if body.Query == "" {
+        body.Query = body.Name
+    }
+
+    // parents come back grouped with their variants, total counts the groups and the facets count them over
+    // all pages
+    result, err := h.ProductService.SearchProduct(ctx, body.Query, body.CategoryID, body.IncludeDescendants, body.Attributes, body.Currency, body.Limit, offset)
     if err != nil {
+        if err.Error() == "category not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("category not found", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidAttributes) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid attribute filter", err.Error()))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when searching products", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when searching products", err.Error()))
         return
@@ -166,8 +307,10 @@ func (h *ProductHandler) SearchProductsHandler(c *gin.Context) {
 
     c.JSON(http.StatusOK.Code(), gin.H{
         "message":  "products search completed",
-        "products": products,
-        "total":    total,
+        "products": result.Groups,
+        "total":    result.Total,
+        "facets":   result.Facets,
+        "fuzzy":    result.Fuzzy,
         "page":     body.Page,
         "limit":    body.Limit,
     })
@@ -192,10 +335,14 @@ func (h *ProductHandler) UpdateProductHandler(c *gin.Context) {
     }
 
     var body struct {
-        Name          string  `json:"name" validate:"omitempty"`
-        Price         float64 `json:"price" validate:"omitempty,numeric"`
-        Category      string  `json:"category" validate:"omitempty,alpha"`
-        StockQuantity int64   `json:"stock_quantity" validate:"omitempty,numeric"`
+        Name            string                  `json:"name" validate:"omitempty"`
+        Price           types.Amount            `json:"price" validate:"omitempty,min=0"`
+        CategoryID      *int64                  `json:"category_id" validate:"omitempty,min=1"`
+        StockQuantity   *int64                  `json:"stock_quantity" validate:"omitempty,min=0"`
+        ReorderPoint    *int64                  `json:"reorder_point" validate:"omitempty,min=0"`
+        ReorderQuantity *int64                  `json:"reorder_quantity" validate:"omitempty,min=0"`
+        IsSerialized    *bool                   `json:"is_serialized"`
+        Attributes      types.ProductAttributes `json:"attributes" validate:"omitempty,max=50"`
     }
 
     if err := c.ShouldBindJSON(&body); err != nil {
@@ -210,8 +357,32 @@ func (h *ProductHandler) UpdateProductHandler(c *gin.Context) {
         return
     }
 
-    updatedProduct, err := h.ProductService.UpdateProduct(ctx, productID, body.Name, body.Price, body.Category, body.StockQuantity)
+    updatedProduct, err := h.ProductService.UpdateProduct(ctx, productID, body.Name, body.Price, body.CategoryID, body.StockQuantity, body.ReorderPoint, body.ReorderQuantity, body.IsSerialized, body.Attributes)
     if err != nil {
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        if err.Error() == "insufficient stock" {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("insufficient stock", "stock cannot drop below the quantity reserved by open orders"))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidVariant) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product variant", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidCategory) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidAttributes) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product attributes", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidSerialNumbers) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid serial numbers", err.Error()))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when updating product", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when updating product", err.Error()))
         return
@@ -274,8 +445,12 @@ func (h *ProductHandler) UpdateInventoryHandler(c *gin.Context) {
     }
 
     var body struct {
-        StockQuantity int64  `json:"stock_quantity" validate:"required,numeric"`
-        Operation     string `json:"operation" validate:"required,oneof=set add subtract"`
+        StockQuantity int64      `json:"stock_quantity" validate:"required,numeric,min=0"`
+        Operation     string     `json:"operation" validate:"required,oneof=set add subtract"`
+        Note          string     `json:"note" validate:"omitempty,max=500"`
+        LocationID    int64      `json:"location_id" validate:"omitempty,min=1"`
+        LotNumber     string     `json:"lot_number" validate:"omitempty,max=100"`
+        ExpiresAt     *time.Time `json:"expires_at"`
     }
 
     if err := c.ShouldBindJSON(&body); err != nil {
@@ -290,8 +465,32 @@ func (h *ProductHandler) UpdateInventoryHandler(c *gin.Context) {
         return
     }
 
-    updatedProduct, err := h.ProductService.UpdateInventory(ctx, productID, body.StockQuantity, body.Operation)
+    updatedProduct, err := h.ProductService.UpdateInventory(ctx, productID, body.LocationID, body.StockQuantity, body.Operation, body.Note, body.LotNumber, body.ExpiresAt)
     if err != nil {
+        if errors.Is(err, service.ErrInvalidLot) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid stock lot", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidVariant) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product variant", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidSerialNumbers) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid serial numbers", err.Error()))
+            return
+        }
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        if err.Error() == "location not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("location not found", err.Error()))
+            return
+        }
+        if err.Error() == "insufficient stock" {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("insufficient stock", "stock cannot drop below the quantity reserved by open orders"))
+            return
+        }
         log.ErrorfWithContext(ctx, logTag+" error when updating inventory", err)
         c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when updating inventory", err.Error()))
         return
@@ -303,3 +502,329 @@ func (h *ProductHandler) UpdateInventoryHandler(c *gin.Context) {
     })
 }
 
+func (h *ProductHandler) SetProductPriceHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][SetProductPriceHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    var body struct {
+        Currency string       `json:"currency" validate:"required,len=3,uppercase"`
+        Price    types.Amount `json:"price" validate:"required,min=0"`
+    }
+
+    if err := c.ShouldBindJSON(&body); err != nil {
+        log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+        return
+    }
+
+    if err := validator.ValidateStruct(ctx, body); err.Exists() {
+        log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+        return
+    }
+
+    price, err := h.ProductService.SetProductPrice(ctx, productID, body.Currency, body.Price)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        if err.Error() == "base currency price is set through the product itself" {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse(err.Error(), err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when setting product price", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when setting product price", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "product price saved successfully",
+        "price":   price,
+    })
+}
+
+func (h *ProductHandler) GetProductPricesHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][GetProductPricesHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    prices, err := h.ProductService.GetProductPrices(ctx, productID)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when getting product prices", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting product prices", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "product prices fetched successfully",
+        "prices":  prices,
+    })
+}
+
+func (h *ProductHandler) DeleteProductPriceHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][DeleteProductPriceHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    err = h.ProductService.DeleteProductPrice(ctx, productID, strings.ToUpper(c.Param("currency")))
+    if err != nil {
+        if err.Error() == "product price not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product price not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when deleting product price", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when deleting product price", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "product price deleted successfully",
+    })
+}
+
+// schedules a price for a future moment, currency defaults to the product's own. With ends_at it is a sale
+// and the price before it comes back when it ends
+func (h *ProductHandler) SchedulePriceChangeHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][SchedulePriceChangeHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    var body struct {
+        Currency      string       `json:"currency" validate:"omitempty,len=3,uppercase"`
+        Price         types.Amount `json:"price" validate:"required,min=0"`
+        EffectiveFrom time.Time    `json:"effective_from" validate:"required"`
+        EndsAt        *time.Time   `json:"ends_at"`
+        Note          string       `json:"note" validate:"omitempty,max=500"`
+    }
+
+    if err := c.ShouldBindJSON(&body); err != nil {
+        log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+        return
+    }
+
+    if err := validator.ValidateStruct(ctx, body); err.Exists() {
+        log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+        return
+    }
+
+    change, err := h.ProductService.SchedulePriceChange(ctx, productID, body.Currency, body.Price, body.EffectiveFrom, body.EndsAt, body.Note)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        if errors.Is(err, service.ErrInvalidPriceChange) {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid price change", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when scheduling price change", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when scheduling price change", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusCreated.Code(), gin.H{
+        "message":      "price change scheduled successfully",
+        "price_change": change,
+    })
+}
+
+func (h *ProductHandler) GetScheduledPriceChangesHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][GetScheduledPriceChangesHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    changes, err := h.ProductService.GetScheduledPriceChanges(ctx, productID)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when getting scheduled price changes", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting scheduled price changes", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message":       "scheduled price changes fetched successfully",
+        "price_changes": changes,
+    })
+}
+
+func (h *ProductHandler) CancelPriceChangeHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][CancelPriceChangeHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    changeID, err := strconv.ParseInt(c.Param("change_id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid price change ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid price change ID format", err.Error()))
+        return
+    }
+
+    change, err := h.ProductService.CancelPriceChange(ctx, productID, changeID)
+    if err != nil {
+        if err.Error() == "price change not found" {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("price change not found", err.Error()))
+            return
+        }
+        if strings.HasPrefix(err.Error(), "price change is already") {
+            c.JSON(http.StatusConflict.Code(), response.ErrorResponse("price change is not scheduled", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when cancelling price change", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when cancelling price change", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message":      "price change cancelled successfully",
+        "price_change": change,
+    })
+}
+
+// the prices a product had newest first, optionally in one currency. With at (RFC 3339) only the price in
+// effect at that moment is returned, such as when an order was placed
+func (h *ProductHandler) GetPriceHistoryHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][GetPriceHistoryHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    currency := strings.ToUpper(c.Query("currency"))
+    if currency != "" && len(currency) != 3 {
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid currency", "currency must be a 3 letter ISO 4217 code"))
+        return
+    }
+
+    if raw := c.Query("at"); raw != "" {
+        at, err := time.Parse(time.RFC3339, raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid at", "at must be an RFC 3339 timestamp"))
+            return
+        }
+
+        change, err := h.ProductService.GetPriceAt(ctx, productID, currency, at)
+        if err != nil {
+            if strings.HasPrefix(err.Error(), "product not found") || strings.HasPrefix(err.Error(), "no recorded price") {
+                c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("price not found", err.Error()))
+                return
+            }
+            log.ErrorfWithContext(ctx, logTag+" error when getting price", err)
+            c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting price", err.Error()))
+            return
+        }
+
+        c.JSON(http.StatusOK.Code(), gin.H{
+            "message": "price fetched successfully",
+            "price":   change,
+        })
+        return
+    }
+
+    page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+    if page < 1 {
+        page = 1
+    }
+    if limit < 1 || limit > 100 {
+        limit = 10
+    }
+
+    history, total, err := h.ProductService.GetPriceHistory(ctx, productID, currency, limit, (page-1)*limit)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when getting price history", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting price history", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "price history fetched successfully",
+        "history": history,
+        "total":   total,
+        "page":    page,
+        "limit":   limit,
+    })
+}
+
+func (h *ProductHandler) GetProductStocksHandler(c *gin.Context) {
+    ctx := c.Request.Context()
+    logTag := "[ProductHandler][GetProductStocksHandler]"
+
+    productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+        c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+        return
+    }
+
+    stocks, err := h.ProductService.GetProductStocks(ctx, productID)
+    if err != nil {
+        if strings.HasPrefix(err.Error(), "product not found") {
+            c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+            return
+        }
+        log.ErrorfWithContext(ctx, logTag+" error when getting location stock", err)
+        c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting location stock", err.Error()))
+        return
+    }
+
+    c.JSON(http.StatusOK.Code(), gin.H{
+        "message": "location stock fetched successfully",
+        "stocks":  stocks,
+    })
+}
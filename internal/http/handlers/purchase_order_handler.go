@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type PurchaseOrderHandler struct {
+	PurchaseOrderService *service.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(purchaseOrderService *service.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{
+		PurchaseOrderService: purchaseOrderService,
+	}
+}
+
+func (h *PurchaseOrderHandler) CreatePurchaseOrderHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[PurchaseOrderHandler][CreatePurchaseOrderHandler]"
+
+	var body struct {
+		SupplierID int64      `json:"supplier_id" validate:"required,min=1"`
+		LocationID int64      `json:"location_id" validate:"omitempty,min=1"`
+		Currency   string     `json:"currency" validate:"omitempty,len=3,uppercase"`
+		ExpectedAt *time.Time `json:"expected_at"`
+		Note       string     `json:"note" validate:"omitempty,max=1000"`
+		Lines      []struct {
+			ProductID  int64        `json:"product_id" validate:"required,min=1"`
+			Quantity   int64        `json:"quantity" validate:"required,min=1"`
+			UnitCost   types.Amount `json:"unit_cost" validate:"min=0"`
+			ExpectedAt *time.Time   `json:"expected_at"`
+		} `json:"lines" validate:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	var lines []types.PurchaseOrderLineRequest
+	for _, line := range body.Lines {
+		lines = append(lines, types.PurchaseOrderLineRequest{
+			ProductID:  line.ProductID,
+			Quantity:   line.Quantity,
+			UnitCost:   line.UnitCost,
+			ExpectedAt: line.ExpectedAt,
+		})
+	}
+
+	po, err := h.PurchaseOrderService.CreatePurchaseOrder(ctx, body.SupplierID, body.LocationID, body.Currency, body.ExpectedAt, body.Note, lines)
+	if err != nil {
+		h.writePurchaseOrderError(c, logTag, "error when creating purchase order", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":        "purchase order created successfully",
+		"purchase_order": po,
+	})
+}
+
+func (h *PurchaseOrderHandler) SearchPurchaseOrdersHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[PurchaseOrderHandler][SearchPurchaseOrdersHandler]"
+
+	status := types.PurchaseOrderStatus(c.Query("status"))
+	if status != "" && !status.IsValid() {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid status", "unknown purchase order status "+string(status)))
+		return
+	}
+
+	delivery := types.PurchaseOrderDelivery(c.Query("delivery"))
+	if delivery != "" && delivery != types.PurchaseOrderDeliveryOver && delivery != types.PurchaseOrderDeliveryUnder && delivery != types.PurchaseOrderDeliveryOverdue {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid delivery", "delivery must be over, under or overdue"))
+		return
+	}
+
+	var supplierID int64
+	if raw := c.Query("supplier_id"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" invalid supplier ID format", err)
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid supplier ID format", err.Error()))
+			return
+		}
+		supplierID = parsed
+	}
+
+	var productID int64
+	if raw := c.Query("product_id"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+			return
+		}
+		productID = parsed
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	orders, total, err := h.PurchaseOrderService.SearchPurchaseOrders(ctx, types.PurchaseOrderSearchParams{
+		Status:     status,
+		SupplierID: supplierID,
+		ProductID:  productID,
+		Delivery:   delivery,
+		Limit:      limit,
+		Offset:     (page - 1) * limit,
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when searching purchase orders", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching purchase orders", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":         "purchase orders fetched successfully",
+		"purchase_orders": orders,
+		"total":           total,
+		"page":            page,
+		"limit":           limit,
+	})
+}
+
+func (h *PurchaseOrderHandler) GetPurchaseOrderHandler(c *gin.Context) {
+	logTag := "[PurchaseOrderHandler][GetPurchaseOrderHandler]"
+
+	id, ok := parsePurchaseOrderPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	po, err := h.PurchaseOrderService.GetPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		h.writePurchaseOrderError(c, logTag, "error when getting purchase order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "purchase order fetched successfully",
+		"purchase_order": po,
+	})
+}
+
+func (h *PurchaseOrderHandler) SubmitPurchaseOrderHandler(c *gin.Context) {
+	logTag := "[PurchaseOrderHandler][SubmitPurchaseOrderHandler]"
+
+	id, ok := parsePurchaseOrderPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	po, err := h.PurchaseOrderService.SubmitPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		h.writePurchaseOrderError(c, logTag, "error when submitting purchase order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "purchase order submitted successfully",
+		"purchase_order": po,
+	})
+}
+
+func (h *PurchaseOrderHandler) CancelPurchaseOrderHandler(c *gin.Context) {
+	logTag := "[PurchaseOrderHandler][CancelPurchaseOrderHandler]"
+
+	id, ok := parsePurchaseOrderPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	po, err := h.PurchaseOrderService.CancelPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		h.writePurchaseOrderError(c, logTag, "error when cancelling purchase order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "purchase order cancelled successfully",
+		"purchase_order": po,
+	})
+}
+
+func (h *PurchaseOrderHandler) ClosePurchaseOrderHandler(c *gin.Context) {
+	logTag := "[PurchaseOrderHandler][ClosePurchaseOrderHandler]"
+
+	id, ok := parsePurchaseOrderPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	po, err := h.PurchaseOrderService.ClosePurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		h.writePurchaseOrderError(c, logTag, "error when closing purchase order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "purchase order closed successfully",
+		"purchase_order": po,
+	})
+}
+
+func (h *PurchaseOrderHandler) ReceiveGoodsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[PurchaseOrderHandler][ReceiveGoodsHandler]"
+
+	id, ok := parsePurchaseOrderPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Note  string `json:"note" validate:"omitempty,max=1000"`
+		Items []struct {
+			ProductID int64      `json:"product_id" validate:"required,min=1"`
+			Quantity  int64      `json:"quantity" validate:"required,min=1"`
+			LotNumber string     `json:"lot_number" validate:"omitempty,max=100"`
+			ExpiresAt *time.Time `json:"expires_at"`
+
+			SerialNumbers []string `json:"serial_numbers" validate:"omitempty,dive,required,max=100"`
+		} `json:"items" validate:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	var items []types.GoodsReceiptLineRequest
+	for _, item := range body.Items {
+		items = append(items, types.GoodsReceiptLineRequest{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			LotNumber: item.LotNumber,
+			ExpiresAt: item.ExpiresAt,
+
+			SerialNumbers: item.SerialNumbers,
+		})
+	}
+
+	po, err := h.PurchaseOrderService.ReceiveGoods(ctx, id, items, body.Note)
+	if err != nil {
+		h.writePurchaseOrderError(c, logTag, "error when receiving goods", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":        "goods received successfully",
+		"purchase_order": po,
+	})
+}
+
+func parsePurchaseOrderPath(c *gin.Context, logTag string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(c.Request.Context(), logTag+" invalid purchase order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid purchase order ID format", err.Error()))
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (h *PurchaseOrderHandler) writePurchaseOrderError(c *gin.Context, logTag, message string, err error) {
+	switch {
+	case err.Error() == "purchase order not found" || err.Error() == "supplier not found" || err.Error() == "location not found" || strings.HasPrefix(err.Error(), "product not found"):
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse(err.Error(), err.Error()))
+	case errors.Is(err, service.ErrInvalidPurchaseOrder) || errors.Is(err, service.ErrInvalidGoodsReceipt):
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse(message, err.Error()))
+	case errors.Is(err, service.ErrInvalidPurchaseOrderTransition):
+		c.JSON(http.StatusConflict.Code(), response.ErrorResponse(message, err.Error()))
+	default:
+		log.ErrorfWithContext(c.Request.Context(), logTag+" "+message, err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse(message, err.Error()))
+	}
+}
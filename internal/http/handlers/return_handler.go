@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type ReturnHandler struct {
+	ReturnService *service.ReturnService
+}
+
+func NewReturnHandler(returnService *service.ReturnService) *ReturnHandler {
+	return &ReturnHandler{
+		ReturnService: returnService,
+	}
+}
+
+func (h *ReturnHandler) CreateReturnHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ReturnHandler][CreateReturnHandler]"
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason" validate:"required,max=1000"`
+		Items  []struct {
+			OrderItemID int64 `json:"order_item_id" validate:"required,numeric"`
+			Quantity    int32 `json:"quantity" validate:"required,numeric,min=1"`
+		} `json:"items" validate:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	var items []types.ReturnItemRequest
+	for _, item := range body.Items {
+		items = append(items, types.ReturnItemRequest{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	ret, err := h.ReturnService.RequestReturn(ctx, orderID, body.Reason, items)
+	if err != nil {
+		h.writeReturnError(c, logTag, "error when requesting return", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message": "return requested successfully",
+		"return":  ret,
+	})
+}
+
+func (h *ReturnHandler) GetOrderReturnsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ReturnHandler][GetOrderReturnsHandler]"
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+		return
+	}
+
+	returns, err := h.ReturnService.GetOrderReturns(ctx, orderID)
+	if err != nil {
+		h.writeReturnError(c, logTag, "error when getting returns", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "returns fetched successfully",
+		"returns": returns,
+	})
+}
+
+func (h *ReturnHandler) GetReturnHandler(c *gin.Context) {
+	logTag := "[ReturnHandler][GetReturnHandler]"
+
+	orderID, returnID, ok := parseReturnPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	ret, err := h.ReturnService.GetReturn(c.Request.Context(), orderID, returnID)
+	if err != nil {
+		h.writeReturnError(c, logTag, "error when getting return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "return fetched successfully",
+		"return":  ret,
+	})
+}
+
+func (h *ReturnHandler) ApproveReturnHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ReturnHandler][ApproveReturnHandler]"
+
+	orderID, returnID, ok := parseReturnPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Note string `json:"note" validate:"omitempty,max=1000"`
+	}
+
+	// the note is optional so an empty body is accepted
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+			return
+		}
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	ret, err := h.ReturnService.ApproveReturn(ctx, orderID, returnID, body.Note)
+	if err != nil {
+		h.writeReturnError(c, logTag, "error when approving return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "return approved successfully",
+		"return":  ret,
+	})
+}
+
+func (h *ReturnHandler) RejectReturnHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ReturnHandler][RejectReturnHandler]"
+
+	orderID, returnID, ok := parseReturnPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Note string `json:"note" validate:"required,max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	ret, err := h.ReturnService.RejectReturn(ctx, orderID, returnID, body.Note)
+	if err != nil {
+		h.writeReturnError(c, logTag, "error when rejecting return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "return rejected successfully",
+		"return":  ret,
+	})
+}
+
+func (h *ReturnHandler) ReceiveReturnHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ReturnHandler][ReceiveReturnHandler]"
+
+	orderID, returnID, ok := parseReturnPath(c, logTag)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Restock bool                      `json:"restock"`
+		Items   []types.ReturnReceiptItem `json:"items"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	ret, err := h.ReturnService.ReceiveReturn(ctx, orderID, returnID, body.Restock, body.Items)
+	if err != nil {
+		h.writeReturnError(c, logTag, "error when receiving return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "return received successfully",
+		"return":  ret,
+	})
+}
+
+func parseReturnPath(c *gin.Context, logTag string) (int64, int64, bool) {
+	ctx := c.Request.Context()
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+		return 0, 0, false
+	}
+
+	returnID, err := strconv.ParseInt(c.Param("return_id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid return ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid return ID format", err.Error()))
+		return 0, 0, false
+	}
+
+	return orderID, returnID, true
+}
+
+func (h *ReturnHandler) writeReturnError(c *gin.Context, logTag, message string, err error) {
+	switch {
+	case err.Error() == "order not found" || err.Error() == "return not found" || err.Error() == "order item not found":
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse(err.Error(), err.Error()))
+	case errors.Is(err, service.ErrInvalidReturnQuantity):
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid return quantity", err.Error()))
+	case errors.Is(err, service.ErrInvalidSerialNumbers):
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid serial numbers", err.Error()))
+	case errors.Is(err, service.ErrOrderNotReturnable) || errors.Is(err, service.ErrInvalidReturnTransition):
+		c.JSON(http.StatusConflict.Code(), response.ErrorResponse(message, err.Error()))
+	default:
+		log.ErrorfWithContext(c.Request.Context(), logTag+" "+message, err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse(message, err.Error()))
+	}
+}
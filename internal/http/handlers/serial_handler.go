@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type SerialHandler struct {
+	SerialService *service.SerialService
+}
+
+func NewSerialHandler(serialService *service.SerialService) *SerialHandler {
+	return &SerialHandler{
+		SerialService: serialService,
+	}
+}
+
+func (h *SerialHandler) RegisterSerialsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SerialHandler][RegisterSerialsHandler]"
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		LocationID    int64    `json:"location_id" validate:"omitempty,min=1"`
+		SerialNumbers []string `json:"serial_numbers" validate:"required,min=1,max=1000,dive,required,max=100"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	serials, err := h.SerialService.RegisterSerials(ctx, productID, body.LocationID, body.SerialNumbers)
+	if err != nil {
+		h.writeSerialError(c, logTag, "error when registering serial numbers", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":        "serial numbers registered successfully",
+		"serial_numbers": serials,
+	})
+}
+
+func (h *SerialHandler) GetProductSerialsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SerialHandler][GetProductSerialsHandler]"
+
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid product ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid product ID format", err.Error()))
+		return
+	}
+
+	status := types.SerialNumberStatus(c.Query("status"))
+	if status != "" && !status.IsValid() {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid status", "status must be serial.in_stock or serial.shipped"))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	serials, total, err := h.SerialService.GetProductSerials(ctx, productID, status, limit, (page-1)*limit)
+	if err != nil {
+		h.writeSerialError(c, logTag, "error when fetching serial numbers", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "serial numbers fetched successfully",
+		"serial_numbers": serials,
+		"total":          total,
+		"page":           page,
+		"limit":          limit,
+	})
+}
+
+func (h *SerialHandler) GetOrderSerialsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SerialHandler][GetOrderSerialsHandler]"
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+		return
+	}
+
+	serials, err := h.SerialService.GetOrderSerials(ctx, orderID)
+	if err != nil {
+		h.writeSerialError(c, logTag, "error when fetching serial numbers", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "serial numbers fetched successfully",
+		"serial_numbers": serials,
+	})
+}
+
+func (h *SerialHandler) LookupSerialHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SerialHandler][LookupSerialHandler]"
+
+	serialNumber := strings.TrimSpace(c.Param("serial_number"))
+	if serialNumber == "" {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("serial number is required", ""))
+		return
+	}
+
+	lookups, err := h.SerialService.LookupSerial(ctx, serialNumber)
+	if err != nil {
+		h.writeSerialError(c, logTag, "error when looking up serial number", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":        "serial number found",
+		"serial_numbers": lookups,
+	})
+}
+
+func (h *SerialHandler) writeSerialError(c *gin.Context, logTag, message string, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "product not found"):
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("product not found", err.Error()))
+	case err.Error() == "order not found" || err.Error() == "location not found" || err.Error() == "serial number not found":
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse(err.Error(), err.Error()))
+	case errors.Is(err, service.ErrInvalidSerialNumbers):
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid serial numbers", err.Error()))
+	default:
+		log.ErrorfWithContext(c.Request.Context(), logTag+" "+message, err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse(message, err.Error()))
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type ShipmentHandler struct {
+	ShipmentService *service.ShipmentService
+}
+
+func NewShipmentHandler(shipmentService *service.ShipmentService) *ShipmentHandler {
+	return &ShipmentHandler{
+		ShipmentService: shipmentService,
+	}
+}
+
+func (h *ShipmentHandler) CreateShipmentHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ShipmentHandler][CreateShipmentHandler]"
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		Carrier        string `json:"carrier" validate:"required,max=100"`
+		TrackingNumber string `json:"tracking_number" validate:"required,max=255"`
+		Items          []struct {
+			OrderItemID   int64    `json:"order_item_id" validate:"required,numeric"`
+			Quantity      int32    `json:"quantity" validate:"required,numeric,min=1"`
+			SerialNumbers []string `json:"serial_numbers" validate:"omitempty,dive,required,max=100"`
+		} `json:"items" validate:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	var items []types.ShipmentItemRequest
+	for _, item := range body.Items {
+		items = append(items, types.ShipmentItemRequest{
+			OrderItemID:   item.OrderItemID,
+			Quantity:      item.Quantity,
+			SerialNumbers: item.SerialNumbers,
+		})
+	}
+
+	shipment, err := h.ShipmentService.CreateShipment(ctx, orderID, body.Carrier, body.TrackingNumber, items)
+	if err != nil {
+		switch {
+		case err.Error() == "order not found" || err.Error() == "order item not found":
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse(err.Error(), err.Error()))
+		case errors.Is(err, service.ErrInvalidShipmentQuantity):
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid shipment quantity", err.Error()))
+		case errors.Is(err, service.ErrInvalidSerialNumbers):
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid serial numbers", err.Error()))
+		case errors.Is(err, service.ErrOrderNotShippable):
+			c.JSON(http.StatusConflict.Code(), response.ErrorResponse("order cannot be shipped", err.Error()))
+		default:
+			log.ErrorfWithContext(ctx, logTag+" error when creating shipment", err)
+			c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creating shipment", err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":  "shipment created successfully",
+		"shipment": shipment,
+	})
+}
+
+func (h *ShipmentHandler) GetOrderShipmentsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ShipmentHandler][GetOrderShipmentsHandler]"
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+		return
+	}
+
+	shipments, err := h.ShipmentService.GetOrderShipments(ctx, orderID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("order not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting shipments", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when getting shipments", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":   "shipments fetched successfully",
+		"shipments": shipments,
+	})
+}
+
+func (h *ShipmentHandler) DeliverShipmentHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[ShipmentHandler][DeliverShipmentHandler]"
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid order ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid order ID format", err.Error()))
+		return
+	}
+
+	shipmentID, err := strconv.ParseInt(c.Param("shipment_id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid shipment ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid shipment ID format", err.Error()))
+		return
+	}
+
+	shipment, err := h.ShipmentService.MarkShipmentDelivered(ctx, orderID, shipmentID)
+	if err != nil {
+		switch {
+		case err.Error() == "order not found" || err.Error() == "shipment not found":
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse(err.Error(), err.Error()))
+		case errors.Is(err, service.ErrShipmentAlreadyDelivered):
+			c.JSON(http.StatusConflict.Code(), response.ErrorResponse("shipment already delivered", err.Error()))
+		default:
+			log.ErrorfWithContext(ctx, logTag+" error when delivering shipment", err)
+			c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when delivering shipment", err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":  "shipment marked as delivered",
+		"shipment": shipment,
+	})
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type StockTakeHandler struct {
+	StockTakeService *service.StockTakeService
+}
+
+func NewStockTakeHandler(stockTakeService *service.StockTakeService) *StockTakeHandler {
+	return &StockTakeHandler{
+		StockTakeService: stockTakeService,
+	}
+}
+
+func (h *StockTakeHandler) OpenStockTakeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[StockTakeHandler][OpenStockTakeHandler]"
+
+	var body struct {
+		LocationID int64   `json:"location_id" validate:"omitempty,min=1"`
+		ProductIDs []int64 `json:"product_ids" validate:"required,min=1,max=1000,dive,min=1"`
+		Note       string  `json:"note" validate:"omitempty,max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	take, err := h.StockTakeService.OpenStockTake(ctx, body.LocationID, body.ProductIDs, body.Note)
+	if err != nil {
+		h.writeStockTakeError(c, logTag, "error when opening stock take", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":    "stock take opened successfully",
+		"stock_take": take,
+	})
+}
+
+func (h *StockTakeHandler) SearchStockTakesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[StockTakeHandler][SearchStockTakesHandler]"
+
+	status := types.StockTakeStatus(c.Query("status"))
+	if status != "" && !status.IsValid() {
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid status", "status must be stock_take.open, stock_take.posted or stock_take.cancelled"))
+		return
+	}
+
+	var locationID int64
+	if raw := c.Query("location_id"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" invalid location ID format", err)
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid location ID format", err.Error()))
+			return
+		}
+		locationID = parsed
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	takes, total, err := h.StockTakeService.SearchStockTakes(ctx, status, locationID, limit, (page-1)*limit)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when searching stock takes", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching stock takes", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":     "stock takes fetched successfully",
+		"stock_takes": takes,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+	})
+}
+
+func (h *StockTakeHandler) GetStockTakeHandler(c *gin.Context) {
+	logTag := "[StockTakeHandler][GetStockTakeHandler]"
+
+	id, ok := parseStockTakePath(c, logTag)
+	if !ok {
+		return
+	}
+
+	take, err := h.StockTakeService.GetStockTake(c.Request.Context(), id)
+	if err != nil {
+		h.writeStockTakeError(c, logTag, "error when getting stock take", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":    "stock take fetched successfully",
+		"stock_take": take,
+	})
+}
+
+func (h *StockTakeHandler) RecordCountsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[StockTakeHandler][RecordCountsHandler]"
+
+	id, ok := parseStockTakePath(c, logTag)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Counts []struct {
+			ProductID       int64  `json:"product_id" validate:"required,min=1"`
+			CountedQuantity *int64 `json:"counted_quantity" validate:"required,min=0"`
+		} `json:"counts" validate:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	var counts []types.StockTakeCountRequest
+	for _, count := range body.Counts {
+		counts = append(counts, types.StockTakeCountRequest{
+			ProductID:       count.ProductID,
+			CountedQuantity: *count.CountedQuantity,
+		})
+	}
+
+	take, err := h.StockTakeService.RecordCounts(ctx, id, counts)
+	if err != nil {
+		h.writeStockTakeError(c, logTag, "error when recording counts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":    "counts recorded successfully",
+		"stock_take": take,
+	})
+}
+
+func (h *StockTakeHandler) PostStockTakeHandler(c *gin.Context) {
+	logTag := "[StockTakeHandler][PostStockTakeHandler]"
+
+	id, ok := parseStockTakePath(c, logTag)
+	if !ok {
+		return
+	}
+
+	take, err := h.StockTakeService.PostStockTake(c.Request.Context(), id)
+	if err != nil {
+		h.writeStockTakeError(c, logTag, "error when posting stock take", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":    "stock take posted successfully",
+		"stock_take": take,
+	})
+}
+
+func (h *StockTakeHandler) CancelStockTakeHandler(c *gin.Context) {
+	logTag := "[StockTakeHandler][CancelStockTakeHandler]"
+
+	id, ok := parseStockTakePath(c, logTag)
+	if !ok {
+		return
+	}
+
+	take, err := h.StockTakeService.CancelStockTake(c.Request.Context(), id)
+	if err != nil {
+		h.writeStockTakeError(c, logTag, "error when cancelling stock take", err)
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":    "stock take cancelled successfully",
+		"stock_take": take,
+	})
+}
+
+func parseStockTakePath(c *gin.Context, logTag string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(c.Request.Context(), logTag+" invalid stock take ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid stock take ID format", err.Error()))
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (h *StockTakeHandler) writeStockTakeError(c *gin.Context, logTag, message string, err error) {
+	switch {
+	case err.Error() == "stock take not found" || err.Error() == "location not found" || strings.HasPrefix(err.Error(), "product not found"):
+		c.JSON(http.StatusNotFound.Code(), response.ErrorResponse(err.Error(), err.Error()))
+	case errors.Is(err, service.ErrInvalidStockTake) || errors.Is(err, service.ErrInvalidSerialNumbers):
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse(message, err.Error()))
+	case errors.Is(err, service.ErrStockTakeNotOpen) || errors.Is(err, service.ErrProductAlreadyCounting) || err.Error() == "insufficient stock":
+		c.JSON(http.StatusConflict.Code(), response.ErrorResponse(message, err.Error()))
+	default:
+		log.ErrorfWithContext(c.Request.Context(), logTag+" "+message, err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse(message, err.Error()))
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type SupplierHandler struct {
+	SupplierService *service.SupplierService
+}
+
+func NewSupplierHandler(supplierService *service.SupplierService) *SupplierHandler {
+	return &SupplierHandler{
+		SupplierService: supplierService,
+	}
+}
+
+func (h *SupplierHandler) CreateSupplierHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SupplierHandler][CreateSupplierHandler]"
+
+	var body struct {
+		Code         string `json:"code" validate:"required,max=50"`
+		Name         string `json:"name" validate:"required,max=255"`
+		Email        string `json:"email" validate:"omitempty,email,max=255"`
+		Phone        string `json:"phone" validate:"omitempty,max=50"`
+		LeadTimeDays int32  `json:"lead_time_days" validate:"min=0,max=365"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	supplier, err := h.SupplierService.CreateSupplier(ctx, &types.Supplier{
+		Code:         body.Code,
+		Name:         body.Name,
+		Email:        body.Email,
+		Phone:        body.Phone,
+		LeadTimeDays: body.LeadTimeDays,
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating supplier", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creating supplier", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":  "supplier created successfully",
+		"supplier": supplier,
+	})
+}
+
+func (h *SupplierHandler) GetSuppliersHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SupplierHandler][GetSuppliersHandler]"
+
+	suppliers, err := h.SupplierService.GetSuppliers(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting suppliers", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching suppliers", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":   "suppliers fetched successfully",
+		"suppliers": suppliers,
+	})
+}
+
+func (h *SupplierHandler) GetSupplierHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SupplierHandler][GetSupplierHandler]"
+
+	supplierID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid supplier ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid supplier ID format", err.Error()))
+		return
+	}
+
+	supplier, err := h.SupplierService.GetSupplier(ctx, supplierID)
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("supplier not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when getting supplier", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching supplier", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":  "supplier fetched successfully",
+		"supplier": supplier,
+	})
+}
+
+func (h *SupplierHandler) UpdateSupplierHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[SupplierHandler][UpdateSupplierHandler]"
+
+	supplierID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid supplier ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid supplier ID format", err.Error()))
+		return
+	}
+
+	var body struct {
+		Name         *string `json:"name" validate:"omitempty,min=1,max=255"`
+		Email        *string `json:"email" validate:"omitempty,email,max=255"`
+		Phone        *string `json:"phone" validate:"omitempty,max=50"`
+		LeadTimeDays *int32  `json:"lead_time_days" validate:"omitempty,min=0,max=365"`
+		IsActive     *bool   `json:"is_active"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	supplier, err := h.SupplierService.UpdateSupplier(ctx, supplierID, service.SupplierUpdate{
+		Name:         body.Name,
+		Email:        body.Email,
+		Phone:        body.Phone,
+		LeadTimeDays: body.LeadTimeDays,
+		IsActive:     body.IsActive,
+	})
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("supplier not found", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when updating supplier", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when updating supplier", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":  "supplier updated successfully",
+		"supplier": supplier,
+	})
+}
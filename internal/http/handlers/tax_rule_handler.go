@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/validator"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+type TaxRuleHandler struct {
+	TaxService *service.TaxService
+}
+
+func NewTaxRuleHandler(taxService *service.TaxService) *TaxRuleHandler {
+	return &TaxRuleHandler{
+		TaxService: taxService,
+	}
+}
+
+func (h *TaxRuleHandler) CreateTaxRuleHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[TaxRuleHandler][CreateTaxRuleHandler]"
+
+	var body struct {
+		Name       string     `json:"name" validate:"required,max=100"`
+		Region     string     `json:"region" validate:"omitempty,min=2,max=10,uppercase"`
+		CategoryID *int64     `json:"category_id" validate:"omitempty,min=1"`
+		Rate       types.Rate `json:"rate" validate:"min=0,max=99999999"`
+		Inclusive  bool       `json:"inclusive"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deserializing body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+		return
+	}
+
+	if err := validator.ValidateStruct(ctx, body); err.Exists() {
+		log.ErrorfWithContext(ctx, logTag+" error when validating the body")
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("please enter valid inputs", err.ErrorMap()))
+		return
+	}
+
+	rule, err := h.TaxService.CreateRule(ctx, &types.TaxRule{
+		Name:       body.Name,
+		Region:     body.Region,
+		CategoryID: body.CategoryID,
+		Rate:       body.Rate,
+		Inclusive:  body.Inclusive,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTaxRulesReadOnly) {
+			c.JSON(http.StatusConflict.Code(), response.ErrorResponse("tax rules are read only", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCategory) {
+			c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid category", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when creating tax rule", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when creating tax rule", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated.Code(), gin.H{
+		"message":  "tax rule created successfully",
+		"tax_rule": rule,
+	})
+}
+
+func (h *TaxRuleHandler) GetTaxRulesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[TaxRuleHandler][GetTaxRulesHandler]"
+
+	rules, err := h.TaxService.GetRules(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting tax rules", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when fetching tax rules", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message":   "tax rules fetched successfully",
+		"source":    h.TaxService.Source,
+		"tax_rules": rules,
+	})
+}
+
+func (h *TaxRuleHandler) DeleteTaxRuleHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	logTag := "[TaxRuleHandler][DeleteTaxRuleHandler]"
+
+	ruleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" invalid tax rule ID format", err)
+		c.JSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid tax rule ID format", err.Error()))
+		return
+	}
+
+	if err := h.TaxService.DeleteRule(ctx, ruleID); err != nil {
+		if err.Error() == "tax rule not found" {
+			c.JSON(http.StatusNotFound.Code(), response.ErrorResponse("tax rule not found", err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrTaxRulesReadOnly) {
+			c.JSON(http.StatusConflict.Code(), response.ErrorResponse("tax rules are read only", err.Error()))
+			return
+		}
+		log.ErrorfWithContext(ctx, logTag+" error when deleting tax rule", err)
+		c.JSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when deleting tax rule", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK.Code(), gin.H{
+		"message": "tax rule deleted successfully",
+	})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/si/internal/types"
+)
+
+const ActorHeader = "X-Actor"
+
+// stores the caller named in the X-Actor header on the request context so audit records such as the
+// stock ledger can attribute the change, requests without it are recorded as the system actor.
+// The header is trusted: the gateway in front of the service sets it to the authenticated caller and
+// drops any value sent by the client, only internal callers reach the service without it
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := c.GetHeader(ActorHeader)
+		if len(actor) > 255 {
+			actor = actor[:255]
+		}
+		if actor != "" {
+			c.Request = c.Request.WithContext(types.WithActor(c.Request.Context(), actor))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/service"
+	"github.com/si/internal/types"
+	"github.com/si/internal/utils/response"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// captures the response so it can be stored against the idempotency key
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// replays the stored response for a repeated Idempotency-Key and rejects a key reused
+// for a different request, requests without the header are passed through untouched.
+// Keys are scoped to the caller, method and path so two callers or routes never share one, the caller
+// comes from the trusted X-Actor header. The request is cut off when its lease on the key runs out so a
+// retry that takes the key over never runs alongside it. Needs the Actor middleware to run first
+func Idempotency(idempotencyService *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		logTag := "[Middleware][Idempotency]"
+
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest.Code(), response.ErrorResponse("idempotency key is too long", "maximum length is 255"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" error when reading request body", err)
+			c.AbortWithStatusJSON(http.StatusBadRequest.Code(), response.ErrorResponse("invalid request body", err.Error()))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		scope := types.ActorFromContext(ctx) + " " + c.Request.Method + " " + c.Request.URL.Path
+
+		// set before the key is claimed so the deadline never outlives the lease
+		requestCtx, cancel := context.WithTimeout(ctx, idempotencyService.Lease)
+		defer cancel()
+
+		existing, err := idempotencyService.Begin(ctx, scope, key, fingerprint)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity.Code(), response.ErrorResponse("idempotency key reused with a different request", err.Error()))
+			case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+				c.AbortWithStatusJSON(http.StatusConflict.Code(), response.ErrorResponse("request with this idempotency key is in progress", err.Error()))
+			default:
+				log.ErrorfWithContext(ctx, logTag+" error when checking idempotency key", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError.Code(), response.ErrorResponse("error when checking idempotency key", err.Error()))
+			}
+			return
+		}
+
+		if existing != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(existing.ResponseStatus, "application/json; charset=utf-8", []byte(existing.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+		c.Request = c.Request.WithContext(requestCtx)
+
+		c.Next()
+
+		// server errors are not cached so the client can retry them
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError.Code() {
+			if err := idempotencyService.Release(ctx, scope, key); err != nil {
+				log.ErrorfWithContext(ctx, logTag+" error when releasing idempotency key", err)
+			}
+			return
+		}
+
+		stored := recorder.body.String()
+		if stored == "" {
+			stored = "null"
+		}
+
+		if err := idempotencyService.Complete(ctx, scope, key, status, stored); err != nil {
+			log.ErrorfWithContext(ctx, logTag+" error when storing idempotent response", err)
+		}
+	}
+}
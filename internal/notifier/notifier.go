@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+)
+
+// Notifier pushes a low stock alert somewhere a person will see it, a returned error makes the
+// dispatcher try the same alert again on its next run
+type Notifier interface {
+	NotifyLowStock(ctx context.Context, alert types.StockAlert, product types.Product) error
+}
+
+// payload sent for every alert, also what the log notifier writes out
+type LowStockNotification struct {
+	AlertID           int64     `json:"alert_id"`
+	ProductID         int64     `json:"product_id"`
+	SKU               string    `json:"sku"`
+	Name              string    `json:"name"`
+	AvailableQuantity int64     `json:"available_quantity"`
+	StockQuantity     int64     `json:"stock_quantity"`
+	ReorderPoint      int64     `json:"reorder_point"`
+	ReorderQuantity   int64     `json:"reorder_quantity"`
+	RaisedAt          time.Time `json:"raised_at"`
+}
+
+func newLowStockNotification(alert types.StockAlert, product types.Product) LowStockNotification {
+	return LowStockNotification{
+		AlertID:           alert.ID,
+		ProductID:         product.ID,
+		SKU:               product.SKU,
+		Name:              product.Name,
+		AvailableQuantity: alert.AvailableQuantity,
+		StockQuantity:     alert.StockQuantity,
+		ReorderPoint:      alert.ReorderPoint,
+		ReorderQuantity:   alert.ReorderQuantity,
+		RaisedAt:          alert.CreatedAt,
+	}
+}
+
+// writes every alert to the service log
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) NotifyLowStock(ctx context.Context, alert types.StockAlert, product types.Product) error {
+	logTag := "[LogNotifier][NotifyLowStock]"
+	notification := newLowStockNotification(alert, product)
+	log.WarnfWithContext(ctx, logTag+" low stock", "alert_id", notification.AlertID, "product_id", notification.ProductID,
+		"sku", notification.SKU, "available", notification.AvailableQuantity, "reorder_point", notification.ReorderPoint,
+		"reorder_quantity", notification.ReorderQuantity)
+	return nil
+}
+
+// POSTs every alert as json to a fixed url, any non 2xx answer counts as a failed delivery
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *WebhookNotifier) NotifyLowStock(ctx context.Context, alert types.StockAlert, product types.Product) error {
+	body, err := json.Marshal(newLowStockNotification(alert, product))
+	if err != nil {
+		return fmt.Errorf("failed to encode notification %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %d", res.StatusCode)
+	}
+
+	return nil
+}
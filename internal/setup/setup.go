@@ -1,11 +1,12 @@
 package setup
 
 import (
+	"github.com/gin-gonic/gin"
 	"github.com/omniful/go_commons/http"
 	"github.com/si/internal/http/handlers"
 )
 
-func SetupRoutes(server *http.Server, userHandler *handlers.UserHandler, productHandler *handlers.ProductHandler, orderHandler *handlers.OrderHandler) {
+func SetupRoutes(server *http.Server, userHandler *handlers.UserHandler, addressHandler *handlers.AddressHandler, productHandler *handlers.ProductHandler, orderHandler *handlers.OrderHandler, returnHandler *handlers.ReturnHandler, shipmentHandler *handlers.ShipmentHandler, exchangeRateHandler *handlers.ExchangeRateHandler, couponHandler *handlers.CouponHandler, taxRuleHandler *handlers.TaxRuleHandler, inventoryHandler *handlers.InventoryHandler, locationHandler *handlers.LocationHandler, supplierHandler *handlers.SupplierHandler, purchaseOrderHandler *handlers.PurchaseOrderHandler, stockTakeHandler *handlers.StockTakeHandler, serialHandler *handlers.SerialHandler, categoryHandler *handlers.CategoryHandler, catalogHandler *handlers.CatalogHandler, idempotent gin.HandlerFunc) {
     v1 := server.Group("/api/v1")
     {
         //user routes
@@ -16,6 +17,13 @@ func SetupRoutes(server *http.Server, userHandler *handlers.UserHandler, product
 			userRoutes.POST("/id", userHandler.GetUserByIdHandler)
 			userRoutes.PUT("", userHandler.UpdateUserHandler)
 			userRoutes.DELETE("", userHandler.DeleteUserHandler)
+
+			//address book routes
+			userRoutes.POST("/:id/addresses", addressHandler.CreateAddressHandler)
+			userRoutes.GET("/:id/addresses", addressHandler.GetAddressesHandler)
+			userRoutes.GET("/:id/addresses/:address_id", addressHandler.GetAddressHandler)
+			userRoutes.PUT("/:id/addresses/:address_id", addressHandler.UpdateAddressHandler)
+			userRoutes.DELETE("/:id/addresses/:address_id", addressHandler.DeleteAddressHandler)
         }
 
         //product routes
@@ -27,20 +35,119 @@ func SetupRoutes(server *http.Server, userHandler *handlers.UserHandler, product
             productRoutes.POST("/search", productHandler.SearchProductsHandler)
             productRoutes.PUT("/:id", productHandler.UpdateProductHandler)
             productRoutes.DELETE("/:id", productHandler.DeleteProductHandler)
-            productRoutes.PATCH("/:id/inventory", productHandler.UpdateInventoryHandler)
+            productRoutes.POST("/:id/variants", productHandler.CreateVariantHandler)
+            productRoutes.GET("/:id/variants", productHandler.GetProductVariantsHandler)
+            productRoutes.PATCH("/:id/inventory", idempotent, productHandler.UpdateInventoryHandler)
+            productRoutes.PUT("/:id/prices", productHandler.SetProductPriceHandler)
+            productRoutes.GET("/:id/prices", productHandler.GetProductPricesHandler)
+            productRoutes.DELETE("/:id/prices/:currency", productHandler.DeleteProductPriceHandler)
+            productRoutes.GET("/:id/price-history", productHandler.GetPriceHistoryHandler)
+            productRoutes.POST("/:id/price-changes", productHandler.SchedulePriceChangeHandler)
+            productRoutes.GET("/:id/price-changes", productHandler.GetScheduledPriceChangesHandler)
+            productRoutes.DELETE("/:id/price-changes/:change_id", productHandler.CancelPriceChangeHandler)
+            productRoutes.GET("/:id/stock-movements", inventoryHandler.GetStockMovementsHandler)
+            productRoutes.GET("/:id/stock-movements/reconcile", inventoryHandler.ReconcileStockHandler)
+            productRoutes.GET("/:id/stock", productHandler.GetProductStocksHandler)
+            productRoutes.POST("/:id/transfers", idempotent, inventoryHandler.TransferStockHandler)
+            productRoutes.GET("/:id/transfers", inventoryHandler.GetStockTransfersHandler)
+            productRoutes.GET("/:id/lots", inventoryHandler.GetProductLotsHandler)
+            productRoutes.POST("/:id/serials", idempotent, serialHandler.RegisterSerialsHandler)
+            productRoutes.GET("/:id/serials", serialHandler.GetProductSerialsHandler)
         }
 
         //order routes
         orderRoutes := v1.Group("/orders")
         {
-            orderRoutes.POST("", orderHandler.CreateOrderHandler)
+            orderRoutes.POST("", idempotent, orderHandler.CreateOrderHandler)
             orderRoutes.GET("/:id", orderHandler.GetOrderByIdHandler)
             orderRoutes.POST("/search", orderHandler.SearchOrdersHandler)
             orderRoutes.PATCH("/:id/status", orderHandler.UpdateOrderStatusHandler)
+            orderRoutes.GET("/:id/transitions", orderHandler.GetOrderTransitionsHandler)
+            orderRoutes.POST("/:id/cancel", orderHandler.CancelOrderHandler)
+            orderRoutes.POST("/:id/cancel-remainder", orderHandler.CancelOrderRemainderHandler)
+            orderRoutes.POST("/:id/pay", orderHandler.MarkOrderPaidHandler)
+            orderRoutes.GET("/:id/reservations", orderHandler.GetOrderReservationsHandler)
+            orderRoutes.GET("/:id/lots", orderHandler.GetOrderLotsHandler)
+            orderRoutes.GET("/:id/serials", serialHandler.GetOrderSerialsHandler)
             
-            orderRoutes.POST("/:id/items", orderHandler.AddOrderItemHandler)
+            orderRoutes.POST("/:id/items", idempotent, orderHandler.AddOrderItemHandler)
             orderRoutes.PUT("/:id/items/:item_id", orderHandler.UpdateOrderItemHandler)
             orderRoutes.DELETE("/:id/items/:item_id", orderHandler.RemoveOrderItemHandler)
+
+            //shipment routes
+            orderRoutes.POST("/:id/shipments", shipmentHandler.CreateShipmentHandler)
+            orderRoutes.GET("/:id/shipments", shipmentHandler.GetOrderShipmentsHandler)
+            orderRoutes.POST("/:id/shipments/:shipment_id/deliver", shipmentHandler.DeliverShipmentHandler)
+
+            //return routes
+            orderRoutes.POST("/:id/returns", returnHandler.CreateReturnHandler)
+            orderRoutes.GET("/:id/returns", returnHandler.GetOrderReturnsHandler)
+            orderRoutes.GET("/:id/returns/:return_id", returnHandler.GetReturnHandler)
+            orderRoutes.POST("/:id/returns/:return_id/approve", returnHandler.ApproveReturnHandler)
+            orderRoutes.POST("/:id/returns/:return_id/reject", returnHandler.RejectReturnHandler)
+            orderRoutes.POST("/:id/returns/:return_id/receive", returnHandler.ReceiveReturnHandler)
+        }
+
+        //inventory routes
+        inventoryRoutes := v1.Group("/inventory")
+        {
+            inventoryRoutes.GET("/alerts", inventoryHandler.GetStockAlertsHandler)
+            inventoryRoutes.GET("/lots/expiring", inventoryHandler.GetExpiringLotsHandler)
+            inventoryRoutes.GET("/serials/:serial_number", serialHandler.LookupSerialHandler)
+
+            inventoryRoutes.POST("/stock-takes", idempotent, stockTakeHandler.OpenStockTakeHandler)
+            inventoryRoutes.GET("/stock-takes", stockTakeHandler.SearchStockTakesHandler)
+            inventoryRoutes.GET("/stock-takes/:id", stockTakeHandler.GetStockTakeHandler)
+            inventoryRoutes.PUT("/stock-takes/:id/counts", stockTakeHandler.RecordCountsHandler)
+            inventoryRoutes.POST("/stock-takes/:id/post", idempotent, stockTakeHandler.PostStockTakeHandler)
+            inventoryRoutes.POST("/stock-takes/:id/cancel", stockTakeHandler.CancelStockTakeHandler)
+        }
+
+        //admin routes
+        adminRoutes := v1.Group("/admin")
+        {
+            adminRoutes.PUT("/exchange-rates", exchangeRateHandler.SetExchangeRateHandler)
+            adminRoutes.GET("/exchange-rates", exchangeRateHandler.GetExchangeRatesHandler)
+            adminRoutes.DELETE("/exchange-rates/:base/:quote", exchangeRateHandler.DeleteExchangeRateHandler)
+
+            adminRoutes.POST("/coupons", couponHandler.CreateCouponHandler)
+            adminRoutes.GET("/coupons", couponHandler.GetCouponsHandler)
+            adminRoutes.GET("/coupons/:id", couponHandler.GetCouponHandler)
+            adminRoutes.PATCH("/coupons/:id", couponHandler.UpdateCouponHandler)
+
+            adminRoutes.POST("/tax-rules", taxRuleHandler.CreateTaxRuleHandler)
+            adminRoutes.GET("/tax-rules", taxRuleHandler.GetTaxRulesHandler)
+            adminRoutes.DELETE("/tax-rules/:id", taxRuleHandler.DeleteTaxRuleHandler)
+
+            adminRoutes.POST("/locations", locationHandler.CreateLocationHandler)
+            adminRoutes.GET("/locations", locationHandler.GetLocationsHandler)
+            adminRoutes.GET("/locations/:id", locationHandler.GetLocationHandler)
+            adminRoutes.PATCH("/locations/:id", locationHandler.UpdateLocationHandler)
+
+            adminRoutes.POST("/categories", categoryHandler.CreateCategoryHandler)
+            adminRoutes.GET("/categories", categoryHandler.GetCategoriesHandler)
+            adminRoutes.GET("/categories/:id", categoryHandler.GetCategoryHandler)
+            adminRoutes.PATCH("/categories/:id", categoryHandler.UpdateCategoryHandler)
+            adminRoutes.DELETE("/categories/:id", categoryHandler.DeleteCategoryHandler)
+
+            adminRoutes.POST("/catalog/imports", idempotent, catalogHandler.CreateImportHandler)
+            adminRoutes.GET("/catalog/imports", catalogHandler.GetImportsHandler)
+            adminRoutes.GET("/catalog/imports/:id", catalogHandler.GetImportHandler)
+            adminRoutes.GET("/catalog/imports/:id/errors", catalogHandler.GetImportErrorsHandler)
+            adminRoutes.POST("/catalog/export", catalogHandler.ExportProductsHandler)
+
+            adminRoutes.POST("/suppliers", supplierHandler.CreateSupplierHandler)
+            adminRoutes.GET("/suppliers", supplierHandler.GetSuppliersHandler)
+            adminRoutes.GET("/suppliers/:id", supplierHandler.GetSupplierHandler)
+            adminRoutes.PATCH("/suppliers/:id", supplierHandler.UpdateSupplierHandler)
+
+            adminRoutes.POST("/purchase-orders", idempotent, purchaseOrderHandler.CreatePurchaseOrderHandler)
+            adminRoutes.GET("/purchase-orders", purchaseOrderHandler.SearchPurchaseOrdersHandler)
+            adminRoutes.GET("/purchase-orders/:id", purchaseOrderHandler.GetPurchaseOrderHandler)
+            adminRoutes.POST("/purchase-orders/:id/submit", purchaseOrderHandler.SubmitPurchaseOrderHandler)
+            adminRoutes.POST("/purchase-orders/:id/cancel", purchaseOrderHandler.CancelPurchaseOrderHandler)
+            adminRoutes.POST("/purchase-orders/:id/close", purchaseOrderHandler.ClosePurchaseOrderHandler)
+            adminRoutes.POST("/purchase-orders/:id/receipts", idempotent, purchaseOrderHandler.ReceiveGoodsHandler)
         }
     }
-}
+}
\ No newline at end of file
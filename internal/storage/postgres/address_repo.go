@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+type AddressRepo struct {
+	DB *Postgres
+}
+
+func NewAddressRepo(db *Postgres) *AddressRepo {
+	return &AddressRepo{
+		DB: db,
+	}
+}
+
+func (r *AddressRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, address *types.UserAddress) (*types.UserAddress, error) {
+	logTag := "[AddressRepo][CreateWithTx]"
+	log.InfofWithContext(ctx, logTag+" creating address", "user_id", address.UserID)
+
+	if err := tx.Create(address).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create address", err, "user_id", address.UserID)
+		return nil, fmt.Errorf("failed to create address %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" address created successfully", "address_id", address.ID)
+	return address, nil
+}
+
+func (r *AddressRepo) UpdateWithTx(tx *gorm.DB, ctx context.Context, address *types.UserAddress) (*types.UserAddress, error) {
+	logTag := "[AddressRepo][UpdateWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating address", "address_id", address.ID)
+
+	if err := tx.Save(address).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update address", err, "address_id", address.ID)
+		return nil, fmt.Errorf("failed to update address %w", err)
+	}
+
+	return address, nil
+}
+
+// unsets the default shipping and/or billing flag on every other address of the user
+func (r *AddressRepo) ClearDefaultsWithTx(tx *gorm.DB, ctx context.Context, userID, exceptID int64, shipping, billing bool) error {
+	logTag := "[AddressRepo][ClearDefaultsWithTx]"
+	log.InfofWithContext(ctx, logTag+" clearing default addresses", "user_id", userID, "shipping", shipping, "billing", billing)
+
+	if shipping {
+		err := tx.Model(&types.UserAddress{}).
+			Where("user_id = ? AND id <> ? AND is_default_shipping", userID, exceptID).
+			Update("is_default_shipping", false).Error
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to clear default shipping address", err, "user_id", userID)
+			return fmt.Errorf("failed to clear default shipping address %w", err)
+		}
+	}
+
+	if billing {
+		err := tx.Model(&types.UserAddress{}).
+			Where("user_id = ? AND id <> ? AND is_default_billing", userID, exceptID).
+			Update("is_default_billing", false).Error
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to clear default billing address", err, "user_id", userID)
+			return fmt.Errorf("failed to clear default billing address %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *AddressRepo) CountByUserIDWithTx(tx *gorm.DB, ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	if err := tx.Model(&types.UserAddress{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		log.ErrorfWithContext(ctx, "[AddressRepo][CountByUserIDWithTx] failed to count addresses", err, "user_id", userID)
+		return 0, fmt.Errorf("failed to count addresses: %w", err)
+	}
+	return count, nil
+}
+
+func (r *AddressRepo) SearchByID(ctx context.Context, userID, addressID int64) (*types.UserAddress, error) {
+	logTag := "[AddressRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching address", "user_id", userID, "address_id", addressID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var address types.UserAddress
+	if err := db.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" address not found", "user_id", userID, "address_id", addressID)
+			return nil, fmt.Errorf("address not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch address", err, "address_id", addressID)
+		return nil, fmt.Errorf("failed to fetch address %w", err)
+	}
+
+	return &address, nil
+}
+
+func (r *AddressRepo) GetByUserID(ctx context.Context, userID int64) ([]types.UserAddress, error) {
+	logTag := "[AddressRepo][GetByUserID]"
+	log.InfofWithContext(ctx, logTag+" fetching addresses of user", "user_id", userID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var addresses []types.UserAddress
+	if err := db.Where("user_id = ?", userID).Order("id ASC").Find(&addresses).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch addresses", err, "user_id", userID)
+		return nil, fmt.Errorf("failed to fetch addresses: %w", err)
+	}
+
+	return addresses, nil
+}
+
+// returns the default shipping and billing addresses of the user, either may be nil
+func (r *AddressRepo) GetDefaults(ctx context.Context, userID int64) (*types.UserAddress, *types.UserAddress, error) {
+	logTag := "[AddressRepo][GetDefaults]"
+	log.InfofWithContext(ctx, logTag+" fetching default addresses", "user_id", userID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var addresses []types.UserAddress
+	if err := db.Where("user_id = ? AND (is_default_shipping OR is_default_billing)", userID).Find(&addresses).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch default addresses", err, "user_id", userID)
+		return nil, nil, fmt.Errorf("failed to fetch default addresses: %w", err)
+	}
+
+	var shipping, billing *types.UserAddress
+	for i := range addresses {
+		if addresses[i].IsDefaultShipping {
+			shipping = &addresses[i]
+		}
+		if addresses[i].IsDefaultBilling {
+			billing = &addresses[i]
+		}
+	}
+
+	return shipping, billing, nil
+}
+
+func (r *AddressRepo) Delete(ctx context.Context, userID, addressID int64) error {
+	logTag := "[AddressRepo][Delete]"
+	log.InfofWithContext(ctx, logTag+" deleting address", "user_id", userID, "address_id", addressID)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Where("id = ? AND user_id = ?", addressID, userID).Delete(&types.UserAddress{})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to delete address", res.Error, "address_id", addressID)
+		return fmt.Errorf("failed to delete address %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		log.WarnfWithContext(ctx, logTag+" address not found", "user_id", userID, "address_id", addressID)
+		return fmt.Errorf("address not found")
+	}
+
+	return nil
+}
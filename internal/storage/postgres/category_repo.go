@@ -0,0 +1,266 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+type CategoryRepo struct {
+	DB *Postgres
+}
+
+func NewCategoryRepo(db *Postgres) *CategoryRepo {
+	return &CategoryRepo{
+		DB: db,
+	}
+}
+
+func (r *CategoryRepo) Create(ctx context.Context, category *types.Category) (*types.Category, error) {
+	logTag := "[CategoryRepo][Create]"
+	log.InfofWithContext(ctx, logTag+" creating category", "name", category.Name, "parent_id", category.ParentID)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Create(category).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create category", err, "name", category.Name)
+		return nil, fmt.Errorf("failed to create category %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" category created successfully", "category_id", category.ID)
+	return category, nil
+}
+
+func (r *CategoryRepo) SearchByID(ctx context.Context, id int64) (*types.Category, error) {
+	logTag := "[CategoryRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching category", "category_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var category types.Category
+	if err := db.Where("id = ?", id).First(&category).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" category not found", "category_id", id)
+			return nil, fmt.Errorf("category not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch category", err, "category_id", id)
+		return nil, fmt.Errorf("failed to fetch category %w", err)
+	}
+
+	return &category, nil
+}
+
+func (r *CategoryRepo) GetByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.Category, error) {
+	logTag := "[CategoryRepo][GetByIDWithTx]"
+
+	var category types.Category
+	if err := tx.Where("id = ?", id).First(&category).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" category not found", "category_id", id)
+			return nil, fmt.Errorf("category not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch category", err, "category_id", id)
+		return nil, fmt.Errorf("failed to fetch category %w", err)
+	}
+
+	return &category, nil
+}
+
+func (r *CategoryRepo) GetAll(ctx context.Context) ([]types.Category, error) {
+	logTag := "[CategoryRepo][GetAll]"
+	log.InfofWithContext(ctx, logTag+" fetching categories")
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var categories []types.Category
+	if err := db.Order("LOWER(name), id").Find(&categories).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch categories", err)
+		return nil, fmt.Errorf("failed to fetch categories %w", err)
+	}
+
+	return categories, nil
+}
+
+// the ids of the category and everything below it
+func (r *CategoryRepo) GetDescendantIDs(ctx context.Context, id int64) ([]int64, error) {
+	logTag := "[CategoryRepo][GetDescendantIDs]"
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	ids, err := descendantIDs(db, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch descendants", err, "category_id", id)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// the category and its ancestors, the root first
+func (r *CategoryRepo) GetPath(ctx context.Context, id int64) ([]types.Category, error) {
+	logTag := "[CategoryRepo][GetPath]"
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var path []types.Category
+	err := db.Raw(`WITH RECURSIVE path AS (
+			SELECT c.*, 0 AS depth FROM categories c WHERE c.id = ?
+			UNION ALL
+			SELECT c.*, p.depth + 1 FROM categories c JOIN path p ON c.id = p.parent_id
+		)
+		SELECT id, parent_id, name, description, attribute_schema, created_at, updated_at
+		FROM path ORDER BY depth DESC`, id).Scan(&path).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch category path", err, "category_id", id)
+		return nil, fmt.Errorf("failed to fetch category path %w", err)
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("category not found")
+	}
+
+	return path, nil
+}
+
+// whether another category under the same parent already has the name, case insensitive
+func (r *CategoryRepo) SiblingExists(ctx context.Context, parentID *int64, name string, excludeID int64) (bool, error) {
+	logTag := "[CategoryRepo][SiblingExists]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	query := db.Model(&types.Category{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, excludeID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to check category name", err, "name", name)
+		return false, fmt.Errorf("failed to check category name %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// blocks other changes to the tree until the transaction ends, so two moves can never build a cycle together
+func (r *CategoryRepo) LockTreeWithTx(tx *gorm.DB, ctx context.Context) error {
+	logTag := "[CategoryRepo][LockTreeWithTx]"
+
+	if err := tx.Exec("LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to lock categories", err)
+		return fmt.Errorf("failed to lock categories %w", err)
+	}
+
+	return nil
+}
+
+func (r *CategoryRepo) GetDescendantIDsWithTx(tx *gorm.DB, ctx context.Context, id int64) ([]int64, error) {
+	logTag := "[CategoryRepo][GetDescendantIDsWithTx]"
+
+	ids, err := descendantIDs(tx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch descendants", err, "category_id", id)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// saves the category and copies a new name onto the products linked to it
+func (r *CategoryRepo) UpdateWithTx(tx *gorm.DB, ctx context.Context, category *types.Category) error {
+	logTag := "[CategoryRepo][UpdateWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating category", "category_id", category.ID)
+
+	res := tx.Model(&types.Category{}).
+		Where("id = ?", category.ID).
+		Updates(map[string]interface{}{
+			"parent_id":        category.ParentID,
+			"name":             category.Name,
+			"description":      gorm.Expr("NULLIF(?, '')", category.Description),
+			"attribute_schema": category.AttributeSchema,
+			"updated_at":       category.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update category", res.Error, "category_id", category.ID)
+		return fmt.Errorf("failed to update category %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	err := tx.Model(&types.Product{}).
+		Where("category_id = ? AND category IS DISTINCT FROM ?", category.ID, category.Name).
+		Updates(map[string]interface{}{"category": category.Name, "updated_at": category.UpdatedAt}).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to rename product categories", err, "category_id", category.ID)
+		return fmt.Errorf("failed to rename product categories %w", err)
+	}
+
+	return nil
+}
+
+// how many child categories, products and coupons or tax rules hang off the category
+func (r *CategoryRepo) GetUsage(ctx context.Context, id int64) (int64, int64, int64, error) {
+	logTag := "[CategoryRepo][GetUsage]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var children, products int64
+	if err := db.Model(&types.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count child categories", err, "category_id", id)
+		return 0, 0, 0, fmt.Errorf("failed to count child categories %w", err)
+	}
+	if err := db.Model(&types.Product{}).Where("category_id = ?", id).Count(&products).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count category products", err, "category_id", id)
+		return 0, 0, 0, fmt.Errorf("failed to count category products %w", err)
+	}
+
+	var coupons, taxRules int64
+	if err := db.Model(&types.Coupon{}).Where("category_id = ?", id).Count(&coupons).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count category coupons", err, "category_id", id)
+		return 0, 0, 0, fmt.Errorf("failed to count category coupons %w", err)
+	}
+	if err := db.Model(&types.TaxRule{}).Where("category_id = ?", id).Count(&taxRules).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count category tax rules", err, "category_id", id)
+		return 0, 0, 0, fmt.Errorf("failed to count category tax rules %w", err)
+	}
+
+	return children, products, coupons + taxRules, nil
+}
+
+func (r *CategoryRepo) Delete(ctx context.Context, id int64) error {
+	logTag := "[CategoryRepo][Delete]"
+	log.InfofWithContext(ctx, logTag+" deleting category", "category_id", id)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Where("id = ?", id).Delete(&types.Category{})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to delete category", res.Error, "category_id", id)
+		return fmt.Errorf("failed to delete category %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
+
+func descendantIDs(db *gorm.DB, id int64) ([]int64, error) {
+	var ids []int64
+	err := db.Raw(`WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION ALL
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT id FROM tree`, id).Scan(&ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch descendant categories %w", err)
+	}
+
+	return ids, nil
+}
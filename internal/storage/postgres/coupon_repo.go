@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepo struct {
+	DB *Postgres
+}
+
+func NewCouponRepo(db *Postgres) *CouponRepo {
+	return &CouponRepo{
+		DB: db,
+	}
+}
+
+func (r *CouponRepo) Create(ctx context.Context, coupon *types.Coupon) (*types.Coupon, error) {
+	logTag := "[CouponRepo][Create]"
+	log.InfofWithContext(ctx, logTag+" creating coupon", "code", coupon.Code)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Create(coupon).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create coupon", err, "code", coupon.Code)
+		return nil, fmt.Errorf("failed to create coupon %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" coupon created successfully", "coupon_id", coupon.ID)
+	return coupon, nil
+}
+
+func (r *CouponRepo) SearchByID(ctx context.Context, id int64) (*types.Coupon, error) {
+	logTag := "[CouponRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching coupon", "coupon_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var coupon types.Coupon
+	if err := db.Where("id = ?", id).First(&coupon).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" coupon not found", "coupon_id", id)
+			return nil, fmt.Errorf("coupon not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch coupon", err, "coupon_id", id)
+		return nil, fmt.Errorf("failed to fetch coupon %w", err)
+	}
+
+	return &coupon, nil
+}
+
+func (r *CouponRepo) GetAll(ctx context.Context, limit, offset int) ([]types.Coupon, int64, error) {
+	logTag := "[CouponRepo][GetAll]"
+	log.InfofWithContext(ctx, logTag+" fetching coupons", "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var total int64
+	if err := db.Model(&types.Coupon{}).Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count coupons", err)
+		return nil, 0, fmt.Errorf("failed to count coupons: %w", err)
+	}
+
+	var coupons []types.Coupon
+	if err := db.Order("created_at DESC").Limit(limit).Offset(offset).Find(&coupons).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch coupons", err)
+		return nil, 0, fmt.Errorf("failed to fetch coupons: %w", err)
+	}
+
+	return coupons, total, nil
+}
+
+// only the availability of a coupon can change, its discount terms are immutable
+func (r *CouponRepo) UpdateAvailability(ctx context.Context, coupon *types.Coupon) error {
+	logTag := "[CouponRepo][UpdateAvailability]"
+	log.InfofWithContext(ctx, logTag+" updating coupon", "coupon_id", coupon.ID)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Model(&types.Coupon{}).
+		Where("id = ?", coupon.ID).
+		Updates(map[string]interface{}{
+			"is_active":         coupon.IsActive,
+			"starts_at":         coupon.StartsAt,
+			"ends_at":           coupon.EndsAt,
+			"max_uses":          gorm.Expr("NULLIF(?, 0)", coupon.MaxUses),
+			"max_uses_per_user": gorm.Expr("NULLIF(?, 0)", coupon.MaxUsesPerUser),
+			"updated_at":        coupon.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update coupon", res.Error, "coupon_id", coupon.ID)
+		return fmt.Errorf("failed to update coupon %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("coupon not found")
+	}
+
+	return nil
+}
+
+// locks the coupon so concurrent orders cannot both take its last redemption
+func (r *CouponRepo) LockByCodeWithTx(tx *gorm.DB, ctx context.Context, code string) (*types.Coupon, error) {
+	logTag := "[CouponRepo][LockByCodeWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking coupon", "code", code)
+
+	var coupon types.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("UPPER(code) = UPPER(?)", code).First(&coupon).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" coupon not found", "code", code)
+			return nil, fmt.Errorf("coupon not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to lock coupon", err, "code", code)
+		return nil, fmt.Errorf("failed to lock coupon %w", err)
+	}
+
+	return &coupon, nil
+}
+
+// counts the orders that redeemed the coupon, cancelled orders give their redemption back
+func (r *CouponRepo) CountRedemptionsWithTx(tx *gorm.DB, ctx context.Context, couponID int64, userID int64) (int64, int64, error) {
+	logTag := "[CouponRepo][CountRedemptionsWithTx]"
+	log.InfofWithContext(ctx, logTag+" counting coupon redemptions", "coupon_id", couponID, "user_id", userID)
+
+	var counts struct {
+		Total     int64
+		UserTotal int64
+	}
+	err := tx.Table("order_discounts od").
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE o.user_id = ?) AS user_total", userID).
+		Joins("JOIN orders o ON o.id = od.order_id").
+		Where("od.coupon_id = ? AND o.status <> ?", couponID, types.OrderStatusCancelled).
+		Scan(&counts).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count coupon redemptions", err, "coupon_id", couponID)
+		return 0, 0, fmt.Errorf("failed to count coupon redemptions: %w", err)
+	}
+
+	return counts.Total, counts.UserTotal, nil
+}
+
+func (r *CouponRepo) GetByIDsWithTx(tx *gorm.DB, ctx context.Context, ids []int64) (map[int64]types.Coupon, error) {
+	logTag := "[CouponRepo][GetByIDsWithTx]"
+	log.InfofWithContext(ctx, logTag+" fetching coupons", "coupon_ids", ids)
+
+	coupons := make(map[int64]types.Coupon, len(ids))
+	if len(ids) == 0 {
+		return coupons, nil
+	}
+
+	var rows []types.Coupon
+	if err := tx.Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch coupons", err)
+		return nil, fmt.Errorf("failed to fetch coupons: %w", err)
+	}
+
+	for _, coupon := range rows {
+		coupons[coupon.ID] = coupon
+	}
+
+	return coupons, nil
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRateRepo struct {
+	DB *Postgres
+}
+
+func NewExchangeRateRepo(db *Postgres) *ExchangeRateRepo {
+	return &ExchangeRateRepo{
+		DB: db,
+	}
+}
+
+func (r *ExchangeRateRepo) Upsert(ctx context.Context, rate *types.ExchangeRate) (*types.ExchangeRate, error) {
+	logTag := "[ExchangeRateRepo][Upsert]"
+	log.InfofWithContext(ctx, logTag+" saving exchange rate", "base", rate.BaseCurrency, "quote", rate.QuoteCurrency)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base_currency"}, {Name: "quote_currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(rate).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to save exchange rate", err)
+		return nil, fmt.Errorf("failed to save exchange rate %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" exchange rate saved successfully", "base", rate.BaseCurrency, "quote", rate.QuoteCurrency, "rate", rate.Rate)
+	return rate, nil
+}
+
+func (r *ExchangeRateRepo) Get(ctx context.Context, base, quote string) (*types.ExchangeRate, error) {
+	logTag := "[ExchangeRateRepo][Get]"
+	log.InfofWithContext(ctx, logTag+" fetching exchange rate", "base", base, "quote", quote)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var rate types.ExchangeRate
+	if err := db.Where("base_currency = ? AND quote_currency = ?", base, quote).First(&rate).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" exchange rate not found", "base", base, "quote", quote)
+			return nil, fmt.Errorf("exchange rate not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch exchange rate", err)
+		return nil, fmt.Errorf("failed to fetch exchange rate %w", err)
+	}
+
+	return &rate, nil
+}
+
+func (r *ExchangeRateRepo) GetAll(ctx context.Context) ([]types.ExchangeRate, error) {
+	logTag := "[ExchangeRateRepo][GetAll]"
+	log.InfofWithContext(ctx, logTag+" fetching exchange rates")
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var rates []types.ExchangeRate
+	if err := db.Order("base_currency ASC, quote_currency ASC").Find(&rates).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch exchange rates", err)
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+func (r *ExchangeRateRepo) Delete(ctx context.Context, base, quote string) error {
+	logTag := "[ExchangeRateRepo][Delete]"
+	log.InfofWithContext(ctx, logTag+" deleting exchange rate", "base", base, "quote", quote)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Where("base_currency = ? AND quote_currency = ?", base, quote).Delete(&types.ExchangeRate{})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to delete exchange rate", res.Error)
+		return fmt.Errorf("failed to delete exchange rate %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		log.WarnfWithContext(ctx, logTag+" exchange rate not found", "base", base, "quote", quote)
+		return fmt.Errorf("exchange rate not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepo struct {
+	DB *Postgres
+}
+
+func NewIdempotencyRepo(db *Postgres) *IdempotencyRepo {
+	return &IdempotencyRepo{
+		DB: db,
+	}
+}
+
+// claims the key within its scope for a new request, returns false when the key is already taken
+func (r *IdempotencyRepo) Reserve(ctx context.Context, record *types.IdempotencyKey) (bool, error) {
+	logTag := "[IdempotencyRepo][Reserve]"
+	log.InfofWithContext(ctx, logTag+" reserving idempotency key", "scope", record.Scope, "key", record.Key)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	// an expired key can be reused by a new request
+	if err := db.Where("scope = ? AND idempotency_key = ? AND expires_at < ?", record.Scope, record.Key, time.Now()).Delete(&types.IdempotencyKey{}).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to delete expired key", err, "key", record.Key)
+		return false, fmt.Errorf("failed to delete expired idempotency key %w", err)
+	}
+
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to reserve key", res.Error, "key", record.Key)
+		return false, fmt.Errorf("failed to reserve idempotency key %w", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// takes over a key whose request never completed and whose lease ran out, returns false when the key
+// was completed or taken over by another request in the meantime
+func (r *IdempotencyRepo) TakeOver(ctx context.Context, scope, key, fingerprint string, lockedUntil time.Time) (bool, error) {
+	logTag := "[IdempotencyRepo][TakeOver]"
+	log.InfofWithContext(ctx, logTag+" taking over idempotency key", "scope", scope, "key", key)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Model(&types.IdempotencyKey{}).
+		Where("scope = ? AND idempotency_key = ? AND request_fingerprint = ? AND completed = ? AND locked_until < ?", scope, key, fingerprint, false, time.Now()).
+		Update("locked_until", lockedUntil)
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to take over key", res.Error, "key", key)
+		return false, fmt.Errorf("failed to take over idempotency key %w", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// reads from master, a replica may not have seen the reservation yet
+func (r *IdempotencyRepo) SearchByKey(ctx context.Context, scope, key string) (*types.IdempotencyKey, error) {
+	logTag := "[IdempotencyRepo][SearchByKey]"
+	log.InfofWithContext(ctx, logTag+" fetching idempotency key", "scope", scope, "key", key)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var record types.IdempotencyKey
+	if err := db.Where("scope = ? AND idempotency_key = ?", scope, key).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" idempotency key not found", "key", key)
+			return nil, fmt.Errorf("idempotency key not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch idempotency key", err, "key", key)
+		return nil, fmt.Errorf("failed to fetch idempotency key %w", err)
+	}
+
+	return &record, nil
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, scope, key string, status int, body string) error {
+	logTag := "[IdempotencyRepo][Complete]"
+	log.InfofWithContext(ctx, logTag+" storing response for idempotency key", "scope", scope, "key", key, "status", status)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Model(&types.IdempotencyKey{}).
+		Where("scope = ? AND idempotency_key = ?", scope, key).
+		Updates(map[string]interface{}{
+			"completed":       true,
+			"response_status": status,
+			"response_body":   body,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to store response", res.Error, "key", key)
+		return fmt.Errorf("failed to store idempotent response %w", res.Error)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepo) Delete(ctx context.Context, scope, key string) error {
+	logTag := "[IdempotencyRepo][Delete]"
+	log.InfofWithContext(ctx, logTag+" releasing idempotency key", "scope", scope, "key", key)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Where("scope = ? AND idempotency_key = ?", scope, key).Delete(&types.IdempotencyKey{}).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to release key", err, "key", key)
+		return fmt.Errorf("failed to release idempotency key %w", err)
+	}
+
+	return nil
+}
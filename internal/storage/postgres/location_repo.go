@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+type LocationRepo struct {
+	DB *Postgres
+}
+
+func NewLocationRepo(db *Postgres) *LocationRepo {
+	return &LocationRepo{
+		DB: db,
+	}
+}
+
+func (r *LocationRepo) Create(ctx context.Context, location *types.Location) (*types.Location, error) {
+	logTag := "[LocationRepo][Create]"
+	log.InfofWithContext(ctx, logTag+" creating location", "code", location.Code)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Create(location).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create location", err, "code", location.Code)
+		return nil, fmt.Errorf("failed to create location %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" location created successfully", "location_id", location.ID)
+	return location, nil
+}
+
+func (r *LocationRepo) SearchByID(ctx context.Context, id int64) (*types.Location, error) {
+	logTag := "[LocationRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching location", "location_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var location types.Location
+	if err := db.Where("id = ?", id).First(&location).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" location not found", "location_id", id)
+			return nil, fmt.Errorf("location not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch location", err, "location_id", id)
+		return nil, fmt.Errorf("failed to fetch location %w", err)
+	}
+
+	return &location, nil
+}
+
+// the location stock changes fall back to when they don't name one
+func (r *LocationRepo) GetDefault(ctx context.Context) (*types.Location, error) {
+	logTag := "[LocationRepo][GetDefault]"
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var location types.Location
+	if err := db.Where("is_default").First(&location).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.ErrorfWithContext(ctx, logTag+" no default location configured", err)
+			return nil, fmt.Errorf("no default location configured")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch default location", err)
+		return nil, fmt.Errorf("failed to fetch default location %w", err)
+	}
+
+	return &location, nil
+}
+
+func (r *LocationRepo) GetAll(ctx context.Context) ([]types.Location, error) {
+	logTag := "[LocationRepo][GetAll]"
+	log.InfofWithContext(ctx, logTag+" fetching locations")
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var locations []types.Location
+	if err := db.Order("priority, id").Find(&locations).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch locations", err)
+		return nil, fmt.Errorf("failed to fetch locations %w", err)
+	}
+
+	return locations, nil
+}
+
+func (r *LocationRepo) Update(ctx context.Context, location *types.Location) error {
+	logTag := "[LocationRepo][Update]"
+	log.InfofWithContext(ctx, logTag+" updating location", "location_id", location.ID)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Model(&types.Location{}).
+		Where("id = ?", location.ID).
+		Updates(map[string]interface{}{
+			"name":        location.Name,
+			"country":     gorm.Expr("NULLIF(?, '')", location.Country),
+			"state":       gorm.Expr("NULLIF(?, '')", location.State),
+			"postal_code": gorm.Expr("NULLIF(?, '')", location.PostalCode),
+			"priority":    location.Priority,
+			"is_active":   location.IsActive,
+			"updated_at":  location.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update location", res.Error, "location_id", location.ID)
+		return fmt.Errorf("failed to update location %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("location not found")
+	}
+
+	return nil
+}
+
+func (r *LocationRepo) CreateTransferWithTx(tx *gorm.DB, ctx context.Context, transfer *types.StockTransfer) error {
+	logTag := "[LocationRepo][CreateTransferWithTx]"
+	log.InfofWithContext(ctx, logTag+" recording stock transfer", "product_id", transfer.ProductID, "from", transfer.FromLocationID, "to", transfer.ToLocationID)
+
+	if err := tx.Create(transfer).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to record stock transfer", err, "product_id", transfer.ProductID)
+		return fmt.Errorf("failed to record stock transfer %w", err)
+	}
+
+	return nil
+}
+
+// pages through the transfers of a product, newest first
+func (r *LocationRepo) GetTransfers(ctx context.Context, productID int64, limit, offset int) ([]types.StockTransfer, int64, error) {
+	logTag := "[LocationRepo][GetTransfers]"
+	log.InfofWithContext(ctx, logTag+" fetching stock transfers", "product_id", productID, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var total int64
+	if err := db.Model(&types.StockTransfer{}).Where("product_id = ?", productID).Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count stock transfers", err, "product_id", productID)
+		return nil, 0, fmt.Errorf("failed to count stock transfers %w", err)
+	}
+
+	var transfers []types.StockTransfer
+	if err := db.Where("product_id = ?", productID).Order("id DESC").Limit(limit).Offset(offset).Find(&transfers).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock transfers", err, "product_id", productID)
+		return nil, 0, fmt.Errorf("failed to fetch stock transfers %w", err)
+	}
+
+	return transfers, total, nil
+}
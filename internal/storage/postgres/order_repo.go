@@ -8,6 +8,7 @@ import (
 	"github.com/omniful/go_commons/log"
 	"github.com/si/internal/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepo struct {
@@ -104,10 +105,33 @@ func (r *OrderRepo) GetOrderDetails(ctx context.Context, orderId int64) (*types.
         return nil, fmt.Errorf("failed to fetch user: %w", err)
     }
 
+	//get returns raised against the order
+	returns, err := fetchOrderReturns(db, orderId)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch order returns", err, "order_id", orderId)
+		return nil, err
+	}
+
+	//get discounts applied to the order
+	var discounts []types.OrderDiscount
+	if err := db.Where("order_id = ?", orderId).Order("id").Find(&discounts).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch order discounts", err, "order_id", orderId)
+		return nil, fmt.Errorf("failed to fetch order discounts: %w", err)
+	}
+
+	taxBreakdown, err := types.BuildTaxBreakdown(orderItems)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to build tax breakdown", err, "order_id", orderId)
+		return nil, err
+	}
+
 	orderWithDetails := &types.OrderWithDetails{
-        Order: order,
-        Items: orderItems,
-        User:  &user,
+        Order:        order,
+        Items:        orderItems,
+        User:         &user,
+        Returns:      returns,
+        Discounts:    discounts,
+        TaxBreakdown: taxBreakdown,
     }
 
     log.InfofWithContext(ctx, logTag+" order with details fetched successfully", "order_id", order.ID, "items_count", len(orderItems))
@@ -147,6 +171,15 @@ func (r *OrderRepo) SearchOrders(ctx context.Context, params types.OrderSearchPa
         baseQuery = baseQuery.Where("o.id IN (?)", subQuery)
     }
 
+    //filter by return status
+    if params.ReturnStatus != "" {
+        returnQuery := db.Table("order_returns rt").
+            Select("rt.order_id").
+            Where("rt.status = ?", params.ReturnStatus)
+
+        baseQuery = baseQuery.Where("o.id IN (?)", returnQuery)
+    }
+
     //count query
     var total int64
     countQuery := baseQuery.Session(&gorm.Session{}) // clean session
@@ -178,6 +211,12 @@ func (r *OrderRepo) SearchOrders(ctx context.Context, params types.OrderSearchPa
             continue
         }
 
+        returns, err := fetchOrderReturns(db, orderResult.ID)
+        if err != nil {
+            log.ErrorfWithContext(ctx, logTag+" failed to fetch order returns", err, "order_id", orderResult.ID)
+            continue
+        }
+
         user := types.User{
             ID:    orderResult.UserID,
             Name:  orderResult.UserName,
@@ -185,9 +224,10 @@ func (r *OrderRepo) SearchOrders(ctx context.Context, params types.OrderSearchPa
         }
 
         orderWithDetail := &types.OrderWithDetails{
-            Order: orderResult.Order,
-            Items: items,
-            User:  &user,
+            Order:   orderResult.Order,
+            Items:   items,
+            User:    &user,
+            Returns: returns,
         }
         orderWithDetails = append(orderWithDetails, orderWithDetail)
     }
@@ -214,6 +254,165 @@ func (r *OrderRepo) Update(ctx context.Context, order *types.Order) (*types.Orde
     return order, nil
 }
 
+// moves the order from one status to another only if it is still in the expected status,
+// returns false when a concurrent update changed the status first
+func (r *OrderRepo) UpdateStatus(ctx context.Context, id int64, from, to types.OrderStatus) (bool, error) {
+    logTag := "[OrderRepo][UpdateStatus]"
+    log.InfofWithContext(ctx, logTag+" updating order status", "order_id", id, "from", from, "to", to)
+
+    db := r.DB.Cluster.GetMasterDB(ctx)
+
+    res := db.Model(&types.Order{}).
+        Where("id = ? AND status = ?", id, from).
+        Updates(map[string]interface{}{
+            "status":     to,
+            "updated_at": time.Now(),
+        })
+    if res.Error != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to update order status", res.Error, "order_id", id)
+        return false, fmt.Errorf("failed to update order status %w", res.Error)
+    }
+
+    if res.RowsAffected == 0 {
+        log.WarnfWithContext(ctx, logTag+" order status changed concurrently", "order_id", id, "expected", from)
+        return false, nil
+    }
+
+    log.InfofWithContext(ctx, logTag+" order status updated successfully", "order_id", id, "status", to)
+    return true, nil
+}
+
+// fetches the order with a row lock held until the transaction ends
+func (r *OrderRepo) LockByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.Order, error) {
+    logTag := "[OrderRepo][LockByIDWithTx]"
+    log.InfofWithContext(ctx, logTag+" locking order", "order_id", id)
+
+    var order types.Order
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&order).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            log.WarnfWithContext(ctx, logTag+" order not found", "order_id", id)
+            return nil, fmt.Errorf("order not found")
+        }
+        log.ErrorfWithContext(ctx, logTag+" failed to lock order", err, "order_id", id)
+        return nil, fmt.Errorf("failed to lock order %w", err)
+    }
+
+    return &order, nil
+}
+
+func (r *OrderRepo) GetOrderItemsWithTx(tx *gorm.DB, ctx context.Context, orderID int64) ([]types.OrderItem, error) {
+    logTag := "[OrderRepo][GetOrderItemsWithTx]"
+    log.InfofWithContext(ctx, logTag+" fetching order items", "order_id", orderID)
+
+    var items []types.OrderItem
+    if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to fetch order items", err, "order_id", orderID)
+        return nil, fmt.Errorf("failed to fetch order items: %w", err)
+    }
+
+    return items, nil
+}
+
+func (r *OrderRepo) CancelWithTx(tx *gorm.DB, ctx context.Context, order *types.Order) error {
+    logTag := "[OrderRepo][CancelWithTx]"
+    log.InfofWithContext(ctx, logTag+" cancelling order", "order_id", order.ID, "reason", order.CancellationReason)
+
+    res := tx.Model(&types.Order{}).
+        Where("id = ?", order.ID).
+        Updates(map[string]interface{}{
+            "status":              types.OrderStatusCancelled,
+            "cancellation_reason": order.CancellationReason,
+            "cancellation_note":   order.CancellationNote,
+            "cancelled_at":        order.CancelledAt,
+            "updated_at":          order.UpdatedAt,
+        })
+    if res.Error != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to cancel order", res.Error, "order_id", order.ID)
+        return fmt.Errorf("failed to cancel order %w", res.Error)
+    }
+
+    log.InfofWithContext(ctx, logTag+" order cancelled successfully", "order_id", order.ID)
+    return nil
+}
+
+// moves a partially shipped order on to status once the part that has not shipped is cancelled, keeping why
+func (r *OrderRepo) CancelRemainderWithTx(tx *gorm.DB, ctx context.Context, order *types.Order) error {
+    logTag := "[OrderRepo][CancelRemainderWithTx]"
+    log.InfofWithContext(ctx, logTag+" cancelling unshipped remainder", "order_id", order.ID, "status", order.Status, "reason", order.CancellationReason)
+
+    res := tx.Model(&types.Order{}).
+        Where("id = ?", order.ID).
+        Updates(map[string]interface{}{
+            "status":              order.Status,
+            "cancellation_reason": order.CancellationReason,
+            "cancellation_note":   order.CancellationNote,
+            "cancelled_at":        order.CancelledAt,
+            "updated_at":          order.UpdatedAt,
+        })
+    if res.Error != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to cancel unshipped remainder", res.Error, "order_id", order.ID)
+        return fmt.Errorf("failed to cancel unshipped remainder %w", res.Error)
+    }
+
+    return nil
+}
+
+func (r *OrderRepo) MarkPaidWithTx(tx *gorm.DB, ctx context.Context, order *types.Order) error {
+    logTag := "[OrderRepo][MarkPaidWithTx]"
+    log.InfofWithContext(ctx, logTag+" marking order paid", "order_id", order.ID)
+
+    res := tx.Model(&types.Order{}).
+        Where("id = ?", order.ID).
+        Updates(map[string]interface{}{
+            "paid_at":    order.PaidAt,
+            "updated_at": order.UpdatedAt,
+        })
+    if res.Error != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to mark order paid", res.Error, "order_id", order.ID)
+        return fmt.Errorf("failed to mark order paid %w", res.Error)
+    }
+
+    return nil
+}
+
+func (r *OrderRepo) UpdateStatusWithTx(tx *gorm.DB, ctx context.Context, id int64, status types.OrderStatus) error {
+    logTag := "[OrderRepo][UpdateStatusWithTx]"
+    log.InfofWithContext(ctx, logTag+" updating order status", "order_id", id, "status", status)
+
+    res := tx.Model(&types.Order{}).
+        Where("id = ?", id).
+        Updates(map[string]interface{}{
+            "status":     status,
+            "updated_at": time.Now(),
+        })
+    if res.Error != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to update order status", res.Error, "order_id", id)
+        return fmt.Errorf("failed to update order status %w", res.Error)
+    }
+
+    return nil
+}
+
+// records the exchange rate converted lines of the order were priced with, set once by the first converted line
+func (r *OrderRepo) SetFxRateWithTx(tx *gorm.DB, ctx context.Context, id int64, baseCurrency string, rate types.Rate) error {
+    logTag := "[OrderRepo][SetFxRateWithTx]"
+    log.InfofWithContext(ctx, logTag+" recording order exchange rate", "order_id", id, "fx_base_currency", baseCurrency, "fx_rate", rate)
+
+    res := tx.Model(&types.Order{}).
+        Where("id = ?", id).
+        Updates(map[string]interface{}{
+            "fx_base_currency": baseCurrency,
+            "fx_rate":          rate,
+            "updated_at":       time.Now(),
+        })
+    if res.Error != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to record order exchange rate", res.Error, "order_id", id)
+        return fmt.Errorf("failed to record order exchange rate %w", res.Error)
+    }
+
+    return nil
+}
+
 func (r *OrderRepo) AddOrderItem(tx *gorm.DB, ctx context.Context, item *types.OrderItem) (*types.OrderItem, error) {
     logTag := "[OrderRepo][AddOrderItem]"
     log.InfofWithContext(ctx, logTag+" adding order item", "order_id", item.OrderID, "product_id", item.ProductID)
@@ -248,13 +447,27 @@ func (r *OrderRepo) GetOrderItem(ctx context.Context, orderID, itemID int64) (*t
     return &orderItem, nil
 }
 
-func (r *OrderRepo) UpdateOrderItem(ctx context.Context, item *types.OrderItem) (*types.OrderItem, error) {
+func (r *OrderRepo) GetOrderItemWithTx(tx *gorm.DB, ctx context.Context, orderID, itemID int64) (*types.OrderItem, error) {
+    logTag := "[OrderRepo][GetOrderItemWithTx]"
+
+    var orderItem types.OrderItem
+    if err := tx.Where("id = ? AND order_id = ?", itemID, orderID).First(&orderItem).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            log.WarnfWithContext(ctx, logTag+" order item not found", "order_id", orderID, "item_id", itemID)
+            return nil, fmt.Errorf("order item not found")
+        }
+        log.ErrorfWithContext(ctx, logTag+" failed to fetch order item", err, "order_id", orderID, "item_id", itemID)
+        return nil, fmt.Errorf("failed to fetch order item %w", err)
+    }
+
+    return &orderItem, nil
+}
+
+func (r *OrderRepo) UpdateOrderItem(tx *gorm.DB, ctx context.Context, item *types.OrderItem) (*types.OrderItem, error) {
     logTag := "[OrderRepo][UpdateOrderItem]"
     log.InfofWithContext(ctx, logTag+" updating order item", "item_id", item.ID)
 
-    db := r.DB.Cluster.GetMasterDB(ctx)
-
-    if err := db.Save(item).Error; err != nil {
+    if err := tx.Save(item).Error; err != nil {
         log.ErrorfWithContext(ctx, logTag+" failed to update order item", err, "item_id", item.ID)
         return nil, fmt.Errorf("failed to update order item %w", err)
     }
@@ -282,28 +495,141 @@ func (r *OrderRepo) RemoveOrderItem(tx *gorm.DB, ctx context.Context, orderID, i
     return nil
 }
 
-func (r *OrderRepo) RecalculateOrderTotal(tx *gorm.DB, ctx context.Context, orderID int64) error {
+// reprices the order from its current items: reapplies the redeemed coupons, spreads the discounts
+// over the lines, recomputes the per line tax with the given rules and stores the new totals
+func (r *OrderRepo) RecalculateOrderTotal(tx *gorm.DB, ctx context.Context, orderID int64, taxRules []types.TaxRule) error {
     logTag := "[OrderRepo][RecalculateOrderTotal]"
     log.InfofWithContext(ctx, logTag+" recalculating order total", "order_id", orderID)
 
+    var order types.Order
+    if err := tx.Where("id = ?", orderID).First(&order).Error; err != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to fetch order", err, "order_id", orderID)
+        return fmt.Errorf("failed to fetch order: %w", err)
+    }
 
     var items []types.OrderItem
-    if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+    if err := tx.Where("order_id = ?", orderID).Order("id").Find(&items).Error; err != nil {
         log.ErrorfWithContext(ctx, logTag+" failed to fetch order items", err, "order_id", orderID)
         return fmt.Errorf("failed to fetch order items: %w", err)
     }
 
-    var newTotal float64
-    for _, item := range items {
-        newTotal += item.Price * float64(item.Quantity)
+    lines, err := r.pricingLinesWithTx(tx, items)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to fetch product categories", err, "order_id", orderID)
+        return err
+    }
+
+    var discounts []types.OrderDiscount
+    if err := tx.Where("order_id = ?", orderID).Order("id").Find(&discounts).Error; err != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to fetch order discounts", err, "order_id", orderID)
+        return fmt.Errorf("failed to fetch order discounts: %w", err)
+    }
+
+    coupons := make([]types.Coupon, 0, len(discounts))
+    if len(discounts) > 0 {
+        couponIDs := make([]int64, 0, len(discounts))
+        for _, discount := range discounts {
+            couponIDs = append(couponIDs, discount.CouponID)
+        }
+
+        var rows []types.Coupon
+        if err := tx.Where("id IN ?", couponIDs).Find(&rows).Error; err != nil {
+            log.ErrorfWithContext(ctx, logTag+" failed to fetch coupons", err, "order_id", orderID)
+            return fmt.Errorf("failed to fetch coupons: %w", err)
+        }
+        couponsByID := make(map[int64]types.Coupon, len(rows))
+        for _, coupon := range rows {
+            couponsByID[coupon.ID] = coupon
+        }
+
+        for _, discount := range discounts {
+            coupons = append(coupons, couponsByID[discount.CouponID])
+        }
+    }
+
+    pricing, err := types.PriceOrder(lines, coupons, order.Currency, order.TaxRegion, taxRules)
+    if err != nil {
+        log.ErrorfWithContext(ctx, logTag+" failed to price order", err, "order_id", orderID)
+        return err
+    }
+
+    for i, discount := range discounts {
+        if pricing.Discounts[i] == discount.Amount {
+            continue
+        }
+        if err := tx.Model(&types.OrderDiscount{}).Where("id = ?", discount.ID).Update("amount", pricing.Discounts[i]).Error; err != nil {
+            log.ErrorfWithContext(ctx, logTag+" failed to update order discount", err, "discount_id", discount.ID)
+            return fmt.Errorf("failed to update order discount %w", err)
+        }
+    }
+
+    for i, item := range items {
+        line := pricing.Lines[i]
+        err := tx.Model(&types.OrderItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+            "discount_amount": line.Discount,
+            "tax_rule_name":   gorm.Expr("NULLIF(?, '')", line.RuleName),
+            "tax_rate":        line.Rate,
+            "tax_amount":      line.Tax,
+            "tax_inclusive":   line.Inclusive,
+        }).Error
+        if err != nil {
+            log.ErrorfWithContext(ctx, logTag+" failed to update order item tax", err, "item_id", item.ID)
+            return fmt.Errorf("failed to update order item tax %w", err)
+        }
     }
 
-    if err := tx.Model(&types.Order{}).Where("id = ?", orderID).Update("total_amount", newTotal).Error; err != nil {
+    err = tx.Model(&types.Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+        "subtotal_amount": pricing.Subtotal,
+        "discount_amount": pricing.Discount,
+        "tax_amount":      pricing.Tax,
+        "total_amount":    pricing.Total,
+    }).Error
+    if err != nil {
         log.ErrorfWithContext(ctx, logTag+" failed to update order total", err, "order_id", orderID)
         return fmt.Errorf("failed to update order total %w", err)
     }
 
-    log.InfofWithContext(ctx, logTag+" order total recalculated successfully", "order_id", orderID, "new_total", newTotal)
+    log.InfofWithContext(ctx, logTag+" order total recalculated successfully", "order_id", orderID, "new_total", pricing.Total, "discount", pricing.Discount, "tax", pricing.Tax)
+    return nil
+}
+
+// builds the pricing input for the items, the category path of the product drives coupon scope and tax rules
+func (r *OrderRepo) pricingLinesWithTx(tx *gorm.DB, items []types.OrderItem) ([]types.DiscountableLine, error) {
+    productIDs := make([]int64, 0, len(items))
+    for _, item := range items {
+        productIDs = append(productIDs, item.ProductID)
+    }
+
+    categoryPaths, err := categoryPathsWithTx(tx, productIDs)
+    if err != nil {
+        return nil, err
+    }
+
+    lines := make([]types.DiscountableLine, 0, len(items))
+    for _, item := range items {
+        lines = append(lines, types.DiscountableLine{
+            ProductID:    item.ProductID,
+            CategoryPath: categoryPaths[item.ProductID],
+            Quantity:     item.Quantity,
+            Price:        item.Price,
+        })
+    }
+
+    return lines, nil
+}
+
+func (r *OrderRepo) AddDiscountsWithTx(tx *gorm.DB, ctx context.Context, orderID int64, discounts []types.OrderDiscount) error {
+    logTag := "[OrderRepo][AddDiscountsWithTx]"
+    log.InfofWithContext(ctx, logTag+" adding order discounts", "order_id", orderID, "discounts_count", len(discounts))
+
+    for i := range discounts {
+        discounts[i].OrderID = orderID
+        if err := tx.Create(&discounts[i]).Error; err != nil {
+            log.ErrorfWithContext(ctx, logTag+" failed to create order discount", err, "coupon_id", discounts[i].CouponID)
+            return fmt.Errorf("failed to create order discount %w", err)
+        }
+    }
+
     return nil
 }
 
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PriceChangeRepo struct {
+	DB *Postgres
+}
+
+func NewPriceChangeRepo(db *Postgres) *PriceChangeRepo {
+	return &PriceChangeRepo{
+		DB: db,
+	}
+}
+
+func (r *PriceChangeRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, change *types.PriceChange) error {
+	logTag := "[PriceChangeRepo][CreateWithTx]"
+
+	if err := tx.Create(change).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to record price change", err, "product_id", change.ProductID)
+		return fmt.Errorf("failed to record price change %w", err)
+	}
+
+	return nil
+}
+
+func (r *PriceChangeRepo) Create(ctx context.Context, change *types.PriceChange) (*types.PriceChange, error) {
+	logTag := "[PriceChangeRepo][Create]"
+	log.InfofWithContext(ctx, logTag+" scheduling price change", "product_id", change.ProductID, "currency", change.Currency, "effective_from", change.EffectiveFrom)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Create(change).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to schedule price change", err, "product_id", change.ProductID)
+		return nil, fmt.Errorf("failed to schedule price change %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" price change scheduled successfully", "change_id", change.ID)
+	return change, nil
+}
+
+// writes back a scheduled change claimed with ClaimDueWithTx once it has been applied
+func (r *PriceChangeRepo) SaveWithTx(tx *gorm.DB, ctx context.Context, change *types.PriceChange) error {
+	logTag := "[PriceChangeRepo][SaveWithTx]"
+
+	change.UpdatedAt = time.Now()
+	if err := tx.Save(change).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to save price change", err, "change_id", change.ID)
+		return fmt.Errorf("failed to save price change %w", err)
+	}
+
+	return nil
+}
+
+// locks the scheduled change that came due first, nil when none is due. Changes locked by another
+// scheduler are skipped
+func (r *PriceChangeRepo) ClaimDueWithTx(tx *gorm.DB, ctx context.Context, now time.Time) (*types.PriceChange, error) {
+	logTag := "[PriceChangeRepo][ClaimDueWithTx]"
+
+	var change types.PriceChange
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND effective_from <= ?", types.PriceChangeStatusScheduled, now).
+		Order("effective_from, id").
+		First(&change).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to claim price change", err)
+		return nil, fmt.Errorf("failed to claim price change %w", err)
+	}
+
+	return &change, nil
+}
+
+// takes a scheduled change that could not be applied out of the queue, keeping the reason
+func (r *PriceChangeRepo) MarkFailed(ctx context.Context, id int64, reason string) error {
+	logTag := "[PriceChangeRepo][MarkFailed]"
+	log.InfofWithContext(ctx, logTag+" marking price change failed", "change_id", id, "reason", reason)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	err := db.Model(&types.PriceChange{}).
+		Where("id = ? AND status = ?", id, types.PriceChangeStatusScheduled).
+		Updates(map[string]interface{}{
+			"status":     types.PriceChangeStatusFailed,
+			"error":      reason,
+			"updated_at": time.Now(),
+		}).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to mark price change failed", err, "change_id", id)
+		return fmt.Errorf("failed to mark price change failed %w", err)
+	}
+
+	return nil
+}
+
+// the applied changes of a product newest first, all currencies when currency is empty
+func (r *PriceChangeRepo) GetHistory(ctx context.Context, productID int64, currency string, limit, offset int) ([]types.PriceChange, int64, error) {
+	logTag := "[PriceChangeRepo][GetHistory]"
+	log.InfofWithContext(ctx, logTag+" fetching price history", "product_id", productID, "currency", currency, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	query := db.Model(&types.PriceChange{}).Where("product_id = ? AND status = ?", productID, types.PriceChangeStatusApplied)
+	if currency != "" {
+		query = query.Where("currency = ?", currency)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count price history", err, "product_id", productID)
+		return nil, 0, fmt.Errorf("failed to count price history %w", err)
+	}
+
+	var changes []types.PriceChange
+	if err := query.Order("applied_at DESC, id DESC").Limit(limit).Offset(offset).Find(&changes).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch price history", err, "product_id", productID)
+		return nil, 0, fmt.Errorf("failed to fetch price history %w", err)
+	}
+
+	return changes, total, nil
+}
+
+// the change in effect for a product and currency at a moment, nil without an error when the history
+// starts after it
+func (r *PriceChangeRepo) GetAt(ctx context.Context, productID int64, currency string, at time.Time) (*types.PriceChange, error) {
+	logTag := "[PriceChangeRepo][GetAt]"
+	log.InfofWithContext(ctx, logTag+" fetching price in effect", "product_id", productID, "currency", currency, "at", at)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var change types.PriceChange
+	err := db.Where("product_id = ? AND currency = ? AND status = ? AND applied_at <= ?", productID, currency, types.PriceChangeStatusApplied, at).
+		Order("applied_at DESC, id DESC").
+		First(&change).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch price in effect", err, "product_id", productID)
+		return nil, fmt.Errorf("failed to fetch price in effect %w", err)
+	}
+
+	return &change, nil
+}
+
+// the changes of a product still waiting to take effect and those that failed to, soonest first
+func (r *PriceChangeRepo) GetScheduled(ctx context.Context, productID int64) ([]types.PriceChange, error) {
+	logTag := "[PriceChangeRepo][GetScheduled]"
+	log.InfofWithContext(ctx, logTag+" fetching scheduled price changes", "product_id", productID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var changes []types.PriceChange
+	err := db.Where("product_id = ? AND status IN ?", productID, []types.PriceChangeStatus{types.PriceChangeStatusScheduled, types.PriceChangeStatusFailed}).
+		Order("effective_from, id").
+		Find(&changes).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch scheduled price changes", err, "product_id", productID)
+		return nil, fmt.Errorf("failed to fetch scheduled price changes %w", err)
+	}
+
+	return changes, nil
+}
+
+// cancels a change that has not been applied yet
+func (r *PriceChangeRepo) Cancel(ctx context.Context, productID, id int64) (*types.PriceChange, error) {
+	logTag := "[PriceChangeRepo][Cancel]"
+	log.InfofWithContext(ctx, logTag+" cancelling price change", "product_id", productID, "change_id", id)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var change types.PriceChange
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND product_id = ?", id, productID).First(&change).Error
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("price change not found")
+		}
+		if err != nil {
+			return err
+		}
+		if change.Status != types.PriceChangeStatusScheduled {
+			return fmt.Errorf("price change is already %s", change.Status)
+		}
+
+		change.Status = types.PriceChangeStatusCancelled
+		change.UpdatedAt = time.Now()
+		return tx.Save(&change).Error
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to cancel price change", err, "change_id", id)
+		return nil, err
+	}
+
+	return &change, nil
+}
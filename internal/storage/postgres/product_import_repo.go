@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProductImportRepo struct {
+	DB *Postgres
+}
+
+func NewProductImportRepo(db *Postgres) *ProductImportRepo {
+	return &ProductImportRepo{
+		DB: db,
+	}
+}
+
+func (r *ProductImportRepo) Create(ctx context.Context, job *types.ProductImport) (*types.ProductImport, error) {
+	logTag := "[ProductImportRepo][Create]"
+	log.InfofWithContext(ctx, logTag+" creating product import", "format", job.Format, "total_rows", job.TotalRows)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Create(job).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create product import", err)
+		return nil, fmt.Errorf("failed to create product import %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" product import created successfully", "import_id", job.ID)
+	return job, nil
+}
+
+func (r *ProductImportRepo) SearchByID(ctx context.Context, id int64) (*types.ProductImport, error) {
+	logTag := "[ProductImportRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching product import", "import_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var job types.ProductImport
+	if err := db.Omit("payload").Where("id = ?", id).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" product import not found", "import_id", id)
+			return nil, fmt.Errorf("product import not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch product import", err, "import_id", id)
+		return nil, fmt.Errorf("failed to fetch product import %w", err)
+	}
+
+	return &job, nil
+}
+
+// the imports newest first, without their files
+func (r *ProductImportRepo) GetAll(ctx context.Context, limit, offset int) ([]types.ProductImport, int64, error) {
+	logTag := "[ProductImportRepo][GetAll]"
+	log.InfofWithContext(ctx, logTag+" fetching product imports", "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var total int64
+	if err := db.Model(&types.ProductImport{}).Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count product imports", err)
+		return nil, 0, fmt.Errorf("failed to count product imports %w", err)
+	}
+
+	var jobs []types.ProductImport
+	if err := db.Omit("payload").Order("id DESC").Limit(limit).Offset(offset).Find(&jobs).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch product imports", err)
+		return nil, 0, fmt.Errorf("failed to fetch product imports %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// takes the oldest pending import, or a running one that made no progress for staleAfter because its worker
+// went away, and marks it running. Nil when there is nothing to do
+func (r *ProductImportRepo) ClaimNext(ctx context.Context, staleAfter time.Duration) (*types.ProductImport, error) {
+	logTag := "[ProductImportRepo][ClaimNext]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var job types.ProductImport
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND updated_at < ?)", types.ProductImportStatusPending, types.ProductImportStatusRunning, time.Now().Add(-staleAfter)).
+			Order("id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if job.StartedAt == nil {
+			job.StartedAt = &now
+		}
+		job.Status = types.ProductImportStatusRunning
+		job.UpdatedAt = now
+
+		return tx.Model(&types.ProductImport{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"status":     job.Status,
+			"started_at": job.StartedAt,
+			"updated_at": job.UpdatedAt,
+		}).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to claim product import", err)
+		return nil, fmt.Errorf("failed to claim product import %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" product import claimed", "import_id", job.ID, "processed_rows", job.ProcessedRows)
+	return &job, nil
+}
+
+// stores the counts of a running import together with the row errors found since the last save, so a
+// resumed import carries on from ProcessedRows
+func (r *ProductImportRepo) SaveProgress(ctx context.Context, job *types.ProductImport, rowErrors []types.ProductImportError) error {
+	logTag := "[ProductImportRepo][SaveProgress]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(rowErrors) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "import_id"}, {Name: "row_number"}},
+				DoNothing: true,
+			}).Create(&rowErrors).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&types.ProductImport{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"processed_rows": job.ProcessedRows,
+			"created_count":  job.CreatedCount,
+			"updated_count":  job.UpdatedCount,
+			"failed_count":   job.FailedCount,
+			"updated_at":     time.Now(),
+		}).Error
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to save import progress", err, "import_id", job.ID)
+		return fmt.Errorf("failed to save import progress %w", err)
+	}
+
+	return nil
+}
+
+// closes the import with its final status and drops the file
+func (r *ProductImportRepo) Finish(ctx context.Context, job *types.ProductImport) error {
+	logTag := "[ProductImportRepo][Finish]"
+	log.InfofWithContext(ctx, logTag+" finishing product import", "import_id", job.ID, "status", job.Status)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	err := db.Model(&types.ProductImport{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":         job.Status,
+		"error":          gorm.Expr("NULLIF(?, '')", job.Error),
+		"payload":        nil,
+		"processed_rows": job.ProcessedRows,
+		"created_count":  job.CreatedCount,
+		"updated_count":  job.UpdatedCount,
+		"failed_count":   job.FailedCount,
+		"finished_at":    job.FinishedAt,
+		"updated_at":     time.Now(),
+	}).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to finish product import", err, "import_id", job.ID)
+		return fmt.Errorf("failed to finish product import %w", err)
+	}
+
+	return nil
+}
+
+func (r *ProductImportRepo) GetErrors(ctx context.Context, importID int64, limit, offset int) ([]types.ProductImportError, int64, error) {
+	logTag := "[ProductImportRepo][GetErrors]"
+	log.InfofWithContext(ctx, logTag+" fetching import errors", "import_id", importID, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	query := db.Model(&types.ProductImportError{}).Where("import_id = ?", importID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count import errors", err, "import_id", importID)
+		return nil, 0, fmt.Errorf("failed to count import errors %w", err)
+	}
+
+	var rowErrors []types.ProductImportError
+	if err := query.Order("row_number").Limit(limit).Offset(offset).Find(&rowErrors).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch import errors", err, "import_id", importID)
+		return nil, 0, fmt.Errorf("failed to fetch import errors %w", err)
+	}
+
+	return rowErrors, total, nil
+}
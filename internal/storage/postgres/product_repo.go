@@ -2,12 +2,17 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/omniful/go_commons/log"
 	"github.com/si/internal/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 
@@ -22,13 +27,48 @@ func NewProductRepo(postgres *Postgres) *ProductRepo {
 }
 
 
-func (r *ProductRepo) Create(ctx context.Context, prod *types.Product) (*types.Product, error) {
+// creates the product with its initial stock held at locationID
+func (r *ProductRepo) Create(ctx context.Context, prod *types.Product, locationID int64) (*types.Product, error) {
 	logTag := "[ProductRepo][Create]"
 	log.InfofWithContext(ctx, logTag+ " creating product", "product", prod)
 
 	db := r.DB.Cluster.GetMasterDB(ctx)
 
-	if err := db.Create(prod).Error; err != nil {
+	// the initial stock opens the product's ledger, so all rows are written together
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(prod).Error; err != nil {
+			return err
+		}
+
+		// the price history opens with the price the product is created at
+		price := prod.Price
+		if err := tx.Create(types.AppliedPriceChange(prod.ID, prod.Currency, &price, nil, types.ActorFromContext(ctx), "opening price")).Error; err != nil {
+			return err
+		}
+
+		err := tx.Exec(`INSERT INTO product_stocks (product_id, location_id, stock_quantity) VALUES (?, ?, ?)`,
+			prod.ID, locationID, prod.StockQuantity).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&types.StockMovement{
+			ProductID:         prod.ID,
+			LocationID:        locationID,
+			Delta:             prod.StockQuantity,
+			ResultingQuantity: prod.StockQuantity,
+			Reason:            types.StockMovementReasonOpeningBalance,
+			Actor:             types.ActorFromContext(ctx),
+			CreatedAt:         time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// a product can start out below its reorder point
+		return r.EvaluateReorderPointWithTx(tx, ctx, prod.ID)
+	})
+	if err != nil {
 		log.ErrorfWithContext(ctx, logTag+" error when creating product")
 		return nil, err
 	}
@@ -56,37 +96,301 @@ func (r *ProductRepo) SearchById(ctx context.Context, id int64) (*types.Product,
 	return product, nil
 }
 
-func (r *ProductRepo) Search(ctx context.Context, name, category string, limit, offset int) ([]*types.Product, int64, error){
-	logTag := "[ProductRepo][Create]"
-	log.InfofWithContext(ctx, logTag+ " fetching product from db", "name", name, "category", category)
+// reads from the master, an import looks up products it may have written a moment ago
+func (r *ProductRepo) GetBySKU(ctx context.Context, sku string) (*types.Product, error) {
+	logTag := "[ProductRepo][GetBySKU]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var product types.Product
+	if err := db.Where("sku = ?", sku).First(&product).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("product not found with sku %s", sku)
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch product", err, "sku", sku)
+		return nil, fmt.Errorf("failed to fetch product %w", err)
+	}
+
+	return &product, nil
+}
+
+// the next top level products matching params after afterID in id order, an export walks the whole result
+// this way without the cost of deep offsets
+func (r *ProductRepo) GetExportBatch(ctx context.Context, params types.ProductSearchParams, afterID int64, limit int) ([]*types.Product, error) {
+	logTag := "[ProductRepo][GetExportBatch]"
 
 	db := r.DB.Cluster.GetSlaveDB(ctx)
-	
-	var total int64
+
 	var products []*types.Product
-	
-	//build query
-	query := db.Model(&types.Product{})
-	if name != "" && category != "" {
-		query = query.Where("name ILIKE ? AND category ILIKE ?", "%"+name+"%", "%"+category+"%")
-	}else if name != ""{
-		query = query.Where("name ILIKE ?", "%"+name+"%")
-	}else {
-		query = query.Where("category ILIKE ?", "%"+category+"%")
+	if err := searchQuery(db, params).Where("products.id > ?", afterID).Order("products.id").Limit(limit).Find(&products).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch products", err, "after_id", afterID)
+		return nil, fmt.Errorf("failed to fetch products %w", err)
 	}
 
-	if err := query.Count(&total).Error; err != nil {
-		log.ErrorfWithContext(ctx, logTag+" error when fetching produc details", err.Error())
-		return nil, 0, err
-	}
+	return products, nil
+}
+
+// pages through the top level products matching params, best match first when there is a query, and counts
+// the whole result into facets. Everything is read in one transaction so the page, total and facets agree
+func (r *ProductRepo) Search(ctx context.Context, params types.ProductSearchParams, priceBands []types.Amount) ([]*types.Product, int64, *types.ProductFacets, error){
+	logTag := "[ProductRepo][Search]"
+	log.InfofWithContext(ctx, logTag+ " fetching product from db", "params", fmt.Sprintf("%+v", params))
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var total int64
+	var products []*types.Product
+	facets := &types.ProductFacets{}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// the threshold of the <% operator, so the fuzzy match can use the trigram index
+		if params.Fuzzy {
+			err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", strconv.FormatFloat(params.FuzzyThreshold, 'f', -1, 64)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := searchQuery(tx, params).Count(&total).Error; err != nil {
+			return err
+		}
+
+		query := searchQuery(tx, params)
+		if rank := searchRank(params); rank != nil {
+			query = query.Order(*rank)
+		}
+		if err := query.Order("created_at DESC").Limit(params.Limit).Offset(params.Offset).Find(&products).Error; err != nil {
+			return err
+		}
+
+		err := searchQuery(tx, params).
+			Select("category_id, COALESCE(category, '') AS name, COUNT(*) AS count").
+			Group("category_id, category").
+			Order("count DESC, name").
+			Scan(&facets.Categories).Error
+		if err != nil {
+			return err
+		}
 
-	if err := query.Limit(limit).Offset(offset).Order("created_at DESC").Find(&products).Error; err != nil {
+		if len(priceBands) == 0 {
+			return nil
+		}
+
+		bounds := make([]string, 0, len(priceBands))
+		for _, band := range priceBands {
+			bounds = append(bounds, band.String())
+		}
+
+		price, priceArgs := searchPriceSQL(params.Currency)
+
+		var bands []struct {
+			Band  int
+			Count int64
+		}
+		err = tx.Table("(?) AS priced", searchQuery(tx, params).Select(price+" AS price", priceArgs...)).
+			Select("width_bucket(price, ?::numeric[]) AS band, COUNT(*) AS count", "{"+strings.Join(bounds, ",")+"}").
+			Where("price IS NOT NULL").
+			Group("band").
+			Scan(&bands).Error
+		if err != nil {
+			return err
+		}
+
+		counts := make(map[int]int64, len(bands))
+		facets.Unpriced = total
+		for _, band := range bands {
+			counts[band.Band] = band.Count
+			facets.Unpriced -= band.Count
+		}
+		facets.PriceBands = types.NewPriceBandFacets(priceBands, counts)
+		facets.PriceBandCurrency = params.Currency
+		return nil
+	})
+	if err != nil {
 		log.ErrorfWithContext(ctx, logTag+" failed to search products", err)
-        return nil, 0, fmt.Errorf("failed to search products: %w", err)
+		return nil, 0, nil, fmt.Errorf("failed to search products: %w", err)
 	}
 
 	log.InfofWithContext(ctx, logTag+" products search completed", "found_count", len(products), "total", total)
-	return products, total, nil
+	return products, total, facets, nil
+}
+
+// what a product costs in the currency, resolved like an order does: its own price, then its price list entry,
+// then its price converted at the configured rate. NULL when there is none
+func searchPriceSQL(currency string) (string, []interface{}) {
+	return `CASE WHEN products.currency = ? THEN products.price ELSE COALESCE(
+			(SELECT pp.price FROM product_prices pp WHERE pp.product_id = products.id AND pp.currency = ?),
+			(SELECT ROUND(products.price * er.rate, 2) FROM exchange_rates er WHERE er.base_currency = products.currency AND er.quote_currency = ?)
+		) END`, []interface{}{currency, currency, currency}
+}
+
+// the filtered products of a search, variants are returned with their parent so only top level products are
+// paged through and a parent also matches on its variants
+func searchQuery(db *gorm.DB, params types.ProductSearchParams) *gorm.DB {
+	query := db.Model(&types.Product{}).Where("parent_id IS NULL")
+
+	if params.Fuzzy {
+		query = query.Where("(? <% products.name OR EXISTS (SELECT 1 FROM products v WHERE v.parent_id = products.id AND ? <% v.name))", params.Query, params.Query)
+	} else if tsQuery := prefixTSQuery(params.Query); tsQuery != "" {
+		query = query.Where("(products.search_vector @@ to_tsquery('simple', ?) OR EXISTS (SELECT 1 FROM products v WHERE v.parent_id = products.id AND v.search_vector @@ to_tsquery('simple', ?)))", tsQuery, tsQuery)
+	}
+
+	if len(params.CategoryIDs) > 0 {
+		query = query.Where("category_id IN ?", params.CategoryIDs)
+	}
+
+	//a variant has its own attributes laid over its parent's, all filters have to hold on the same row
+	if len(params.Attributes) > 0 {
+		own, ownArgs := attributeFiltersSQL("products.attributes", params.Attributes)
+		merged, mergedArgs := attributeFiltersSQL("(COALESCE(products.attributes, '{}') || COALESCE(v.attributes, '{}'))", params.Attributes)
+		query = query.Where("(("+own+") OR EXISTS (SELECT 1 FROM products v WHERE v.parent_id = products.id AND "+merged+"))", append(ownArgs, mergedArgs...)...)
+	}
+
+	return query
+}
+
+// orders by how well a product or its best matching variant fits the query, nil without a query
+func searchRank(params types.ProductSearchParams) *clause.OrderBy {
+	if params.Fuzzy {
+		return &clause.OrderBy{Expression: clause.Expr{
+			SQL:  "GREATEST(word_similarity(?, products.name), COALESCE((SELECT MAX(word_similarity(?, v.name)) FROM products v WHERE v.parent_id = products.id), 0)) DESC",
+			Vars: []interface{}{params.Query, params.Query},
+		}}
+	}
+
+	tsQuery := prefixTSQuery(params.Query)
+	if tsQuery == "" {
+		return nil
+	}
+	return &clause.OrderBy{Expression: clause.Expr{
+		SQL:  "GREATEST(ts_rank(products.search_vector, to_tsquery('simple', ?)), COALESCE((SELECT MAX(ts_rank(v.search_vector, to_tsquery('simple', ?))) FROM products v WHERE v.parent_id = products.id), 0)) DESC",
+		Vars: []interface{}{tsQuery, tsQuery},
+	}}
+}
+
+// turns free text into a tsquery that needs every word, each matched as a prefix so "oxf shi" finds
+// "Oxford Shirt". Anything but letters and digits separates words, which also keeps tsquery syntax out
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// the variants of each parent, keyed by parent id
+func (r *ProductRepo) GetVariants(ctx context.Context, parentIDs []int64) (map[int64][]*types.Product, error) {
+	logTag := "[ProductRepo][GetVariants]"
+	log.InfofWithContext(ctx, logTag+" fetching variants", "parents_count", len(parentIDs))
+
+	variants := make(map[int64][]*types.Product, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return variants, nil
+	}
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var products []*types.Product
+	if err := db.Where("parent_id IN ?", parentIDs).Order("parent_id, id").Find(&products).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch variants", err)
+		return nil, fmt.Errorf("failed to fetch variants %w", err)
+	}
+	for _, product := range products {
+		variants[*product.ParentID] = append(variants[*product.ParentID], product)
+	}
+
+	return variants, nil
+}
+
+// the category path of each product, the ids of its category and the ancestors with the root first. Products
+// without a category are left out
+func (r *ProductRepo) GetCategoryPathsWithTx(tx *gorm.DB, ctx context.Context, productIDs []int64) (map[int64][]int64, error) {
+	logTag := "[ProductRepo][GetCategoryPathsWithTx]"
+
+	paths, err := categoryPathsWithTx(tx, productIDs)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch category paths", err)
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+func categoryPathsWithTx(tx *gorm.DB, productIDs []int64) (map[int64][]int64, error) {
+	paths := make(map[int64][]int64, len(productIDs))
+	if len(productIDs) == 0 {
+		return paths, nil
+	}
+
+	var rows []struct {
+		ProductID  int64
+		CategoryID int64
+	}
+	err := tx.Raw(`WITH RECURSIVE path AS (
+			SELECT p.id AS product_id, c.id, c.parent_id, 0 AS depth
+			FROM products p JOIN categories c ON c.id = p.category_id
+			WHERE p.id IN ?
+			UNION ALL
+			SELECT path.product_id, c.id, c.parent_id, path.depth + 1
+			FROM categories c JOIN path ON c.id = path.parent_id
+		)
+		SELECT product_id, id AS category_id FROM path ORDER BY product_id, depth DESC`, productIDs).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category paths %w", err)
+	}
+	for _, row := range rows {
+		paths[row.ProductID] = append(paths[row.ProductID], row.CategoryID)
+	}
+
+	return paths, nil
+}
+
+// copies what variants take from their parent onto them: name, category, currency, serial tracking and
+// the price of variants without an override. Only those columns are written, stock stays untouched. A
+// variant whose price moves with its parent's gets the change in its own price history
+func (r *ProductRepo) SyncVariantsWithTx(tx *gorm.DB, ctx context.Context, parent *types.Product) error {
+	logTag := "[ProductRepo][SyncVariantsWithTx]"
+	log.InfofWithContext(ctx, logTag+" syncing variants", "parent_id", parent.ID)
+
+	var variants []types.Product
+	if err := tx.Where("parent_id = ?", parent.ID).Order("id").Find(&variants).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch variants", err, "parent_id", parent.ID)
+		return fmt.Errorf("failed to fetch variants %w", err)
+	}
+
+	now := time.Now()
+	for _, variant := range variants {
+		price := parent.Price
+		if variant.PriceOverride != nil {
+			price = *variant.PriceOverride
+		}
+
+		err := tx.Model(&types.Product{}).Where("id = ?", variant.ID).Updates(map[string]interface{}{
+			"name":          types.VariantName(parent.Name, parent.OptionAxes, variant.Options),
+			"category":      parent.Category,
+			"category_id":   parent.CategoryID,
+			"currency":      parent.Currency,
+			"is_serialized": parent.IsSerialized,
+			"price":         price,
+			"updated_at":    now,
+		}).Error
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to sync variant", err, "variant_id", variant.ID)
+			return fmt.Errorf("failed to sync variant %w", err)
+		}
+
+		if price != variant.Price {
+			previous := variant.Price
+			if err := tx.Create(types.AppliedPriceChange(variant.ID, parent.Currency, &price, &previous, types.ActorFromContext(ctx), "parent price change")).Error; err != nil {
+				log.ErrorfWithContext(ctx, logTag+" failed to record variant price change", err, "variant_id", variant.ID)
+				return fmt.Errorf("failed to record variant price change %w", err)
+			}
+		}
+	}
+
+	return nil
 }
 
 func (r *ProductRepo) GetAll(ctx context.Context, limit, offset int) ([]*types.Product, int64, error){
@@ -110,62 +414,451 @@ func (r *ProductRepo) GetAll(ctx context.Context, limit, offset int) ([]*types.P
 	return products, total, nil
 }
 
-func (r *ProductRepo) Update(ctx context.Context, prod *types.Product) (*types.Product, error){
-	logTag := "[ProductRepo][Update]"
-	log.InfofWithContext(ctx, logTag+" updating product", "product", prod)
+// fetches the product from the master inside the transaction with a row lock held until it ends
+func (r *ProductRepo) LockByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.Product, error) {
+	logTag := "[ProductRepo][LockByIDWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking product", "product_id", id)
 
-	db := r.DB.Cluster.GetMasterDB(ctx)
+	var product types.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" product not found", "product_id", id)
+			return nil, fmt.Errorf("product not found with id %d", id)
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to lock product", err, "product_id", id)
+		return nil, fmt.Errorf("failed to lock product %w", err)
+	}
+
+	return &product, nil
+}
+
+// reads the product inside the transaction without locking it, stock checks made on this copy are only
+// hints, the conditional updates below are what enforce them
+func (r *ProductRepo) GetByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.Product, error) {
+	logTag := "[ProductRepo][GetByIDWithTx]"
+
+	var product types.Product
+	if err := tx.Where("id = ?", id).First(&product).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" product not found", "product_id", id)
+			return nil, fmt.Errorf("product not found with id %d", id)
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch product", err, "product_id", id)
+		return nil, fmt.Errorf("failed to fetch product %w", err)
+	}
+
+	return &product, nil
+}
+
+// saves a product previously locked with LockByIDWithTx
+func (r *ProductRepo) UpdateWithTx(tx *gorm.DB, ctx context.Context, prod *types.Product) (*types.Product, error){
+	logTag := "[ProductRepo][UpdateWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating product", "product_id", prod.ID)
 
 	prod.UpdatedAt = time.Now()
 
-	if err := db.Save(prod).Error; err != nil {
-		log.ErrorfWithContext(ctx, logTag+" error when updating the product")
-		return nil, err
+	if err := tx.Save(prod).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when updating the product", err, "product_id", prod.ID)
+		return nil, fmt.Errorf("failed to update product %w", err)
 	}
 
 	log.InfofWithContext(ctx, logTag+" product details updated successfully")
 	return prod, nil
 }
 
-func (r *ProductRepo) UpdateStock(tx *gorm.DB, ctx context.Context, id int64, quantity int64, operation string) error{
+// changes the on hand stock at one location with one conditional statement so concurrent callers can never
+// oversell, reserved units belong to open orders and can neither be subtracted nor set away. The change is
+// appended to the stock ledger together with the movement details
+func (r *ProductRepo) UpdateStock(tx *gorm.DB, ctx context.Context, id, locationID int64, quantity int64, operation string, movement types.StockMovement) error{
 	logTag := "[ProductRepo][UpdateStock]"
-    log.InfofWithContext(ctx, logTag+" updating stock", "product_id", id, "quantity", quantity, "operation", operation)
+	log.InfofWithContext(ctx, logTag+" updating stock", "product_id", id, "location_id", locationID, "quantity", quantity, "operation", operation, "reason", movement.Reason)
 
+	if quantity < 0 {
+		return fmt.Errorf("invalid quantity %d", quantity)
+	}
 
-	var product types.Product
-	if err := tx.Where("id = ?", id).First(&product).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.WarnfWithContext(ctx, logTag+" product not found", "product_id", id)
-            return fmt.Errorf("product not found")
-        }
-        log.ErrorfWithContext(ctx, logTag+" failed to fetch product", err, "product_id", id)
-        return fmt.Errorf("failed to fetch product %w", err)
-    }
-
+	var ok bool
+	var err error
 	switch operation{
 	case "set":
-		product.StockQuantity = quantity
+		// the delta depends on the current value, so the product is locked before the location row is read
+		stock, lockErr := r.LockStockWithTx(tx, ctx, id, locationID)
+		if lockErr != nil {
+			return lockErr
+		}
+		ok, err = r.moveStockWithTx(tx, ctx, id, locationID, quantity-stock.StockQuantity, 0, movement, "reserved_quantity <= ?", quantity)
 	case "add":
-		product.StockQuantity += quantity
+		ok, err = r.moveStockWithTx(tx, ctx, id, locationID, quantity, 0, movement, "TRUE")
 	case "subtract":
-		if product.StockQuantity < quantity {
-			return fmt.Errorf("insufficient stock")
-		}
-		product.StockQuantity -= quantity
+		ok, err = r.moveStockWithTx(tx, ctx, id, locationID, -quantity, 0, movement, "stock_quantity - reserved_quantity >= ?", quantity)
 	default:
 		return fmt.Errorf("invalid operation: %s", operation)
 	}
 
-	product.UpdatedAt = time.Now()
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update stock", err, "product_id", id)
+		return fmt.Errorf("failed to update stock %w", err)
+	}
 
-    if err := tx.Save(&product).Error; err != nil {
-        log.ErrorfWithContext(ctx, logTag+" failed to update stock", err, "product_id", id)
-        return fmt.Errorf("failed to update stock %w", err)
-    }
+	if !ok {
+		var count int64
+		if err := tx.Model(&types.Product{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to fetch product %w", err)
+		}
+		if count == 0 {
+			log.WarnfWithContext(ctx, logTag+" product not found", "product_id", id)
+			return fmt.Errorf("product not found")
+		}
+		log.WarnfWithContext(ctx, logTag+" insufficient stock", "product_id", id, "location_id", locationID, "quantity", quantity, "operation", operation)
+		return fmt.Errorf("insufficient stock")
+	}
+
+	log.InfofWithContext(ctx, logTag+" stock updated successfully", "product_id", id)
+	return nil
+}
+
+// takes quantity units off hand at a location even when orders have them reserved, for counts that found less
+// than is held. The reservations stay, the units they are short of are returned and an alert is raised for
+// the product so the orders can be sorted out. Stock never goes below zero
+func (r *ProductRepo) WriteOffStockWithTx(tx *gorm.DB, ctx context.Context, id, locationID int64, quantity int64, movement types.StockMovement) (int64, error) {
+	logTag := "[ProductRepo][WriteOffStockWithTx]"
+	log.InfofWithContext(ctx, logTag+" writing off stock", "product_id", id, "location_id", locationID, "quantity", quantity, "reason", movement.Reason)
+
+	ok, err := r.moveStockWithTx(tx, ctx, id, locationID, -quantity, 0, movement, "stock_quantity >= ?", quantity)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to write off stock", err, "product_id", id)
+		return 0, fmt.Errorf("failed to write off stock %w", err)
+	}
+
+	if !ok {
+		var count int64
+		if err := tx.Model(&types.Product{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to fetch product %w", err)
+		}
+		if count == 0 {
+			log.WarnfWithContext(ctx, logTag+" product not found", "product_id", id)
+			return 0, fmt.Errorf("product not found")
+		}
+		log.WarnfWithContext(ctx, logTag+" insufficient stock", "product_id", id, "location_id", locationID, "quantity", quantity)
+		return 0, fmt.Errorf("insufficient stock")
+	}
+
+	var overReserved int64
+	err = tx.Raw(`SELECT GREATEST(reserved_quantity - stock_quantity, 0) FROM product_stocks WHERE product_id = ? AND location_id = ?`,
+		id, locationID).Scan(&overReserved).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch stock level %w", err)
+	}
+	if overReserved == 0 {
+		return 0, nil
+	}
 
-    log.InfofWithContext(ctx, logTag+" stock updated successfully", "product_id", id, "new_stock", product.StockQuantity)
-    return nil
+	// raised whatever the reorder point, it resolves once the product is back above it
+	var level stockLevel
+	err = tx.Raw(`SELECT stock_quantity, reserved_quantity, reorder_point, reorder_quantity FROM products WHERE id = ?`, id).Scan(&level).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch stock level %w", err)
+	}
+	log.WarnfWithContext(ctx, logTag+" location over-reserved", "product_id", id, "location_id", locationID, "over_reserved", overReserved)
+	if err := r.raiseStockAlertWithTx(tx, ctx, id, level, time.Now()); err != nil {
+		return 0, err
+	}
 
+	return overReserved, nil
+}
+
+// holds quantity units at a location for an order, the condition makes the check and the increment one atomic statement
+func (r *ProductRepo) ReserveStockWithTx(tx *gorm.DB, ctx context.Context, id, locationID int64, quantity int64, movement types.StockMovement) error {
+	logTag := "[ProductRepo][ReserveStockWithTx]"
+	log.InfofWithContext(ctx, logTag+" reserving stock", "product_id", id, "location_id", locationID, "quantity", quantity)
+
+	ok, err := r.moveStockWithTx(tx, ctx, id, locationID, 0, quantity, movement, "stock_quantity - reserved_quantity >= ?", quantity)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to reserve stock", err, "product_id", id)
+		return fmt.Errorf("failed to reserve stock %w", err)
+	}
+
+	if !ok {
+		log.WarnfWithContext(ctx, logTag+" insufficient stock", "product_id", id, "location_id", locationID, "required", quantity)
+		return fmt.Errorf("insufficient stock")
+	}
+
+	return nil
+}
+
+// gives reserved units back to the available pool of their location
+func (r *ProductRepo) ReleaseStockWithTx(tx *gorm.DB, ctx context.Context, id, locationID int64, quantity int64, movement types.StockMovement) error {
+	logTag := "[ProductRepo][ReleaseStockWithTx]"
+	log.InfofWithContext(ctx, logTag+" releasing reserved stock", "product_id", id, "location_id", locationID, "quantity", quantity)
+
+	ok, err := r.moveStockWithTx(tx, ctx, id, locationID, 0, -quantity, movement, "reserved_quantity >= ?", quantity)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to release stock", err, "product_id", id)
+		return fmt.Errorf("failed to release stock %w", err)
+	}
+
+	if !ok {
+		log.ErrorfWithContext(ctx, logTag+" reserved quantity out of sync", fmt.Errorf("release of %d units failed", quantity), "product_id", id, "location_id", locationID)
+		return fmt.Errorf("reserved quantity of product %d at location %d is lower than %d", id, locationID, quantity)
+	}
+
+	return nil
+}
+
+// turns reserved units into shipped ones, taking them off hand and out of the reservation together
+func (r *ProductRepo) ConsumeReservedStockWithTx(tx *gorm.DB, ctx context.Context, id, locationID int64, quantity int64, movement types.StockMovement) error {
+	logTag := "[ProductRepo][ConsumeReservedStockWithTx]"
+	log.InfofWithContext(ctx, logTag+" consuming reserved stock", "product_id", id, "location_id", locationID, "quantity", quantity)
+
+	ok, err := r.moveStockWithTx(tx, ctx, id, locationID, -quantity, -quantity, movement, "reserved_quantity >= ? AND stock_quantity >= ?", quantity, quantity)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to consume reserved stock", err, "product_id", id)
+		return fmt.Errorf("failed to consume reserved stock %w", err)
+	}
+
+	if !ok {
+		log.ErrorfWithContext(ctx, logTag+" reserved quantity out of sync", fmt.Errorf("consume of %d units failed", quantity), "product_id", id, "location_id", locationID)
+		return fmt.Errorf("reserved quantity of product %d at location %d is lower than %d", id, locationID, quantity)
+	}
+
+	return nil
+}
+
+// locks the product and returns its stock at the location, a location that never held it reads as zero
+func (r *ProductRepo) LockStockWithTx(tx *gorm.DB, ctx context.Context, id, locationID int64) (*types.ProductStock, error) {
+	logTag := "[ProductRepo][LockStockWithTx]"
+
+	found, err := r.lockProductWithTx(tx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to lock product", err, "product_id", id)
+		return nil, fmt.Errorf("failed to lock product %w", err)
+	}
+	if !found {
+		log.WarnfWithContext(ctx, logTag+" product not found", "product_id", id)
+		return nil, fmt.Errorf("product not found with id %d", id)
+	}
+
+	var stocks []types.ProductStock
+	if err := tx.Where("product_id = ? AND location_id = ?", id, locationID).Find(&stocks).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch location stock", err, "product_id", id, "location_id", locationID)
+		return nil, fmt.Errorf("failed to fetch location stock %w", err)
+	}
+	if len(stocks) == 0 {
+		return &types.ProductStock{ProductID: id, LocationID: locationID}, nil
+	}
+
+	return &stocks[0], nil
+}
+
+// locks the product and lists the active locations with available stock of it, the lock keeps the
+// picture valid until the allocation made from it has been reserved
+func (r *ProductRepo) GetAllocationCandidatesWithTx(tx *gorm.DB, ctx context.Context, id int64) ([]types.AllocationCandidate, error) {
+	logTag := "[ProductRepo][GetAllocationCandidatesWithTx]"
+
+	found, err := r.lockProductWithTx(tx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to lock product", err, "product_id", id)
+		return nil, fmt.Errorf("failed to lock product %w", err)
+	}
+	if !found {
+		log.WarnfWithContext(ctx, logTag+" product not found", "product_id", id)
+		return nil, fmt.Errorf("product not found with id %d", id)
+	}
+
+	// free stock in expired lots can't be sold, so it doesn't count towards what a location can fill
+	var candidates []types.AllocationCandidate
+	err = tx.Raw(`
+		SELECT * FROM (
+			SELECT l.id AS location_id,
+				COALESCE(l.country, '') AS country,
+				COALESCE(l.state, '') AS state,
+				COALESCE(l.postal_code, '') AS postal_code,
+				l.priority,
+				ps.available_quantity - CAST(COALESCE((
+					SELECT SUM(sl.available_quantity) FROM stock_lots sl
+					WHERE sl.product_id = ps.product_id AND sl.location_id = ps.location_id AND sl.expires_at < CURRENT_DATE
+				), 0) AS BIGINT) AS available
+			FROM product_stocks ps
+			JOIN locations l ON l.id = ps.location_id
+			WHERE ps.product_id = ? AND l.is_active
+		) c WHERE c.available > 0`, id).Scan(&candidates).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch allocation candidates", err, "product_id", id)
+		return nil, fmt.Errorf("failed to fetch allocation candidates %w", err)
+	}
+
+	return candidates, nil
+}
+
+// the stock of a product at every location holding it
+func (r *ProductRepo) GetStocks(ctx context.Context, id int64) ([]types.ProductStock, error) {
+	logTag := "[ProductRepo][GetStocks]"
+	log.InfofWithContext(ctx, logTag+" fetching location stock", "product_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var stocks []types.ProductStock
+	if err := db.Where("product_id = ?", id).Order("location_id").Find(&stocks).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch location stock", err, "product_id", id)
+		return nil, fmt.Errorf("failed to fetch location stock %w", err)
+	}
+
+	return stocks, nil
+}
+
+// every stock change takes the product row lock first, so changes to the same product queue up in one
+// order no matter which locations they touch
+func (r *ProductRepo) lockProductWithTx(tx *gorm.DB, id int64) (bool, error) {
+	var ids []int64
+	if err := tx.Raw("SELECT id FROM products WHERE id = ? FOR UPDATE", id).Scan(&ids).Error; err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
+
+// product totals after a stock change together with the reorder settings they are checked against
+type stockLevel struct {
+	StockQuantity    int64
+	ReservedQuantity int64
+	ReorderPoint     int64
+	ReorderQuantity  int64
+}
+
+// applies both deltas to the location when condition holds, carries them into the product totals, appends
+// the ledger row and checks the reorder point in the same transaction, ok is false when nothing was changed
+func (r *ProductRepo) moveStockWithTx(tx *gorm.DB, ctx context.Context, id, locationID, delta, reservedDelta int64, movement types.StockMovement, condition string, conditionArgs ...interface{}) (bool, error) {
+	now := time.Now()
+
+	found, err := r.lockProductWithTx(tx, id)
+	if err != nil || !found {
+		return false, err
+	}
+
+	// a location that never held the product starts from zero
+	if delta > 0 {
+		err := tx.Exec(`INSERT INTO product_stocks (product_id, location_id, updated_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+			id, locationID, now).Error
+		if err != nil {
+			return false, err
+		}
+	}
+
+	args := append([]interface{}{delta, reservedDelta, now, id, locationID}, conditionArgs...)
+
+	var levels []struct {
+		StockQuantity    int64
+		ReservedQuantity int64
+	}
+	err = tx.Raw(`UPDATE product_stocks SET stock_quantity = stock_quantity + ?, reserved_quantity = reserved_quantity + ?, updated_at = ?
+		WHERE product_id = ? AND location_id = ? AND `+condition+` RETURNING stock_quantity, reserved_quantity`, args...).Scan(&levels).Error
+	if err != nil {
+		return false, err
+	}
+	if len(levels) == 0 {
+		return false, nil
+	}
+
+	// a plain decrement doesn't say which lot it came out of, consumed reservations update their lots themselves
+	if delta < 0 && reservedDelta == 0 {
+		if err := trimStockLotsWithTx(tx, id, locationID, now); err != nil {
+			return false, fmt.Errorf("failed to update lots %w", err)
+		}
+	}
+
+	// the movement records the product totals, the location levels are in product_stocks
+	var totals []stockLevel
+	err = tx.Raw(`UPDATE products SET stock_quantity = stock_quantity + ?, reserved_quantity = reserved_quantity + ?, updated_at = ?
+		WHERE id = ? RETURNING stock_quantity, reserved_quantity, reorder_point, reorder_quantity`, delta, reservedDelta, now, id).Scan(&totals).Error
+	if err != nil {
+		return false, err
+	}
+	if len(totals) == 0 {
+		return false, fmt.Errorf("product %d disappeared while its stock was locked", id)
+	}
+
+	movement.ID = 0
+	movement.ProductID = id
+	movement.LocationID = locationID
+	movement.Delta = delta
+	movement.ReservedDelta = reservedDelta
+	movement.ResultingQuantity = totals[0].StockQuantity
+	movement.ResultingReserved = totals[0].ReservedQuantity
+	if movement.Actor == "" {
+		movement.Actor = types.ActorFromContext(ctx)
+	}
+	movement.CreatedAt = now
+
+	if err := tx.Create(&movement).Error; err != nil {
+		return false, fmt.Errorf("failed to record stock movement %w", err)
+	}
+
+	// only crossing the reorder point matters, staying on one side of it changes nothing
+	level := totals[0]
+	available := level.StockQuantity - level.ReservedQuantity
+	previous := available - (delta - reservedDelta)
+	switch {
+	case level.ReorderPoint > 0 && available < level.ReorderPoint && previous >= level.ReorderPoint:
+		err = r.raiseStockAlertWithTx(tx, ctx, id, level, now)
+	case available >= level.ReorderPoint && previous < level.ReorderPoint:
+		err = r.resolveStockAlertWithTx(tx, ctx, id, now)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// checks the product against its reorder point without a stock change, used when the reorder settings
+// themselves change
+func (r *ProductRepo) EvaluateReorderPointWithTx(tx *gorm.DB, ctx context.Context, id int64) error {
+	logTag := "[ProductRepo][EvaluateReorderPointWithTx]"
+
+	var levels []stockLevel
+	err := tx.Raw(`SELECT stock_quantity, reserved_quantity, reorder_point, reorder_quantity FROM products WHERE id = ?`, id).Scan(&levels).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock level", err, "product_id", id)
+		return fmt.Errorf("failed to fetch stock level %w", err)
+	}
+	if len(levels) == 0 {
+		return fmt.Errorf("product not found with id %d", id)
+	}
+
+	level := levels[0]
+	if level.ReorderPoint > 0 && level.StockQuantity-level.ReservedQuantity < level.ReorderPoint {
+		return r.raiseStockAlertWithTx(tx, ctx, id, level, time.Now())
+	}
+	return r.resolveStockAlertWithTx(tx, ctx, id, time.Now())
+}
+
+// opens a low stock alert unless the product already has one, the notifier picks it up after commit
+func (r *ProductRepo) raiseStockAlertWithTx(tx *gorm.DB, ctx context.Context, id int64, level stockLevel, now time.Time) error {
+	logTag := "[ProductRepo][raiseStockAlertWithTx]"
+	log.WarnfWithContext(ctx, logTag+" stock below reorder point", "product_id", id,
+		"available", level.StockQuantity-level.ReservedQuantity, "reorder_point", level.ReorderPoint)
+
+	err := tx.Exec(`INSERT INTO stock_alerts (product_id, reorder_point, reorder_quantity, available_quantity, stock_quantity, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (product_id) WHERE status = 'alert.open' DO NOTHING`,
+		id, level.ReorderPoint, level.ReorderQuantity, level.StockQuantity-level.ReservedQuantity, level.StockQuantity, now).Error
+	if err != nil {
+		return fmt.Errorf("failed to raise stock alert %w", err)
+	}
+
+	return nil
+}
+
+func (r *ProductRepo) resolveStockAlertWithTx(tx *gorm.DB, ctx context.Context, id int64, now time.Time) error {
+	err := tx.Model(&types.StockAlert{}).
+		Where("product_id = ? AND status = ?", id, types.StockAlertStatusOpen).
+		Updates(map[string]interface{}{
+			"status":      types.StockAlertStatusResolved,
+			"resolved_at": now,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to resolve stock alert %w", err)
+	}
+
+	return nil
 }
 
 func (r *ProductRepo) Delete(ctx context.Context, id int64) error {
@@ -189,3 +882,123 @@ func (r *ProductRepo) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+// saves the price of a product in a currency within tx, the caller records the change in the price history
+func (r *ProductRepo) UpsertPriceWithTx(tx *gorm.DB, ctx context.Context, price *types.ProductPrice) (*types.ProductPrice, error) {
+	logTag := "[ProductRepo][UpsertPriceWithTx]"
+	log.InfofWithContext(ctx, logTag+" saving product price", "product_id", price.ProductID, "currency", price.Currency)
+
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at"}),
+	}).Create(price).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to save product price", err, "product_id", price.ProductID)
+		return nil, fmt.Errorf("failed to save product price %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" product price saved successfully", "product_id", price.ProductID, "currency", price.Currency)
+	return price, nil
+}
+
+func (r *ProductRepo) GetPrices(ctx context.Context, productID int64) ([]types.ProductPrice, error) {
+	logTag := "[ProductRepo][GetPrices]"
+	log.InfofWithContext(ctx, logTag+" fetching product prices", "product_id", productID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var prices []types.ProductPrice
+	if err := db.Where("product_id = ?", productID).Order("currency ASC").Find(&prices).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch product prices", err, "product_id", productID)
+		return nil, fmt.Errorf("failed to fetch product prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+// returns nil without an error when the product has no price in that currency
+func (r *ProductRepo) GetPrice(ctx context.Context, productID int64, currency string) (*types.ProductPrice, error) {
+	logTag := "[ProductRepo][GetPrice]"
+	log.InfofWithContext(ctx, logTag+" fetching product price", "product_id", productID, "currency", currency)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var price types.ProductPrice
+	if err := db.Where("product_id = ? AND currency = ?", productID, currency).First(&price).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch product price", err, "product_id", productID)
+		return nil, fmt.Errorf("failed to fetch product price %w", err)
+	}
+
+	return &price, nil
+}
+
+// the price row of a currency read within tx, nil without an error when there is none
+func (r *ProductRepo) GetPriceWithTx(tx *gorm.DB, ctx context.Context, productID int64, currency string) (*types.ProductPrice, error) {
+	logTag := "[ProductRepo][GetPriceWithTx]"
+
+	var price types.ProductPrice
+	if err := tx.Where("product_id = ? AND currency = ?", productID, currency).First(&price).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch product price", err, "product_id", productID)
+		return nil, fmt.Errorf("failed to fetch product price %w", err)
+	}
+
+	return &price, nil
+}
+
+func (r *ProductRepo) DeletePriceWithTx(tx *gorm.DB, ctx context.Context, productID int64, currency string) error {
+	logTag := "[ProductRepo][DeletePriceWithTx]"
+	log.InfofWithContext(ctx, logTag+" deleting product price", "product_id", productID, "currency", currency)
+
+	res := tx.Where("product_id = ? AND currency = ?", productID, currency).Delete(&types.ProductPrice{})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to delete product price", res.Error, "product_id", productID)
+		return fmt.Errorf("failed to delete product price %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		log.WarnfWithContext(ctx, logTag+" product price not found", "product_id", productID, "currency", currency)
+		return fmt.Errorf("product price not found")
+	}
+
+	return nil
+}
+
+// the conditions of the attribute filters on a jsonb column, joined with AND. eq and in go through jsonb
+// containment so strings, numbers and booleans compare by type, range only takes numbers
+func attributeFiltersSQL(column string, filters []types.AttributeFilter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	for _, filter := range filters {
+		switch filter.Op {
+		case types.AttributeFilterOpEq, types.AttributeFilterOpIn:
+			values := filter.Values
+			if filter.Op == types.AttributeFilterOpEq {
+				values = []interface{}{filter.Value}
+			}
+			var matches []string
+			for _, value := range values {
+				// the values were decoded from json, so they always encode again
+				doc, _ := json.Marshal(map[string]interface{}{filter.Name: value})
+				matches = append(matches, column+" @> ?::jsonb")
+				args = append(args, string(doc))
+			}
+			conds = append(conds, "("+strings.Join(matches, " OR ")+")")
+		case types.AttributeFilterOpRange:
+			number := "(CASE WHEN jsonb_typeof(" + column + " -> ?::text) = 'number' THEN (" + column + " ->> ?::text)::numeric END)"
+			if filter.Min != nil {
+				conds = append(conds, number+" >= ?")
+				args = append(args, filter.Name, filter.Name, *filter.Min)
+			}
+			if filter.Max != nil {
+				conds = append(conds, number+" <= ?")
+				args = append(args, filter.Name, filter.Name, *filter.Max)
+			}
+		}
+	}
+	return strings.Join(conds, " AND "), args
+}
@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PurchaseOrderRepo struct {
+	DB *Postgres
+}
+
+func NewPurchaseOrderRepo(db *Postgres) *PurchaseOrderRepo {
+	return &PurchaseOrderRepo{
+		DB: db,
+	}
+}
+
+func (r *PurchaseOrderRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, po *types.PurchaseOrder, lines []types.PurchaseOrderLine) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderRepo][CreateWithTx]"
+	log.InfofWithContext(ctx, logTag+" creating purchase order", "supplier_id", po.SupplierID, "lines_count", len(lines))
+
+	if err := tx.Create(po).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create purchase order", err, "supplier_id", po.SupplierID)
+		return nil, fmt.Errorf("failed to create purchase order %w", err)
+	}
+
+	for i := range lines {
+		lines[i].PurchaseOrderID = po.ID
+		if err := tx.Create(&lines[i]).Error; err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to create purchase order line", err, "product_id", lines[i].ProductID)
+			return nil, fmt.Errorf("failed to create purchase order line %w", err)
+		}
+	}
+
+	log.InfofWithContext(ctx, logTag+" purchase order created successfully", "purchase_order_id", po.ID)
+	return r.GetByIDWithTx(tx, ctx, po.ID)
+}
+
+// locks the purchase order so receipts and status changes against it are serialised, the lines come back
+// ordered by product so stock is always locked in the same order
+func (r *PurchaseOrderRepo) LockByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderRepo][LockByIDWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking purchase order", "purchase_order_id", id)
+
+	var po types.PurchaseOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&po).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" purchase order not found", "purchase_order_id", id)
+			return nil, fmt.Errorf("purchase order not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to lock purchase order", err, "purchase_order_id", id)
+		return nil, fmt.Errorf("failed to lock purchase order %w", err)
+	}
+
+	var lines []types.PurchaseOrderLine
+	if err := tx.Where("purchase_order_id = ?", po.ID).Order("product_id").Find(&lines).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch purchase order lines", err, "purchase_order_id", id)
+		return nil, fmt.Errorf("failed to fetch purchase order lines: %w", err)
+	}
+
+	return &types.PurchaseOrderWithLines{
+		PurchaseOrder: po,
+		Lines:         lines,
+	}, nil
+}
+
+func (r *PurchaseOrderRepo) UpdateWithTx(tx *gorm.DB, ctx context.Context, po *types.PurchaseOrder) error {
+	logTag := "[PurchaseOrderRepo][UpdateWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating purchase order", "purchase_order_id", po.ID, "status", po.Status)
+
+	res := tx.Model(&types.PurchaseOrder{}).
+		Where("id = ?", po.ID).
+		Updates(map[string]interface{}{
+			"status":       po.Status,
+			"submitted_at": po.SubmittedAt,
+			"received_at":  po.ReceivedAt,
+			"closed_at":    po.ClosedAt,
+			"cancelled_at": po.CancelledAt,
+			"updated_at":   po.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update purchase order", res.Error, "purchase_order_id", po.ID)
+		return fmt.Errorf("failed to update purchase order %w", res.Error)
+	}
+
+	return nil
+}
+
+// records one delivery and adds its quantities to the received quantities of the purchase order lines
+func (r *PurchaseOrderRepo) CreateReceiptWithTx(tx *gorm.DB, ctx context.Context, receipt *types.GoodsReceipt, lines []types.GoodsReceiptLine) (*types.GoodsReceiptWithLines, error) {
+	logTag := "[PurchaseOrderRepo][CreateReceiptWithTx]"
+	log.InfofWithContext(ctx, logTag+" creating goods receipt", "purchase_order_id", receipt.PurchaseOrderID, "lines_count", len(lines))
+
+	if err := tx.Create(receipt).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create goods receipt", err, "purchase_order_id", receipt.PurchaseOrderID)
+		return nil, fmt.Errorf("failed to create goods receipt %w", err)
+	}
+
+	for i := range lines {
+		lines[i].ReceiptID = receipt.ID
+		if err := tx.Create(&lines[i]).Error; err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to create goods receipt line", err, "product_id", lines[i].ProductID)
+			return nil, fmt.Errorf("failed to create goods receipt line %w", err)
+		}
+
+		err := tx.Model(&types.PurchaseOrderLine{}).
+			Where("id = ?", lines[i].PurchaseOrderLineID).
+			Update("received_quantity", gorm.Expr("received_quantity + ?", lines[i].Quantity)).Error
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to update received quantity", err, "purchase_order_line_id", lines[i].PurchaseOrderLineID)
+			return nil, fmt.Errorf("failed to update received quantity %w", err)
+		}
+	}
+
+	log.InfofWithContext(ctx, logTag+" goods receipt created successfully", "receipt_id", receipt.ID)
+	return &types.GoodsReceiptWithLines{
+		Receipt: *receipt,
+		Lines:   lines,
+	}, nil
+}
+
+// reads the purchase order with its lines and receipts inside the transaction, so changes made by it are seen
+func (r *PurchaseOrderRepo) GetByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderRepo][GetByIDWithTx]"
+
+	po, err := fetchPurchaseOrder(tx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch purchase order", err, "purchase_order_id", id)
+		return nil, err
+	}
+
+	return po, nil
+}
+
+func (r *PurchaseOrderRepo) SearchByID(ctx context.Context, id int64) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching purchase order", "purchase_order_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	po, err := fetchPurchaseOrder(db, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch purchase order", err, "purchase_order_id", id)
+		return nil, err
+	}
+
+	return po, nil
+}
+
+// pages through purchase orders newest first, zero values in params match everything
+func (r *PurchaseOrderRepo) Search(ctx context.Context, params types.PurchaseOrderSearchParams) ([]types.PurchaseOrder, int64, error) {
+	logTag := "[PurchaseOrderRepo][Search]"
+	log.InfofWithContext(ctx, logTag+" searching purchase orders", "params", fmt.Sprintf("%+v", params))
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	query := db.Model(&types.PurchaseOrder{})
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if params.SupplierID != 0 {
+		query = query.Where("supplier_id = ?", params.SupplierID)
+	}
+	if params.ProductID != 0 {
+		query = query.Where("id IN (?)", db.Table("purchase_order_lines").Select("purchase_order_id").Where("product_id = ?", params.ProductID))
+	}
+
+	switch params.Delivery {
+	case types.PurchaseOrderDeliveryOver:
+		query = query.Where("id IN (?)", db.Table("purchase_order_lines").Select("purchase_order_id").Where("over_received_quantity > 0"))
+	case types.PurchaseOrderDeliveryUnder:
+		query = query.Where("status = ?", types.PurchaseOrderStatusClosed).
+			Where("id IN (?)", db.Table("purchase_order_lines").Select("purchase_order_id").Where("outstanding_quantity > 0"))
+	case types.PurchaseOrderDeliveryOverdue:
+		overdue := db.Table("purchase_order_lines l").
+			Select("l.purchase_order_id").
+			Joins("JOIN purchase_orders po ON po.id = l.purchase_order_id").
+			Where("l.outstanding_quantity > 0 AND COALESCE(l.expected_at, po.expected_at) < ?", time.Now().Format("2006-01-02"))
+		query = query.Where("status IN ?", []types.PurchaseOrderStatus{types.PurchaseOrderStatusSubmitted, types.PurchaseOrderStatusPartiallyReceived}).
+			Where("id IN (?)", overdue)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count purchase orders", err)
+		return nil, 0, fmt.Errorf("failed to count purchase orders %w", err)
+	}
+
+	var orders []types.PurchaseOrder
+	if err := query.Order("id DESC").Limit(params.Limit).Offset(params.Offset).Find(&orders).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch purchase orders", err)
+		return nil, 0, fmt.Errorf("failed to fetch purchase orders %w", err)
+	}
+
+	return orders, total, nil
+}
+
+// loads a purchase order together with its lines and its receipts, oldest receipt first
+func fetchPurchaseOrder(db *gorm.DB, id int64) (*types.PurchaseOrderWithLines, error) {
+	var po types.PurchaseOrder
+	if err := db.Where("id = ?", id).First(&po).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("purchase order not found")
+		}
+		return nil, fmt.Errorf("failed to fetch purchase order %w", err)
+	}
+
+	var lines []types.PurchaseOrderLine
+	if err := db.Where("purchase_order_id = ?", po.ID).Order("id").Find(&lines).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch purchase order lines: %w", err)
+	}
+
+	var receipts []types.GoodsReceipt
+	if err := db.Where("purchase_order_id = ?", po.ID).Order("id").Find(&receipts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch goods receipts: %w", err)
+	}
+
+	result := &types.PurchaseOrderWithLines{
+		PurchaseOrder: po,
+		Lines:         lines,
+	}
+	if len(receipts) == 0 {
+		return result, nil
+	}
+
+	receiptIDs := make([]int64, 0, len(receipts))
+	for _, receipt := range receipts {
+		receiptIDs = append(receiptIDs, receipt.ID)
+	}
+
+	var receiptLines []types.GoodsReceiptLine
+	if err := db.Where("receipt_id IN ?", receiptIDs).Order("id").Find(&receiptLines).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch goods receipt lines: %w", err)
+	}
+
+	linesByReceipt := make(map[int64][]types.GoodsReceiptLine, len(receipts))
+	for _, line := range receiptLines {
+		linesByReceipt[line.ReceiptID] = append(linesByReceipt[line.ReceiptID], line)
+	}
+
+	for _, receipt := range receipts {
+		result.Receipts = append(result.Receipts, types.GoodsReceiptWithLines{
+			Receipt: receipt,
+			Lines:   linesByReceipt[receipt.ID],
+		})
+	}
+
+	return result, nil
+}
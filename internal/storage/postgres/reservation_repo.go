@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReservationRepo struct {
+	DB *Postgres
+}
+
+func NewReservationRepo(db *Postgres) *ReservationRepo {
+	return &ReservationRepo{
+		DB: db,
+	}
+}
+
+func (r *ReservationRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, reservations []types.StockReservation) error {
+	logTag := "[ReservationRepo][CreateWithTx]"
+	log.InfofWithContext(ctx, logTag+" creating stock reservations", "count", len(reservations))
+
+	for i := range reservations {
+		if err := tx.Create(&reservations[i]).Error; err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to create stock reservation", err, "order_item_id", reservations[i].OrderItemID)
+			return fmt.Errorf("failed to create stock reservation %w", err)
+		}
+	}
+
+	return nil
+}
+
+// fetches the active reservations of an order with row locks held until the transaction ends, ordered by
+// product so the product rows they touch are always locked in the same order
+func (r *ReservationRepo) LockActiveByOrderWithTx(tx *gorm.DB, ctx context.Context, orderID int64) ([]types.StockReservation, error) {
+	logTag := "[ReservationRepo][LockActiveByOrderWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking active reservations", "order_id", orderID)
+
+	var reservations []types.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, types.ReservationStatusActive).
+		Order("product_id, id").
+		Find(&reservations).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to lock reservations", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to lock reservations %w", err)
+	}
+
+	return reservations, nil
+}
+
+// locks the reservation of one order item whatever its status, nil when the item never had one
+func (r *ReservationRepo) LockByOrderItemWithTx(tx *gorm.DB, ctx context.Context, orderItemID int64) (*types.StockReservation, error) {
+	logTag := "[ReservationRepo][LockByOrderItemWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking item reservation", "order_item_id", orderItemID)
+
+	var reservation types.StockReservation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_item_id = ?", orderItemID).First(&reservation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to lock reservation", err, "order_item_id", orderItemID)
+		return nil, fmt.Errorf("failed to lock reservation %w", err)
+	}
+
+	return &reservation, nil
+}
+
+func (r *ReservationRepo) UpdateWithTx(tx *gorm.DB, ctx context.Context, reservation *types.StockReservation) error {
+	logTag := "[ReservationRepo][UpdateWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating reservation", "reservation_id", reservation.ID, "status", reservation.Status)
+
+	res := tx.Model(&types.StockReservation{}).
+		Where("id = ?", reservation.ID).
+		Updates(map[string]interface{}{
+			"quantity":           reservation.Quantity,
+			"fulfilled_quantity": reservation.FulfilledQuantity,
+			"status":             reservation.Status,
+			"expires_at":         reservation.ExpiresAt,
+			"updated_at":         time.Now(),
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update reservation", res.Error, "reservation_id", reservation.ID)
+		return fmt.Errorf("failed to update reservation %w", res.Error)
+	}
+
+	return nil
+}
+
+// moves the expiry of every active reservation of the order, nil keeps them until fulfilment
+func (r *ReservationRepo) SetExpiryWithTx(tx *gorm.DB, ctx context.Context, orderID int64, expiresAt *time.Time) error {
+	logTag := "[ReservationRepo][SetExpiryWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating reservation expiry", "order_id", orderID)
+
+	res := tx.Model(&types.StockReservation{}).
+		Where("order_id = ? AND status = ?", orderID, types.ReservationStatusActive).
+		Updates(map[string]interface{}{
+			"expires_at": expiresAt,
+			"updated_at": time.Now(),
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update reservation expiry", res.Error, "order_id", orderID)
+		return fmt.Errorf("failed to update reservation expiry %w", res.Error)
+	}
+
+	return nil
+}
+
+// lists orders holding at least one active reservation past its expiry, read from the master
+// so the sweeper never acts on replica lag
+func (r *ReservationRepo) GetExpiredOrderIDs(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	logTag := "[ReservationRepo][GetExpiredOrderIDs]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var orderIDs []int64
+	err := db.Model(&types.StockReservation{}).
+		Distinct("order_id").
+		Where("status = ? AND expires_at <= ?", types.ReservationStatusActive, now).
+		Limit(limit).
+		Pluck("order_id", &orderIDs).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch expired reservations", err)
+		return nil, fmt.Errorf("failed to fetch expired reservations %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+func (r *ReservationRepo) GetByOrderID(ctx context.Context, orderID int64) ([]types.StockReservation, error) {
+	logTag := "[ReservationRepo][GetByOrderID]"
+	log.InfofWithContext(ctx, logTag+" fetching reservations of order", "order_id", orderID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var reservations []types.StockReservation
+	if err := db.Where("order_id = ?", orderID).Order("id").Find(&reservations).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch reservations", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to fetch reservations %w", err)
+	}
+
+	return reservations, nil
+}
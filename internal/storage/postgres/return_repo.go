@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnRepo struct {
+	DB *Postgres
+}
+
+func NewReturnRepo(db *Postgres) *ReturnRepo {
+	return &ReturnRepo{
+		DB: db,
+	}
+}
+
+func (r *ReturnRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, ret *types.OrderReturn, items []types.OrderReturnItem) (*types.OrderReturnWithItems, error) {
+	logTag := "[ReturnRepo][CreateWithTx]"
+	log.InfofWithContext(ctx, logTag+" creating return", "order_id", ret.OrderID, "items_count", len(items))
+
+	if err := tx.Create(ret).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create return", err, "order_id", ret.OrderID)
+		return nil, fmt.Errorf("failed to create return %w", err)
+	}
+
+	for i := range items {
+		items[i].ReturnID = ret.ID
+		if err := tx.Create(&items[i]).Error; err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to create return item", err, "order_item_id", items[i].OrderItemID)
+			return nil, fmt.Errorf("failed to create return item %w", err)
+		}
+	}
+
+	log.InfofWithContext(ctx, logTag+" return created successfully", "return_id", ret.ID)
+	return &types.OrderReturnWithItems{
+		Return: *ret,
+		Items:  items,
+	}, nil
+}
+
+// sums the quantities already claimed per order item by returns that were not rejected
+func (r *ReturnRepo) GetReturnedQuantitiesWithTx(tx *gorm.DB, ctx context.Context, orderID int64) (map[int64]int32, error) {
+	logTag := "[ReturnRepo][GetReturnedQuantitiesWithTx]"
+	log.InfofWithContext(ctx, logTag+" fetching returned quantities", "order_id", orderID)
+
+	var rows []struct {
+		OrderItemID int64
+		Quantity    int32
+	}
+	err := tx.Table("order_return_items ri").
+		Select("ri.order_item_id, SUM(ri.quantity) AS quantity").
+		Joins("JOIN order_returns rt ON rt.id = ri.return_id").
+		Where("rt.order_id = ? AND rt.status <> ?", orderID, types.ReturnStatusRejected).
+		Group("ri.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch returned quantities", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to fetch returned quantities: %w", err)
+	}
+
+	quantities := make(map[int64]int32, len(rows))
+	for _, row := range rows {
+		quantities[row.OrderItemID] = row.Quantity
+	}
+
+	return quantities, nil
+}
+
+func (r *ReturnRepo) LockByIDWithTx(tx *gorm.DB, ctx context.Context, orderID, returnID int64) (*types.OrderReturnWithItems, error) {
+	logTag := "[ReturnRepo][LockByIDWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking return", "order_id", orderID, "return_id", returnID)
+
+	var ret types.OrderReturn
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND order_id = ?", returnID, orderID).First(&ret).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" return not found", "order_id", orderID, "return_id", returnID)
+			return nil, fmt.Errorf("return not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to lock return", err, "return_id", returnID)
+		return nil, fmt.Errorf("failed to lock return %w", err)
+	}
+
+	var items []types.OrderReturnItem
+	if err := tx.Where("return_id = ?", ret.ID).Find(&items).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch return items", err, "return_id", returnID)
+		return nil, fmt.Errorf("failed to fetch return items: %w", err)
+	}
+
+	return &types.OrderReturnWithItems{
+		Return: ret,
+		Items:  items,
+	}, nil
+}
+
+func (r *ReturnRepo) UpdateWithTx(tx *gorm.DB, ctx context.Context, ret *types.OrderReturn) error {
+	logTag := "[ReturnRepo][UpdateWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating return", "return_id", ret.ID, "status", ret.Status)
+
+	res := tx.Model(&types.OrderReturn{}).
+		Where("id = ?", ret.ID).
+		Updates(map[string]interface{}{
+			"status":          ret.Status,
+			"resolution_note": ret.ResolutionNote,
+			"restocked":       ret.Restocked,
+			"approved_at":     ret.ApprovedAt,
+			"rejected_at":     ret.RejectedAt,
+			"received_at":     ret.ReceivedAt,
+			"updated_at":      ret.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update return", res.Error, "return_id", ret.ID)
+		return fmt.Errorf("failed to update return %w", res.Error)
+	}
+
+	log.InfofWithContext(ctx, logTag+" return updated successfully", "return_id", ret.ID)
+	return nil
+}
+
+func (r *ReturnRepo) SearchByID(ctx context.Context, orderID, returnID int64) (*types.OrderReturnWithItems, error) {
+	logTag := "[ReturnRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching return", "order_id", orderID, "return_id", returnID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var ret types.OrderReturn
+	if err := db.Where("id = ? AND order_id = ?", returnID, orderID).First(&ret).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" return not found", "order_id", orderID, "return_id", returnID)
+			return nil, fmt.Errorf("return not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch return", err, "return_id", returnID)
+		return nil, fmt.Errorf("failed to fetch return %w", err)
+	}
+
+	var items []types.OrderReturnItem
+	if err := db.Where("return_id = ?", ret.ID).Find(&items).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch return items", err, "return_id", returnID)
+		return nil, fmt.Errorf("failed to fetch return items: %w", err)
+	}
+
+	return &types.OrderReturnWithItems{
+		Return: ret,
+		Items:  items,
+	}, nil
+}
+
+func (r *ReturnRepo) GetByOrderID(ctx context.Context, orderID int64) ([]types.OrderReturnWithItems, error) {
+	logTag := "[ReturnRepo][GetByOrderID]"
+	log.InfofWithContext(ctx, logTag+" fetching returns of order", "order_id", orderID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	returns, err := fetchOrderReturns(db, orderID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch returns", err, "order_id", orderID)
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+// loads every return of an order together with its items, shared with the order search
+func fetchOrderReturns(db *gorm.DB, orderID int64) ([]types.OrderReturnWithItems, error) {
+	var returns []types.OrderReturn
+	if err := db.Where("order_id = ?", orderID).Order("created_at DESC").Find(&returns).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch returns: %w", err)
+	}
+
+	if len(returns) == 0 {
+		return nil, nil
+	}
+
+	returnIDs := make([]int64, 0, len(returns))
+	for _, ret := range returns {
+		returnIDs = append(returnIDs, ret.ID)
+	}
+
+	var items []types.OrderReturnItem
+	if err := db.Where("return_id IN ?", returnIDs).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch return items: %w", err)
+	}
+
+	itemsByReturn := make(map[int64][]types.OrderReturnItem, len(returns))
+	for _, item := range items {
+		itemsByReturn[item.ReturnID] = append(itemsByReturn[item.ReturnID], item)
+	}
+
+	result := make([]types.OrderReturnWithItems, 0, len(returns))
+	for _, ret := range returns {
+		result = append(result, types.OrderReturnWithItems{
+			Return: ret,
+			Items:  itemsByReturn[ret.ID],
+		})
+	}
+
+	return result, nil
+}
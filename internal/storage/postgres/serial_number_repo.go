@@ -0,0 +1,219 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// serial numbers are written while the product row is locked by a stock change or by LockByIDWithTx, so
+// registration and assignment of the same product never interleave
+type SerialNumberRepo struct {
+	DB *Postgres
+}
+
+func NewSerialNumberRepo(db *Postgres) *SerialNumberRepo {
+	return &SerialNumberRepo{
+		DB: db,
+	}
+}
+
+// which of the products are serialized
+func (r *SerialNumberRepo) GetSerializedWithTx(tx *gorm.DB, ctx context.Context, productIDs []int64) (map[int64]bool, error) {
+	logTag := "[SerialNumberRepo][GetSerializedWithTx]"
+
+	serialized := make(map[int64]bool, len(productIDs))
+	if len(productIDs) == 0 {
+		return serialized, nil
+	}
+
+	var ids []int64
+	if err := tx.Model(&types.Product{}).Where("id IN ? AND is_serialized", productIDs).Pluck("id", &ids).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch serialized products", err)
+		return nil, fmt.Errorf("failed to fetch serialized products %w", err)
+	}
+	for _, id := range ids {
+		serialized[id] = true
+	}
+
+	return serialized, nil
+}
+
+func (r *SerialNumberRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, serials []types.SerialNumber) error {
+	logTag := "[SerialNumberRepo][CreateWithTx]"
+	log.InfofWithContext(ctx, logTag+" registering serial numbers", "count", len(serials))
+
+	if len(serials) == 0 {
+		return nil
+	}
+
+	if err := tx.Create(&serials).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to register serial numbers", err)
+		return fmt.Errorf("failed to register serial numbers %w", err)
+	}
+
+	return nil
+}
+
+func (r *SerialNumberRepo) CountInStockWithTx(tx *gorm.DB, ctx context.Context, productID int64) (int64, error) {
+	logTag := "[SerialNumberRepo][CountInStockWithTx]"
+
+	var count int64
+	err := tx.Model(&types.SerialNumber{}).
+		Where("product_id = ? AND status = ?", productID, types.SerialNumberStatusInStock).
+		Count(&count).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count serial numbers", err, "product_id", productID)
+		return 0, fmt.Errorf("failed to count serial numbers %w", err)
+	}
+
+	return count, nil
+}
+
+// locks the product's serial numbers out of serials, numbers that are not registered are missing from the result
+func (r *SerialNumberRepo) LockByNumbersWithTx(tx *gorm.DB, ctx context.Context, productID int64, serials []string) ([]types.SerialNumber, error) {
+	logTag := "[SerialNumberRepo][LockByNumbersWithTx]"
+
+	var locked []types.SerialNumber
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND serial_number IN ?", productID, serials).
+		Order("id").
+		Find(&locked).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to lock serial numbers", err, "product_id", productID)
+		return nil, fmt.Errorf("failed to lock serial numbers %w", err)
+	}
+
+	return locked, nil
+}
+
+// marks the serial numbers shipped with the order item
+func (r *SerialNumberRepo) AssignWithTx(tx *gorm.DB, ctx context.Context, ids []int64, orderItemID, shipmentID int64, now time.Time) error {
+	logTag := "[SerialNumberRepo][AssignWithTx]"
+	log.InfofWithContext(ctx, logTag+" assigning serial numbers", "order_item_id", orderItemID, "shipment_id", shipmentID, "count", len(ids))
+
+	err := tx.Model(&types.SerialNumber{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"status":        types.SerialNumberStatusShipped,
+			"order_item_id": orderItemID,
+			"shipment_id":   shipmentID,
+			"shipped_at":    now,
+			"updated_at":    now,
+		}).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to assign serial numbers", err, "order_item_id", orderItemID)
+		return fmt.Errorf("failed to assign serial numbers %w", err)
+	}
+
+	return nil
+}
+
+// puts shipped units back into stock at a location, they no longer belong to the order they shipped with
+func (r *SerialNumberRepo) RestockWithTx(tx *gorm.DB, ctx context.Context, ids []int64, locationID int64, now time.Time) error {
+	logTag := "[SerialNumberRepo][RestockWithTx]"
+	log.InfofWithContext(ctx, logTag+" restocking serial numbers", "location_id", locationID, "count", len(ids))
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := tx.Model(&types.SerialNumber{}).
+		Where("id IN ? AND status = ?", ids, types.SerialNumberStatusShipped).
+		Updates(map[string]interface{}{
+			"status":        types.SerialNumberStatusInStock,
+			"location_id":   locationID,
+			"order_item_id": nil,
+			"shipment_id":   nil,
+			"shipped_at":    nil,
+			"received_at":   now,
+			"updated_at":    now,
+		}).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to restock serial numbers", err, "location_id", locationID)
+		return fmt.Errorf("failed to restock serial numbers %w", err)
+	}
+
+	return nil
+}
+
+// pages through the product's serial numbers, an empty status matches every status
+func (r *SerialNumberRepo) GetByProductID(ctx context.Context, productID int64, status types.SerialNumberStatus, limit, offset int) ([]types.SerialNumber, int64, error) {
+	logTag := "[SerialNumberRepo][GetByProductID]"
+	log.InfofWithContext(ctx, logTag+" fetching serial numbers", "product_id", productID, "status", status, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	query := db.Model(&types.SerialNumber{}).Where("product_id = ?", productID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count serial numbers", err)
+		return nil, 0, fmt.Errorf("failed to count serial numbers %w", err)
+	}
+
+	var serials []types.SerialNumber
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&serials).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch serial numbers", err)
+		return nil, 0, fmt.Errorf("failed to fetch serial numbers %w", err)
+	}
+
+	return serials, total, nil
+}
+
+// the serial numbers that shipped with the items of an order
+func (r *SerialNumberRepo) GetByOrderID(ctx context.Context, orderID int64) ([]types.SerialNumber, error) {
+	logTag := "[SerialNumberRepo][GetByOrderID]"
+	log.InfofWithContext(ctx, logTag+" fetching order serial numbers", "order_id", orderID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var serials []types.SerialNumber
+	err := db.Table("serial_numbers s").
+		Select("s.*").
+		Joins("JOIN order_items oi ON oi.id = s.order_item_id").
+		Where("oi.order_id = ?", orderID).
+		Order("s.order_item_id, s.serial_number").
+		Scan(&serials).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch order serial numbers", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to fetch order serial numbers %w", err)
+	}
+
+	return serials, nil
+}
+
+// every product's unit with this serial number together with the order and customer it shipped to
+func (r *SerialNumberRepo) SearchByNumber(ctx context.Context, serialNumber string) ([]types.SerialNumberLookup, error) {
+	logTag := "[SerialNumberRepo][SearchByNumber]"
+	log.InfofWithContext(ctx, logTag+" looking up serial number", "serial_number", serialNumber)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var lookups []types.SerialNumberLookup
+	err := db.Table("serial_numbers s").
+		Select(`s.serial_number, s.status, s.product_id, p.sku AS product_sku, p.name AS product_name, s.received_at,
+			oi.order_id, s.order_item_id, s.shipment_id, s.shipped_at,
+			u.id AS user_id, u.name AS customer_name, u.email AS customer_email`).
+		Joins("JOIN products p ON p.id = s.product_id").
+		Joins("LEFT JOIN order_items oi ON oi.id = s.order_item_id").
+		Joins("LEFT JOIN orders o ON o.id = oi.order_id").
+		Joins("LEFT JOIN users u ON u.id = o.user_id").
+		Where("s.serial_number = ?", serialNumber).
+		Order("s.product_id").
+		Scan(&lookups).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to look up serial number", err, "serial_number", serialNumber)
+		return nil, fmt.Errorf("failed to look up serial number %w", err)
+	}
+
+	return lookups, nil
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShipmentRepo struct {
+	DB *Postgres
+}
+
+func NewShipmentRepo(db *Postgres) *ShipmentRepo {
+	return &ShipmentRepo{
+		DB: db,
+	}
+}
+
+func (r *ShipmentRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, shipment *types.Shipment, items []types.ShipmentItem) (*types.ShipmentWithItems, error) {
+	logTag := "[ShipmentRepo][CreateWithTx]"
+	log.InfofWithContext(ctx, logTag+" creating shipment", "order_id", shipment.OrderID, "items_count", len(items))
+
+	if err := tx.Create(shipment).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create shipment", err, "order_id", shipment.OrderID)
+		return nil, fmt.Errorf("failed to create shipment %w", err)
+	}
+
+	for i := range items {
+		items[i].ShipmentID = shipment.ID
+		if err := tx.Create(&items[i]).Error; err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to create shipment item", err, "order_item_id", items[i].OrderItemID)
+			return nil, fmt.Errorf("failed to create shipment item %w", err)
+		}
+	}
+
+	log.InfofWithContext(ctx, logTag+" shipment created successfully", "shipment_id", shipment.ID)
+	return &types.ShipmentWithItems{
+		Shipment: *shipment,
+		Items:    items,
+	}, nil
+}
+
+// sums the quantity already shipped per order item
+func (r *ShipmentRepo) GetShippedQuantitiesWithTx(tx *gorm.DB, ctx context.Context, orderID int64) (map[int64]int32, error) {
+	logTag := "[ShipmentRepo][GetShippedQuantitiesWithTx]"
+	log.InfofWithContext(ctx, logTag+" fetching shipped quantities", "order_id", orderID)
+
+	var rows []struct {
+		OrderItemID int64
+		Quantity    int32
+	}
+	err := tx.Table("shipment_items si").
+		Select("si.order_item_id, SUM(si.quantity) AS quantity").
+		Joins("JOIN shipments s ON s.id = si.shipment_id").
+		Where("s.order_id = ?", orderID).
+		Group("si.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch shipped quantities", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to fetch shipped quantities: %w", err)
+	}
+
+	quantities := make(map[int64]int32, len(rows))
+	for _, row := range rows {
+		quantities[row.OrderItemID] = row.Quantity
+	}
+
+	return quantities, nil
+}
+
+func (r *ShipmentRepo) LockByIDWithTx(tx *gorm.DB, ctx context.Context, orderID, shipmentID int64) (*types.Shipment, error) {
+	logTag := "[ShipmentRepo][LockByIDWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking shipment", "order_id", orderID, "shipment_id", shipmentID)
+
+	var shipment types.Shipment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND order_id = ?", shipmentID, orderID).First(&shipment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" shipment not found", "order_id", orderID, "shipment_id", shipmentID)
+			return nil, fmt.Errorf("shipment not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to lock shipment", err, "shipment_id", shipmentID)
+		return nil, fmt.Errorf("failed to lock shipment %w", err)
+	}
+
+	return &shipment, nil
+}
+
+func (r *ShipmentRepo) MarkDeliveredWithTx(tx *gorm.DB, ctx context.Context, shipment *types.Shipment) error {
+	logTag := "[ShipmentRepo][MarkDeliveredWithTx]"
+	log.InfofWithContext(ctx, logTag+" marking shipment delivered", "shipment_id", shipment.ID)
+
+	res := tx.Model(&types.Shipment{}).
+		Where("id = ?", shipment.ID).
+		Updates(map[string]interface{}{
+			"status":       types.ShipmentStatusDelivered,
+			"delivered_at": shipment.DeliveredAt,
+			"updated_at":   shipment.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update shipment", res.Error, "shipment_id", shipment.ID)
+		return fmt.Errorf("failed to update shipment %w", res.Error)
+	}
+
+	return nil
+}
+
+func (r *ShipmentRepo) CountUndeliveredWithTx(tx *gorm.DB, ctx context.Context, orderID int64) (int64, error) {
+	logTag := "[ShipmentRepo][CountUndeliveredWithTx]"
+
+	var count int64
+	if err := tx.Model(&types.Shipment{}).Where("order_id = ? AND status <> ?", orderID, types.ShipmentStatusDelivered).Count(&count).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count undelivered shipments", err, "order_id", orderID)
+		return 0, fmt.Errorf("failed to count undelivered shipments: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *ShipmentRepo) GetByOrderID(ctx context.Context, orderID int64) ([]types.ShipmentWithItems, error) {
+	logTag := "[ShipmentRepo][GetByOrderID]"
+	log.InfofWithContext(ctx, logTag+" fetching shipments of order", "order_id", orderID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var shipments []types.Shipment
+	if err := db.Where("order_id = ?", orderID).Order("shipped_at ASC").Find(&shipments).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch shipments", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to fetch shipments: %w", err)
+	}
+
+	if len(shipments) == 0 {
+		return nil, nil
+	}
+
+	shipmentIDs := make([]int64, 0, len(shipments))
+	for _, shipment := range shipments {
+		shipmentIDs = append(shipmentIDs, shipment.ID)
+	}
+
+	var items []types.ShipmentItem
+	if err := db.Where("shipment_id IN ?", shipmentIDs).Find(&items).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch shipment items", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to fetch shipment items: %w", err)
+	}
+
+	itemsByShipment := make(map[int64][]types.ShipmentItem, len(shipments))
+	for _, item := range items {
+		itemsByShipment[item.ShipmentID] = append(itemsByShipment[item.ShipmentID], item)
+	}
+
+	result := make([]types.ShipmentWithItems, 0, len(shipments))
+	for _, shipment := range shipments {
+		result = append(result, types.ShipmentWithItems{
+			Shipment: shipment,
+			Items:    itemsByShipment[shipment.ID],
+		})
+	}
+
+	return result, nil
+}
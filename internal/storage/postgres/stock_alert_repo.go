@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+)
+
+// alerts are raised and resolved by ProductRepo as part of the stock change that causes them
+type StockAlertRepo struct {
+	DB *Postgres
+}
+
+func NewStockAlertRepo(db *Postgres) *StockAlertRepo {
+	return &StockAlertRepo{
+		DB: db,
+	}
+}
+
+// pages through alerts newest first, an empty status or a zero product id matches everything
+func (r *StockAlertRepo) Search(ctx context.Context, status types.StockAlertStatus, productID int64, limit, offset int) ([]types.StockAlert, int64, error) {
+	logTag := "[StockAlertRepo][Search]"
+	log.InfofWithContext(ctx, logTag+" searching stock alerts", "status", status, "product_id", productID, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	query := db.Model(&types.StockAlert{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if productID > 0 {
+		query = query.Where("product_id = ?", productID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count stock alerts", err)
+		return nil, 0, fmt.Errorf("failed to count stock alerts %w", err)
+	}
+
+	var alerts []types.StockAlert
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&alerts).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock alerts", err)
+		return nil, 0, fmt.Errorf("failed to fetch stock alerts %w", err)
+	}
+
+	return alerts, total, nil
+}
+
+// alerts the notifier has not delivered yet, oldest first and read from the master so nothing raised
+// just now is missed
+func (r *StockAlertRepo) GetUnnotified(ctx context.Context, limit int) ([]types.StockAlert, error) {
+	logTag := "[StockAlertRepo][GetUnnotified]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var alerts []types.StockAlert
+	if err := db.Where("notified_at IS NULL").Order("id").Limit(limit).Find(&alerts).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch unnotified alerts", err)
+		return nil, fmt.Errorf("failed to fetch unnotified alerts %w", err)
+	}
+
+	return alerts, nil
+}
+
+func (r *StockAlertRepo) MarkNotified(ctx context.Context, id int64, notifiedAt time.Time) error {
+	logTag := "[StockAlertRepo][MarkNotified]"
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Model(&types.StockAlert{}).Where("id = ?", id).Update("notified_at", notifiedAt).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to mark alert notified", err, "alert_id", id)
+		return fmt.Errorf("failed to mark alert notified %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// every lot change runs inside a stock change of the same product, so the product row lock taken by
+// ProductRepo also serialises the lots
+type StockLotRepo struct {
+	DB *Postgres
+}
+
+func NewStockLotRepo(db *Postgres) *StockLotRepo {
+	return &StockLotRepo{
+		DB: db,
+	}
+}
+
+// an order item's open allocation from one lot
+type lotAllocation struct {
+	ID    int64
+	LotID int64
+	Open  int64
+}
+
+// the lot with this number at the location, nil when there is none yet
+func (r *StockLotRepo) GetByNumberWithTx(tx *gorm.DB, ctx context.Context, productID, locationID int64, lotNumber string) (*types.StockLot, error) {
+	logTag := "[StockLotRepo][GetByNumberWithTx]"
+
+	var lots []types.StockLot
+	if err := tx.Where("product_id = ? AND location_id = ? AND lot_number = ?", productID, locationID, lotNumber).Find(&lots).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch lot", err, "product_id", productID, "lot_number", lotNumber)
+		return nil, fmt.Errorf("failed to fetch lot %w", err)
+	}
+	if len(lots) == 0 {
+		return nil, nil
+	}
+
+	return &lots[0], nil
+}
+
+// puts quantity units that were just added to the location's stock into a lot, creating it on first use
+func (r *StockLotRepo) AddWithTx(tx *gorm.DB, ctx context.Context, productID, locationID int64, lotNumber string, expiresAt *time.Time, quantity int64) error {
+	logTag := "[StockLotRepo][AddWithTx]"
+	log.InfofWithContext(ctx, logTag+" adding stock to lot", "product_id", productID, "location_id", locationID, "lot_number", lotNumber, "quantity", quantity)
+
+	now := time.Now()
+	err := tx.Exec(`INSERT INTO stock_lots (product_id, location_id, lot_number, expires_at, quantity, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (product_id, location_id, lot_number) DO UPDATE SET quantity = stock_lots.quantity + EXCLUDED.quantity, updated_at = EXCLUDED.updated_at`,
+		productID, locationID, lotNumber, expiresAt, quantity, now, now).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to add stock to lot", err, "product_id", productID, "lot_number", lotNumber)
+		return fmt.Errorf("failed to add stock to lot %w", err)
+	}
+
+	return nil
+}
+
+// reserves up to quantity units for an order item from the unexpired lots at the location, earliest expiry
+// first. What the lots can't cover comes from untracked stock, the call fails with insufficient stock when
+// that would eat into the free stock of the lots
+func (r *StockLotRepo) ReserveWithTx(tx *gorm.DB, ctx context.Context, orderItemID, productID, locationID, quantity int64) error {
+	logTag := "[StockLotRepo][ReserveWithTx]"
+
+	var lots []types.StockLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND location_id = ? AND quantity > reserved_quantity AND (expires_at IS NULL OR expires_at >= CURRENT_DATE)", productID, locationID).
+		Order("expires_at NULLS LAST, id").
+		Find(&lots).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch lots", err, "product_id", productID, "location_id", locationID)
+		return fmt.Errorf("failed to fetch lots %w", err)
+	}
+
+	remaining := quantity
+	for _, lot := range lots {
+		if remaining == 0 {
+			break
+		}
+		take := min(lot.Quantity-lot.ReservedQuantity, remaining)
+
+		err := tx.Exec(`UPDATE stock_lots SET reserved_quantity = reserved_quantity + ?, updated_at = ? WHERE id = ?`, take, time.Now(), lot.ID).Error
+		if err != nil {
+			return fmt.Errorf("failed to reserve lot %w", err)
+		}
+		err = tx.Exec(`INSERT INTO order_item_lots (order_item_id, lot_id, quantity) VALUES (?, ?, ?)
+			ON CONFLICT (order_item_id, lot_id) DO UPDATE SET quantity = order_item_lots.quantity + EXCLUDED.quantity`,
+			orderItemID, lot.ID, take).Error
+		if err != nil {
+			return fmt.Errorf("failed to record lot allocation %w", err)
+		}
+		remaining -= take
+	}
+
+	if remaining > 0 {
+		excess, err := lotExcessWithTx(tx, productID, locationID)
+		if err != nil {
+			return fmt.Errorf("failed to check untracked stock %w", err)
+		}
+		if excess > 0 {
+			log.WarnfWithContext(ctx, logTag+" only expired lots left to reserve from", "product_id", productID, "location_id", locationID, "missing", excess)
+			return fmt.Errorf("insufficient stock")
+		}
+	}
+
+	return nil
+}
+
+// gives back up to quantity units of an order item's open lot allocations, latest expiry first so the item
+// keeps the lots that have to go soonest
+func (r *StockLotRepo) ReleaseWithTx(tx *gorm.DB, ctx context.Context, orderItemID, quantity int64) error {
+	logTag := "[StockLotRepo][ReleaseWithTx]"
+
+	allocations, err := openAllocationsWithTx(tx, orderItemID, "l.expires_at DESC NULLS FIRST, a.id DESC")
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch lot allocations", err, "order_item_id", orderItemID)
+		return err
+	}
+
+	remaining := quantity
+	for _, allocation := range allocations {
+		if remaining == 0 {
+			break
+		}
+		take := min(allocation.Open, remaining)
+
+		if err := tx.Exec(`UPDATE order_item_lots SET quantity = quantity - ? WHERE id = ?`, take, allocation.ID).Error; err != nil {
+			return fmt.Errorf("failed to release lot allocation %w", err)
+		}
+		if err := tx.Exec(`DELETE FROM order_item_lots WHERE id = ? AND quantity = 0`, allocation.ID).Error; err != nil {
+			return fmt.Errorf("failed to release lot allocation %w", err)
+		}
+		err := tx.Exec(`UPDATE stock_lots SET reserved_quantity = reserved_quantity - ?, updated_at = ? WHERE id = ?`, take, time.Now(), allocation.LotID).Error
+		if err != nil {
+			return fmt.Errorf("failed to release lot %w", err)
+		}
+		remaining -= take
+	}
+
+	return nil
+}
+
+// ships up to quantity units of an order item's open lot allocations, earliest expiry first
+func (r *StockLotRepo) ConsumeWithTx(tx *gorm.DB, ctx context.Context, orderItemID, quantity int64) error {
+	logTag := "[StockLotRepo][ConsumeWithTx]"
+
+	allocations, err := openAllocationsWithTx(tx, orderItemID, "l.expires_at NULLS LAST, a.id")
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch lot allocations", err, "order_item_id", orderItemID)
+		return err
+	}
+
+	remaining := quantity
+	for _, allocation := range allocations {
+		if remaining == 0 {
+			break
+		}
+		take := min(allocation.Open, remaining)
+
+		if err := tx.Exec(`UPDATE order_item_lots SET fulfilled_quantity = fulfilled_quantity + ? WHERE id = ?`, take, allocation.ID).Error; err != nil {
+			return fmt.Errorf("failed to fulfil lot allocation %w", err)
+		}
+		err := tx.Exec(`UPDATE stock_lots SET quantity = quantity - ?, reserved_quantity = reserved_quantity - ?, updated_at = ? WHERE id = ?`,
+			take, take, time.Now(), allocation.LotID).Error
+		if err != nil {
+			return fmt.Errorf("failed to consume lot %w", err)
+		}
+		remaining -= take
+	}
+
+	return nil
+}
+
+// the lots of a product that still hold stock, earliest expiry first
+func (r *StockLotRepo) GetByProductID(ctx context.Context, productID int64) ([]types.StockLot, error) {
+	logTag := "[StockLotRepo][GetByProductID]"
+	log.InfofWithContext(ctx, logTag+" fetching lots", "product_id", productID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var lots []types.StockLot
+	if err := db.Where("product_id = ? AND quantity > 0", productID).Order("expires_at NULLS LAST, id").Find(&lots).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch lots", err, "product_id", productID)
+		return nil, fmt.Errorf("failed to fetch lots %w", err)
+	}
+
+	return lots, nil
+}
+
+// pages through the lots with stock that expire on or before until, already expired ones included,
+// a zero location id matches every location
+func (r *StockLotRepo) GetExpiring(ctx context.Context, until time.Time, locationID int64, limit, offset int) ([]types.StockLot, int64, error) {
+	logTag := "[StockLotRepo][GetExpiring]"
+	log.InfofWithContext(ctx, logTag+" fetching expiring lots", "until", until, "location_id", locationID, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	query := db.Model(&types.StockLot{}).Where("quantity > 0 AND expires_at <= ?", until.Format("2006-01-02"))
+	if locationID > 0 {
+		query = query.Where("location_id = ?", locationID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count expiring lots", err)
+		return nil, 0, fmt.Errorf("failed to count expiring lots %w", err)
+	}
+
+	var lots []types.StockLot
+	if err := query.Order("expires_at, id").Limit(limit).Offset(offset).Find(&lots).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch expiring lots", err)
+		return nil, 0, fmt.Errorf("failed to fetch expiring lots %w", err)
+	}
+
+	return lots, total, nil
+}
+
+// the lot allocations of every item of an order
+func (r *StockLotRepo) GetByOrderID(ctx context.Context, orderID int64) ([]types.OrderItemLot, error) {
+	logTag := "[StockLotRepo][GetByOrderID]"
+	log.InfofWithContext(ctx, logTag+" fetching order lots", "order_id", orderID)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var lots []types.OrderItemLot
+	err := db.Table("order_item_lots a").
+		Select("a.*, l.lot_number, l.expires_at").
+		Joins("JOIN order_items oi ON oi.id = a.order_item_id").
+		Joins("JOIN stock_lots l ON l.id = a.lot_id").
+		Where("oi.order_id = ?", orderID).
+		Order("a.order_item_id, l.expires_at NULLS LAST, a.id").
+		Scan(&lots).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch order lots", err, "order_id", orderID)
+		return nil, fmt.Errorf("failed to fetch order lots %w", err)
+	}
+
+	return lots, nil
+}
+
+func openAllocationsWithTx(tx *gorm.DB, orderItemID int64, order string) ([]lotAllocation, error) {
+	var allocations []lotAllocation
+	err := tx.Table("order_item_lots a").
+		Select("a.id, a.lot_id, a.quantity - a.fulfilled_quantity AS open").
+		Joins("JOIN stock_lots l ON l.id = a.lot_id").
+		Where("a.order_item_id = ? AND a.quantity > a.fulfilled_quantity", orderItemID).
+		Order(order).
+		Scan(&allocations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lot allocations %w", err)
+	}
+
+	return allocations, nil
+}
+
+// how much the free stock of the lots exceeds the free stock of the location, anything above zero is lot
+// stock the location no longer holds
+func lotExcessWithTx(tx *gorm.DB, productID, locationID int64) (int64, error) {
+	var excess int64
+	err := tx.Raw(`SELECT CAST(COALESCE((SELECT SUM(quantity - reserved_quantity) FROM stock_lots WHERE product_id = ? AND location_id = ?), 0)
+		- COALESCE((SELECT stock_quantity - reserved_quantity FROM product_stocks WHERE product_id = ? AND location_id = ?), 0)
+		AS BIGINT)`,
+		productID, locationID, productID, locationID).Row().Scan(&excess)
+	return excess, err
+}
+
+// takes stock a plain decrement removed from the location out of the lots as well, untracked stock is
+// used up first and the lots after it in expiry order
+func trimStockLotsWithTx(tx *gorm.DB, productID, locationID int64, now time.Time) error {
+	excess, err := lotExcessWithTx(tx, productID, locationID)
+	if err != nil || excess <= 0 {
+		return err
+	}
+
+	return tx.Exec(`WITH ranked AS (
+			SELECT id, quantity - reserved_quantity AS free,
+				SUM(quantity - reserved_quantity) OVER (ORDER BY expires_at NULLS LAST, id) - (quantity - reserved_quantity) AS used_before
+			FROM stock_lots
+			WHERE product_id = ? AND location_id = ? AND quantity > reserved_quantity
+		)
+		UPDATE stock_lots l SET quantity = l.quantity - LEAST(r.free, ? - r.used_before), updated_at = ?
+		FROM ranked r
+		WHERE l.id = r.id AND r.used_before < ?`, productID, locationID, excess, now, excess).Error
+}
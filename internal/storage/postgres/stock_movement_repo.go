@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+)
+
+// rows are written by ProductRepo together with the stock change they describe, this repo only reads them
+type StockMovementRepo struct {
+	DB *Postgres
+}
+
+func NewStockMovementRepo(db *Postgres) *StockMovementRepo {
+	return &StockMovementRepo{
+		DB: db,
+	}
+}
+
+// pages through the movements of a product, newest first
+func (r *StockMovementRepo) GetByProductID(ctx context.Context, productID int64, limit, offset int) ([]types.StockMovement, int64, error) {
+	logTag := "[StockMovementRepo][GetByProductID]"
+	log.InfofWithContext(ctx, logTag+" fetching stock movements", "product_id", productID, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var total int64
+	if err := db.Model(&types.StockMovement{}).Where("product_id = ?", productID).Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count stock movements", err, "product_id", productID)
+		return nil, 0, fmt.Errorf("failed to count stock movements %w", err)
+	}
+
+	var movements []types.StockMovement
+	if err := db.Where("product_id = ?", productID).Order("id DESC").Limit(limit).Offset(offset).Find(&movements).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock movements", err, "product_id", productID)
+		return nil, 0, fmt.Errorf("failed to fetch stock movements %w", err)
+	}
+
+	return movements, total, nil
+}
+
+// replays the ledger of a product next to its stored totals and the sum over its locations, all come from
+// one statement on the master so a concurrent stock change can never show up on only one side
+func (r *StockMovementRepo) Reconcile(ctx context.Context, productID int64) (*types.StockReconciliation, error) {
+	logTag := "[StockMovementRepo][Reconcile]"
+	log.InfofWithContext(ctx, logTag+" reconciling stock", "product_id", productID)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	var reconciliation types.StockReconciliation
+	res := db.Raw(`
+		SELECT p.id AS product_id,
+			p.stock_quantity,
+			p.reserved_quantity,
+			COALESCE(m.ledger_quantity, 0) AS ledger_quantity,
+			COALESCE(m.ledger_reserved, 0) AS ledger_reserved,
+			COALESCE(m.movement_count, 0) AS movement_count,
+			COALESCE(ps.location_quantity, 0) AS location_quantity,
+			COALESCE(ps.location_reserved, 0) AS location_reserved
+		FROM products p
+		LEFT JOIN (
+			SELECT product_id, SUM(delta) AS ledger_quantity, SUM(reserved_delta) AS ledger_reserved, COUNT(*) AS movement_count
+			FROM stock_movements WHERE product_id = ? GROUP BY product_id
+		) m ON m.product_id = p.id
+		LEFT JOIN (
+			SELECT product_id, SUM(stock_quantity) AS location_quantity, SUM(reserved_quantity) AS location_reserved
+			FROM product_stocks WHERE product_id = ? GROUP BY product_id
+		) ps ON ps.product_id = p.id
+		WHERE p.id = ?`, productID, productID, productID).Scan(&reconciliation)
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to reconcile stock", res.Error, "product_id", productID)
+		return nil, fmt.Errorf("failed to reconcile stock %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		log.WarnfWithContext(ctx, logTag+" product not found", "product_id", productID)
+		return nil, fmt.Errorf("product not found with id %d", productID)
+	}
+
+	reconciliation.InSync = reconciliation.StockQuantity == reconciliation.LedgerQuantity &&
+		reconciliation.ReservedQuantity == reconciliation.LedgerReserved &&
+		reconciliation.StockQuantity == reconciliation.LocationQuantity &&
+		reconciliation.ReservedQuantity == reconciliation.LocationReserved
+	return &reconciliation, nil
+}
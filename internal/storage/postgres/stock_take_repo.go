@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockTakeRepo struct {
+	DB *Postgres
+}
+
+func NewStockTakeRepo(db *Postgres) *StockTakeRepo {
+	return &StockTakeRepo{
+		DB: db,
+	}
+}
+
+func (r *StockTakeRepo) CreateWithTx(tx *gorm.DB, ctx context.Context, take *types.StockTake, lines []types.StockTakeLine) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeRepo][CreateWithTx]"
+	log.InfofWithContext(ctx, logTag+" creating stock take", "location_id", take.LocationID, "lines_count", len(lines))
+
+	if err := tx.Create(take).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create stock take", err, "location_id", take.LocationID)
+		return nil, fmt.Errorf("failed to create stock take %w", err)
+	}
+
+	for i := range lines {
+		lines[i].StockTakeID = take.ID
+		if err := tx.Create(&lines[i]).Error; err != nil {
+			log.ErrorfWithContext(ctx, logTag+" failed to create stock take line", err, "product_id", lines[i].ProductID)
+			return nil, fmt.Errorf("failed to create stock take line %w", err)
+		}
+	}
+
+	log.InfofWithContext(ctx, logTag+" stock take created successfully", "stock_take_id", take.ID)
+	return &types.StockTakeWithLines{
+		StockTake: *take,
+		Lines:     lines,
+	}, nil
+}
+
+// the products among productIDs that are already being counted at the location by another open session
+func (r *StockTakeRepo) GetOpenProductIDsWithTx(tx *gorm.DB, ctx context.Context, locationID int64, productIDs []int64) ([]int64, error) {
+	logTag := "[StockTakeRepo][GetOpenProductIDsWithTx]"
+
+	var ids []int64
+	err := tx.Table("stock_take_lines l").
+		Joins("JOIN stock_takes t ON t.id = l.stock_take_id").
+		Where("t.location_id = ? AND t.status = ? AND l.product_id IN ?", locationID, types.StockTakeStatusOpen, productIDs).
+		Order("l.product_id").
+		Pluck("l.product_id", &ids).Error
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch products under count", err, "location_id", locationID)
+		return nil, fmt.Errorf("failed to fetch products under count %w", err)
+	}
+
+	return ids, nil
+}
+
+// locks the stock take, its lines come back ordered by product so stock is always locked in the same order
+func (r *StockTakeRepo) LockByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeRepo][LockByIDWithTx]"
+	log.InfofWithContext(ctx, logTag+" locking stock take", "stock_take_id", id)
+
+	var take types.StockTake
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&take).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" stock take not found", "stock_take_id", id)
+			return nil, fmt.Errorf("stock take not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to lock stock take", err, "stock_take_id", id)
+		return nil, fmt.Errorf("failed to lock stock take %w", err)
+	}
+
+	var lines []types.StockTakeLine
+	if err := tx.Where("stock_take_id = ?", take.ID).Order("product_id").Find(&lines).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock take lines", err, "stock_take_id", id)
+		return nil, fmt.Errorf("failed to fetch stock take lines: %w", err)
+	}
+
+	return &types.StockTakeWithLines{
+		StockTake: take,
+		Lines:     lines,
+	}, nil
+}
+
+// records a counted quantity, counting a line again replaces the earlier count
+func (r *StockTakeRepo) SetCountWithTx(tx *gorm.DB, ctx context.Context, lineID, counted int64, actor string, now time.Time) error {
+	logTag := "[StockTakeRepo][SetCountWithTx]"
+
+	res := tx.Model(&types.StockTakeLine{}).
+		Where("id = ?", lineID).
+		Updates(map[string]interface{}{
+			"counted_quantity": counted,
+			"counted_by":       actor,
+			"counted_at":       now,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to record count", res.Error, "stock_take_line_id", lineID)
+		return fmt.Errorf("failed to record count %w", res.Error)
+	}
+
+	return nil
+}
+
+// flags a posted line whose count took the location below what orders have reserved
+func (r *StockTakeRepo) SetOverReservedWithTx(tx *gorm.DB, ctx context.Context, lineID, overReserved int64) error {
+	logTag := "[StockTakeRepo][SetOverReservedWithTx]"
+
+	res := tx.Model(&types.StockTakeLine{}).
+		Where("id = ?", lineID).
+		Update("over_reserved_quantity", overReserved)
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to flag over-reservation", res.Error, "stock_take_line_id", lineID)
+		return fmt.Errorf("failed to flag over-reservation %w", res.Error)
+	}
+
+	return nil
+}
+
+func (r *StockTakeRepo) UpdateWithTx(tx *gorm.DB, ctx context.Context, take *types.StockTake) error {
+	logTag := "[StockTakeRepo][UpdateWithTx]"
+	log.InfofWithContext(ctx, logTag+" updating stock take", "stock_take_id", take.ID, "status", take.Status)
+
+	res := tx.Model(&types.StockTake{}).
+		Where("id = ?", take.ID).
+		Updates(map[string]interface{}{
+			"status":       take.Status,
+			"posted_by":    gorm.Expr("NULLIF(?, '')", take.PostedBy),
+			"posted_at":    take.PostedAt,
+			"cancelled_at": take.CancelledAt,
+			"updated_at":   take.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update stock take", res.Error, "stock_take_id", take.ID)
+		return fmt.Errorf("failed to update stock take %w", res.Error)
+	}
+
+	return nil
+}
+
+// reads the stock take inside the transaction, so counts made by it are seen with their variances
+func (r *StockTakeRepo) GetByIDWithTx(tx *gorm.DB, ctx context.Context, id int64) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeRepo][GetByIDWithTx]"
+
+	take, err := fetchStockTake(tx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock take", err, "stock_take_id", id)
+		return nil, err
+	}
+
+	return take, nil
+}
+
+func (r *StockTakeRepo) SearchByID(ctx context.Context, id int64) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching stock take", "stock_take_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	take, err := fetchStockTake(db, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock take", err, "stock_take_id", id)
+		return nil, err
+	}
+
+	return take, nil
+}
+
+// pages through stock takes newest first, an empty status or a zero location id matches everything
+func (r *StockTakeRepo) Search(ctx context.Context, status types.StockTakeStatus, locationID int64, limit, offset int) ([]types.StockTake, int64, error) {
+	logTag := "[StockTakeRepo][Search]"
+	log.InfofWithContext(ctx, logTag+" searching stock takes", "status", status, "location_id", locationID, "limit", limit, "offset", offset)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	query := db.Model(&types.StockTake{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if locationID > 0 {
+		query = query.Where("location_id = ?", locationID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to count stock takes", err)
+		return nil, 0, fmt.Errorf("failed to count stock takes %w", err)
+	}
+
+	var takes []types.StockTake
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&takes).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch stock takes", err)
+		return nil, 0, fmt.Errorf("failed to fetch stock takes %w", err)
+	}
+
+	return takes, total, nil
+}
+
+func fetchStockTake(db *gorm.DB, id int64) (*types.StockTakeWithLines, error) {
+	var take types.StockTake
+	if err := db.Where("id = ?", id).First(&take).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("stock take not found")
+		}
+		return nil, fmt.Errorf("failed to fetch stock take %w", err)
+	}
+
+	var lines []types.StockTakeLine
+	if err := db.Where("stock_take_id = ?", take.ID).Order("product_id").Find(&lines).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stock take lines: %w", err)
+	}
+
+	return &types.StockTakeWithLines{
+		StockTake: take,
+		Lines:     lines,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+type SupplierRepo struct {
+	DB *Postgres
+}
+
+func NewSupplierRepo(db *Postgres) *SupplierRepo {
+	return &SupplierRepo{
+		DB: db,
+	}
+}
+
+func (r *SupplierRepo) Create(ctx context.Context, supplier *types.Supplier) (*types.Supplier, error) {
+	logTag := "[SupplierRepo][Create]"
+	log.InfofWithContext(ctx, logTag+" creating supplier", "code", supplier.Code)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Create(supplier).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create supplier", err, "code", supplier.Code)
+		return nil, fmt.Errorf("failed to create supplier %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" supplier created successfully", "supplier_id", supplier.ID)
+	return supplier, nil
+}
+
+func (r *SupplierRepo) SearchByID(ctx context.Context, id int64) (*types.Supplier, error) {
+	logTag := "[SupplierRepo][SearchByID]"
+	log.InfofWithContext(ctx, logTag+" fetching supplier", "supplier_id", id)
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var supplier types.Supplier
+	if err := db.Where("id = ?", id).First(&supplier).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.WarnfWithContext(ctx, logTag+" supplier not found", "supplier_id", id)
+			return nil, fmt.Errorf("supplier not found")
+		}
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch supplier", err, "supplier_id", id)
+		return nil, fmt.Errorf("failed to fetch supplier %w", err)
+	}
+
+	return &supplier, nil
+}
+
+func (r *SupplierRepo) GetAll(ctx context.Context) ([]types.Supplier, error) {
+	logTag := "[SupplierRepo][GetAll]"
+	log.InfofWithContext(ctx, logTag+" fetching suppliers")
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var suppliers []types.Supplier
+	if err := db.Order("name, id").Find(&suppliers).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch suppliers", err)
+		return nil, fmt.Errorf("failed to fetch suppliers %w", err)
+	}
+
+	return suppliers, nil
+}
+
+func (r *SupplierRepo) Update(ctx context.Context, supplier *types.Supplier) error {
+	logTag := "[SupplierRepo][Update]"
+	log.InfofWithContext(ctx, logTag+" updating supplier", "supplier_id", supplier.ID)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Model(&types.Supplier{}).
+		Where("id = ?", supplier.ID).
+		Updates(map[string]interface{}{
+			"name":           supplier.Name,
+			"email":          gorm.Expr("NULLIF(?, '')", supplier.Email),
+			"phone":          gorm.Expr("NULLIF(?, '')", supplier.Phone),
+			"lead_time_days": supplier.LeadTimeDays,
+			"is_active":      supplier.IsActive,
+			"updated_at":     supplier.UpdatedAt,
+		})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to update supplier", res.Error, "supplier_id", supplier.ID)
+		return fmt.Errorf("failed to update supplier %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("supplier not found")
+	}
+
+	return nil
+}
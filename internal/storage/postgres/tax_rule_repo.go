@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/types"
+)
+
+type TaxRuleRepo struct {
+	DB *Postgres
+}
+
+func NewTaxRuleRepo(db *Postgres) *TaxRuleRepo {
+	return &TaxRuleRepo{
+		DB: db,
+	}
+}
+
+func (r *TaxRuleRepo) Create(ctx context.Context, rule *types.TaxRule) (*types.TaxRule, error) {
+	logTag := "[TaxRuleRepo][Create]"
+	log.InfofWithContext(ctx, logTag+" creating tax rule", "name", rule.Name, "region", rule.Region, "category_id", rule.CategoryID)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	if err := db.Create(rule).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to create tax rule", err)
+		return nil, fmt.Errorf("failed to create tax rule %w", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" tax rule created successfully", "tax_rule_id", rule.ID)
+	return rule, nil
+}
+
+func (r *TaxRuleRepo) GetAll(ctx context.Context) ([]types.TaxRule, error) {
+	logTag := "[TaxRuleRepo][GetAll]"
+	log.InfofWithContext(ctx, logTag+" fetching tax rules")
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var rules []types.TaxRule
+	if err := db.Order("id ASC").Find(&rules).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch tax rules", err)
+		return nil, fmt.Errorf("failed to fetch tax rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *TaxRuleRepo) GetActive(ctx context.Context) ([]types.TaxRule, error) {
+	logTag := "[TaxRuleRepo][GetActive]"
+	log.InfofWithContext(ctx, logTag+" fetching active tax rules")
+
+	db := r.DB.Cluster.GetSlaveDB(ctx)
+
+	var rules []types.TaxRule
+	if err := db.Where("is_active = ?", true).Order("id ASC").Find(&rules).Error; err != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to fetch active tax rules", err)
+		return nil, fmt.Errorf("failed to fetch active tax rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *TaxRuleRepo) Delete(ctx context.Context, id int64) error {
+	logTag := "[TaxRuleRepo][Delete]"
+	log.InfofWithContext(ctx, logTag+" deleting tax rule", "tax_rule_id", id)
+
+	db := r.DB.Cluster.GetMasterDB(ctx)
+
+	res := db.Where("id = ?", id).Delete(&types.TaxRule{})
+	if res.Error != nil {
+		log.ErrorfWithContext(ctx, logTag+" failed to delete tax rule", res.Error)
+		return fmt.Errorf("failed to delete tax rule %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		log.WarnfWithContext(ctx, logTag+" tax rule not found", "tax_rule_id", id)
+		return fmt.Errorf("tax rule not found")
+	}
+
+	return nil
+}
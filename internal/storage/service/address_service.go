@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+type AddressService struct {
+	AddressRepo *postgres.AddressRepo
+	UserRepo    *postgres.UserRepo
+}
+
+func NewAddressService(addressRepo *postgres.AddressRepo, userRepo *postgres.UserRepo) *AddressService {
+	return &AddressService{
+		AddressRepo: addressRepo,
+		UserRepo:    userRepo,
+	}
+}
+
+// saves a new address, the first address of a user becomes the default for shipping and billing
+func (s *AddressService) CreateAddress(ctx context.Context, userID int64, address *types.UserAddress) (*types.UserAddress, error) {
+	logTag := "[AddressService][CreateAddress]"
+	log.InfofWithContext(ctx, logTag+" creating address", "user_id", userID)
+
+	if _, err := s.UserRepo.SearchByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db := s.AddressRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	count, err := s.AddressRepo.CountByUserIDWithTx(tx, ctx, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if count == 0 {
+		address.IsDefaultShipping = true
+		address.IsDefaultBilling = true
+	}
+
+	if err := s.AddressRepo.ClearDefaultsWithTx(tx, ctx, userID, 0, address.IsDefaultShipping, address.IsDefaultBilling); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	address.UserID = userID
+	address.CreatedAt = time.Now()
+	address.UpdatedAt = time.Now()
+
+	created, err := s.AddressRepo.CreateWithTx(tx, ctx, address)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	return created, nil
+}
+
+func (s *AddressService) GetAddresses(ctx context.Context, userID int64) ([]types.UserAddress, error) {
+	logTag := "[AddressService][GetAddresses]"
+	log.InfofWithContext(ctx, logTag+" getting addresses", "user_id", userID)
+
+	if _, err := s.UserRepo.SearchByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	addresses, err := s.AddressRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting addresses", err)
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (s *AddressService) GetAddress(ctx context.Context, userID, addressID int64) (*types.UserAddress, error) {
+	logTag := "[AddressService][GetAddress]"
+	log.InfofWithContext(ctx, logTag+" getting address", "user_id", userID, "address_id", addressID)
+
+	address, err := s.AddressRepo.SearchByID(ctx, userID, addressID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting address", err)
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// replaces the address fields, orders placed earlier keep their own copy
+func (s *AddressService) UpdateAddress(ctx context.Context, userID, addressID int64, update *types.UserAddress) (*types.UserAddress, error) {
+	logTag := "[AddressService][UpdateAddress]"
+	log.InfofWithContext(ctx, logTag+" updating address", "user_id", userID, "address_id", addressID)
+
+	existing, err := s.AddressRepo.SearchByID(ctx, userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.AddressRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	if err := s.AddressRepo.ClearDefaultsWithTx(tx, ctx, userID, addressID, update.IsDefaultShipping, update.IsDefaultBilling); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	update.ID = existing.ID
+	update.UserID = existing.UserID
+	update.CreatedAt = existing.CreatedAt
+	update.UpdatedAt = time.Now()
+
+	updated, err := s.AddressRepo.UpdateWithTx(tx, ctx, update)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" address updated successfully", "address_id", addressID)
+	return updated, nil
+}
+
+func (s *AddressService) DeleteAddress(ctx context.Context, userID, addressID int64) error {
+	logTag := "[AddressService][DeleteAddress]"
+	log.InfofWithContext(ctx, logTag+" deleting address", "user_id", userID, "address_id", addressID)
+
+	if err := s.AddressRepo.Delete(ctx, userID, addressID); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deleting address", err)
+		return err
+	}
+
+	return nil
+}
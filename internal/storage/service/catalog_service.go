@@ -0,0 +1,339 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+var ErrInvalidImport = errors.New("invalid product import")
+
+const (
+	// rows applied between two progress saves, a resumed import repeats at most this many
+	importProgressEvery = 100
+
+	// a running import whose progress has not moved for this long lost its worker and is taken over
+	importStaleAfter = 5 * time.Minute
+
+	exportBatchSize = 500
+)
+
+// CatalogService moves the product catalog in and out as files. Imports are queued and applied by
+// RunImportWorker in the background, exports are streamed as they are read
+type CatalogService struct {
+	ProductImportRepo *postgres.ProductImportRepo
+	ProductRepo       *postgres.ProductRepo
+	ProductService    *ProductService
+}
+
+func NewCatalogService(productImportRepo *postgres.ProductImportRepo, productRepo *postgres.ProductRepo, productService *ProductService) *CatalogService {
+	return &CatalogService{
+		ProductImportRepo: productImportRepo,
+		ProductRepo:       productRepo,
+		ProductService:    productService,
+	}
+}
+
+// SubmitImport checks that the file can be read and queues it, its rows are validated and applied one by one
+// by the worker
+func (s *CatalogService) SubmitImport(ctx context.Context, format types.ProductFileFormat, fileName string, data []byte) (*types.ProductImport, error) {
+	logTag := "[CatalogService][SubmitImport]"
+	log.InfofWithContext(ctx, logTag+" submitting product import", "format", format, "file_name", fileName, "size", len(data))
+
+	if !format.IsValid() {
+		return nil, fmt.Errorf("%w: unknown file format %s, use csv or jsonl", ErrInvalidImport, format)
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: the file is not utf-8 text", ErrInvalidImport)
+	}
+
+	var totalRows int64
+	err := types.ReadProductRecords(format, bytes.NewReader(data), func(rowNumber int64, record types.ProductRecord, err error) error {
+		totalRows = rowNumber
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if totalRows == 0 {
+		return nil, fmt.Errorf("%w: the file has no rows", ErrInvalidImport)
+	}
+
+	payload := string(data)
+	job, err := s.ProductImportRepo.Create(ctx, &types.ProductImport{
+		Format:    format,
+		FileName:  fileName,
+		Status:    types.ProductImportStatusPending,
+		Payload:   &payload,
+		TotalRows: totalRows,
+		CreatedBy: types.ActorFromContext(ctx),
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating product import", err)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (s *CatalogService) GetImport(ctx context.Context, id int64) (*types.ProductImport, error) {
+	return s.ProductImportRepo.SearchByID(ctx, id)
+}
+
+func (s *CatalogService) GetImports(ctx context.Context, limit, offset int) ([]types.ProductImport, int64, error) {
+	return s.ProductImportRepo.GetAll(ctx, limit, offset)
+}
+
+func (s *CatalogService) GetImportErrors(ctx context.Context, id int64, limit, offset int) ([]types.ProductImportError, int64, error) {
+	if _, err := s.ProductImportRepo.SearchByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return s.ProductImportRepo.GetErrors(ctx, id, limit, offset)
+}
+
+// RunImportWorker applies queued imports every interval until ctx is cancelled, several workers can run
+// side by side as each import is claimed by one of them
+func (s *CatalogService) RunImportWorker(ctx context.Context, interval time.Duration) {
+	logTag := "[CatalogService][RunImportWorker]"
+	log.InfofWithContext(ctx, logTag+" starting product import worker", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.InfofWithContext(ctx, logTag+" product import worker stopped")
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				job, err := s.ProductImportRepo.ClaimNext(ctx, importStaleAfter)
+				if err != nil {
+					log.ErrorfWithContext(ctx, logTag+" error when claiming product import", err)
+					break
+				}
+				if job == nil {
+					break
+				}
+				if err := s.processImport(ctx, job); err != nil {
+					log.ErrorfWithContext(ctx, logTag+" error when processing product import", err, "import_id", job.ID)
+				}
+			}
+		}
+	}
+}
+
+// applies the rows of a claimed import after ProcessedRows, so an import taken over from a lost worker
+// carries on where that worker last saved. Changes are recorded under the user who uploaded the file
+func (s *CatalogService) processImport(ctx context.Context, job *types.ProductImport) error {
+	logTag := "[CatalogService][processImport]"
+	log.InfofWithContext(ctx, logTag+" processing product import", "import_id", job.ID, "processed_rows", job.ProcessedRows, "total_rows", job.TotalRows)
+
+	ctx = types.WithActor(ctx, job.CreatedBy)
+
+	var rowErrors []types.ProductImportError
+	var saveErr error
+	readErr := fmt.Errorf("the file of the import is gone")
+	if job.Payload != nil {
+		readErr = types.ReadProductRecords(job.Format, strings.NewReader(*job.Payload), func(rowNumber int64, record types.ProductRecord, err error) error {
+			if rowNumber <= job.ProcessedRows {
+				return nil
+			}
+
+			created := false
+			if err == nil {
+				err = record.Validate()
+			}
+			if err == nil {
+				created, err = s.applyRecord(ctx, record)
+			}
+
+			job.ProcessedRows = rowNumber
+			switch {
+			case err != nil:
+				job.FailedCount++
+				rowErrors = append(rowErrors, types.ProductImportError{
+					ImportID:  job.ID,
+					RowNumber: rowNumber,
+					SKU:       record.SKU,
+					Message:   err.Error(),
+				})
+			case created:
+				job.CreatedCount++
+			default:
+				job.UpdatedCount++
+			}
+
+			if rowNumber%importProgressEvery == 0 {
+				if saveErr = s.ProductImportRepo.SaveProgress(ctx, job, rowErrors); saveErr != nil {
+					return saveErr
+				}
+				rowErrors = nil
+			}
+			return ctx.Err()
+		})
+	}
+
+	// a failed save leaves the import running, it is taken over and resumed once it goes stale
+	if saveErr != nil {
+		return saveErr
+	}
+
+	// on shutdown the import stays running and is taken over once it goes stale
+	if ctx.Err() != nil {
+		log.InfofWithContext(ctx, logTag+" product import interrupted", "import_id", job.ID, "processed_rows", job.ProcessedRows)
+		return s.ProductImportRepo.SaveProgress(context.WithoutCancel(ctx), job, rowErrors)
+	}
+
+	if err := s.ProductImportRepo.SaveProgress(ctx, job, rowErrors); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = types.ProductImportStatusCompleted
+	if readErr != nil {
+		job.Status = types.ProductImportStatusFailed
+		job.Error = readErr.Error()
+	}
+
+	if err := s.ProductImportRepo.Finish(ctx, job); err != nil {
+		return err
+	}
+
+	log.InfofWithContext(ctx, logTag+" product import finished", "import_id", job.ID, "status", job.Status, "created", job.CreatedCount, "updated", job.UpdatedCount, "failed", job.FailedCount)
+	return nil
+}
+
+// upserts one row by sku and reports whether the product was created. A new product needs a name, price and
+// category, a new variant an existing parent. On an existing product only the values in the row change
+func (s *CatalogService) applyRecord(ctx context.Context, record types.ProductRecord) (bool, error) {
+	existing, err := s.ProductRepo.GetBySKU(ctx, record.SKU)
+	if err != nil && !strings.HasPrefix(err.Error(), "product not found") {
+		return false, err
+	}
+
+	var price types.Amount
+	if record.Price != nil {
+		price = *record.Price
+	}
+	var stockQuantity, reorderPoint, reorderQuantity int64
+	if record.StockQuantity != nil {
+		stockQuantity = *record.StockQuantity
+	}
+	if record.ReorderPoint != nil {
+		reorderPoint = *record.ReorderPoint
+	}
+	if record.ReorderQuantity != nil {
+		reorderQuantity = *record.ReorderQuantity
+	}
+
+	// name, category and serial tracking of a variant row belong to its parent and are not applied
+	if record.IsVariant() {
+		parent, err := s.ProductRepo.GetBySKU(ctx, record.ParentSKU)
+		if err != nil {
+			if strings.HasPrefix(err.Error(), "product not found") {
+				return false, fmt.Errorf("parent product %s not found", record.ParentSKU)
+			}
+			return false, err
+		}
+
+		if existing != nil {
+			if existing.ParentID == nil || *existing.ParentID != parent.ID {
+				return false, fmt.Errorf("product %s is not a variant of %s", record.SKU, record.ParentSKU)
+			}
+			_, err := s.ProductService.UpdateProduct(ctx, existing.ID, "", price, nil, record.StockQuantity, record.ReorderPoint, record.ReorderQuantity, nil, record.Attributes)
+			return false, err
+		}
+
+		_, err = s.ProductService.CreateVariant(ctx, parent.ID, record.SKU, record.Options, record.Price, stockQuantity, reorderPoint, reorderQuantity, record.Attributes)
+		return err == nil, err
+	}
+
+	if existing != nil {
+		if existing.IsVariant() {
+			return false, fmt.Errorf("product %s is a variant, give its parent_sku", record.SKU)
+		}
+		name := ""
+		if record.Name != nil {
+			name = *record.Name
+		}
+		_, err := s.ProductService.UpdateProduct(ctx, existing.ID, name, price, record.CategoryID, record.StockQuantity, record.ReorderPoint, record.ReorderQuantity, record.IsSerialized, record.Attributes)
+		return false, err
+	}
+
+	if record.Name == nil || record.Price == nil || record.CategoryID == nil {
+		return false, fmt.Errorf("a new product needs a name, price and category_id")
+	}
+	isSerialized := record.IsSerialized != nil && *record.IsSerialized
+
+	_, err = s.ProductService.CreateProduct(ctx, *record.Name, record.SKU, *record.Price, *record.CategoryID, stockQuantity, reorderPoint, reorderQuantity, isSerialized, record.OptionAxes, record.Attributes)
+	return err == nil, err
+}
+
+// ExportProducts writes every product matching params to w, each parent followed by its variants. The
+// catalog is read in batches and w is flushed after each one when it can be, so the whole catalog is never
+// held in memory
+func (s *CatalogService) ExportProducts(ctx context.Context, params types.ProductSearchParams, format types.ProductFileFormat, w io.Writer) error {
+	logTag := "[CatalogService][ExportProducts]"
+	log.InfofWithContext(ctx, logTag+" exporting products", "format", format, "query", params.Query)
+
+	writer := types.NewProductRecordWriter(format, w)
+	flusher, canFlush := w.(interface{ Flush() })
+
+	var afterID, rows int64
+	for {
+		products, err := s.ProductRepo.GetExportBatch(ctx, params, afterID, exportBatchSize)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" error when fetching products", err)
+			return err
+		}
+
+		var parentIDs []int64
+		for _, product := range products {
+			if product.HasVariants() {
+				parentIDs = append(parentIDs, product.ID)
+			}
+		}
+		variants, err := s.ProductRepo.GetVariants(ctx, parentIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, product := range products {
+			if err := writer.Write(types.NewProductRecord(product, "")); err != nil {
+				return err
+			}
+			for _, variant := range variants[product.ID] {
+				if err := writer.Write(types.NewProductRecord(variant, product.SKU)); err != nil {
+					return err
+				}
+			}
+			rows += int64(1 + len(variants[product.ID]))
+		}
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+
+		if len(products) < exportBatchSize {
+			break
+		}
+		afterID = products[len(products)-1].ID
+	}
+
+	log.InfofWithContext(ctx, logTag+" products exported", "rows", rows)
+	return nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+var (
+	ErrInvalidCategory = errors.New("invalid category")
+	ErrCategoryInUse   = errors.New("category is in use")
+)
+
+type CategoryService struct {
+	CategoryRepo *postgres.CategoryRepo
+}
+
+func NewCategoryService(categoryRepo *postgres.CategoryRepo) *CategoryService {
+	return &CategoryService{
+		CategoryRepo: categoryRepo,
+	}
+}
+
+// CategoryUpdate holds the category fields that may change, nil leaves a field as is. A ParentID of 0 moves
+// the category to the root
+type CategoryUpdate struct {
+	Name            *string
+	Description     *string
+	ParentID        *int64
+	AttributeSchema *types.AttributeSchema
+}
+
+// creates a category under parentID, nil creates a root. The schema lists the attributes the category adds
+// to the ones of its ancestors
+func (s *CategoryService) CreateCategory(ctx context.Context, name, description string, parentID *int64, schema types.AttributeSchema) (*types.Category, error) {
+	logTag := "[CategoryService][CreateCategory]"
+	log.InfofWithContext(ctx, logTag+" creating category", "name", name, "parent_id", parentID)
+
+	name = strings.TrimSpace(name)
+	if err := schema.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCategory, err)
+	}
+	if parentID != nil {
+		if _, err := s.CategoryRepo.SearchByID(ctx, *parentID); err != nil {
+			if err.Error() == "category not found" {
+				return nil, fmt.Errorf("%w: parent category %d does not exist", ErrInvalidCategory, *parentID)
+			}
+			return nil, err
+		}
+	}
+
+	exists, err := s.CategoryRepo.SiblingExists(ctx, parentID, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: a sibling category is already named %s", ErrInvalidCategory, name)
+	}
+
+	now := time.Now()
+	category, err := s.CategoryRepo.Create(ctx, &types.Category{
+		ParentID:        parentID,
+		Name:            name,
+		Description:     description,
+		AttributeSchema: schema,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating category", err)
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *CategoryService) GetCategory(ctx context.Context, id int64) (*types.Category, error) {
+	logTag := "[CategoryService][GetCategory]"
+	log.InfofWithContext(ctx, logTag+" getting category", "category_id", id)
+
+	category, err := s.CategoryRepo.SearchByID(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting category", err)
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// the attributes products of the category carry, its own together with the inherited ones
+func (s *CategoryService) GetAttributeSchema(ctx context.Context, id int64) (types.AttributeSchema, error) {
+	logTag := "[CategoryService][GetAttributeSchema]"
+	log.InfofWithContext(ctx, logTag+" getting attribute schema", "category_id", id)
+
+	path, err := s.CategoryRepo.GetPath(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting category path", err)
+		return nil, err
+	}
+
+	return types.EffectiveAttributeSchema(path), nil
+}
+
+// the whole tree, roots first with their children nested below them
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]*types.CategoryNode, error) {
+	logTag := "[CategoryService][GetCategoryTree]"
+	log.InfofWithContext(ctx, logTag+" getting category tree")
+
+	categories, err := s.CategoryRepo.GetAll(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting categories", err)
+		return nil, err
+	}
+
+	return types.BuildCategoryTree(categories), nil
+}
+
+// renames, describes or moves a category or replaces its attribute schema. A category can't move below
+// itself or one of its descendants, a rename is copied onto the products linked to it. Products already
+// filed under the category keep their attributes, they are checked against the new schema on their next write
+func (s *CategoryService) UpdateCategory(ctx context.Context, id int64, update CategoryUpdate) (*types.Category, error) {
+	logTag := "[CategoryService][UpdateCategory]"
+	log.InfofWithContext(ctx, logTag+" updating category", "category_id", id)
+
+	if update.AttributeSchema != nil {
+		if err := update.AttributeSchema.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCategory, err)
+		}
+	}
+
+	db := s.CategoryRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	if err := s.CategoryRepo.LockTreeWithTx(tx, ctx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	category, err := s.CategoryRepo.GetByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if update.Name != nil {
+		category.Name = strings.TrimSpace(*update.Name)
+	}
+	if update.Description != nil {
+		category.Description = *update.Description
+	}
+	if update.AttributeSchema != nil {
+		category.AttributeSchema = *update.AttributeSchema
+	}
+	if update.ParentID != nil {
+		if *update.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			descendants, err := s.CategoryRepo.GetDescendantIDsWithTx(tx, ctx, id)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if slices.Contains(descendants, *update.ParentID) {
+				tx.Rollback()
+				return nil, fmt.Errorf("%w: a category cannot move below itself or one of its descendants", ErrInvalidCategory)
+			}
+			if _, err := s.CategoryRepo.GetByIDWithTx(tx, ctx, *update.ParentID); err != nil {
+				tx.Rollback()
+				if err.Error() == "category not found" {
+					return nil, fmt.Errorf("%w: parent category %d does not exist", ErrInvalidCategory, *update.ParentID)
+				}
+				return nil, err
+			}
+			parentID := *update.ParentID
+			category.ParentID = &parentID
+		}
+	}
+
+	exists, err := s.CategoryRepo.SiblingExists(ctx, category.ParentID, category.Name, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if exists {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: a sibling category is already named %s", ErrInvalidCategory, category.Name)
+	}
+
+	category.UpdatedAt = time.Now()
+	if err := s.CategoryRepo.UpdateWithTx(tx, ctx, category); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when updating category", err)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	return category, nil
+}
+
+// only a leaf without products can be deleted
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int64) error {
+	logTag := "[CategoryService][DeleteCategory]"
+	log.InfofWithContext(ctx, logTag+" deleting category", "category_id", id)
+
+	if _, err := s.CategoryRepo.SearchByID(ctx, id); err != nil {
+		return err
+	}
+
+	children, products, rules, err := s.CategoryRepo.GetUsage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if children > 0 || products > 0 || rules > 0 {
+		return fmt.Errorf("%w: category has %d child categories, %d products and %d coupons or tax rules", ErrCategoryInUse, children, products, rules)
+	}
+
+	if err := s.CategoryRepo.Delete(ctx, id); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deleting category", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+var ErrInvalidCoupon = errors.New("invalid coupon definition")
+
+type CouponService struct {
+	CouponRepo   *postgres.CouponRepo
+	CategoryRepo *postgres.CategoryRepo
+}
+
+func NewCouponService(couponRepo *postgres.CouponRepo, categoryRepo *postgres.CategoryRepo) *CouponService {
+	return &CouponService{
+		CouponRepo:   couponRepo,
+		CategoryRepo: categoryRepo,
+	}
+}
+
+// CouponAvailability holds the coupon fields that may change after creation, nil leaves a field as is
+type CouponAvailability struct {
+	IsActive       *bool
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	MaxUses        *int32
+	MaxUsesPerUser *int32
+}
+
+// checks that exactly the values required by the coupon type are set
+func validateCoupon(coupon *types.Coupon) error {
+	switch coupon.Type {
+	case types.CouponTypePercentage:
+		if coupon.PercentOff < 1 || coupon.PercentOff > 100 {
+			return fmt.Errorf("%w: percent_off must be between 1 and 100", ErrInvalidCoupon)
+		}
+		if coupon.AmountOff != 0 || coupon.BuyQuantity != 0 || coupon.GetQuantity != 0 {
+			return fmt.Errorf("%w: percentage coupons only take percent_off", ErrInvalidCoupon)
+		}
+	case types.CouponTypeFixed:
+		if coupon.AmountOff <= 0 || coupon.Currency == "" {
+			return fmt.Errorf("%w: fixed coupons need a positive amount_off and a currency", ErrInvalidCoupon)
+		}
+		if coupon.PercentOff != 0 || coupon.BuyQuantity != 0 || coupon.GetQuantity != 0 {
+			return fmt.Errorf("%w: fixed coupons only take amount_off and currency", ErrInvalidCoupon)
+		}
+	case types.CouponTypeBuyXGetY:
+		if coupon.BuyQuantity < 1 || coupon.GetQuantity < 1 {
+			return fmt.Errorf("%w: buy_quantity and get_quantity must be at least 1", ErrInvalidCoupon)
+		}
+		if coupon.PercentOff != 0 || coupon.AmountOff != 0 {
+			return fmt.Errorf("%w: buy x get y coupons only take buy_quantity and get_quantity", ErrInvalidCoupon)
+		}
+	default:
+		return fmt.Errorf("%w: unknown coupon type %s", ErrInvalidCoupon, coupon.Type)
+	}
+
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCoupon)
+	}
+	if coupon.MaxUses < 0 || coupon.MaxUsesPerUser < 0 {
+		return fmt.Errorf("%w: usage limits cannot be negative", ErrInvalidCoupon)
+	}
+
+	return nil
+}
+
+func (s *CouponService) CreateCoupon(ctx context.Context, coupon *types.Coupon) (*types.Coupon, error) {
+	logTag := "[CouponService][CreateCoupon]"
+	log.InfofWithContext(ctx, logTag+" creating coupon", "code", coupon.Code, "type", coupon.Type)
+
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	if err := validateCoupon(coupon); err != nil {
+		log.WarnfWithContext(ctx, logTag+" invalid coupon", "code", coupon.Code, "error", err.Error())
+		return nil, err
+	}
+	if err := ruleCategoryExists(ctx, s.CategoryRepo, coupon.CategoryID); err != nil {
+		return nil, err
+	}
+
+	coupon.IsActive = true
+	coupon.CreatedAt = time.Now()
+	coupon.UpdatedAt = time.Now()
+
+	created, err := s.CouponRepo.Create(ctx, coupon)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating coupon", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *CouponService) GetCoupon(ctx context.Context, id int64) (*types.Coupon, error) {
+	logTag := "[CouponService][GetCoupon]"
+	log.InfofWithContext(ctx, logTag+" getting coupon", "coupon_id", id)
+
+	coupon, err := s.CouponRepo.SearchByID(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting coupon", err)
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func (s *CouponService) GetCoupons(ctx context.Context, limit, offset int) ([]types.Coupon, int64, error) {
+	logTag := "[CouponService][GetCoupons]"
+	log.InfofWithContext(ctx, logTag+" getting coupons", "limit", limit, "offset", offset)
+
+	coupons, total, err := s.CouponRepo.GetAll(ctx, limit, offset)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting coupons", err)
+		return nil, 0, err
+	}
+
+	return coupons, total, nil
+}
+
+func (s *CouponService) UpdateCouponAvailability(ctx context.Context, id int64, update CouponAvailability) (*types.Coupon, error) {
+	logTag := "[CouponService][UpdateCouponAvailability]"
+	log.InfofWithContext(ctx, logTag+" updating coupon availability", "coupon_id", id)
+
+	coupon, err := s.CouponRepo.SearchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.IsActive != nil {
+		coupon.IsActive = *update.IsActive
+	}
+	if update.StartsAt != nil {
+		coupon.StartsAt = update.StartsAt
+	}
+	if update.EndsAt != nil {
+		coupon.EndsAt = update.EndsAt
+	}
+	if update.MaxUses != nil {
+		coupon.MaxUses = *update.MaxUses
+	}
+	if update.MaxUsesPerUser != nil {
+		coupon.MaxUsesPerUser = *update.MaxUsesPerUser
+	}
+
+	if err := validateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	coupon.UpdatedAt = time.Now()
+	if err := s.CouponRepo.UpdateAvailability(ctx, coupon); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when updating coupon", err)
+		return nil, err
+	}
+
+	log.InfofWithContext(ctx, logTag+" coupon updated successfully", "coupon_id", id)
+	return coupon, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+type ExchangeRateService struct {
+	ExchangeRateRepo *postgres.ExchangeRateRepo
+}
+
+func NewExchangeRateService(exchangeRateRepo *postgres.ExchangeRateRepo) *ExchangeRateService {
+	return &ExchangeRateService{
+		ExchangeRateRepo: exchangeRateRepo,
+	}
+}
+
+func (s *ExchangeRateService) SetRate(ctx context.Context, base, quote string, rate types.Rate) (*types.ExchangeRate, error) {
+	logTag := "[ExchangeRateService][SetRate]"
+	log.InfofWithContext(ctx, logTag+" setting exchange rate", "base", base, "quote", quote, "rate", rate)
+
+	if base == quote {
+		return nil, fmt.Errorf("base and quote currency must differ")
+	}
+
+	exchangeRate, err := s.ExchangeRateRepo.Upsert(ctx, &types.ExchangeRate{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Rate:          rate,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when setting exchange rate", err)
+		return nil, err
+	}
+
+	return exchangeRate, nil
+}
+
+func (s *ExchangeRateService) GetRates(ctx context.Context) ([]types.ExchangeRate, error) {
+	logTag := "[ExchangeRateService][GetRates]"
+	log.InfofWithContext(ctx, logTag+" getting exchange rates")
+
+	rates, err := s.ExchangeRateRepo.GetAll(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting exchange rates", err)
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func (s *ExchangeRateService) DeleteRate(ctx context.Context, base, quote string) error {
+	logTag := "[ExchangeRateService][DeleteRate]"
+	log.InfofWithContext(ctx, logTag+" deleting exchange rate", "base", base, "quote", quote)
+
+	if err := s.ExchangeRateRepo.Delete(ctx, base, quote); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deleting exchange rate", err)
+		return err
+	}
+
+	return nil
+}
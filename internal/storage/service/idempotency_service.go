@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+var (
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotencyService keeps the keys of each caller and route apart. A request holds its key for Lease and is
+// cut off when it runs out, a key left in progress by a request that never completed can be taken over by a
+// retry after that
+type IdempotencyService struct {
+	IdempotencyRepo *postgres.IdempotencyRepo
+	TTL             time.Duration
+	Lease           time.Duration
+}
+
+func NewIdempotencyService(idempotencyRepo *postgres.IdempotencyRepo, ttl, lease time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		IdempotencyRepo: idempotencyRepo,
+		TTL:             ttl,
+		Lease:           lease,
+	}
+}
+
+// claims the key within scope for the request, when the key was already used for the same request
+// the stored record is returned so the original response can be replayed
+func (s *IdempotencyService) Begin(ctx context.Context, scope, key, fingerprint string) (*types.IdempotencyKey, error) {
+	logTag := "[IdempotencyService][Begin]"
+	log.InfofWithContext(ctx, logTag+" beginning idempotent request", "scope", scope, "key", key)
+
+	now := time.Now()
+	reserved, err := s.IdempotencyRepo.Reserve(ctx, &types.IdempotencyKey{
+		Scope:              scope,
+		Key:                key,
+		RequestFingerprint: fingerprint,
+		CreatedAt:          now,
+		ExpiresAt:          now.Add(s.TTL),
+		LockedUntil:        now.Add(s.Lease),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := s.IdempotencyRepo.SearchByKey(ctx, scope, key)
+	if err != nil {
+		// the request holding the key released it after the reservation failed, the client can retry
+		if err.Error() == "idempotency key not found" {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		return nil, err
+	}
+
+	if existing.RequestFingerprint != fingerprint {
+		log.WarnfWithContext(ctx, logTag+" idempotency key reused with a different request", "key", key)
+		return nil, ErrIdempotencyKeyMismatch
+	}
+
+	if !existing.Completed {
+		if existing.LockedUntil.After(now) {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+
+		// the request holding the key died or could not store its response, it was cut off when its lease ran
+		// out so it can no longer be running
+		takenOver, err := s.IdempotencyRepo.TakeOver(ctx, scope, key, fingerprint, now.Add(s.Lease))
+		if err != nil {
+			return nil, err
+		}
+		if !takenOver {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		log.WarnfWithContext(ctx, logTag+" took over stale idempotency key", "key", key, "locked_until", existing.LockedUntil)
+		return nil, nil
+	}
+
+	log.InfofWithContext(ctx, logTag+" replaying stored response", "key", key, "status", existing.ResponseStatus)
+	return existing, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, scope, key string, status int, body string) error {
+	if err := s.IdempotencyRepo.Complete(ctx, scope, key, status, body); err != nil {
+		return fmt.Errorf("failed to complete idempotent request: %w", err)
+	}
+	return nil
+}
+
+// frees the key so the client can retry after a failure that had no effect
+func (s *IdempotencyService) Release(ctx context.Context, scope, key string) error {
+	return s.IdempotencyRepo.Delete(ctx, scope, key)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/notifier"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidTransfer = errors.New("invalid stock transfer")
+	ErrInvalidLot      = errors.New("invalid stock lot")
+)
+
+// how many alerts one notifier run pushes, the rest go out on the next tick
+const alertDispatchBatch = 100
+
+type InventoryService struct {
+	StockMovementRepo *postgres.StockMovementRepo
+	ProductRepo       *postgres.ProductRepo
+	LocationRepo      *postgres.LocationRepo
+	StockAlertRepo    *postgres.StockAlertRepo
+	StockLotRepo      *postgres.StockLotRepo
+	Notifier          notifier.Notifier
+}
+
+func NewInventoryService(stockMovementRepo *postgres.StockMovementRepo, productRepo *postgres.ProductRepo, locationRepo *postgres.LocationRepo, stockAlertRepo *postgres.StockAlertRepo, stockLotRepo *postgres.StockLotRepo, alertNotifier notifier.Notifier) *InventoryService {
+	return &InventoryService{
+		StockMovementRepo: stockMovementRepo,
+		ProductRepo:       productRepo,
+		LocationRepo:      locationRepo,
+		StockAlertRepo:    stockAlertRepo,
+		StockLotRepo:      stockLotRepo,
+		Notifier:          alertNotifier,
+	}
+}
+
+func (s *InventoryService) GetStockMovements(ctx context.Context, productID int64, limit, offset int) ([]types.StockMovement, int64, error) {
+	logTag := "[InventoryService][GetStockMovements]"
+	log.InfofWithContext(ctx, logTag+" getting stock movements", "product_id", productID, "limit", limit, "offset", offset)
+
+	if _, err := s.ProductRepo.SearchById(ctx, productID); err != nil {
+		return nil, 0, err
+	}
+
+	movements, total, err := s.StockMovementRepo.GetByProductID(ctx, productID, limit, offset)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting stock movements", err)
+		return nil, 0, err
+	}
+
+	return movements, total, nil
+}
+
+func (s *InventoryService) GetProductLots(ctx context.Context, productID int64) ([]types.StockLot, error) {
+	logTag := "[InventoryService][GetProductLots]"
+	log.InfofWithContext(ctx, logTag+" getting product lots", "product_id", productID)
+
+	if _, err := s.ProductRepo.SearchById(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	lots, err := s.StockLotRepo.GetByProductID(ctx, productID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting product lots", err)
+		return nil, err
+	}
+
+	return lots, nil
+}
+
+// lots with stock left that expire within the next days, already expired ones included. locationID 0 means
+// every location
+func (s *InventoryService) GetExpiringLots(ctx context.Context, days int, locationID int64, limit, offset int) ([]types.StockLot, int64, error) {
+	logTag := "[InventoryService][GetExpiringLots]"
+	log.InfofWithContext(ctx, logTag+" getting expiring lots", "days", days, "location_id", locationID, "limit", limit, "offset", offset)
+
+	if locationID != 0 {
+		if _, err := s.LocationRepo.SearchByID(ctx, locationID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	until := types.ExpiryDate(time.Now()).AddDate(0, 0, days)
+	lots, total, err := s.StockLotRepo.GetExpiring(ctx, until, locationID, limit, offset)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting expiring lots", err)
+		return nil, 0, err
+	}
+
+	return lots, total, nil
+}
+
+// puts units that were just added to a location's stock into the named lot. A lot keeps the expiry date it
+// was first received with, a different one is rejected
+func addToLotWithTx(tx *gorm.DB, ctx context.Context, lotRepo *postgres.StockLotRepo, productID, locationID int64, lotNumber string, expiresAt *time.Time, quantity int64) error {
+	if expiresAt != nil {
+		expiry := types.ExpiryDate(*expiresAt)
+		expiresAt = &expiry
+	}
+
+	lot, err := lotRepo.GetByNumberWithTx(tx, ctx, productID, locationID, lotNumber)
+	if err != nil {
+		return err
+	}
+	if lot != nil && !lot.SameExpiry(expiresAt) {
+		return fmt.Errorf("%w: lot %s is already held with a different expiry date", ErrInvalidLot, lotNumber)
+	}
+
+	return lotRepo.AddWithTx(tx, ctx, productID, locationID, lotNumber, expiresAt, quantity)
+}
+
+// recomputes the product's quantities from its ledger, InSync is false when some change bypassed it
+func (s *InventoryService) ReconcileStock(ctx context.Context, productID int64) (*types.StockReconciliation, error) {
+	logTag := "[InventoryService][ReconcileStock]"
+	log.InfofWithContext(ctx, logTag+" reconciling stock", "product_id", productID)
+
+	reconciliation, err := s.StockMovementRepo.Reconcile(ctx, productID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when reconciling stock", err)
+		return nil, err
+	}
+
+	if !reconciliation.InSync {
+		log.WarnfWithContext(ctx, logTag+" stock does not match the ledger", "product_id", productID,
+			"stock_quantity", reconciliation.StockQuantity, "ledger_quantity", reconciliation.LedgerQuantity,
+			"reserved_quantity", reconciliation.ReservedQuantity, "ledger_reserved", reconciliation.LedgerReserved)
+	}
+
+	return reconciliation, nil
+}
+
+// moves available stock between two locations, the product total stays the same and the ledger gets a
+// transfer movement out of one location and into the other
+func (s *InventoryService) TransferStock(ctx context.Context, productID, fromLocationID, toLocationID, quantity int64, note string) (*types.StockTransfer, error) {
+	logTag := "[InventoryService][TransferStock]"
+	log.InfofWithContext(ctx, logTag+" transferring stock", "product_id", productID, "from", fromLocationID, "to", toLocationID, "quantity", quantity)
+
+	if fromLocationID == toLocationID {
+		return nil, fmt.Errorf("%w: source and destination are the same location", ErrInvalidTransfer)
+	}
+	if _, err := s.LocationRepo.SearchByID(ctx, fromLocationID); err != nil {
+		return nil, err
+	}
+	destination, err := s.LocationRepo.SearchByID(ctx, toLocationID)
+	if err != nil {
+		return nil, err
+	}
+	if !destination.IsActive {
+		return nil, fmt.Errorf("%w: location %s is inactive", ErrInvalidTransfer, destination.Code)
+	}
+
+	db := s.ProductRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	if _, err := s.ProductRepo.LockByIDWithTx(tx, ctx, productID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	transfer := &types.StockTransfer{
+		ProductID:      productID,
+		FromLocationID: fromLocationID,
+		ToLocationID:   toLocationID,
+		Quantity:       quantity,
+		Actor:          types.ActorFromContext(ctx),
+		Note:           note,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.LocationRepo.CreateTransferWithTx(tx, ctx, transfer); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	movement := types.StockMovement{
+		Reason:        types.StockMovementReasonTransfer,
+		ReferenceType: "stock_transfer",
+		ReferenceID:   &transfer.ID,
+		Note:          note,
+	}
+	if err := s.ProductRepo.UpdateStock(tx, ctx, productID, fromLocationID, quantity, "subtract", movement); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when taking stock out", err)
+		return nil, err
+	}
+	if err := s.ProductRepo.UpdateStock(tx, ctx, productID, toLocationID, quantity, "add", movement); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when putting stock in", err)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" stock transferred successfully", "transfer_id", transfer.ID)
+	return transfer, nil
+}
+
+func (s *InventoryService) GetStockTransfers(ctx context.Context, productID int64, limit, offset int) ([]types.StockTransfer, int64, error) {
+	logTag := "[InventoryService][GetStockTransfers]"
+	log.InfofWithContext(ctx, logTag+" getting stock transfers", "product_id", productID, "limit", limit, "offset", offset)
+
+	if _, err := s.ProductRepo.SearchById(ctx, productID); err != nil {
+		return nil, 0, err
+	}
+
+	transfers, total, err := s.LocationRepo.GetTransfers(ctx, productID, limit, offset)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting stock transfers", err)
+		return nil, 0, err
+	}
+
+	return transfers, total, nil
+}
+
+func (s *InventoryService) GetStockAlerts(ctx context.Context, status types.StockAlertStatus, productID int64, limit, offset int) ([]types.StockAlert, int64, error) {
+	logTag := "[InventoryService][GetStockAlerts]"
+	log.InfofWithContext(ctx, logTag+" getting stock alerts", "status", status, "product_id", productID)
+
+	alerts, total, err := s.StockAlertRepo.Search(ctx, status, productID, limit, offset)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting stock alerts", err)
+		return nil, 0, err
+	}
+
+	return alerts, total, nil
+}
+
+// pushes every alert the notifier has not delivered yet, an alert is only marked once its delivery
+// succeeded so a failing notifier gets it again on the next run. Returns how many were delivered
+func (s *InventoryService) DispatchAlerts(ctx context.Context) (int, error) {
+	logTag := "[InventoryService][DispatchAlerts]"
+
+	alerts, err := s.StockAlertRepo.GetUnnotified(ctx, alertDispatchBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	master := s.ProductRepo.DB.Cluster.GetMasterDB(ctx)
+
+	delivered := 0
+	for _, alert := range alerts {
+		product, err := s.ProductRepo.GetByIDWithTx(master, ctx, alert.ProductID)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" error when getting alert product", err, "alert_id", alert.ID)
+			continue
+		}
+
+		if err := s.Notifier.NotifyLowStock(ctx, alert, *product); err != nil {
+			log.ErrorfWithContext(ctx, logTag+" error when notifying alert", err, "alert_id", alert.ID)
+			continue
+		}
+
+		if err := s.StockAlertRepo.MarkNotified(ctx, alert.ID, time.Now()); err != nil {
+			continue
+		}
+		delivered++
+	}
+
+	if delivered > 0 {
+		log.InfofWithContext(ctx, logTag+" stock alerts delivered", "count", delivered)
+	}
+	return delivered, nil
+}
+
+// runs DispatchAlerts every interval until ctx is cancelled
+func (s *InventoryService) RunAlertNotifier(ctx context.Context, interval time.Duration) {
+	logTag := "[InventoryService][RunAlertNotifier]"
+	log.InfofWithContext(ctx, logTag+" starting alert notifier", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.InfofWithContext(ctx, logTag+" alert notifier stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.DispatchAlerts(ctx); err != nil {
+				log.ErrorfWithContext(ctx, logTag+" error when dispatching alerts", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+var ErrInvalidLocation = errors.New("invalid location")
+
+type LocationService struct {
+	LocationRepo *postgres.LocationRepo
+}
+
+func NewLocationService(locationRepo *postgres.LocationRepo) *LocationService {
+	return &LocationService{
+		LocationRepo: locationRepo,
+	}
+}
+
+// LocationUpdate holds the location fields that may change, nil leaves a field as is
+type LocationUpdate struct {
+	Name       *string
+	Country    *string
+	State      *string
+	PostalCode *string
+	Priority   *int32
+	IsActive   *bool
+}
+
+func (s *LocationService) CreateLocation(ctx context.Context, location *types.Location) (*types.Location, error) {
+	logTag := "[LocationService][CreateLocation]"
+	log.InfofWithContext(ctx, logTag+" creating location", "code", location.Code)
+
+	location.Code = strings.ToUpper(strings.TrimSpace(location.Code))
+	location.Country = strings.ToUpper(location.Country)
+	location.IsActive = true
+	location.CreatedAt = time.Now()
+	location.UpdatedAt = time.Now()
+
+	created, err := s.LocationRepo.Create(ctx, location)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating location", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *LocationService) GetLocation(ctx context.Context, id int64) (*types.Location, error) {
+	logTag := "[LocationService][GetLocation]"
+	log.InfofWithContext(ctx, logTag+" getting location", "location_id", id)
+
+	location, err := s.LocationRepo.SearchByID(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting location", err)
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (s *LocationService) GetLocations(ctx context.Context) ([]types.Location, error) {
+	logTag := "[LocationService][GetLocations]"
+	log.InfofWithContext(ctx, logTag+" getting locations")
+
+	locations, err := s.LocationRepo.GetAll(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting locations", err)
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// an inactive location keeps its stock but is skipped by allocation, the default location always stays active
+func (s *LocationService) UpdateLocation(ctx context.Context, id int64, update LocationUpdate) (*types.Location, error) {
+	logTag := "[LocationService][UpdateLocation]"
+	log.InfofWithContext(ctx, logTag+" updating location", "location_id", id)
+
+	location, err := s.LocationRepo.SearchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		location.Name = *update.Name
+	}
+	if update.Country != nil {
+		location.Country = strings.ToUpper(*update.Country)
+	}
+	if update.State != nil {
+		location.State = *update.State
+	}
+	if update.PostalCode != nil {
+		location.PostalCode = *update.PostalCode
+	}
+	if update.Priority != nil {
+		location.Priority = *update.Priority
+	}
+	if update.IsActive != nil {
+		if location.IsDefault && !*update.IsActive {
+			return nil, fmt.Errorf("%w: the default location cannot be deactivated", ErrInvalidLocation)
+		}
+		location.IsActive = *update.IsActive
+	}
+	location.UpdatedAt = time.Now()
+
+	if err := s.LocationRepo.Update(ctx, location); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when updating location", err)
+		return nil, err
+	}
+
+	return location, nil
+}
@@ -9,23 +9,171 @@ import (
 	"github.com/omniful/go_commons/log"
 	"github.com/si/internal/storage/postgres"
 	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidStatusTransition    = errors.New("invalid order status transition")
+	ErrOrderStatusChanged         = errors.New("order status was changed by another request")
+	ErrStatusDerivedFromShipments = errors.New("fulfilment status is derived from shipments")
+	ErrPriceUnavailable           = errors.New("no price available in the order currency")
+	ErrCouponNotRedeemable        = errors.New("coupon is not redeemable")
+	ErrCouponUsageExceeded        = errors.New("coupon usage limit reached")
+	ErrCouponNotApplicable        = errors.New("coupon does not apply to this order")
+	ErrOrderAlreadyPaid           = errors.New("order is already paid")
+	ErrOrderNotEditable           = errors.New("order items can no longer be changed")
+	ErrProductNotOrderable        = errors.New("product cannot be ordered")
 )
 
 type OrderService struct {
 	OrderRepo *postgres.OrderRepo
 	UserRepo *postgres.UserRepo
 	ProductRepo *postgres.ProductRepo
+	ExchangeRateRepo *postgres.ExchangeRateRepo
+	CouponRepo *postgres.CouponRepo
+	TaxService *TaxService
+	AddressRepo *postgres.AddressRepo
+	ReservationService *ReservationService
+	ShipmentRepo *postgres.ShipmentRepo
+	DefaultCurrency string
 }
 
-func NewOrderService(orderRepo *postgres.OrderRepo, userRepo *postgres.UserRepo, productRepo *postgres.ProductRepo) *OrderService{
+func NewOrderService(orderRepo *postgres.OrderRepo, userRepo *postgres.UserRepo, productRepo *postgres.ProductRepo, exchangeRateRepo *postgres.ExchangeRateRepo, couponRepo *postgres.CouponRepo, taxService *TaxService, addressRepo *postgres.AddressRepo, reservationService *ReservationService, shipmentRepo *postgres.ShipmentRepo, defaultCurrency string) *OrderService{
 	return &OrderService{
 		OrderRepo: orderRepo,
 		UserRepo: userRepo,
 		ProductRepo: productRepo,
+		ExchangeRateRepo: exchangeRateRepo,
+		CouponRepo: couponRepo,
+		TaxService: taxService,
+		AddressRepo: addressRepo,
+		ReservationService: reservationService,
+		ShipmentRepo: shipmentRepo,
+		DefaultCurrency: defaultCurrency,
+	}
+}
+
+// resolves the unit price of a product in the order currency, an explicit price list entry wins,
+// otherwise the base price is converted using fxRate when given or the stored exchange rate
+func (s *OrderService) resolvePrice(ctx context.Context, product *types.Product, currency string, fxRate *types.Rate) (types.Amount, *types.Rate, error) {
+	if product.Currency == currency {
+		return product.Price, nil, nil
+	}
+
+	listPrice, err := s.ProductRepo.GetPrice(ctx, product.ID, currency)
+	if err != nil {
+		return 0, nil, err
+	}
+	if listPrice != nil {
+		return listPrice.Price, nil, nil
+	}
+
+	if fxRate == nil {
+		exchangeRate, err := s.ExchangeRateRepo.Get(ctx, product.Currency, currency)
+		if err != nil {
+			if err.Error() == "exchange rate not found" {
+				return 0, nil, fmt.Errorf("%w: product %d has no %s price and no %s to %s rate is configured", ErrPriceUnavailable, product.ID, currency, product.Currency, currency)
+			}
+			return 0, nil, err
+		}
+		fxRate = &exchangeRate.Rate
+	}
+
+	price, err := product.Price.Convert(*fxRate)
+	if err != nil {
+		return 0, nil, err
+	}
+	return price, fxRate, nil
+}
+
+// locks and validates every coupon code, the row locks are held until the order
+// transaction ends so usage limits cannot be overrun by concurrent orders
+func (s *OrderService) redeemCoupons(tx *gorm.DB, ctx context.Context, userID int64, currency string, codes []string) ([]types.Coupon, error) {
+	now := time.Now()
+	seen := make(map[int64]bool, len(codes))
+	var coupons []types.Coupon
+
+	for _, code := range codes {
+		coupon, err := s.CouponRepo.LockByCodeWithTx(tx, ctx, code)
+		if err != nil {
+			if err.Error() == "coupon not found" {
+				return nil, fmt.Errorf("%w: coupon %s does not exist", ErrCouponNotRedeemable, code)
+			}
+			return nil, err
+		}
+
+		if seen[coupon.ID] {
+			continue
+		}
+		seen[coupon.ID] = true
+
+		if !coupon.IsRedeemableAt(now) {
+			return nil, fmt.Errorf("%w: coupon %s is inactive or outside its validity window", ErrCouponNotRedeemable, coupon.Code)
+		}
+
+		if coupon.Type == types.CouponTypeFixed && coupon.Currency != currency {
+			return nil, fmt.Errorf("%w: coupon %s is only valid for %s orders", ErrCouponNotApplicable, coupon.Code, coupon.Currency)
+		}
+
+		if coupon.MaxUses > 0 || coupon.MaxUsesPerUser > 0 {
+			total, byUser, err := s.CouponRepo.CountRedemptionsWithTx(tx, ctx, coupon.ID, userID)
+			if err != nil {
+				return nil, err
+			}
+			if coupon.MaxUses > 0 && total >= int64(coupon.MaxUses) {
+				return nil, fmt.Errorf("%w: coupon %s has been fully redeemed", ErrCouponUsageExceeded, coupon.Code)
+			}
+			if coupon.MaxUsesPerUser > 0 && byUser >= int64(coupon.MaxUsesPerUser) {
+				return nil, fmt.Errorf("%w: coupon %s was already used %d times by this user", ErrCouponUsageExceeded, coupon.Code, byUser)
+			}
+		}
+
+		coupons = append(coupons, *coupon)
 	}
+
+	return coupons, nil
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, userID int64, items []types.OrderItemRequest) (*types.OrderWithDetails, error) {
+// picks the addresses to copy onto a new order: the requested ones, otherwise the user's defaults,
+// billing falls back to the shipping address
+func (s *OrderService) resolveOrderAddresses(ctx context.Context, userID int64, opts types.CreateOrderOptions) (*types.OrderAddress, *types.OrderAddress, error) {
+	var shipping, billing *types.UserAddress
+
+	if opts.ShippingAddressID == 0 || opts.BillingAddressID == 0 {
+		defaultShipping, defaultBilling, err := s.AddressRepo.GetDefaults(ctx, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		shipping, billing = defaultShipping, defaultBilling
+	}
+
+	if opts.ShippingAddressID != 0 {
+		address, err := s.AddressRepo.SearchByID(ctx, userID, opts.ShippingAddressID)
+		if err != nil {
+			return nil, nil, err
+		}
+		shipping = address
+	}
+
+	if opts.BillingAddressID != 0 {
+		address, err := s.AddressRepo.SearchByID(ctx, userID, opts.BillingAddressID)
+		if err != nil {
+			return nil, nil, err
+		}
+		billing = address
+	}
+
+	if billing == nil {
+		billing = shipping
+	}
+	if shipping == nil {
+		return nil, nil, nil
+	}
+
+	return shipping.Snapshot(), billing.Snapshot(), nil
+}
+
+func (s *OrderService) CreateOrder(ctx context.Context, userID int64, items []types.OrderItemRequest, opts types.CreateOrderOptions) (*types.OrderWithDetails, error) {
 	logTag := "[OrderService][CreateOrder]"
     log.InfofWithContext(ctx, logTag+" creating order", "user_id", userID, "items_count", len(items))
 
@@ -46,41 +194,163 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID int64, items []ty
         return nil, err
 	}
 
-	var total float64
+	currency := opts.Currency
+	if currency == "" {
+		currency = s.DefaultCurrency
+	}
+
+	shippingAddress, billingAddress, err := s.resolveOrderAddresses(ctx, userID, opts)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when resolving addresses", err)
+		return nil, err
+	}
+
+	// tax follows the destination unless the caller asked for a specific region
+	taxRegion := opts.TaxRegion
+	if taxRegion == "" && shippingAddress != nil {
+		taxRegion = shippingAddress.Country
+	}
+
+	taxRules, err := s.TaxService.Rules(ctx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	var orderItems []types.OrderItem
+	var lines []types.DiscountableLine
+	var fxBaseCurrency string
+	var fxRate *types.Rate
 
 	for _, item := range items {
-		product, err := s.ProductRepo.SearchById(ctx, item.ProductID)
+		// read from the master inside the transaction, a lagging replica would report stock that is already gone
+		product, err := s.ProductRepo.GetByIDWithTx(tx, ctx, item.ProductID)
 		if err != nil {
 			tx.Rollback()
             log.ErrorfWithContext(ctx, logTag+" product not found", err, "product_id", item.ProductID)
             return nil, err
         }
+		if product.HasVariants() {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: product %d has variants, order one of them", ErrProductNotOrderable, product.ID)
+		}
 
-		if product.StockQuantity < int64(item.Quantity){
+		// early exit only, the reservation below is what actually guards the stock
+		if product.AvailableQuantity < int64(item.Quantity){
 			tx.Rollback()
-			log.ErrorfWithContext(ctx, logTag+" insufficient stock", "product_id", item.ProductID, "required", item.Quantity, "available", product.StockQuantity)
+			log.ErrorfWithContext(ctx, logTag+" insufficient stock", "product_id", item.ProductID, "required", item.Quantity, "available", product.AvailableQuantity)
             return nil, fmt.Errorf("insufficient stock")
 		}
 
-		total += float64(product.Price) * float64(item.Quantity)
+		// every converted line has to use the same rate so the order records a single one
+		var knownRate *types.Rate
+		if fxBaseCurrency == product.Currency {
+			knownRate = fxRate
+		}
+
+		price, usedRate, err := s.resolvePrice(ctx, product, currency, knownRate)
+		if err != nil {
+			tx.Rollback()
+			log.ErrorfWithContext(ctx, logTag+" error when resolving price", err, "product_id", item.ProductID, "currency", currency)
+			return nil, err
+		}
+		if usedRate != nil {
+			if fxBaseCurrency != "" && fxBaseCurrency != product.Currency {
+				tx.Rollback()
+				return nil, fmt.Errorf("%w: order mixes products converted from %s and %s", ErrPriceUnavailable, fxBaseCurrency, product.Currency)
+			}
+			fxBaseCurrency = product.Currency
+			fxRate = usedRate
+		}
 
 		orderItems = append(orderItems, types.OrderItem{
 			ProductID: product.ID,
 			Quantity: item.Quantity,
-			Price: product.Price,
+			Price: price,
 			Name: product.Name,
 		})
+		lines = append(lines, types.DiscountableLine{
+			ProductID: product.ID,
+			Quantity: item.Quantity,
+			Price: price,
+		})
+	}
+
+	productIDs := make([]int64, 0, len(lines))
+	for _, line := range lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	categoryPaths, err := s.ProductRepo.GetCategoryPathsWithTx(tx, ctx, productIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for i := range lines {
+		lines[i].CategoryPath = categoryPaths[lines[i].ProductID]
+	}
+
+	coupons, err := s.redeemCoupons(tx, ctx, userID, currency, opts.CouponCodes)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when applying coupons", err, "coupons", opts.CouponCodes)
+		return nil, err
+	}
+
+	pricing, err := types.PriceOrder(lines, coupons, currency, taxRegion, taxRules)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when pricing order", err)
+		return nil, err
+	}
+
+	discounts := make([]types.OrderDiscount, 0, len(coupons))
+	for i, coupon := range coupons {
+		if pricing.Discounts[i] == 0 {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: coupon %s gives no discount on these items", ErrCouponNotApplicable, coupon.Code)
+		}
+		discounts = append(discounts, types.OrderDiscount{
+			CouponID: coupon.ID,
+			Code: coupon.Code,
+			Amount: pricing.Discounts[i],
+			CreatedAt: time.Now(),
+		})
+	}
+
+	for i := range orderItems {
+		line := pricing.Lines[i]
+		orderItems[i].DiscountAmount = line.Discount
+		orderItems[i].TaxRuleName = line.RuleName
+		orderItems[i].TaxRate = line.Rate
+		orderItems[i].TaxAmount = line.Tax
+		orderItems[i].TaxInclusive = line.Inclusive
 	}
 
 	order := &types.Order{
 		UserID: userID,
 		Status: types.OrderStatusPending,
-		TotalAmount: total,
+		TotalAmount: pricing.Total,
+		SubtotalAmount: pricing.Subtotal,
+		DiscountAmount: pricing.Discount,
+		TaxAmount: pricing.Tax,
+		TaxRegion: taxRegion,
+		ShippingAddress: shippingAddress,
+		BillingAddress: billingAddress,
+		Currency: currency,
+		FxBaseCurrency: fxBaseCurrency,
+		FxRate: fxRate,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 	
+	// every line needs its location before it is stored
+	if err := s.ReservationService.AllocateWithTx(tx, ctx, shippingAddress, orderItems); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when allocating stock", err)
+		return nil, err
+	}
+
 	createdOrder, err := s.OrderRepo.CreateWithTx(tx, ctx, order, orderItems)
 	if err != nil {
 		tx.Rollback()
@@ -88,15 +358,23 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID int64, items []ty
         return nil, err
     }
 
+	if err := s.OrderRepo.AddDiscountsWithTx(tx, ctx, createdOrder.ID, discounts); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-	//update stocks
-	for _, item := range items{
-		err := s.ProductRepo.UpdateStock(tx, ctx, item.ProductID, int64(item.Quantity), "subtract")
-		if err != nil {
-			tx.Rollback()
-			log.ErrorfWithContext(ctx, logTag+" error when updating stock", err, "product_id", item.ProductID)
-			return nil, err
-		}
+
+	//hold the stock until the order ships, is cancelled or the reservation expires
+	if err := s.ReservationService.ReserveWithTx(tx, ctx, createdOrder, orderItems); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when reserving stock", err, "order_id", createdOrder.ID)
+		return nil, err
+	}
+
+	taxBreakdown, err := types.BuildTaxBreakdown(orderItems)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -108,6 +386,8 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID int64, items []ty
 		Order: *createdOrder,
 		Items: orderItems,
 		User: nil,
+		Discounts: discounts,
+		TaxBreakdown: taxBreakdown,
 	}, nil
 }
 
@@ -150,23 +430,293 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, id int64, status t
         return nil, err
     }
 
-	existingOrder.Status = status
-    existingOrder.UpdatedAt = time.Now()
+	// cancelling has to give the stock back, so it goes through the dedicated cancel flow
+	if status == types.OrderStatusCancelled {
+		return s.CancelOrder(ctx, id, types.CancellationReasonOther, "")
+	}
 
-	updatedOrder, err := s.OrderRepo.Update(ctx, existingOrder)
+	if status.IsFulfilmentStatus() {
+		return nil, fmt.Errorf("%w: record a shipment instead of setting %s", ErrStatusDerivedFromShipments, status)
+	}
+
+	if !existingOrder.Status.CanTransitionTo(status) {
+		log.WarnfWithContext(ctx, logTag+" status transition rejected", "order_id", id, "from", existingOrder.Status, "to", status)
+		return nil, fmt.Errorf("%w: cannot move order from %s to %s", ErrInvalidStatusTransition, existingOrder.Status, status)
+	}
+
+	// conditional update so two concurrent requests cannot both move the order out of the same status
+	updated, err := s.OrderRepo.UpdateStatus(ctx, id, existingOrder.Status, status)
     if err != nil {
         log.ErrorfWithContext(ctx, logTag+" error when updating order", err)
         return nil, fmt.Errorf("failed to update order: %w", err)
     }
+	if !updated {
+		return nil, fmt.Errorf("%w: order %d is no longer %s", ErrOrderStatusChanged, id, existingOrder.Status)
+	}
+
+	existingOrder.Status = status
+    existingOrder.UpdatedAt = time.Now()
+
+    log.InfofWithContext(ctx, logTag+" order status updated successfully", "order_id", existingOrder.ID, "status", existingOrder.Status)
+    return existingOrder, nil
+}
+
+// cancels the order, releasing its stock reservations, all inside one master transaction
+func (s *OrderService) CancelOrder(ctx context.Context, id int64, reason types.CancellationReason, note string) (*types.Order, error) {
+	logTag := "[OrderService][CancelOrder]"
+	log.InfofWithContext(ctx, logTag+" cancelling order", "order_id", id, "reason", reason)
+
+	db := s.OrderRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when locking order", err)
+		return nil, err
+	}
+
+	if !order.Status.CanTransitionTo(types.OrderStatusCancelled) {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" order cannot be cancelled", "order_id", id, "status", order.Status)
+		return nil, fmt.Errorf("%w: cannot cancel order in status %s", ErrInvalidStatusTransition, order.Status)
+	}
+
+	released, err := s.ReservationService.ReleaseOrderWithTx(tx, ctx, id, types.ReservationStatusReleased)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when releasing reservations", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	order.Status = types.OrderStatusCancelled
+	order.CancellationReason = reason
+	order.CancellationNote = note
+	order.CancelledAt = &now
+	order.UpdatedAt = now
+
+	if err := s.OrderRepo.CancelWithTx(tx, ctx, order); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" order cancelled successfully", "order_id", id, "reservations_released", released)
+	return order, nil
+}
+
+// cancels what a partially shipped order has not shipped yet: the reservations of the rest are released, every
+// line is cut down to what shipped and the order is repriced. The order then counts as shipped, or as delivered
+// when all its shipments have arrived
+func (s *OrderService) CancelOrderRemainder(ctx context.Context, id int64, reason types.CancellationReason, note string) (*types.Order, error) {
+	logTag := "[OrderService][CancelOrderRemainder]"
+	log.InfofWithContext(ctx, logTag+" cancelling unshipped remainder", "order_id", id, "reason", reason)
+
+	taxRules, err := s.TaxService.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.OrderRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when locking order", err)
+		return nil, err
+	}
+
+	if order.Status != types.OrderStatusPartiallyShipped {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" order has no unshipped remainder", "order_id", id, "status", order.Status)
+		return nil, fmt.Errorf("%w: only a partially shipped order has a remainder to cancel, order is in status %s", ErrInvalidStatusTransition, order.Status)
+	}
+
+	released, err := s.ReservationService.ReleaseOrderWithTx(tx, ctx, id, types.ReservationStatusReleased)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when releasing reservations", err)
+		return nil, err
+	}
+
+	orderItems, err := s.OrderRepo.GetOrderItemsWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	shipped, err := s.ShipmentRepo.GetShippedQuantitiesWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for i := range orderItems {
+		item := &orderItems[i]
+		if shipped[item.ID] >= item.Quantity {
+			continue
+		}
+		if shipped[item.ID] == 0 {
+			if err := s.OrderRepo.RemoveOrderItem(tx, ctx, id, item.ID); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			continue
+		}
+		item.Quantity = shipped[item.ID]
+		if _, err := s.OrderRepo.UpdateOrderItem(tx, ctx, item); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := s.OrderRepo.RecalculateOrderTotal(tx, ctx, id, taxRules); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when recalculating order total", err)
+		return nil, err
+	}
+
+	undelivered, err := s.ShipmentRepo.CountUndeliveredWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	now := time.Now()
+	order.Status = types.OrderStatusShipped
+	if undelivered == 0 {
+		order.Status = types.OrderStatusDelivered
+	}
+	order.CancellationReason = reason
+	order.CancellationNote = note
+	order.CancelledAt = &now
+	order.UpdatedAt = now
+
+	if err := s.OrderRepo.CancelRemainderWithTx(tx, ctx, order); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" unshipped remainder cancelled successfully", "order_id", id, "status", order.Status, "reservations_released", released)
+	return s.OrderRepo.SearchByID(ctx, id)
+}
+
+// records the payment of an order, its reservations stop expiring and are held until fulfilment
+func (s *OrderService) MarkOrderPaid(ctx context.Context, id int64) (*types.Order, error) {
+	logTag := "[OrderService][MarkOrderPaid]"
+	log.InfofWithContext(ctx, logTag+" marking order paid", "order_id", id)
+
+	db := s.OrderRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if order.PaidAt != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: order %d was paid at %s", ErrOrderAlreadyPaid, id, order.PaidAt.Format(time.RFC3339))
+	}
+	if order.Status == types.OrderStatusCancelled {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" cancelled order cannot be paid", "order_id", id, "reason", order.CancellationReason)
+		return nil, fmt.Errorf("%w: cannot pay order in status %s", ErrInvalidStatusTransition, order.Status)
+	}
+
+	now := time.Now()
+	order.PaidAt = &now
+	order.UpdatedAt = now
+
+	if err := s.OrderRepo.MarkPaidWithTx(tx, ctx, order); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := s.ReservationService.ReservationRepo.SetExpiryWithTx(tx, ctx, id, nil); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" order marked paid successfully", "order_id", id)
+	return order, nil
+}
+
+func (s *OrderService) GetOrderReservations(ctx context.Context, id int64) ([]types.StockReservation, error) {
+	return s.ReservationService.GetOrderReservations(ctx, id)
+}
+
+func (s *OrderService) GetOrderLots(ctx context.Context, id int64) ([]types.OrderItemLot, error) {
+	return s.ReservationService.GetOrderLots(ctx, id)
+}
+
+func (s *OrderService) GetOrderTransitions(ctx context.Context, id int64) (*types.OrderTransitions, error) {
+	logTag := "[OrderService][GetOrderTransitions]"
+	log.InfofWithContext(ctx, logTag+" getting allowed order transitions", "order_id", id)
+
+	order, err := s.OrderRepo.SearchByID(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting order by id", err)
+		return nil, err
+	}
+
+	return &types.OrderTransitions{
+		OrderID:            order.ID,
+		CurrentStatus:      order.Status,
+		NextStatuses:       order.Status.NextStatuses(),
+		CanCancelRemainder: order.Status == types.OrderStatusPartiallyShipped,
+	}, nil
+}
+
+// locks the order for an item change, the lock serialises the change with cancellation, shipping and
+// the reservation sweeper, which all lock the order first as well
+func (s *OrderService) lockEditableOrder(tx *gorm.DB, ctx context.Context, orderID int64) (*types.Order, error) {
+    order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, orderID)
+    if err != nil {
+        return nil, err
+    }
 
-    log.InfofWithContext(ctx, logTag+" order status updated successfully", "order_id", updatedOrder.ID, "status", updatedOrder.Status)
-    return updatedOrder, nil
+    if order.Status != types.OrderStatusPending {
+        return nil, fmt.Errorf("%w: order is in status %s", ErrOrderNotEditable, order.Status)
+    }
+
+    return order, nil
 }
 
 func (s *OrderService) AddOrderItem(ctx context.Context, orderID, productID int64, quantity int32) (*types.OrderItem, error) {
     logTag := "[OrderService][AddOrderItem]"
     log.InfofWithContext(ctx, logTag+" adding order item", "order_id", orderID, "product_id", productID, "quantity", quantity)
 
+    taxRules, err := s.TaxService.Rules(ctx)
+    if err != nil {
+        return nil, err
+    }
+
 	db := s.OrderRepo.DB.Cluster.GetMasterDB(ctx)
 
 	tx := db.Begin()
@@ -175,27 +725,67 @@ func (s *OrderService) AddOrderItem(ctx context.Context, orderID, productID int6
 		return nil, fmt.Errorf("error when starting transaction")
 	}
 
-    _, err := s.OrderRepo.SearchByID(ctx, orderID)
+    order, err := s.lockEditableOrder(tx, ctx, orderID)
     if err != nil  {
+        tx.Rollback()
         return nil, err
     }
 
-    product, err := s.ProductRepo.SearchById(ctx, productID)
+    product, err := s.ProductRepo.GetByIDWithTx(tx, ctx, productID)
     if err != nil  {
+        tx.Rollback()
         return nil, err
     }
+    if product.HasVariants() {
+        tx.Rollback()
+        return nil, fmt.Errorf("%w: product %d has variants, order one of them", ErrProductNotOrderable, product.ID)
+    }
 
-    if product.StockQuantity < int64(quantity) {
+    if product.AvailableQuantity < int64(quantity) {
+        tx.Rollback()
         return nil, fmt.Errorf("insufficient stock")
     }
 
+    // reuse the rate the order was placed with so all converted lines stay consistent
+    var fxRate *types.Rate
+    if order.FxBaseCurrency == product.Currency {
+        fxRate = order.FxRate
+    }
+
+    price, usedRate, err := s.resolvePrice(ctx, product, order.Currency, fxRate)
+    if err != nil {
+        tx.Rollback()
+        return nil, err
+    }
+
+    // like CreateOrder, an order records a single rate so it can only convert from one base currency
+    if usedRate != nil && fxRate == nil {
+        if order.FxBaseCurrency != "" {
+            tx.Rollback()
+            return nil, fmt.Errorf("%w: order already converts from %s and cannot also convert from %s", ErrPriceUnavailable, order.FxBaseCurrency, product.Currency)
+        }
+        if err := s.OrderRepo.SetFxRateWithTx(tx, ctx, orderID, product.Currency, *usedRate); err != nil {
+            tx.Rollback()
+            return nil, err
+        }
+    }
+
     orderItem := &types.OrderItem{
         OrderID:   orderID,
         ProductID: productID,
+        Name:      product.Name,
         Quantity:  quantity,
-        Price:     product.Price,
+        Price:     price,
     }
 
+    orderItems := []types.OrderItem{*orderItem}
+    if err := s.ReservationService.AllocateWithTx(tx, ctx, order.ShippingAddress, orderItems); err != nil {
+        tx.Rollback()
+        log.ErrorfWithContext(ctx, logTag+" error when allocating stock", err)
+        return nil, err
+    }
+    orderItem.LocationID = orderItems[0].LocationID
+
     createdItem, err := s.OrderRepo.AddOrderItem(tx, ctx, orderItem)
     if err != nil {
 		tx.Rollback()
@@ -203,22 +793,25 @@ func (s *OrderService) AddOrderItem(ctx context.Context, orderID, productID int6
         return nil, fmt.Errorf("failed to add order item: %w", err)
     }
 
-    err = s.ProductRepo.UpdateStock(tx, ctx, productID, int64(quantity), "subtract")
+    err = s.ReservationService.ReserveWithTx(tx, ctx, order, []types.OrderItem{*createdItem})
     if err != nil {
 		tx.Rollback()
-        log.ErrorfWithContext(ctx, logTag+" error when updating stock", err)
+        log.ErrorfWithContext(ctx, logTag+" error when reserving stock", err)
+        return nil, err
     }
 
-    err = s.OrderRepo.RecalculateOrderTotal(tx, ctx, orderID)
+    err = s.OrderRepo.RecalculateOrderTotal(tx, ctx, orderID, taxRules)
     if err != nil {
 		tx.Rollback()
         log.ErrorfWithContext(ctx, logTag+" error when recalculating order total", err)
+        return nil, err
     }
 
 	//commit all changes
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		log.ErrorfWithContext(ctx, logTag+" error when commiting changes to database")
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
 	}
 
     log.InfofWithContext(ctx, logTag+" order item added successfully", "item_id", createdItem.ID)
@@ -230,6 +823,11 @@ func (s *OrderService) UpdateOrderItem(ctx context.Context, orderID, itemID int6
     logTag := "[OrderService][UpdateOrderItem]"
     log.InfofWithContext(ctx, logTag+" updating order item", "order_id", orderID, "item_id", itemID, "quantity", quantity)
 
+    taxRules, err := s.TaxService.Rules(ctx)
+    if err != nil {
+        return nil, err
+    }
+
 	db := s.OrderRepo.DB.Cluster.GetMasterDB(ctx)
 
 	tx := db.Begin()
@@ -238,55 +836,57 @@ func (s *OrderService) UpdateOrderItem(ctx context.Context, orderID, itemID int6
 		return nil, fmt.Errorf("error when starting transaction")
 	}
 
-    existingItem, err := s.OrderRepo.GetOrderItem(ctx, orderID, itemID)
+    order, err := s.lockEditableOrder(tx, ctx, orderID)
+    if err != nil {
+		tx.Rollback()
+        return nil, err
+    }
+
+    existingItem, err := s.OrderRepo.GetOrderItemWithTx(tx, ctx, orderID, itemID)
     if err != nil {
 		tx.Rollback()
         return nil, err
     }
 
-    product, err := s.ProductRepo.SearchById(ctx, existingItem.ProductID)
+    product, err := s.ProductRepo.GetByIDWithTx(tx, ctx, existingItem.ProductID)
     if err != nil {
 		tx.Rollback()
         return nil, err
     }
 
     stockDifference := int64(quantity) - int64(existingItem.Quantity)
-    if stockDifference > 0 && product.StockQuantity < stockDifference {
+    if stockDifference > 0 && product.AvailableQuantity < stockDifference {
 		tx.Rollback()
         return nil, fmt.Errorf("insufficient stock")
     }
 
+    err = s.ReservationService.ResizeItemWithTx(tx, ctx, order, *existingItem, quantity)
+    if err != nil {
+		tx.Rollback()
+        log.ErrorfWithContext(ctx, logTag+" error when resizing reservation", err)
+        return nil, err
+    }
+
     existingItem.Quantity = quantity
-    updatedItem, err := s.OrderRepo.UpdateOrderItem(ctx, existingItem)
+    updatedItem, err := s.OrderRepo.UpdateOrderItem(tx, ctx, existingItem)
     if err != nil {
 		tx.Rollback()
         log.ErrorfWithContext(ctx, logTag+" error when updating order item", err)
         return nil, fmt.Errorf("failed to update order item: %w", err)
     }
 
-    if stockDifference != 0 {
-        operation := "subtract"
-        if stockDifference < 0 {
-            operation = "add"
-            stockDifference = -stockDifference
-        }
-        err = s.ProductRepo.UpdateStock(tx, ctx, existingItem.ProductID, stockDifference, operation)
-        if err != nil {
-			tx.Rollback()
-            log.ErrorfWithContext(ctx, logTag+" error when updating stock", err)
-        }
-    }
-
-    err = s.OrderRepo.RecalculateOrderTotal(tx, ctx, orderID)
+    err = s.OrderRepo.RecalculateOrderTotal(tx, ctx, orderID, taxRules)
     if err != nil {
 		tx.Rollback()
         log.ErrorfWithContext(ctx, logTag+" error when recalculating order total", err)
+        return nil, err
     }
 
 	//commit all changes
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		log.ErrorfWithContext(ctx, logTag+" error when commiting changes to database")
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
 	}
 
     log.InfofWithContext(ctx, logTag+" order item updated successfully", "item_id", updatedItem.ID)
@@ -297,6 +897,11 @@ func (s *OrderService) RemoveOrderItem(ctx context.Context, orderID, itemID int6
     logTag := "[OrderService][RemoveOrderItem]"
     log.InfofWithContext(ctx, logTag+" removing order item", "order_id", orderID, "item_id", itemID)
 
+    taxRules, err := s.TaxService.Rules(ctx)
+    if err != nil {
+        return err
+    }
+
 	db := s.OrderRepo.DB.Cluster.GetMasterDB(ctx)
 
 	tx := db.Begin()
@@ -305,27 +910,45 @@ func (s *OrderService) RemoveOrderItem(ctx context.Context, orderID, itemID int6
 		return errors.New("error when starting transaction")
 	}
 
-    existingItem, err := s.OrderRepo.GetOrderItem(ctx, orderID, itemID)
-    if err != nil || existingItem == nil {
-        return errors.New("order item not found")
+    if _, err := s.lockEditableOrder(tx, ctx, orderID); err != nil {
+        tx.Rollback()
+        return err
     }
 
-    err = s.OrderRepo.RemoveOrderItem(tx,ctx, orderID, itemID)
+    existingItem, err := s.OrderRepo.GetOrderItemWithTx(tx, ctx, orderID, itemID)
     if err != nil {
-        log.ErrorfWithContext(ctx, logTag+" error when removing order item", err)
-        return fmt.Errorf("failed to remove order item: %w", err)
+        tx.Rollback()
+        return err
     }
 
-    err = s.ProductRepo.UpdateStock(tx, ctx, existingItem.ProductID, int64(existingItem.Quantity), "add")
+    // released first, the reservation row goes with the item
+    err = s.ReservationService.RemoveItemWithTx(tx, ctx, *existingItem)
     if err != nil {
-        log.ErrorfWithContext(ctx, logTag+" error when restoring stock", err)
+        tx.Rollback()
+        log.ErrorfWithContext(ctx, logTag+" error when releasing reservation", err)
+        return err
     }
 
-    err = s.OrderRepo.RecalculateOrderTotal(tx, ctx, orderID)
+    err = s.OrderRepo.RemoveOrderItem(tx,ctx, orderID, itemID)
     if err != nil {
+        tx.Rollback()
+        log.ErrorfWithContext(ctx, logTag+" error when removing order item", err)
+        return fmt.Errorf("failed to remove order item: %w", err)
+    }
+
+    err = s.OrderRepo.RecalculateOrderTotal(tx, ctx, orderID, taxRules)
+    if err != nil {
+        tx.Rollback()
         log.ErrorfWithContext(ctx, logTag+" error when recalculating order total", err)
+        return err
     }
 
+	//commit all changes
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to comit transaction %v", err)
+	}
+
     log.InfofWithContext(ctx, logTag+" order item removed successfully", "item_id", itemID)
     return nil
 }
@@ -2,37 +2,163 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"strings"
 	"time"
 
 	"github.com/omniful/go_commons/log"
 	"github.com/si/internal/storage/postgres"
 	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidVariant     = errors.New("invalid product variant")
+	ErrInvalidAttributes  = errors.New("invalid product attributes")
+	ErrInvalidPriceChange = errors.New("invalid price change")
 )
 
 type ProductService struct {
-	ProductRepo *postgres.ProductRepo
+	ProductRepo     *postgres.ProductRepo
+	LocationRepo    *postgres.LocationRepo
+	StockLotRepo    *postgres.StockLotRepo
+	CategoryRepo    *postgres.CategoryRepo
+	PriceChangeRepo *postgres.PriceChangeRepo
+	DefaultCurrency string
+
+	// the upper bounds of the price facet bands and the similarity a name needs to match a query fuzzily
+	PriceBands     []types.Amount
+	FuzzyThreshold float64
 }
 
-func NewProductService(productRepo *postgres.ProductRepo) *ProductService {
+func NewProductService(productRepo *postgres.ProductRepo, locationRepo *postgres.LocationRepo, stockLotRepo *postgres.StockLotRepo, categoryRepo *postgres.CategoryRepo, priceChangeRepo *postgres.PriceChangeRepo, defaultCurrency string, priceBands []types.Amount, fuzzyThreshold float64) *ProductService {
 	return &ProductService{
-		ProductRepo: productRepo,
+		ProductRepo:     productRepo,
+		LocationRepo:    locationRepo,
+		StockLotRepo:    stockLotRepo,
+		CategoryRepo:    categoryRepo,
+		PriceChangeRepo: priceChangeRepo,
+		DefaultCurrency: defaultCurrency,
+		PriceBands:      priceBands,
+		FuzzyThreshold:  fuzzyThreshold,
+	}
+}
+
+// the location a stock change is applied to, 0 means the default location
+func (s *ProductService) stockLocation(ctx context.Context, locationID int64) (*types.Location, error) {
+	if locationID == 0 {
+		return s.LocationRepo.GetDefault(ctx)
+	}
+	return s.LocationRepo.SearchByID(ctx, locationID)
+}
+
+// the category a product is filed under, it has to exist
+func (s *ProductService) productCategory(ctx context.Context, categoryID int64) (*types.Category, error) {
+	category, err := s.CategoryRepo.SearchByID(ctx, categoryID)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return nil, fmt.Errorf("%w: category %d does not exist", ErrInvalidCategory, categoryID)
+		}
+		return nil, err
 	}
+	return category, nil
 }
 
-func (s *ProductService) CreateProduct(ctx context.Context, name string, sku string, price float64, category string, stockQuantity int64) (*types.Product, error) {
+// the attributes products of the category carry, without a category a product carries none
+func (s *ProductService) attributeSchema(ctx context.Context, categoryID *int64) (types.AttributeSchema, error) {
+	if categoryID == nil {
+		return nil, nil
+	}
+	path, err := s.CategoryRepo.GetPath(ctx, *categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return types.EffectiveAttributeSchema(path), nil
+}
+
+// serial tracking can only be turned on for a product without stock, units already on hand would have no
+// serial number to ship with. A parent is checked through its variants, which hold its stock
+func (s *ProductService) checkCanSerialize(ctx context.Context, product *types.Product) error {
+	if product.StockQuantity != 0 {
+		return fmt.Errorf("%w: product %d holds %d units, serial tracking is turned on before any stock is received", ErrInvalidSerialNumbers, product.ID, product.StockQuantity)
+	}
+	if !product.HasVariants() {
+		return nil
+	}
+
+	variants, err := s.ProductRepo.GetVariants(ctx, []int64{product.ID})
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants[product.ID] {
+		if variant.StockQuantity != 0 {
+			return fmt.Errorf("%w: variant %s holds %d units, serial tracking is turned on before any stock is received", ErrInvalidSerialNumbers, variant.SKU, variant.StockQuantity)
+		}
+	}
+	return nil
+}
+
+func checkAttributes(schema types.AttributeSchema, attributes types.ProductAttributes) error {
+	if err := schema.Check(attributes); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
+	}
+	return nil
+}
+
+func (s *ProductService) CreateProduct(ctx context.Context, name string, sku string, price types.Amount, categoryID int64, stockQuantity, reorderPoint, reorderQuantity int64, isSerialized bool, optionAxes []string, attributes types.ProductAttributes) (*types.Product, error) {
 	logTag := "[ProductService][CreateProduct]"
 	log.InfofWithContext(ctx, logTag+" creating product", "product", name)
 
+	// a parent only describes its variants, the stock is held by them
+	if len(optionAxes) > 0 {
+		if err := types.OptionAxes(optionAxes).Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidVariant, err)
+		}
+		if stockQuantity != 0 {
+			return nil, fmt.Errorf("%w: a product with variants holds no stock of its own", ErrInvalidVariant)
+		}
+	}
+	if isSerialized && stockQuantity != 0 {
+		return nil, fmt.Errorf("%w: serialized product %s starts without stock, its units are received with their serial numbers", ErrInvalidSerialNumbers, sku)
+	}
+
+	category, err := s.productCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := s.attributeSchema(ctx, &category.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAttributes(schema, attributes); err != nil {
+		return nil, err
+	}
+
 	product := &types.Product{
-		Name:          name,
-		SKU:           sku,
-		Price:         price,
-		Category:      category,
-		StockQuantity: stockQuantity,
+		Name:            name,
+		SKU:             sku,
+		Price:           price,
+		Currency:        s.DefaultCurrency,
+		Category:        category.Name,
+		CategoryID:      &category.ID,
+		StockQuantity:   stockQuantity,
+		ReorderPoint:    reorderPoint,
+		ReorderQuantity: reorderQuantity,
+		IsSerialized:    isSerialized,
+		OptionAxes:      optionAxes,
+		Attributes:      attributes,
+	}
+
+	// new stock is received at the default location, transfers move it elsewhere
+	location, err := s.LocationRepo.GetDefault(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	prod, err := s.ProductRepo.Create(ctx, product)
+	prod, err := s.ProductRepo.Create(ctx, product, location.ID)
 	if err != nil {
 		log.ErrorfWithContext(ctx, logTag+" error when creating product", err)
 		return nil, err
@@ -42,6 +168,104 @@ func (s *ProductService) CreateProduct(ctx context.Context, name string, sku str
 	return prod, nil
 }
 
+// adds a variant under a parent product, it takes the parent's name, category and serial tracking and sells
+// at the parent's price unless priceOverride is given. Its attributes are laid over the parent's
+func (s *ProductService) CreateVariant(ctx context.Context, parentID int64, sku string, options types.ProductOptions, priceOverride *types.Amount, stockQuantity, reorderPoint, reorderQuantity int64, attributes types.ProductAttributes) (*types.Product, error) {
+	logTag := "[ProductService][CreateVariant]"
+	log.InfofWithContext(ctx, logTag+" creating variant", "parent_id", parentID, "sku", sku)
+
+	parent, err := s.ProductRepo.SearchById(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.HasVariants() {
+		return nil, fmt.Errorf("%w: product %d has no option axes", ErrInvalidVariant, parentID)
+	}
+	if err := parent.OptionAxes.Match(options); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVariant, err)
+	}
+	if parent.IsSerialized && stockQuantity != 0 {
+		return nil, fmt.Errorf("%w: variant %s of a serialized product starts without stock, its units are received with their serial numbers", ErrInvalidSerialNumbers, sku)
+	}
+
+	siblings, err := s.ProductRepo.GetVariants(ctx, []int64{parentID})
+	if err != nil {
+		return nil, err
+	}
+	for _, sibling := range siblings[parentID] {
+		if maps.Equal(sibling.Options, options) {
+			return nil, fmt.Errorf("%w: variant %s already has these options", ErrInvalidVariant, sibling.SKU)
+		}
+	}
+
+	schema, err := s.attributeSchema(ctx, parent.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAttributes(schema, types.MergeAttributes(parent.Attributes, attributes)); err != nil {
+		return nil, err
+	}
+
+	price := parent.Price
+	if priceOverride != nil {
+		price = *priceOverride
+	}
+
+	variant := &types.Product{
+		Name:            types.VariantName(parent.Name, parent.OptionAxes, options),
+		SKU:             sku,
+		Price:           price,
+		Currency:        parent.Currency,
+		Category:        parent.Category,
+		CategoryID:      parent.CategoryID,
+		StockQuantity:   stockQuantity,
+		ReorderPoint:    reorderPoint,
+		ReorderQuantity: reorderQuantity,
+		IsSerialized:    parent.IsSerialized,
+		ParentID:        &parent.ID,
+		Options:         options,
+		PriceOverride:   priceOverride,
+		Attributes:      attributes,
+	}
+
+	location, err := s.LocationRepo.GetDefault(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.ProductRepo.Create(ctx, variant, location.ID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating variant", err)
+		return nil, err
+	}
+
+	log.InfofWithContext(ctx, logTag+" variant created successfully", "variant_id", created.ID, "parent_id", parentID)
+	return created, nil
+}
+
+// the parent together with its variants and their availability
+func (s *ProductService) GetProductVariants(ctx context.Context, parentID int64) (*types.ProductGroup, error) {
+	logTag := "[ProductService][GetProductVariants]"
+	log.InfofWithContext(ctx, logTag+" getting product variants", "parent_id", parentID)
+
+	parent, err := s.ProductRepo.SearchById(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.HasVariants() {
+		return nil, fmt.Errorf("%w: product %d has no option axes", ErrInvalidVariant, parentID)
+	}
+
+	variants, err := s.ProductRepo.GetVariants(ctx, []int64{parentID})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting variants", err)
+		return nil, err
+	}
+
+	group := types.NewProductGroup(parent, variants[parentID])
+	return &group, nil
+}
+
 
 func (s *ProductService) GetProductById(ctx context.Context, id int64) (*types.Product, error){
 	logTag := "[ProductService][GetByID]"
@@ -71,50 +295,291 @@ func (s *ProductService) GetAllProducts(ctx context.Context, limit, offset int)
 	return products, total, nil
 }
 
-func (s *ProductService) SearchProduct(ctx context.Context, name, category string, limit, offset int) ([]*types.Product, int64, error) {
+// BuildSearchParams checks the filters of a product search and resolves its category, includeDescendants
+// adds the subtree below it
+func (s *ProductService) BuildSearchParams(ctx context.Context, query string, categoryID int64, includeDescendants bool, filters []types.AttributeFilter) (types.ProductSearchParams, error) {
+	for _, filter := range filters {
+		if err := filter.Validate(); err != nil {
+			return types.ProductSearchParams{}, fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
+		}
+	}
+
+	params := types.ProductSearchParams{
+		Query:          strings.TrimSpace(query),
+		Attributes:     filters,
+		FuzzyThreshold: s.FuzzyThreshold,
+	}
+	if categoryID != 0 {
+		if _, err := s.CategoryRepo.SearchByID(ctx, categoryID); err != nil {
+			return types.ProductSearchParams{}, err
+		}
+		params.CategoryIDs = []int64{categoryID}
+		if includeDescendants {
+			ids, err := s.CategoryRepo.GetDescendantIDs(ctx, categoryID)
+			if err != nil {
+				return types.ProductSearchParams{}, err
+			}
+			params.CategoryIDs = ids
+		}
+	}
+
+	return params, nil
+}
+
+// pages through standalone and parent products, every parent comes back grouped with its variants. The query
+// is matched as full text over name, sku, category and attributes, best match first, and when nothing
+// matches names are compared by similarity instead to get past typos. A categoryID of 0 searches all
+// categories, includeDescendants also searches the subtree below it. A parent matches the query and the
+// attribute filters when it or one of its variants does. The price bands are counted in currency, or in the
+// default currency when it is empty
+func (s *ProductService) SearchProduct(ctx context.Context, query string, categoryID int64, includeDescendants bool, filters []types.AttributeFilter, currency string, limit, offset int) (*types.ProductSearchResult, error) {
 	logTag := "[ProductService][SearchProducts]"
-	log.InfofWithContext(ctx, logTag+" searching products", "name", name, "category", category, "limit", limit, "offset", offset)
+	log.InfofWithContext(ctx, logTag+" searching products", "query", query, "category_id", categoryID, "include_descendants", includeDescendants, "currency", currency, "limit", limit, "offset", offset)
+
+	params, err := s.BuildSearchParams(ctx, query, categoryID, includeDescendants, filters)
+	if err != nil {
+		return nil, err
+	}
+	params.Limit = limit
+	params.Offset = offset
+	params.Currency = currency
+	if params.Currency == "" {
+		params.Currency = s.DefaultCurrency
+	}
 
-	products, total, err := s.ProductRepo.Search(ctx, name, category, limit, offset)
+	products, total, facets, err := s.ProductRepo.Search(ctx, params, s.PriceBands)
 	if err != nil {
 		log.ErrorfWithContext(ctx, logTag+" error when searching products", err)
-		return nil, 0, err
+		return nil, err
 	}
 
-	log.InfofWithContext(ctx, logTag+"  products search completed", "found_count", len(products), "total", total)
-	return products, total, nil
+	if total == 0 && params.Query != "" {
+		log.InfofWithContext(ctx, logTag+" no full text match, falling back to fuzzy search", "query", params.Query)
+		params.Fuzzy = true
+		products, total, facets, err = s.ProductRepo.Search(ctx, params, s.PriceBands)
+		if err != nil {
+			log.ErrorfWithContext(ctx, logTag+" error when fuzzy searching products", err)
+			return nil, err
+		}
+	}
+
+	var parentIDs []int64
+	for _, product := range products {
+		if product.HasVariants() {
+			parentIDs = append(parentIDs, product.ID)
+		}
+	}
+
+	variants, err := s.ProductRepo.GetVariants(ctx, parentIDs)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting variants", err)
+		return nil, err
+	}
+
+	groups := make([]types.ProductGroup, 0, len(products))
+	for _, product := range products {
+		groups = append(groups, types.NewProductGroup(product, variants[product.ID]))
+	}
+
+	log.InfofWithContext(ctx, logTag+"  products search completed", "found_count", len(groups), "total", total, "fuzzy", params.Fuzzy)
+	return &types.ProductSearchResult{
+		Groups: groups,
+		Total:  total,
+		Facets: *facets,
+		Fuzzy:  params.Fuzzy,
+	}, nil
 }
 
-func (s *ProductService) UpdateProduct(ctx context.Context, id int64, name string, price float64, category string, stockQuantity int64) (*types.Product, error) {
+func (s *ProductService) checkProductAttributes(ctx context.Context, product *types.Product) error {
+	schema, err := s.attributeSchema(ctx, product.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	if product.IsVariant() {
+		parent, err := s.ProductRepo.SearchById(ctx, *product.ParentID)
+		if err != nil {
+			return err
+		}
+		return checkAttributes(schema, types.MergeAttributes(parent.Attributes, product.Attributes))
+	}
+
+	if err := checkAttributes(schema, product.Attributes); err != nil {
+		return err
+	}
+	if !product.HasVariants() {
+		return nil
+	}
+
+	variants, err := s.ProductRepo.GetVariants(ctx, []int64{product.ID})
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants[product.ID] {
+		if err := checkAttributes(schema, types.MergeAttributes(product.Attributes, variant.Attributes)); err != nil {
+			return fmt.Errorf("%w, on variant %s", err, variant.SKU)
+		}
+	}
+	return nil
+}
+
+// stock is only touched when stockQuantity is given, it is the new total over all locations and the
+// difference is applied at the default location. Non nil attributes replace the product's attributes. The
+// product row stays locked while it is edited
+func (s *ProductService) UpdateProduct(ctx context.Context, id int64, name string, price types.Amount, categoryID *int64, stockQuantity, reorderPoint, reorderQuantity *int64, isSerialized *bool, attributes types.ProductAttributes) (*types.Product, error) {
 	logTag := "[ProductService][UpdateProduct]"
 	log.InfofWithContext(ctx, logTag+" updating product", "product_id", id)
 
-	existingProduct, err := s.ProductRepo.SearchById(ctx, id)
+	var category *types.Category
+	if categoryID != nil {
+		var err error
+		if category, err = s.productCategory(ctx, *categoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	location, err := s.LocationRepo.GetDefault(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.ProductRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	existingProduct, err := s.ProductRepo.LockByIDWithTx(tx, ctx, id)
 	if err != nil {
+		tx.Rollback()
 		log.ErrorfWithContext(ctx, logTag+" error when getting existing product", err)
 		return nil, err
 	}
 
+	// a variant takes its name, category and serial tracking from the parent, a price set on it overrides
+	// the parent's. A parent holds no stock
+	if existingProduct.IsVariant() && (name != "" || categoryID != nil || isSerialized != nil) {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: name, category and serial tracking of a variant are set on its parent", ErrInvalidVariant)
+	}
+	if existingProduct.HasVariants() && stockQuantity != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: a product with variants holds no stock of its own", ErrInvalidVariant)
+	}
+	serialized := existingProduct.IsSerialized
+	if isSerialized != nil {
+		serialized = *isSerialized
+	}
+	if serialized && stockQuantity != nil && *stockQuantity != existingProduct.StockQuantity {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: stock of serialized product %d is received with its serial numbers", ErrInvalidSerialNumbers, id)
+	}
+	if isSerialized != nil && *isSerialized && !existingProduct.IsSerialized {
+		if err := s.checkCanSerialize(ctx, existingProduct); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	previousPrice := existingProduct.Price
+
 	if name != "" {
 		existingProduct.Name = name
 	}
 	if price > 0 {
 		existingProduct.Price = price
+		if existingProduct.IsVariant() {
+			existingProduct.PriceOverride = &price
+		}
+	}
+	if category != nil {
+		existingProduct.Category = category.Name
+		existingProduct.CategoryID = &category.ID
 	}
-	if category != "" {
-		existingProduct.Category = category
+	if reorderPoint != nil {
+		existingProduct.ReorderPoint = *reorderPoint
 	}
-	if stockQuantity >= 0 {
-		existingProduct.StockQuantity = stockQuantity
+	if reorderQuantity != nil {
+		existingProduct.ReorderQuantity = *reorderQuantity
+	}
+	if isSerialized != nil {
+		existingProduct.IsSerialized = *isSerialized
+	}
+	if attributes != nil {
+		existingProduct.Attributes = attributes
+	}
+
+	// the attributes are checked again when they or the category change, a variant together with its parent's
+	// and a parent together with each of its variants
+	if attributes != nil || category != nil {
+		if err := s.checkProductAttributes(ctx, existingProduct); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 	}
-	existingProduct.UpdatedAt = time.Now()
 
-	updatedProduct, err := s.ProductRepo.Update(ctx, existingProduct)
+	updatedProduct, err := s.ProductRepo.UpdateWithTx(tx, ctx, existingProduct)
 	if err != nil {
+		tx.Rollback()
 		log.ErrorfWithContext(ctx, logTag+" error when updating product", err)
 		return nil, err
 	}
 
+	if price > 0 && price != previousPrice {
+		change := types.AppliedPriceChange(id, existingProduct.Currency, &price, &previousPrice, types.ActorFromContext(ctx), "product update")
+		if err := s.PriceChangeRepo.CreateWithTx(tx, ctx, change); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if existingProduct.HasVariants() {
+		if err := s.ProductRepo.SyncVariantsWithTx(tx, ctx, updatedProduct); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// stock only moves through the ledger, the save above wrote back the unchanged locked value
+	if stockQuantity != nil && *stockQuantity != existingProduct.StockQuantity {
+		stock, err := s.ProductRepo.LockStockWithTx(tx, ctx, id, location.ID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		// what the other locations hold stays where it is
+		target := *stockQuantity - (existingProduct.StockQuantity - stock.StockQuantity)
+		if target < 0 {
+			tx.Rollback()
+			return nil, fmt.Errorf("insufficient stock")
+		}
+
+		movement := types.StockMovement{Reason: types.StockMovementReasonManualAdjustment, Note: "product update"}
+		if err := s.ProductRepo.UpdateStock(tx, ctx, id, location.ID, target, "set", movement); err != nil {
+			tx.Rollback()
+			log.ErrorfWithContext(ctx, logTag+" error when updating stock", err)
+			return nil, err
+		}
+		updatedProduct.StockQuantity = *stockQuantity
+	}
+
+	// a new reorder point may put the product below or above it without any stock moving
+	if reorderPoint != nil {
+		if err := s.ProductRepo.EvaluateReorderPointWithTx(tx, ctx, id); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	// the generated column is computed by postgres, keep the response consistent with what was saved
+	updatedProduct.AvailableQuantity = updatedProduct.StockQuantity - updatedProduct.ReservedQuantity
+
 	log.InfofWithContext(ctx, logTag+" product updated successfully", "product_id", updatedProduct.ID)
 	return updatedProduct, nil
 }
@@ -133,39 +598,471 @@ func (s *ProductService) DeleteProduct(ctx context.Context, id int64) error {
     return nil
 }
 
-//updates quanity only
-func (s *ProductService) UpdateInventory(ctx context.Context, id int64, quantity int64, operation string) (*types.Product, error){
+//updates quanity only at one location, 0 is the default one. The change is a single conditional update so
+//concurrent orders cannot oversell. Stock that is added can be put into a lot, the other operations never name one
+func (s *ProductService) UpdateInventory(ctx context.Context, id, locationID int64, quantity int64, operation, note, lotNumber string, expiresAt *time.Time) (*types.Product, error){
 	logTag := "[ProductService][UpdateInventory]"
-    log.InfofWithContext(ctx, logTag+" updating inventory", "product_id", id, "quantity", quantity, "operation", operation)
-
-	existingProduct, err := s.ProductRepo.SearchById(ctx, id)
-    if err != nil {
-        log.ErrorfWithContext(ctx, logTag+" error when getting existing product", err)
-        return nil, err
-    }
+    log.InfofWithContext(ctx, logTag+" updating inventory", "product_id", id, "location_id", locationID, "quantity", quantity, "operation", operation, "lot_number", lotNumber)
 
-	switch operation {
-	case "set":
-		existingProduct.StockQuantity = quantity
-	case "add":
-		existingProduct.StockQuantity += quantity
-	case "subtract":
-		if existingProduct.StockQuantity < quantity{
-			return nil, fmt.Errorf("insufficient stock")
-		}
-		existingProduct.StockQuantity -= quantity
-	default:
-		return nil, fmt.Errorf("invalid operation %s", operation)
+	if lotNumber == "" && expiresAt != nil {
+		return nil, fmt.Errorf("%w: an expiry date needs a lot number", ErrInvalidLot)
+	}
+	if lotNumber != "" && operation != "add" {
+		return nil, fmt.Errorf("%w: only added stock can be put into a lot", ErrInvalidLot)
 	}
 
-	existingProduct.UpdatedAt = time.Now()
+	product, err := s.ProductRepo.SearchById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product.HasVariants() {
+		return nil, fmt.Errorf("%w: a product with variants holds no stock of its own", ErrInvalidVariant)
+	}
+	if product.IsSerialized && (operation == "add" || operation == "set") {
+		return nil, fmt.Errorf("%w: stock of serialized product %d is received with its serial numbers", ErrInvalidSerialNumbers, id)
+	}
 
-	updatedProduct, err := s.ProductRepo.Update(ctx, existingProduct)
+	location, err := s.stockLocation(ctx, locationID)
 	if err != nil {
+		return nil, err
+	}
+
+	db := s.ProductRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	movement := types.StockMovement{Reason: types.StockMovementReasonManualAdjustment, Note: note}
+	if err := s.ProductRepo.UpdateStock(tx, ctx, id, location.ID, quantity, operation, movement); err != nil {
+		tx.Rollback()
 		log.ErrorfWithContext(ctx, logTag+" error when updating inventory", err)
-        return nil, fmt.Errorf("failed to update inventory %w", err)
+		return nil, err
+	}
+
+	if lotNumber != "" && quantity > 0 {
+		if err := addToLotWithTx(tx, ctx, s.StockLotRepo, id, location.ID, lotNumber, expiresAt, quantity); err != nil {
+			tx.Rollback()
+			log.ErrorfWithContext(ctx, logTag+" error when adding stock to lot", err)
+			return nil, err
+		}
+	}
+
+	// the update above already holds the row lock, this read sees exactly what is committed
+	updatedProduct, err := s.ProductRepo.GetByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
 	}
 
 	log.InfofWithContext(ctx, logTag+" inventory updated successfully", "product_id", updatedProduct.ID, "new_stock", updatedProduct.StockQuantity)
     return updatedProduct, nil
 }
+
+// sets an explicit price for the product in a currency other than its base currency
+func (s *ProductService) SetProductPrice(ctx context.Context, id int64, currency string, price types.Amount) (*types.ProductPrice, error) {
+	logTag := "[ProductService][SetProductPrice]"
+	log.InfofWithContext(ctx, logTag+" setting product price", "product_id", id, "currency", currency)
+
+	product, err := s.ProductRepo.SearchById(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting product", err)
+		return nil, err
+	}
+
+	if product.Currency == currency {
+		return nil, fmt.Errorf("base currency price is set through the product itself")
+	}
+
+	db := s.ProductRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	// price changes of a product are serialized on its row so the history records the right previous price
+	if _, err := s.ProductRepo.LockByIDWithTx(tx, ctx, id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	productPrice, previousPrice, err := s.setCurrencyPriceWithTx(tx, ctx, id, currency, &price)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when setting product price", err)
+		return nil, err
+	}
+
+	if previousPrice == nil || *previousPrice != price {
+		change := types.AppliedPriceChange(id, currency, &price, previousPrice, types.ActorFromContext(ctx), "price list update")
+		if err := s.PriceChangeRepo.CreateWithTx(tx, ctx, change); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	return productPrice, nil
+}
+
+func (s *ProductService) GetProductPrices(ctx context.Context, id int64) ([]types.ProductPrice, error) {
+	logTag := "[ProductService][GetProductPrices]"
+	log.InfofWithContext(ctx, logTag+" getting product prices", "product_id", id)
+
+	if _, err := s.ProductRepo.SearchById(ctx, id); err != nil {
+		return nil, err
+	}
+
+	prices, err := s.ProductRepo.GetPrices(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting product prices", err)
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+func (s *ProductService) DeleteProductPrice(ctx context.Context, id int64, currency string) error {
+	logTag := "[ProductService][DeleteProductPrice]"
+	log.InfofWithContext(ctx, logTag+" deleting product price", "product_id", id, "currency", currency)
+
+	db := s.ProductRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	if _, err := s.ProductRepo.LockByIDWithTx(tx, ctx, id); err != nil {
+		tx.Rollback()
+		if strings.HasPrefix(err.Error(), "product not found") {
+			return fmt.Errorf("product price not found")
+		}
+		return err
+	}
+
+	_, previousPrice, err := s.setCurrencyPriceWithTx(tx, ctx, id, currency, nil)
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when deleting product price", err)
+		return err
+	}
+
+	// orders in this currency convert the base price from now on
+	change := types.AppliedPriceChange(id, currency, nil, previousPrice, types.ActorFromContext(ctx), "price list update")
+	if err := s.PriceChangeRepo.CreateWithTx(tx, ctx, change); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	return nil
+}
+
+// saves or, with a nil price, removes the explicit price of a non base currency within tx and returns the
+// price it replaced, nil when there was none
+func (s *ProductService) setCurrencyPriceWithTx(tx *gorm.DB, ctx context.Context, id int64, currency string, price *types.Amount) (*types.ProductPrice, *types.Amount, error) {
+	existing, err := s.ProductRepo.GetPriceWithTx(tx, ctx, id, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	var previousPrice *types.Amount
+	if existing != nil {
+		previousPrice = &existing.Price
+	}
+
+	if price == nil {
+		if existing == nil {
+			return nil, nil, fmt.Errorf("product price not found")
+		}
+		return nil, previousPrice, s.ProductRepo.DeletePriceWithTx(tx, ctx, id, currency)
+	}
+
+	productPrice, err := s.ProductRepo.UpsertPriceWithTx(tx, ctx, &types.ProductPrice{
+		ProductID: id,
+		Currency:  currency,
+		Price:     *price,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return productPrice, previousPrice, nil
+}
+
+// SchedulePriceChange sets a price that takes effect at effectiveFrom, an empty currency is the product's own.
+// With endsAt the change is a sale and the price it replaces comes back when it ends
+func (s *ProductService) SchedulePriceChange(ctx context.Context, id int64, currency string, price types.Amount, effectiveFrom time.Time, endsAt *time.Time, note string) (*types.PriceChange, error) {
+	logTag := "[ProductService][SchedulePriceChange]"
+	log.InfofWithContext(ctx, logTag+" scheduling price change", "product_id", id, "currency", currency, "effective_from", effectiveFrom)
+
+	product, err := s.ProductRepo.SearchById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if currency == "" {
+		currency = product.Currency
+	}
+
+	if !effectiveFrom.After(time.Now()) {
+		return nil, fmt.Errorf("%w: effective_from must be in the future, set the price directly to change it now", ErrInvalidPriceChange)
+	}
+	if endsAt != nil && !endsAt.After(effectiveFrom) {
+		return nil, fmt.Errorf("%w: ends_at must be after effective_from", ErrInvalidPriceChange)
+	}
+
+	change, err := s.PriceChangeRepo.Create(ctx, &types.PriceChange{
+		ProductID:     id,
+		Currency:      currency,
+		Price:         &price,
+		Status:        types.PriceChangeStatusScheduled,
+		EffectiveFrom: effectiveFrom,
+		EndsAt:        endsAt,
+		Actor:         types.ActorFromContext(ctx),
+		Note:          note,
+	})
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when scheduling price change", err)
+		return nil, err
+	}
+
+	return change, nil
+}
+
+func (s *ProductService) GetScheduledPriceChanges(ctx context.Context, id int64) ([]types.PriceChange, error) {
+	if _, err := s.ProductRepo.SearchById(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.PriceChangeRepo.GetScheduled(ctx, id)
+}
+
+func (s *ProductService) CancelPriceChange(ctx context.Context, id, changeID int64) (*types.PriceChange, error) {
+	logTag := "[ProductService][CancelPriceChange]"
+	log.InfofWithContext(ctx, logTag+" cancelling price change", "product_id", id, "change_id", changeID)
+
+	return s.PriceChangeRepo.Cancel(ctx, id, changeID)
+}
+
+// the prices a product had newest first, an empty currency lists all of them
+func (s *ProductService) GetPriceHistory(ctx context.Context, id int64, currency string, limit, offset int) ([]types.PriceChange, int64, error) {
+	if _, err := s.ProductRepo.SearchById(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return s.PriceChangeRepo.GetHistory(ctx, id, currency, limit, offset)
+}
+
+// GetPriceAt is the history entry in effect at a moment, such as when an order was placed. An empty currency
+// is the product's own, an entry without a price means the currency was converted from the base price then
+func (s *ProductService) GetPriceAt(ctx context.Context, id int64, currency string, at time.Time) (*types.PriceChange, error) {
+	product, err := s.ProductRepo.SearchById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if currency == "" {
+		currency = product.Currency
+	}
+
+	change, err := s.PriceChangeRepo.GetAt(ctx, id, currency, at)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, fmt.Errorf("no recorded price for product %d in %s at that time", id, currency)
+	}
+
+	return change, nil
+}
+
+// RunPriceScheduler applies scheduled price changes as they come due every interval until ctx is cancelled
+func (s *ProductService) RunPriceScheduler(ctx context.Context, interval time.Duration) {
+	logTag := "[ProductService][RunPriceScheduler]"
+	log.InfofWithContext(ctx, logTag+" starting price scheduler", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.InfofWithContext(ctx, logTag+" price scheduler stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.ApplyDuePriceChanges(ctx); err != nil {
+				log.ErrorfWithContext(ctx, logTag+" error when applying price changes", err)
+			}
+		}
+	}
+}
+
+// ApplyDuePriceChanges applies every scheduled change whose time has come, oldest first, each in its own
+// transaction. A change that cannot be applied is marked failed and the ones after it still go ahead. It
+// returns how many were applied
+func (s *ProductService) ApplyDuePriceChanges(ctx context.Context) (int, error) {
+	logTag := "[ProductService][ApplyDuePriceChanges]"
+
+	applied, failed := 0, 0
+	for ctx.Err() == nil {
+		change, err := s.applyNextPriceChange(ctx)
+		if err != nil {
+			return applied, err
+		}
+		if change == nil {
+			break
+		}
+		if change.Status == types.PriceChangeStatusFailed {
+			failed++
+			continue
+		}
+		applied++
+	}
+
+	if applied > 0 || failed > 0 {
+		log.InfofWithContext(ctx, logTag+" scheduled price changes applied", "count", applied, "failed", failed)
+	}
+	return applied, nil
+}
+
+// claims the change that came due first and applies it, nil when nothing is due. A change that fails is
+// rolled back and marked failed with the error, it is returned with that status
+func (s *ProductService) applyNextPriceChange(ctx context.Context) (*types.PriceChange, error) {
+	logTag := "[ProductService][applyNextPriceChange]"
+
+	db := s.ProductRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	change, err := s.PriceChangeRepo.ClaimDueWithTx(tx, ctx, time.Now())
+	if err != nil || change == nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// whoever scheduled the change is recorded for everything it moves
+	ctx = types.WithActor(ctx, change.Actor)
+
+	err = s.applyPriceChangeWithTx(tx, ctx, change)
+	if err == nil {
+		if err = tx.Commit().Error; err != nil {
+			err = fmt.Errorf("failed to comit transaction %v", err)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when applying price change, marking it failed", err, "change_id", change.ID, "product_id", change.ProductID)
+
+		// the claim went with the rollback, marking it failed keeps it from being claimed again
+		if err := s.PriceChangeRepo.MarkFailed(ctx, change.ID, err.Error()); err != nil {
+			return nil, err
+		}
+		change.Status = types.PriceChangeStatusFailed
+		change.AppliedAt = nil
+		change.Error = err.Error()
+		return change, nil
+	}
+
+	log.InfofWithContext(ctx, logTag+" price change applied", "change_id", change.ID, "product_id", change.ProductID, "currency", change.Currency, "status", change.Status)
+	return change, nil
+}
+
+// applies a claimed change to the product, a change removing the base price is cancelled instead
+func (s *ProductService) applyPriceChangeWithTx(tx *gorm.DB, ctx context.Context, change *types.PriceChange) error {
+	logTag := "[ProductService][applyPriceChangeWithTx]"
+
+	product, err := s.ProductRepo.LockByIDWithTx(tx, ctx, change.ProductID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if change.Currency == product.Currency {
+		// the base price cannot be removed, cancel the change so it does not hold up the ones behind it
+		if change.Price == nil {
+			log.WarnfWithContext(ctx, logTag+" price change removes the base price, cancelling it", "change_id", change.ID, "product_id", product.ID)
+			change.Status = types.PriceChangeStatusCancelled
+			return s.PriceChangeRepo.SaveWithTx(tx, ctx, change)
+		}
+
+		previousPrice := product.Price
+		change.PreviousPrice = &previousPrice
+		product.Price = *change.Price
+		if product.IsVariant() {
+			override := *change.Price
+			product.PriceOverride = &override
+		}
+
+		if _, err := s.ProductRepo.UpdateWithTx(tx, ctx, product); err != nil {
+			return err
+		}
+		if product.HasVariants() {
+			if err := s.ProductRepo.SyncVariantsWithTx(tx, ctx, product); err != nil {
+				return err
+			}
+		}
+	} else {
+		// removing a price that is already gone leaves nothing to do
+		_, previousPrice, err := s.setCurrencyPriceWithTx(tx, ctx, product.ID, change.Currency, change.Price)
+		if err != nil && err.Error() != "product price not found" {
+			return err
+		}
+		change.PreviousPrice = previousPrice
+	}
+
+	change.Status = types.PriceChangeStatusApplied
+	change.AppliedAt = &now
+	if err := s.PriceChangeRepo.SaveWithTx(tx, ctx, change); err != nil {
+		return err
+	}
+
+	// the end of a sale brings back the price it replaced, nil for a currency that had no explicit price
+	if change.EndsAt != nil {
+		err := s.PriceChangeRepo.CreateWithTx(tx, ctx, &types.PriceChange{
+			ProductID:       product.ID,
+			Currency:        change.Currency,
+			Price:           change.PreviousPrice,
+			Status:          types.PriceChangeStatusScheduled,
+			EffectiveFrom:   *change.EndsAt,
+			RevertsChangeID: &change.ID,
+			Actor:           change.Actor,
+			Note:            "end of sale",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// the stock of a product at every location holding it
+func (s *ProductService) GetProductStocks(ctx context.Context, id int64) ([]types.ProductStock, error) {
+	logTag := "[ProductService][GetProductStocks]"
+	log.InfofWithContext(ctx, logTag+" getting location stock", "product_id", id)
+
+	if _, err := s.ProductRepo.SearchById(ctx, id); err != nil {
+		return nil, err
+	}
+
+	stocks, err := s.ProductRepo.GetStocks(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting location stock", err)
+		return nil, err
+	}
+
+	return stocks, nil
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPurchaseOrder           = errors.New("invalid purchase order")
+	ErrInvalidGoodsReceipt            = errors.New("invalid goods receipt")
+	ErrInvalidPurchaseOrderTransition = errors.New("invalid purchase order status transition")
+)
+
+type PurchaseOrderService struct {
+	PurchaseOrderRepo *postgres.PurchaseOrderRepo
+	SupplierRepo      *postgres.SupplierRepo
+	ProductRepo       *postgres.ProductRepo
+	LocationRepo      *postgres.LocationRepo
+	StockLotRepo      *postgres.StockLotRepo
+	SerialNumberRepo  *postgres.SerialNumberRepo
+	DefaultCurrency   string
+}
+
+func NewPurchaseOrderService(purchaseOrderRepo *postgres.PurchaseOrderRepo, supplierRepo *postgres.SupplierRepo, productRepo *postgres.ProductRepo, locationRepo *postgres.LocationRepo, stockLotRepo *postgres.StockLotRepo, serialNumberRepo *postgres.SerialNumberRepo, defaultCurrency string) *PurchaseOrderService {
+	return &PurchaseOrderService{
+		PurchaseOrderRepo: purchaseOrderRepo,
+		SupplierRepo:      supplierRepo,
+		ProductRepo:       productRepo,
+		LocationRepo:      locationRepo,
+		StockLotRepo:      stockLotRepo,
+		SerialNumberRepo:  serialNumberRepo,
+		DefaultCurrency:   defaultCurrency,
+	}
+}
+
+// creates a draft purchase order, goods are received into locationID or the default location when it is 0 and
+// an order without an expected date is expected after the supplier's lead time
+func (s *PurchaseOrderService) CreatePurchaseOrder(ctx context.Context, supplierID, locationID int64, currency string, expectedAt *time.Time, note string, lines []types.PurchaseOrderLineRequest) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderService][CreatePurchaseOrder]"
+	log.InfofWithContext(ctx, logTag+" creating purchase order", "supplier_id", supplierID, "location_id", locationID, "lines_count", len(lines))
+
+	supplier, err := s.SupplierRepo.SearchByID(ctx, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	if !supplier.IsActive {
+		return nil, fmt.Errorf("%w: supplier %s is inactive", ErrInvalidPurchaseOrder, supplier.Code)
+	}
+
+	var location *types.Location
+	if locationID == 0 {
+		location, err = s.LocationRepo.GetDefault(ctx)
+	} else {
+		location, err = s.LocationRepo.SearchByID(ctx, locationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !location.IsActive {
+		return nil, fmt.Errorf("%w: location %s is inactive", ErrInvalidPurchaseOrder, location.Code)
+	}
+
+	if currency == "" {
+		currency = s.DefaultCurrency
+	}
+
+	now := time.Now()
+	if expectedAt == nil && supplier.LeadTimeDays > 0 {
+		expected := now.AddDate(0, 0, int(supplier.LeadTimeDays))
+		expectedAt = &expected
+	}
+
+	var total types.Amount
+	seen := make(map[int64]bool, len(lines))
+	orderLines := make([]types.PurchaseOrderLine, 0, len(lines))
+
+	for _, line := range lines {
+		if seen[line.ProductID] {
+			return nil, fmt.Errorf("%w: product %d is listed more than once", ErrInvalidPurchaseOrder, line.ProductID)
+		}
+		seen[line.ProductID] = true
+
+		product, err := s.ProductRepo.SearchById(ctx, line.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if product.HasVariants() {
+			return nil, fmt.Errorf("%w: product %d has variants, order one of them", ErrInvalidPurchaseOrder, line.ProductID)
+		}
+
+		lineTotal, err := line.UnitCost.Mul(line.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPurchaseOrder, err)
+		}
+		total = total.Add(lineTotal)
+
+		orderLines = append(orderLines, types.PurchaseOrderLine{
+			ProductID:       line.ProductID,
+			OrderedQuantity: line.Quantity,
+			UnitCost:        line.UnitCost,
+			ExpectedAt:      line.ExpectedAt,
+		})
+	}
+
+	po := &types.PurchaseOrder{
+		SupplierID: supplier.ID,
+		LocationID: location.ID,
+		Status:     types.PurchaseOrderStatusDraft,
+		Currency:   currency,
+		TotalCost:  total,
+		ExpectedAt: expectedAt,
+		Note:       note,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	db := s.PurchaseOrderRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	created, err := s.PurchaseOrderRepo.CreateWithTx(tx, ctx, po, orderLines)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" purchase order created successfully", "purchase_order_id", po.ID, "total_cost", total)
+	return created, nil
+}
+
+func (s *PurchaseOrderService) SubmitPurchaseOrder(ctx context.Context, id int64) (*types.PurchaseOrderWithLines, error) {
+	return s.movePurchaseOrder(ctx, id, types.PurchaseOrderStatusSubmitted, func(po *types.PurchaseOrder, now time.Time) {
+		po.SubmittedAt = &now
+	})
+}
+
+func (s *PurchaseOrderService) CancelPurchaseOrder(ctx context.Context, id int64) (*types.PurchaseOrderWithLines, error) {
+	return s.movePurchaseOrder(ctx, id, types.PurchaseOrderStatusCancelled, func(po *types.PurchaseOrder, now time.Time) {
+		po.CancelledAt = &now
+	})
+}
+
+// closes a partially received order short, whatever is still outstanding on its lines is the under-delivery
+func (s *PurchaseOrderService) ClosePurchaseOrder(ctx context.Context, id int64) (*types.PurchaseOrderWithLines, error) {
+	return s.movePurchaseOrder(ctx, id, types.PurchaseOrderStatusClosed, func(po *types.PurchaseOrder, now time.Time) {
+		po.ClosedAt = &now
+	})
+}
+
+// locks the purchase order, checks the transition and persists the new status in one transaction
+func (s *PurchaseOrderService) movePurchaseOrder(ctx context.Context, id int64, status types.PurchaseOrderStatus, apply func(po *types.PurchaseOrder, now time.Time)) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderService][movePurchaseOrder]"
+	log.InfofWithContext(ctx, logTag+" updating purchase order status", "purchase_order_id", id, "status", status)
+
+	db := s.PurchaseOrderRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	po, err := s.PurchaseOrderRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if !po.PurchaseOrder.Status.CanTransitionTo(status) {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" purchase order transition rejected", "purchase_order_id", id, "from", po.PurchaseOrder.Status, "to", status)
+		return nil, fmt.Errorf("%w: cannot move purchase order from %s to %s", ErrInvalidPurchaseOrderTransition, po.PurchaseOrder.Status, status)
+	}
+
+	now := time.Now()
+	apply(&po.PurchaseOrder, now)
+	po.PurchaseOrder.Status = status
+	po.PurchaseOrder.UpdatedAt = now
+
+	updated, err := s.savePurchaseOrderWithTx(tx, ctx, &po.PurchaseOrder)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" purchase order status updated successfully", "purchase_order_id", id, "status", status)
+	return updated, nil
+}
+
+// books a delivery against a submitted purchase order. Every received line is added to stock at the order's
+// location through UpdateStock, so it shows up in the ledger as a receipt movement. Deliveries may be partial
+// and may exceed what is outstanding on a line, the excess is kept on the receipt line as over_quantity
+func (s *PurchaseOrderService) ReceiveGoods(ctx context.Context, id int64, items []types.GoodsReceiptLineRequest, note string) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderService][ReceiveGoods]"
+	log.InfofWithContext(ctx, logTag+" receiving goods", "purchase_order_id", id, "items_count", len(items))
+
+	db := s.PurchaseOrderRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	po, err := s.PurchaseOrderRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	status := po.PurchaseOrder.Status
+	if status != types.PurchaseOrderStatusSubmitted && status != types.PurchaseOrderStatusPartiallyReceived {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" purchase order not open for receipts", "purchase_order_id", id, "status", status)
+		return nil, fmt.Errorf("%w: cannot receive goods against a purchase order in status %s", ErrInvalidPurchaseOrderTransition, status)
+	}
+
+	received := make(map[int64]int64, len(items))
+	lotItems := make(map[int64][]types.GoodsReceiptLineRequest)
+	serials := make(map[int64][]string)
+	for _, item := range items {
+		if item.LotNumber == "" && item.ExpiresAt != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: an expiry date needs a lot number", ErrInvalidGoodsReceipt)
+		}
+		received[item.ProductID] += item.Quantity
+		if item.LotNumber != "" {
+			lotItems[item.ProductID] = append(lotItems[item.ProductID], item)
+		}
+		serials[item.ProductID] = append(serials[item.ProductID], item.SerialNumbers...)
+	}
+
+	linesByProduct := make(map[int64]types.PurchaseOrderLine, len(po.Lines))
+	for _, line := range po.Lines {
+		linesByProduct[line.ProductID] = line
+	}
+	for productID := range received {
+		if _, ok := linesByProduct[productID]; !ok {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: product %d is not on purchase order %d", ErrInvalidGoodsReceipt, productID, id)
+		}
+	}
+
+	// every received unit of a serialized product comes with its serial number
+	productIDs := make([]int64, 0, len(received))
+	for productID := range received {
+		productIDs = append(productIDs, productID)
+	}
+	serialized, err := s.SerialNumberRepo.GetSerializedWithTx(tx, ctx, productIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for productID, quantity := range received {
+		if serialized[productID] && int64(len(serials[productID])) != quantity {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: %d units of product %d are received with %d serial numbers", ErrInvalidGoodsReceipt, quantity, productID, len(serials[productID]))
+		}
+		if !serialized[productID] && len(serials[productID]) > 0 {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: product %d is not serialized", ErrInvalidGoodsReceipt, productID)
+		}
+	}
+
+	// the lines are ordered by product, so the stock rows below are locked in product order
+	complete := true
+	var receiptLines []types.GoodsReceiptLine
+	for _, line := range po.Lines {
+		quantity := received[line.ProductID]
+		if line.ReceivedQuantity+quantity < line.OrderedQuantity {
+			complete = false
+		}
+		if quantity == 0 {
+			continue
+		}
+
+		receiptLines = append(receiptLines, types.GoodsReceiptLine{
+			PurchaseOrderLineID: line.ID,
+			ProductID:           line.ProductID,
+			Quantity:            quantity,
+			OverQuantity:        max(quantity-line.OutstandingQuantity, 0),
+		})
+	}
+
+	next := types.PurchaseOrderStatusPartiallyReceived
+	if complete {
+		next = types.PurchaseOrderStatusReceived
+	}
+	if !status.CanTransitionTo(next) {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: cannot move purchase order from %s to %s", ErrInvalidPurchaseOrderTransition, status, next)
+	}
+
+	now := time.Now()
+	receipt := &types.GoodsReceipt{
+		PurchaseOrderID: po.PurchaseOrder.ID,
+		LocationID:      po.PurchaseOrder.LocationID,
+		Actor:           types.ActorFromContext(ctx),
+		Note:            note,
+		ReceivedAt:      now,
+	}
+	if _, err := s.PurchaseOrderRepo.CreateReceiptWithTx(tx, ctx, receipt, receiptLines); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, line := range receiptLines {
+		movement := types.StockMovement{
+			Reason:        types.StockMovementReasonReceipt,
+			ReferenceType: "goods_receipt",
+			ReferenceID:   &receipt.ID,
+			Note:          note,
+		}
+		if err := s.ProductRepo.UpdateStock(tx, ctx, line.ProductID, receipt.LocationID, line.Quantity, "add", movement); err != nil {
+			tx.Rollback()
+			log.ErrorfWithContext(ctx, logTag+" error when adding received stock", err, "product_id", line.ProductID)
+			return nil, err
+		}
+
+		for _, item := range lotItems[line.ProductID] {
+			if err := addToLotWithTx(tx, ctx, s.StockLotRepo, line.ProductID, receipt.LocationID, item.LotNumber, item.ExpiresAt, item.Quantity); err != nil {
+				tx.Rollback()
+				log.ErrorfWithContext(ctx, logTag+" error when adding received stock to lot", err, "product_id", line.ProductID, "lot_number", item.LotNumber)
+				if errors.Is(err, ErrInvalidLot) {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidGoodsReceipt, err)
+				}
+				return nil, err
+			}
+		}
+
+		if len(serials[line.ProductID]) > 0 {
+			if _, err := registerSerialsWithTx(tx, ctx, s.SerialNumberRepo, line.ProductID, receipt.LocationID, &receipt.ID, serials[line.ProductID], now); err != nil {
+				tx.Rollback()
+				log.ErrorfWithContext(ctx, logTag+" error when registering serial numbers", err, "product_id", line.ProductID)
+				if errors.Is(err, ErrInvalidSerialNumbers) {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidGoodsReceipt, err)
+				}
+				return nil, err
+			}
+		}
+	}
+
+	po.PurchaseOrder.Status = next
+	po.PurchaseOrder.UpdatedAt = now
+	if complete {
+		po.PurchaseOrder.ReceivedAt = &now
+	}
+
+	updated, err := s.savePurchaseOrderWithTx(tx, ctx, &po.PurchaseOrder)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" goods received successfully", "purchase_order_id", id, "receipt_id", receipt.ID, "status", next)
+	return updated, nil
+}
+
+// persists the purchase order and reads it back with the quantities generated by the database
+func (s *PurchaseOrderService) savePurchaseOrderWithTx(tx *gorm.DB, ctx context.Context, po *types.PurchaseOrder) (*types.PurchaseOrderWithLines, error) {
+	if err := s.PurchaseOrderRepo.UpdateWithTx(tx, ctx, po); err != nil {
+		return nil, err
+	}
+
+	return s.PurchaseOrderRepo.GetByIDWithTx(tx, ctx, po.ID)
+}
+
+func (s *PurchaseOrderService) GetPurchaseOrder(ctx context.Context, id int64) (*types.PurchaseOrderWithLines, error) {
+	logTag := "[PurchaseOrderService][GetPurchaseOrder]"
+	log.InfofWithContext(ctx, logTag+" getting purchase order", "purchase_order_id", id)
+
+	po, err := s.PurchaseOrderRepo.SearchByID(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting purchase order", err)
+		return nil, err
+	}
+
+	return po, nil
+}
+
+func (s *PurchaseOrderService) SearchPurchaseOrders(ctx context.Context, params types.PurchaseOrderSearchParams) ([]types.PurchaseOrder, int64, error) {
+	logTag := "[PurchaseOrderService][SearchPurchaseOrders]"
+	log.InfofWithContext(ctx, logTag+" searching purchase orders", "params", fmt.Sprintf("%+v", params))
+
+	orders, total, err := s.PurchaseOrderRepo.Search(ctx, params)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when searching purchase orders", err)
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
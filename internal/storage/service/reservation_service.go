@@ -0,0 +1,452 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrItemPartlyFulfilled = errors.New("order item is already partly fulfilled")
+)
+
+// how many expired orders one sweep handles, the rest are picked up by the next tick
+const reservationSweepBatch = 100
+
+// ReservationService keeps the reserved quantities and stock_reservations in step: each order line is allocated
+// to a location, its stock is held there when the order is placed, converted to an on-hand decrement when it
+// ships and given back on cancel or expiry. Within the location the held units are drawn from lots earliest
+// expiry first, and the lots follow the reservation through release and fulfilment
+type ReservationService struct {
+	ReservationRepo *postgres.ReservationRepo
+	ProductRepo     *postgres.ProductRepo
+	StockLotRepo    *postgres.StockLotRepo
+	OrderRepo       *postgres.OrderRepo
+	TTL             time.Duration
+	Strategy        types.AllocationStrategy
+}
+
+func NewReservationService(reservationRepo *postgres.ReservationRepo, productRepo *postgres.ProductRepo, stockLotRepo *postgres.StockLotRepo, orderRepo *postgres.OrderRepo, ttl time.Duration, strategy types.AllocationStrategy) *ReservationService {
+	return &ReservationService{
+		ReservationRepo: reservationRepo,
+		ProductRepo:     productRepo,
+		StockLotRepo:    stockLotRepo,
+		OrderRepo:       orderRepo,
+		TTL:             ttl,
+		Strategy:        strategy,
+	}
+}
+
+// paid orders hold their stock until fulfilment, unpaid ones only for the configured ttl
+func (s *ReservationService) expiryFor(order *types.Order, now time.Time) *time.Time {
+	if order.PaidAt != nil || s.TTL <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(s.TTL)
+	return &expiresAt
+}
+
+// picks the location every new line is held and shipped from and sets it on the item, a line is never split so
+// it goes to the best ranked location that can fill it on its own. The products stay locked until the
+// transaction ends, so the stock seen here is still there when ReserveWithTx runs
+func (s *ReservationService) AllocateWithTx(tx *gorm.DB, ctx context.Context, destination *types.OrderAddress, items []types.OrderItem) error {
+	logTag := "[ReservationService][AllocateWithTx]"
+	log.InfofWithContext(ctx, logTag+" allocating order lines", "strategy", s.Strategy, "items_count", len(items))
+
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		return cmp.Compare(items[a].ProductID, items[b].ProductID)
+	})
+
+	candidates := make(map[int64][]types.AllocationCandidate)
+	for _, i := range indexes {
+		item := &items[i]
+
+		productCandidates, ok := candidates[item.ProductID]
+		if !ok {
+			fetched, err := s.ProductRepo.GetAllocationCandidatesWithTx(tx, ctx, item.ProductID)
+			if err != nil {
+				return err
+			}
+			productCandidates = fetched
+			candidates[item.ProductID] = productCandidates
+		}
+
+		chosen := -1
+		for _, candidate := range s.Strategy.Rank(productCandidates, destination) {
+			if candidate.Available >= int64(item.Quantity) {
+				chosen = slices.IndexFunc(productCandidates, func(c types.AllocationCandidate) bool {
+					return c.LocationID == candidate.LocationID
+				})
+				break
+			}
+		}
+		if chosen < 0 {
+			log.WarnfWithContext(ctx, logTag+" no location can fill the line", "product_id", item.ProductID, "required", item.Quantity)
+			return fmt.Errorf("insufficient stock")
+		}
+
+		// a later line of the same product only sees what this one left
+		productCandidates[chosen].Available -= int64(item.Quantity)
+		item.LocationID = productCandidates[chosen].LocationID
+	}
+
+	return nil
+}
+
+// reserves stock for freshly created order items at their allocated locations, the items must already have their ids
+func (s *ReservationService) ReserveWithTx(tx *gorm.DB, ctx context.Context, order *types.Order, items []types.OrderItem) error {
+	logTag := "[ReservationService][ReserveWithTx]"
+	log.InfofWithContext(ctx, logTag+" reserving stock", "order_id", order.ID, "items_count", len(items))
+
+	now := time.Now()
+	expiresAt := s.expiryFor(order, now)
+
+	reservations := make([]types.StockReservation, 0, len(items))
+	for _, item := range byProduct(items) {
+		movement := stockMovement(types.StockMovementReasonOrder, order.ID, "order_item", item.ID)
+		if err := s.ProductRepo.ReserveStockWithTx(tx, ctx, item.ProductID, item.LocationID, int64(item.Quantity), movement); err != nil {
+			return err
+		}
+		if err := s.StockLotRepo.ReserveWithTx(tx, ctx, item.ID, item.ProductID, item.LocationID, int64(item.Quantity)); err != nil {
+			return err
+		}
+
+		reservations = append(reservations, types.StockReservation{
+			OrderID:     order.ID,
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			LocationID:  item.LocationID,
+			Quantity:    item.Quantity,
+			Status:      types.ReservationStatusActive,
+			ExpiresAt:   expiresAt,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	return s.ReservationRepo.CreateWithTx(tx, ctx, reservations)
+}
+
+// gives back the unfulfilled part of every active reservation of the order, status is released or expired
+func (s *ReservationService) ReleaseOrderWithTx(tx *gorm.DB, ctx context.Context, orderID int64, status types.ReservationStatus) (int, error) {
+	logTag := "[ReservationService][ReleaseOrderWithTx]"
+	log.InfofWithContext(ctx, logTag+" releasing reservations", "order_id", orderID, "status", status)
+
+	reservations, err := s.ReservationRepo.LockActiveByOrderWithTx(tx, ctx, orderID)
+	if err != nil {
+		return 0, err
+	}
+
+	reason := types.StockMovementReasonCancellation
+	if status == types.ReservationStatusExpired {
+		reason = types.StockMovementReasonReservationExpired
+	}
+
+	for i := range reservations {
+		if err := s.release(tx, ctx, &reservations[i], status, reason); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(reservations), nil
+}
+
+func (s *ReservationService) release(tx *gorm.DB, ctx context.Context, reservation *types.StockReservation, status types.ReservationStatus, reason types.StockMovementReason) error {
+	if open := reservation.OpenQuantity(); open > 0 {
+		movement := stockMovement(reason, reservation.OrderID, "order_item", reservation.OrderItemID)
+		if err := s.ProductRepo.ReleaseStockWithTx(tx, ctx, reservation.ProductID, reservation.LocationID, int64(open), movement); err != nil {
+			return err
+		}
+		if err := s.StockLotRepo.ReleaseWithTx(tx, ctx, reservation.OrderItemID, int64(open)); err != nil {
+			return err
+		}
+	}
+
+	reservation.Status = status
+	return s.ReservationRepo.UpdateWithTx(tx, ctx, reservation)
+}
+
+// converts reservations into shipped stock, quantities are keyed by order item id. Units without an
+// open reservation are taken straight off the available stock
+func (s *ReservationService) FulfilWithTx(tx *gorm.DB, ctx context.Context, orderID, shipmentID int64, items []types.OrderItem, quantities map[int64]int32) error {
+	logTag := "[ReservationService][FulfilWithTx]"
+	log.InfofWithContext(ctx, logTag+" fulfilling reservations", "order_id", orderID, "items_count", len(quantities))
+
+	reservations, err := s.ReservationRepo.LockActiveByOrderWithTx(tx, ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	byItem := make(map[int64]*types.StockReservation, len(reservations))
+	for i := range reservations {
+		byItem[reservations[i].OrderItemID] = &reservations[i]
+	}
+
+	for _, item := range byProduct(items) {
+		quantity := quantities[item.ID]
+		if quantity <= 0 {
+			continue
+		}
+
+		movement := stockMovement(types.StockMovementReasonFulfilment, orderID, "shipment", shipmentID)
+
+		fromReservation := int32(0)
+		if reservation, ok := byItem[item.ID]; ok {
+			fromReservation = min(quantity, reservation.OpenQuantity())
+			if err := s.ProductRepo.ConsumeReservedStockWithTx(tx, ctx, item.ProductID, reservation.LocationID, int64(fromReservation), movement); err != nil {
+				return err
+			}
+			if err := s.StockLotRepo.ConsumeWithTx(tx, ctx, item.ID, int64(fromReservation)); err != nil {
+				return err
+			}
+
+			reservation.FulfilledQuantity += fromReservation
+			if reservation.OpenQuantity() == 0 {
+				reservation.Status = types.ReservationStatusFulfilled
+			}
+			if err := s.ReservationRepo.UpdateWithTx(tx, ctx, reservation); err != nil {
+				return err
+			}
+		}
+
+		if rest := quantity - fromReservation; rest > 0 {
+			log.WarnfWithContext(ctx, logTag+" shipping units without reservation", "order_item_id", item.ID, "quantity", rest)
+			if err := s.ProductRepo.UpdateStock(tx, ctx, item.ProductID, item.LocationID, int64(rest), "subtract", movement); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resizes the reservation of an order item to its new quantity, reserving or releasing the difference at the
+// location the item was allocated to
+func (s *ReservationService) ResizeItemWithTx(tx *gorm.DB, ctx context.Context, order *types.Order, item types.OrderItem, quantity int32) error {
+	logTag := "[ReservationService][ResizeItemWithTx]"
+	log.InfofWithContext(ctx, logTag+" resizing reservation", "order_item_id", item.ID, "from", item.Quantity, "to", quantity)
+
+	reservation, err := s.ReservationRepo.LockByOrderItemWithTx(tx, ctx, item.ID)
+	if err != nil {
+		return err
+	}
+	if reservation == nil {
+		// nothing held for this item, reserve the full new quantity
+		item.Quantity = quantity
+		return s.ReserveWithTx(tx, ctx, order, []types.OrderItem{item})
+	}
+
+	if quantity < reservation.FulfilledQuantity {
+		return fmt.Errorf("%w: %d units of order item %d have already shipped", ErrItemPartlyFulfilled, reservation.FulfilledQuantity, item.ID)
+	}
+
+	movement := stockMovement(types.StockMovementReasonOrder, item.OrderID, "order_item", item.ID)
+
+	difference := int64(quantity) - int64(reservation.Quantity)
+	switch {
+	case difference > 0:
+		err = s.ProductRepo.ReserveStockWithTx(tx, ctx, item.ProductID, reservation.LocationID, difference, movement)
+		if err == nil {
+			err = s.StockLotRepo.ReserveWithTx(tx, ctx, item.ID, item.ProductID, reservation.LocationID, difference)
+		}
+	case difference < 0:
+		err = s.ProductRepo.ReleaseStockWithTx(tx, ctx, item.ProductID, reservation.LocationID, -difference, movement)
+		if err == nil {
+			err = s.StockLotRepo.ReleaseWithTx(tx, ctx, item.ID, -difference)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	reservation.Quantity = quantity
+	if reservation.OpenQuantity() == 0 {
+		reservation.Status = types.ReservationStatusFulfilled
+	} else if reservation.Status != types.ReservationStatusActive {
+		// a fully shipped item that grows holds stock again
+		reservation.Status = types.ReservationStatusActive
+		reservation.ExpiresAt = s.expiryFor(order, time.Now())
+	}
+	return s.ReservationRepo.UpdateWithTx(tx, ctx, reservation)
+}
+
+// releases the reservation of an order item that is being removed
+func (s *ReservationService) RemoveItemWithTx(tx *gorm.DB, ctx context.Context, item types.OrderItem) error {
+	logTag := "[ReservationService][RemoveItemWithTx]"
+	log.InfofWithContext(ctx, logTag+" releasing item reservation", "order_item_id", item.ID)
+
+	reservation, err := s.ReservationRepo.LockByOrderItemWithTx(tx, ctx, item.ID)
+	if err != nil || reservation == nil {
+		return err
+	}
+
+	if reservation.FulfilledQuantity > 0 {
+		return fmt.Errorf("%w: %d units of order item %d have already shipped", ErrItemPartlyFulfilled, reservation.FulfilledQuantity, item.ID)
+	}
+
+	if reservation.Status != types.ReservationStatusActive {
+		return nil
+	}
+	return s.release(tx, ctx, reservation, types.ReservationStatusReleased, types.StockMovementReasonOrder)
+}
+
+func (s *ReservationService) GetOrderReservations(ctx context.Context, orderID int64) ([]types.StockReservation, error) {
+	logTag := "[ReservationService][GetOrderReservations]"
+	log.InfofWithContext(ctx, logTag+" getting order reservations", "order_id", orderID)
+
+	if _, err := s.OrderRepo.SearchByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	reservations, err := s.ReservationRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting reservations", err)
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// the lots every item of the order was allocated from and how much of each has shipped
+func (s *ReservationService) GetOrderLots(ctx context.Context, orderID int64) ([]types.OrderItemLot, error) {
+	logTag := "[ReservationService][GetOrderLots]"
+	log.InfofWithContext(ctx, logTag+" getting order lots", "order_id", orderID)
+
+	if _, err := s.OrderRepo.SearchByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	lots, err := s.StockLotRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting order lots", err)
+		return nil, err
+	}
+
+	return lots, nil
+}
+
+// releases every expired reservation and cancels the unpaid orders holding them, returns how many orders were handled
+func (s *ReservationService) ExpireStale(ctx context.Context) (int, error) {
+	logTag := "[ReservationService][ExpireStale]"
+
+	orderIDs, err := s.ReservationRepo.GetExpiredOrderIDs(ctx, time.Now(), reservationSweepBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	handled := 0
+	for _, orderID := range orderIDs {
+		if err := s.expireOrder(ctx, orderID); err != nil {
+			// one broken order must not stop the sweep, it is retried on the next tick
+			log.ErrorfWithContext(ctx, logTag+" error when expiring order reservations", err, "order_id", orderID)
+			continue
+		}
+		handled++
+	}
+
+	if handled > 0 {
+		log.InfofWithContext(ctx, logTag+" expired stale reservations", "orders", handled)
+	}
+	return handled, nil
+}
+
+func (s *ReservationService) expireOrder(ctx context.Context, orderID int64) error {
+	logTag := "[ReservationService][expireOrder]"
+
+	db := s.OrderRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// the order was paid or moved on while the sweep was running, it keeps its stock
+	if order.PaidAt != nil || !order.Status.CanTransitionTo(types.OrderStatusCancelled) {
+		if err := s.ReservationRepo.SetExpiryWithTx(tx, ctx, orderID, nil); err != nil {
+			tx.Rollback()
+			return err
+		}
+	} else {
+		if _, err := s.ReleaseOrderWithTx(tx, ctx, orderID, types.ReservationStatusExpired); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		now := time.Now()
+		order.Status = types.OrderStatusCancelled
+		order.CancellationReason = types.CancellationReasonReservationExpired
+		order.CancelledAt = &now
+		order.UpdatedAt = now
+
+		if err := s.OrderRepo.CancelWithTx(tx, ctx, order); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" order reservations expired", "order_id", orderID, "status", order.Status)
+	return nil
+}
+
+// runs ExpireStale every interval until ctx is cancelled
+func (s *ReservationService) RunSweeper(ctx context.Context, interval time.Duration) {
+	logTag := "[ReservationService][RunSweeper]"
+	log.InfofWithContext(ctx, logTag+" starting reservation sweeper", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.InfofWithContext(ctx, logTag+" reservation sweeper stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.ExpireStale(ctx); err != nil {
+				log.ErrorfWithContext(ctx, logTag+" error when sweeping reservations", err)
+			}
+		}
+	}
+}
+
+// orders items by product id, every path that touches several product rows walks them in this order so
+// two transactions can never wait on each other's locks
+func byProduct(items []types.OrderItem) []types.OrderItem {
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b types.OrderItem) int {
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+	return sorted
+}
+
+// ledger details for a stock change caused by an order, the repo fills in quantities and actor
+func stockMovement(reason types.StockMovementReason, orderID int64, referenceType string, referenceID int64) types.StockMovement {
+	return types.StockMovement{
+		Reason:        reason,
+		OrderID:       &orderID,
+		ReferenceType: referenceType,
+		ReferenceID:   &referenceID,
+	}
+}
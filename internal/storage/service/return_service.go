@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderNotReturnable      = errors.New("order is not eligible for returns")
+	ErrInvalidReturnQuantity   = errors.New("invalid return quantity")
+	ErrInvalidReturnTransition = errors.New("invalid return status transition")
+)
+
+type ReturnService struct {
+	ReturnRepo       *postgres.ReturnRepo
+	OrderRepo        *postgres.OrderRepo
+	ProductRepo      *postgres.ProductRepo
+	SerialNumberRepo *postgres.SerialNumberRepo
+}
+
+func NewReturnService(returnRepo *postgres.ReturnRepo, orderRepo *postgres.OrderRepo, productRepo *postgres.ProductRepo, serialNumberRepo *postgres.SerialNumberRepo) *ReturnService {
+	return &ReturnService{
+		ReturnRepo:       returnRepo,
+		OrderRepo:        orderRepo,
+		ProductRepo:      productRepo,
+		SerialNumberRepo: serialNumberRepo,
+	}
+}
+
+func (s *ReturnService) RequestReturn(ctx context.Context, orderID int64, reason string, items []types.ReturnItemRequest) (*types.OrderReturnWithItems, error) {
+	logTag := "[ReturnService][RequestReturn]"
+	log.InfofWithContext(ctx, logTag+" requesting return", "order_id", orderID, "items_count", len(items))
+
+	db := s.ReturnRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	// locking the order serialises concurrent return requests for the same items
+	order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if order.Status != types.OrderStatusDelivered {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" order not delivered", "order_id", orderID, "status", order.Status)
+		return nil, fmt.Errorf("%w: order is in status %s", ErrOrderNotReturnable, order.Status)
+	}
+
+	orderItems, err := s.OrderRepo.GetOrderItemsWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	orderItemsByID := make(map[int64]types.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		orderItemsByID[item.ID] = item
+	}
+
+	returned, err := s.ReturnRepo.GetReturnedQuantitiesWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var refund types.Amount
+	requested := make(map[int64]int32, len(items))
+	var returnItems []types.OrderReturnItem
+
+	for _, item := range items {
+		orderItem, ok := orderItemsByID[item.OrderItemID]
+		if !ok {
+			tx.Rollback()
+			return nil, fmt.Errorf("order item not found")
+		}
+
+		requested[item.OrderItemID] += item.Quantity
+		if returned[item.OrderItemID]+requested[item.OrderItemID] > orderItem.Quantity {
+			tx.Rollback()
+			log.WarnfWithContext(ctx, logTag+" return quantity exceeds purchased quantity", "order_item_id", item.OrderItemID)
+			return nil, fmt.Errorf("%w: only %d of order item %d can still be returned", ErrInvalidReturnQuantity, orderItem.Quantity-returned[item.OrderItemID], item.OrderItemID)
+		}
+
+		// this request's earlier entries for the same line count as returned already
+		lineRefund, err := orderItem.RefundFor(returned[item.OrderItemID]+requested[item.OrderItemID]-item.Quantity, item.Quantity)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		refund = refund.Add(lineRefund)
+
+		returnItems = append(returnItems, types.OrderReturnItem{
+			OrderItemID: orderItem.ID,
+			ProductID:   orderItem.ProductID,
+			Quantity:    item.Quantity,
+			Price:       orderItem.Price,
+		})
+	}
+
+	ret := &types.OrderReturn{
+		OrderID:      orderID,
+		UserID:       order.UserID,
+		Status:       types.ReturnStatusRequested,
+		Reason:       reason,
+		RefundAmount: refund,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	created, err := s.ReturnRepo.CreateWithTx(tx, ctx, ret, returnItems)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" return requested successfully", "return_id", created.Return.ID, "refund_amount", refund)
+	return created, nil
+}
+
+func (s *ReturnService) ApproveReturn(ctx context.Context, orderID, returnID int64, note string) (*types.OrderReturnWithItems, error) {
+	return s.moveReturn(ctx, orderID, returnID, types.ReturnStatusApproved, func(tx *gorm.DB, ret *types.OrderReturnWithItems, now time.Time) error {
+		ret.Return.ApprovedAt = &now
+		ret.Return.ResolutionNote = note
+		return nil
+	})
+}
+
+func (s *ReturnService) RejectReturn(ctx context.Context, orderID, returnID int64, note string) (*types.OrderReturnWithItems, error) {
+	return s.moveReturn(ctx, orderID, returnID, types.ReturnStatusRejected, func(tx *gorm.DB, ret *types.OrderReturnWithItems, now time.Time) error {
+		ret.Return.RejectedAt = &now
+		ret.Return.ResolutionNote = note
+		return nil
+	})
+}
+
+// marks the goods as received back, optionally putting them back on sale at the location they shipped from.
+// Restocking a serialized product needs the serial numbers of every unit that came back
+func (s *ReturnService) ReceiveReturn(ctx context.Context, orderID, returnID int64, restock bool, items []types.ReturnReceiptItem) (*types.OrderReturnWithItems, error) {
+	return s.moveReturn(ctx, orderID, returnID, types.ReturnStatusReceived, func(tx *gorm.DB, ret *types.OrderReturnWithItems, now time.Time) error {
+		ret.Return.ReceivedAt = &now
+		if !restock {
+			return nil
+		}
+
+		orderItems, err := s.OrderRepo.GetOrderItemsWithTx(tx, ctx, ret.Return.OrderID)
+		if err != nil {
+			return err
+		}
+		locations := make(map[int64]int64, len(orderItems))
+		for _, orderItem := range orderItems {
+			locations[orderItem.ID] = orderItem.LocationID
+		}
+
+		serials := make(map[int64][]string, len(items))
+		for _, item := range items {
+			serials[item.OrderItemID] = append(serials[item.OrderItemID], item.SerialNumbers...)
+		}
+		returned := make(map[int64]int32, len(ret.Items))
+		productIDs := make([]int64, 0, len(ret.Items))
+		for _, item := range ret.Items {
+			returned[item.OrderItemID] += item.Quantity
+			productIDs = append(productIDs, item.ProductID)
+		}
+		for orderItemID := range serials {
+			if _, ok := returned[orderItemID]; !ok {
+				return fmt.Errorf("%w: order item %d is not part of the return", ErrInvalidSerialNumbers, orderItemID)
+			}
+		}
+
+		serialized, err := s.SerialNumberRepo.GetSerializedWithTx(tx, ctx, productIDs)
+		if err != nil {
+			return err
+		}
+		for _, item := range ret.Items {
+			if serialized[item.ProductID] && len(serials[item.OrderItemID]) != int(returned[item.OrderItemID]) {
+				return fmt.Errorf("%w: order item %d returns %d units but names %d serial numbers", ErrInvalidSerialNumbers, item.OrderItemID, returned[item.OrderItemID], len(serials[item.OrderItemID]))
+			}
+			if !serialized[item.ProductID] && len(serials[item.OrderItemID]) > 0 {
+				return fmt.Errorf("%w: product %d of order item %d is not serialized", ErrInvalidSerialNumbers, item.ProductID, item.OrderItemID)
+			}
+		}
+
+		restocked := make(map[int64]bool, len(ret.Items))
+		for _, item := range ret.Items {
+			movement := stockMovement(types.StockMovementReasonReturn, ret.Return.OrderID, "return", ret.Return.ID)
+			if err := s.ProductRepo.UpdateStock(tx, ctx, item.ProductID, locations[item.OrderItemID], int64(item.Quantity), "add", movement); err != nil {
+				return err
+			}
+
+			// the stock update holds the product lock from here on
+			if !serialized[item.ProductID] || restocked[item.OrderItemID] {
+				continue
+			}
+			if err := restockReturnedSerialsWithTx(tx, ctx, s.SerialNumberRepo, item.ProductID, item.OrderItemID, locations[item.OrderItemID], serials[item.OrderItemID], now); err != nil {
+				return err
+			}
+			restocked[item.OrderItemID] = true
+		}
+		ret.Return.Restocked = true
+		return nil
+	})
+}
+
+// locks the return, checks the transition, applies the status specific changes and persists it in one transaction
+func (s *ReturnService) moveReturn(ctx context.Context, orderID, returnID int64, status types.ReturnStatus, apply func(tx *gorm.DB, ret *types.OrderReturnWithItems, now time.Time) error) (*types.OrderReturnWithItems, error) {
+	logTag := "[ReturnService][moveReturn]"
+	log.InfofWithContext(ctx, logTag+" updating return status", "order_id", orderID, "return_id", returnID, "status", status)
+
+	db := s.ReturnRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	ret, err := s.ReturnRepo.LockByIDWithTx(tx, ctx, orderID, returnID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if !ret.Return.Status.CanTransitionTo(status) {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" return transition rejected", "return_id", returnID, "from", ret.Return.Status, "to", status)
+		return nil, fmt.Errorf("%w: cannot move return from %s to %s", ErrInvalidReturnTransition, ret.Return.Status, status)
+	}
+
+	now := time.Now()
+	if err := apply(tx, ret, now); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when applying return update", err, "return_id", returnID)
+		return nil, err
+	}
+
+	ret.Return.Status = status
+	ret.Return.UpdatedAt = now
+
+	if err := s.ReturnRepo.UpdateWithTx(tx, ctx, &ret.Return); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" return status updated successfully", "return_id", returnID, "status", status)
+	return ret, nil
+}
+
+func (s *ReturnService) GetReturn(ctx context.Context, orderID, returnID int64) (*types.OrderReturnWithItems, error) {
+	logTag := "[ReturnService][GetReturn]"
+	log.InfofWithContext(ctx, logTag+" getting return", "order_id", orderID, "return_id", returnID)
+
+	ret, err := s.ReturnRepo.SearchByID(ctx, orderID, returnID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting return", err)
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (s *ReturnService) GetOrderReturns(ctx context.Context, orderID int64) ([]types.OrderReturnWithItems, error) {
+	logTag := "[ReturnService][GetOrderReturns]"
+	log.InfofWithContext(ctx, logTag+" getting order returns", "order_id", orderID)
+
+	if _, err := s.OrderRepo.SearchByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	returns, err := s.ReturnRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting order returns", err)
+		return nil, err
+	}
+
+	return returns, nil
+}
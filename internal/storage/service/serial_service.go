@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSerialNumbers = errors.New("invalid serial numbers")
+
+type SerialService struct {
+	SerialNumberRepo *postgres.SerialNumberRepo
+	ProductRepo      *postgres.ProductRepo
+	LocationRepo     *postgres.LocationRepo
+	OrderRepo        *postgres.OrderRepo
+}
+
+func NewSerialService(serialNumberRepo *postgres.SerialNumberRepo, productRepo *postgres.ProductRepo, locationRepo *postgres.LocationRepo, orderRepo *postgres.OrderRepo) *SerialService {
+	return &SerialService{
+		SerialNumberRepo: serialNumberRepo,
+		ProductRepo:      productRepo,
+		LocationRepo:     locationRepo,
+		OrderRepo:        orderRepo,
+	}
+}
+
+// registers serial numbers for units of a serialized product that are already on hand, stock left from before
+// serial tracking could only be turned on for a product without stock. There can't be more units in stock
+// with a serial number than the product holds
+func (s *SerialService) RegisterSerials(ctx context.Context, productID, locationID int64, serials []string) ([]types.SerialNumber, error) {
+	logTag := "[SerialService][RegisterSerials]"
+	log.InfofWithContext(ctx, logTag+" registering serial numbers", "product_id", productID, "location_id", locationID, "count", len(serials))
+
+	var location *types.Location
+	var err error
+	if locationID == 0 {
+		location, err = s.LocationRepo.GetDefault(ctx)
+	} else {
+		location, err = s.LocationRepo.SearchByID(ctx, locationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.SerialNumberRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	product, err := s.ProductRepo.LockByIDWithTx(tx, ctx, productID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if !product.IsSerialized {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: product %d is not serialized", ErrInvalidSerialNumbers, productID)
+	}
+
+	inStock, err := s.SerialNumberRepo.CountInStockWithTx(tx, ctx, productID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if inStock+int64(len(serials)) > product.StockQuantity {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: product %d holds %d units and %d of them already have a serial number", ErrInvalidSerialNumbers, productID, product.StockQuantity, inStock)
+	}
+
+	registered, err := registerSerialsWithTx(tx, ctx, s.SerialNumberRepo, productID, location.ID, nil, serials, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" serial numbers registered successfully", "product_id", productID, "count", len(registered))
+	return registered, nil
+}
+
+func (s *SerialService) GetProductSerials(ctx context.Context, productID int64, status types.SerialNumberStatus, limit, offset int) ([]types.SerialNumber, int64, error) {
+	logTag := "[SerialService][GetProductSerials]"
+	log.InfofWithContext(ctx, logTag+" getting product serial numbers", "product_id", productID, "status", status)
+
+	if _, err := s.ProductRepo.SearchById(ctx, productID); err != nil {
+		return nil, 0, err
+	}
+
+	serials, total, err := s.SerialNumberRepo.GetByProductID(ctx, productID, status, limit, offset)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting product serial numbers", err)
+		return nil, 0, err
+	}
+
+	return serials, total, nil
+}
+
+func (s *SerialService) GetOrderSerials(ctx context.Context, orderID int64) ([]types.SerialNumber, error) {
+	logTag := "[SerialService][GetOrderSerials]"
+	log.InfofWithContext(ctx, logTag+" getting order serial numbers", "order_id", orderID)
+
+	if _, err := s.OrderRepo.SearchByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	serials, err := s.SerialNumberRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting order serial numbers", err)
+		return nil, err
+	}
+
+	return serials, nil
+}
+
+// finds the unit with this serial number and the order and customer it shipped to, for warranty claims.
+// Serial numbers are unique per product, so more than one product can match
+func (s *SerialService) LookupSerial(ctx context.Context, serialNumber string) ([]types.SerialNumberLookup, error) {
+	logTag := "[SerialService][LookupSerial]"
+	log.InfofWithContext(ctx, logTag+" looking up serial number", "serial_number", serialNumber)
+
+	lookups, err := s.SerialNumberRepo.SearchByNumber(ctx, serialNumber)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when looking up serial number", err)
+		return nil, err
+	}
+	if len(lookups) == 0 {
+		return nil, fmt.Errorf("serial number not found")
+	}
+
+	return lookups, nil
+}
+
+// checks a list of serial numbers for blanks and repeats before it is matched against the database
+func validateSerials(serials []string) error {
+	seen := make(map[string]bool, len(serials))
+	for _, serial := range serials {
+		if strings.TrimSpace(serial) == "" {
+			return fmt.Errorf("%w: serial numbers cannot be blank", ErrInvalidSerialNumbers)
+		}
+		if seen[serial] {
+			return fmt.Errorf("%w: serial number %s is listed more than once", ErrInvalidSerialNumbers, serial)
+		}
+		seen[serial] = true
+	}
+
+	return nil
+}
+
+// registers serial numbers as in stock, the caller holds the product lock. A number that shipped before is a
+// unit coming back and is taken into stock again, a number that is already in stock is rejected
+func registerSerialsWithTx(tx *gorm.DB, ctx context.Context, repo *postgres.SerialNumberRepo, productID, locationID int64, goodsReceiptID *int64, serials []string, now time.Time) ([]types.SerialNumber, error) {
+	if err := validateSerials(serials); err != nil {
+		return nil, err
+	}
+
+	locked, err := repo.LockByNumbersWithTx(tx, ctx, productID, serials)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(locked))
+	var inStock []string
+	var returning []int64
+	registered := make([]types.SerialNumber, 0, len(serials))
+	for _, serial := range locked {
+		known[serial.SerialNumber] = true
+		if serial.Status == types.SerialNumberStatusInStock {
+			inStock = append(inStock, serial.SerialNumber)
+			continue
+		}
+		returning = append(returning, serial.ID)
+		registered = append(registered, restockedSerial(serial, locationID, now))
+	}
+	if len(inStock) > 0 {
+		return nil, fmt.Errorf("%w: already in stock for product %d: %s", ErrInvalidSerialNumbers, productID, strings.Join(inStock, ", "))
+	}
+
+	if err := repo.RestockWithTx(tx, ctx, returning, locationID, now); err != nil {
+		return nil, err
+	}
+
+	var created []types.SerialNumber
+	for _, serial := range serials {
+		if known[serial] {
+			continue
+		}
+		created = append(created, types.SerialNumber{
+			ProductID:      productID,
+			SerialNumber:   serial,
+			Status:         types.SerialNumberStatusInStock,
+			LocationID:     locationID,
+			GoodsReceiptID: goodsReceiptID,
+			ReceivedAt:     now,
+		})
+	}
+	if err := repo.CreateWithTx(tx, ctx, created); err != nil {
+		return nil, err
+	}
+
+	return append(registered, created...), nil
+}
+
+// takes the units of a returned order item back into stock, every serial number has to have shipped with
+// that order item. The caller holds the product lock
+func restockReturnedSerialsWithTx(tx *gorm.DB, ctx context.Context, repo *postgres.SerialNumberRepo, productID, orderItemID, locationID int64, serials []string, now time.Time) error {
+	if err := validateSerials(serials); err != nil {
+		return err
+	}
+
+	locked, err := repo.LockByNumbersWithTx(tx, ctx, productID, serials)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(locked))
+	for _, serial := range locked {
+		if serial.Status != types.SerialNumberStatusShipped || serial.OrderItemID == nil || *serial.OrderItemID != orderItemID {
+			return fmt.Errorf("%w: serial number %s did not ship with order item %d", ErrInvalidSerialNumbers, serial.SerialNumber, orderItemID)
+		}
+		ids = append(ids, serial.ID)
+	}
+	if len(locked) != len(serials) {
+		for _, serial := range serials {
+			if !slices.ContainsFunc(locked, func(l types.SerialNumber) bool { return l.SerialNumber == serial }) {
+				return fmt.Errorf("%w: serial number %s is not registered for product %d", ErrInvalidSerialNumbers, serial, productID)
+			}
+		}
+	}
+
+	return repo.RestockWithTx(tx, ctx, ids, locationID, now)
+}
+
+// the serial number as RestockWithTx leaves it
+func restockedSerial(serial types.SerialNumber, locationID int64, now time.Time) types.SerialNumber {
+	serial.Status = types.SerialNumberStatusInStock
+	serial.LocationID = locationID
+	serial.OrderItemID = nil
+	serial.ShipmentID = nil
+	serial.ShippedAt = nil
+	serial.ReceivedAt = now
+	serial.UpdatedAt = now
+	return serial
+}
+
+// assigns in stock serial numbers of the product to the order item they ship with, the caller holds the
+// product lock
+func assignSerialsWithTx(tx *gorm.DB, ctx context.Context, repo *postgres.SerialNumberRepo, productID, orderItemID, shipmentID int64, serials []string, now time.Time) error {
+	if err := validateSerials(serials); err != nil {
+		return err
+	}
+
+	locked, err := repo.LockByNumbersWithTx(tx, ctx, productID, serials)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(locked))
+	for _, serial := range locked {
+		if serial.Status != types.SerialNumberStatusInStock {
+			return fmt.Errorf("%w: serial number %s has already shipped", ErrInvalidSerialNumbers, serial.SerialNumber)
+		}
+		ids = append(ids, serial.ID)
+	}
+	if len(locked) != len(serials) {
+		for _, serial := range serials {
+			if !slices.ContainsFunc(locked, func(l types.SerialNumber) bool { return l.SerialNumber == serial }) {
+				return fmt.Errorf("%w: serial number %s is not registered for product %d", ErrInvalidSerialNumbers, serial, productID)
+			}
+		}
+	}
+
+	return repo.AssignWithTx(tx, ctx, ids, orderItemID, shipmentID, now)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+var (
+	ErrOrderNotShippable        = errors.New("order cannot be shipped")
+	ErrInvalidShipmentQuantity  = errors.New("invalid shipment quantity")
+	ErrShipmentAlreadyDelivered = errors.New("shipment already delivered")
+)
+
+type ShipmentService struct {
+	ShipmentRepo       *postgres.ShipmentRepo
+	OrderRepo          *postgres.OrderRepo
+	SerialNumberRepo   *postgres.SerialNumberRepo
+	ReservationService *ReservationService
+}
+
+func NewShipmentService(shipmentRepo *postgres.ShipmentRepo, orderRepo *postgres.OrderRepo, serialNumberRepo *postgres.SerialNumberRepo, reservationService *ReservationService) *ShipmentService {
+	return &ShipmentService{
+		ShipmentRepo:       shipmentRepo,
+		OrderRepo:          orderRepo,
+		SerialNumberRepo:   serialNumberRepo,
+		ReservationService: reservationService,
+	}
+}
+
+// records a shipment for some or all of the order items and derives the order status from it. Items of
+// serialized products name the serial number of every unit they ship
+func (s *ShipmentService) CreateShipment(ctx context.Context, orderID int64, carrier, trackingNumber string, items []types.ShipmentItemRequest) (*types.ShipmentWithItems, error) {
+	logTag := "[ShipmentService][CreateShipment]"
+	log.InfofWithContext(ctx, logTag+" creating shipment", "order_id", orderID, "carrier", carrier, "items_count", len(items))
+
+	db := s.ShipmentRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if order.Status != types.OrderStatusPending && order.Status != types.OrderStatusPartiallyShipped {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" order cannot be shipped", "order_id", orderID, "status", order.Status)
+		return nil, fmt.Errorf("%w: order is in status %s", ErrOrderNotShippable, order.Status)
+	}
+
+	orderItems, err := s.OrderRepo.GetOrderItemsWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	orderItemsByID := make(map[int64]types.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		orderItemsByID[item.ID] = item
+	}
+
+	shipped, err := s.ShipmentRepo.GetShippedQuantitiesWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	productIDs := make([]int64, 0, len(orderItems))
+	for _, item := range orderItems {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	serialized, err := s.SerialNumberRepo.GetSerializedWithTx(tx, ctx, productIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var shipmentItems []types.ShipmentItem
+	shipping := make(map[int64]int32, len(items))
+	serials := make(map[int64][]string)
+	for _, item := range items {
+		orderItem, ok := orderItemsByID[item.OrderItemID]
+		if !ok {
+			tx.Rollback()
+			return nil, fmt.Errorf("order item not found")
+		}
+
+		if shipped[item.OrderItemID]+item.Quantity > orderItem.Quantity {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: only %d of order item %d are left to ship", ErrInvalidShipmentQuantity, orderItem.Quantity-shipped[item.OrderItemID], item.OrderItemID)
+		}
+		if serialized[orderItem.ProductID] && len(item.SerialNumbers) != int(item.Quantity) {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: order item %d ships %d units but names %d serial numbers", ErrInvalidSerialNumbers, item.OrderItemID, item.Quantity, len(item.SerialNumbers))
+		}
+		if !serialized[orderItem.ProductID] && len(item.SerialNumbers) > 0 {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: product %d of order item %d is not serialized", ErrInvalidSerialNumbers, orderItem.ProductID, item.OrderItemID)
+		}
+		shipped[item.OrderItemID] += item.Quantity
+		shipping[item.OrderItemID] += item.Quantity
+		serials[item.OrderItemID] = append(serials[item.OrderItemID], item.SerialNumbers...)
+
+		shipmentItems = append(shipmentItems, types.ShipmentItem{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	now := time.Now()
+	shipment := &types.Shipment{
+		OrderID:        orderID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		Status:         types.ShipmentStatusInTransit,
+		ShippedAt:      now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	created, err := s.ShipmentRepo.CreateWithTx(tx, ctx, shipment, shipmentItems)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// the shipped units leave the warehouse, so their reservations become on hand decrements
+	if err := s.ReservationService.FulfilWithTx(tx, ctx, orderID, created.Shipment.ID, orderItems, shipping); err != nil {
+		tx.Rollback()
+		log.ErrorfWithContext(ctx, logTag+" error when fulfilling reservations", err, "order_id", orderID)
+		return nil, err
+	}
+
+	// the products are locked by the fulfilment above
+	for _, item := range byProduct(orderItems) {
+		if len(serials[item.ID]) == 0 {
+			continue
+		}
+		if err := assignSerialsWithTx(tx, ctx, s.SerialNumberRepo, item.ProductID, item.ID, created.Shipment.ID, serials[item.ID], now); err != nil {
+			tx.Rollback()
+			log.ErrorfWithContext(ctx, logTag+" error when assigning serial numbers", err, "order_item_id", item.ID)
+			return nil, err
+		}
+	}
+
+	status := types.OrderStatusShipped
+	for _, item := range orderItems {
+		if shipped[item.ID] < item.Quantity {
+			status = types.OrderStatusPartiallyShipped
+			break
+		}
+	}
+
+	if status != order.Status {
+		if err := s.OrderRepo.UpdateStatusWithTx(tx, ctx, orderID, status); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" shipment created successfully", "shipment_id", created.Shipment.ID, "order_status", status)
+	return created, nil
+}
+
+// marks a shipment delivered, the order becomes delivered once everything shipped has arrived
+func (s *ShipmentService) MarkShipmentDelivered(ctx context.Context, orderID, shipmentID int64) (*types.Shipment, error) {
+	logTag := "[ShipmentService][MarkShipmentDelivered]"
+	log.InfofWithContext(ctx, logTag+" marking shipment delivered", "order_id", orderID, "shipment_id", shipmentID)
+
+	db := s.ShipmentRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	order, err := s.OrderRepo.LockByIDWithTx(tx, ctx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	shipment, err := s.ShipmentRepo.LockByIDWithTx(tx, ctx, orderID, shipmentID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if shipment.Status == types.ShipmentStatusDelivered {
+		tx.Rollback()
+		return nil, ErrShipmentAlreadyDelivered
+	}
+
+	now := time.Now()
+	shipment.Status = types.ShipmentStatusDelivered
+	shipment.DeliveredAt = &now
+	shipment.UpdatedAt = now
+
+	if err := s.ShipmentRepo.MarkDeliveredWithTx(tx, ctx, shipment); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if order.Status == types.OrderStatusShipped {
+		undelivered, err := s.ShipmentRepo.CountUndeliveredWithTx(tx, ctx, orderID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if undelivered == 0 {
+			if err := s.OrderRepo.UpdateStatusWithTx(tx, ctx, orderID, types.OrderStatusDelivered); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" shipment delivered successfully", "shipment_id", shipmentID)
+	return shipment, nil
+}
+
+func (s *ShipmentService) GetOrderShipments(ctx context.Context, orderID int64) ([]types.ShipmentWithItems, error) {
+	logTag := "[ShipmentService][GetOrderShipments]"
+	log.InfofWithContext(ctx, logTag+" getting order shipments", "order_id", orderID)
+
+	if _, err := s.OrderRepo.SearchByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	shipments, err := s.ShipmentRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting order shipments", err)
+		return nil, err
+	}
+
+	return shipments, nil
+}
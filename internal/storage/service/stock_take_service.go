@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+var (
+	ErrInvalidStockTake       = errors.New("invalid stock take")
+	ErrStockTakeNotOpen       = errors.New("stock take is not open")
+	ErrProductAlreadyCounting = errors.New("product is already being counted")
+)
+
+type StockTakeService struct {
+	StockTakeRepo *postgres.StockTakeRepo
+	ProductRepo   *postgres.ProductRepo
+	LocationRepo  *postgres.LocationRepo
+}
+
+func NewStockTakeService(stockTakeRepo *postgres.StockTakeRepo, productRepo *postgres.ProductRepo, locationRepo *postgres.LocationRepo) *StockTakeService {
+	return &StockTakeService{
+		StockTakeRepo: stockTakeRepo,
+		ProductRepo:   productRepo,
+		LocationRepo:  locationRepo,
+	}
+}
+
+// opens a count of the products at a location, 0 meaning the default one, and snapshots their on hand stock
+// as the expected quantities. A product can be in one open session per location at a time
+func (s *StockTakeService) OpenStockTake(ctx context.Context, locationID int64, productIDs []int64, note string) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeService][OpenStockTake]"
+	log.InfofWithContext(ctx, logTag+" opening stock take", "location_id", locationID, "products_count", len(productIDs))
+
+	var location *types.Location
+	var err error
+	if locationID == 0 {
+		location, err = s.LocationRepo.GetDefault(ctx)
+	} else {
+		location, err = s.LocationRepo.SearchByID(ctx, locationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// products are locked in id order like every other stock change, which also keeps the snapshot consistent
+	ids := slices.Clone(productIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) != len(productIDs) {
+		return nil, fmt.Errorf("%w: a product is listed more than once", ErrInvalidStockTake)
+	}
+
+	db := s.StockTakeRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	lines := make([]types.StockTakeLine, 0, len(ids))
+	for _, id := range ids {
+		stock, err := s.ProductRepo.LockStockWithTx(tx, ctx, id, location.ID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		lines = append(lines, types.StockTakeLine{
+			ProductID:        id,
+			ExpectedQuantity: stock.StockQuantity,
+		})
+	}
+
+	counting, err := s.StockTakeRepo.GetOpenProductIDsWithTx(tx, ctx, location.ID, ids)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(counting) > 0 {
+		tx.Rollback()
+		log.WarnfWithContext(ctx, logTag+" products already under count", "location_id", location.ID, "product_ids", counting)
+		return nil, fmt.Errorf("%w: products %v have an open stock take at location %s", ErrProductAlreadyCounting, counting, location.Code)
+	}
+
+	now := time.Now()
+	take := &types.StockTake{
+		LocationID: location.ID,
+		Status:     types.StockTakeStatusOpen,
+		Note:       note,
+		OpenedBy:   types.ActorFromContext(ctx),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	created, err := s.StockTakeRepo.CreateWithTx(tx, ctx, take, lines)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" stock take opened successfully", "stock_take_id", take.ID)
+	return created, nil
+}
+
+// records counted quantities for products of an open session, lines not mentioned keep their count
+func (s *StockTakeService) RecordCounts(ctx context.Context, id int64, counts []types.StockTakeCountRequest) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeService][RecordCounts]"
+	log.InfofWithContext(ctx, logTag+" recording counts", "stock_take_id", id, "counts_count", len(counts))
+
+	db := s.StockTakeRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	take, err := s.StockTakeRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if take.StockTake.Status != types.StockTakeStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: stock take is in status %s", ErrStockTakeNotOpen, take.StockTake.Status)
+	}
+
+	lineIDs := make(map[int64]int64, len(take.Lines))
+	for _, line := range take.Lines {
+		lineIDs[line.ProductID] = line.ID
+	}
+
+	now := time.Now()
+	actor := types.ActorFromContext(ctx)
+	for _, count := range counts {
+		lineID, ok := lineIDs[count.ProductID]
+		if !ok {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: product %d is not part of stock take %d", ErrInvalidStockTake, count.ProductID, id)
+		}
+		if err := s.StockTakeRepo.SetCountWithTx(tx, ctx, lineID, count.CountedQuantity, actor, now); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	updated, err := s.StockTakeRepo.GetByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" counts recorded successfully", "stock_take_id", id)
+	return updated, nil
+}
+
+// books the variance of every line as a stock_take movement in one transaction. The variance is applied
+// relative to the current stock rather than setting it to the count, so sales and receipts made while the
+// count was running are kept. Every line has to be counted first
+func (s *StockTakeService) PostStockTake(ctx context.Context, id int64) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeService][PostStockTake]"
+	log.InfofWithContext(ctx, logTag+" posting stock take", "stock_take_id", id)
+
+	db := s.StockTakeRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	take, err := s.StockTakeRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if take.StockTake.Status != types.StockTakeStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: stock take is in status %s", ErrStockTakeNotOpen, take.StockTake.Status)
+	}
+
+	var uncounted []int64
+	for _, line := range take.Lines {
+		if line.CountedQuantity == nil {
+			uncounted = append(uncounted, line.ProductID)
+		}
+	}
+	if len(uncounted) > 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: products %v have not been counted", ErrInvalidStockTake, uncounted)
+	}
+
+	// the lines are ordered by product, so the stock rows are locked in product order
+	for i, line := range take.Lines {
+		variance := *line.CountedQuantity - line.ExpectedQuantity
+		if variance == 0 {
+			continue
+		}
+
+		movement := types.StockMovement{
+			Reason:        types.StockMovementReasonStockTake,
+			ReferenceType: "stock_take",
+			ReferenceID:   &take.StockTake.ID,
+			Note:          take.StockTake.Note,
+		}
+
+		if variance > 0 {
+			// a surplus of a serialized product is received with its serial numbers, not posted blind
+			product, err := s.ProductRepo.GetByIDWithTx(tx, ctx, line.ProductID)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if product.IsSerialized {
+				tx.Rollback()
+				return nil, fmt.Errorf("%w: %d more units of serialized product %d were counted than expected, serialized stock is received with its serial numbers", ErrInvalidSerialNumbers, variance, line.ProductID)
+			}
+
+			if err := s.ProductRepo.UpdateStock(tx, ctx, line.ProductID, take.StockTake.LocationID, variance, "add", movement); err != nil {
+				tx.Rollback()
+				log.ErrorfWithContext(ctx, logTag+" error when posting variance", err, "product_id", line.ProductID)
+				return nil, err
+			}
+			continue
+		}
+
+		// the count is what is on the shelf, so it is posted even when orders have more of it reserved
+		overReserved, err := s.ProductRepo.WriteOffStockWithTx(tx, ctx, line.ProductID, take.StockTake.LocationID, -variance, movement)
+		if err != nil {
+			tx.Rollback()
+			log.ErrorfWithContext(ctx, logTag+" error when posting variance", err, "product_id", line.ProductID)
+			return nil, err
+		}
+		if overReserved > 0 {
+			if err := s.StockTakeRepo.SetOverReservedWithTx(tx, ctx, line.ID, overReserved); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			take.Lines[i].OverReservedQuantity = overReserved
+			log.WarnfWithContext(ctx, logTag+" stock take left reserved units without stock", "product_id", line.ProductID, "over_reserved", overReserved)
+		}
+	}
+
+	now := time.Now()
+	take.StockTake.Status = types.StockTakeStatusPosted
+	take.StockTake.PostedBy = types.ActorFromContext(ctx)
+	take.StockTake.PostedAt = &now
+	take.StockTake.UpdatedAt = now
+
+	if err := s.StockTakeRepo.UpdateWithTx(tx, ctx, &take.StockTake); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" stock take posted successfully", "stock_take_id", id)
+	return take, nil
+}
+
+func (s *StockTakeService) CancelStockTake(ctx context.Context, id int64) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeService][CancelStockTake]"
+	log.InfofWithContext(ctx, logTag+" cancelling stock take", "stock_take_id", id)
+
+	db := s.StockTakeRepo.DB.Cluster.GetMasterDB(ctx)
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction %w", tx.Error)
+	}
+
+	take, err := s.StockTakeRepo.LockByIDWithTx(tx, ctx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if take.StockTake.Status != types.StockTakeStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: stock take is in status %s", ErrStockTakeNotOpen, take.StockTake.Status)
+	}
+
+	now := time.Now()
+	take.StockTake.Status = types.StockTakeStatusCancelled
+	take.StockTake.CancelledAt = &now
+	take.StockTake.UpdatedAt = now
+
+	if err := s.StockTakeRepo.UpdateWithTx(tx, ctx, &take.StockTake); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to comit transaction %v", err)
+	}
+
+	log.InfofWithContext(ctx, logTag+" stock take cancelled successfully", "stock_take_id", id)
+	return take, nil
+}
+
+func (s *StockTakeService) GetStockTake(ctx context.Context, id int64) (*types.StockTakeWithLines, error) {
+	logTag := "[StockTakeService][GetStockTake]"
+	log.InfofWithContext(ctx, logTag+" getting stock take", "stock_take_id", id)
+
+	take, err := s.StockTakeRepo.SearchByID(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting stock take", err)
+		return nil, err
+	}
+
+	return take, nil
+}
+
+func (s *StockTakeService) SearchStockTakes(ctx context.Context, status types.StockTakeStatus, locationID int64, limit, offset int) ([]types.StockTake, int64, error) {
+	logTag := "[StockTakeService][SearchStockTakes]"
+	log.InfofWithContext(ctx, logTag+" searching stock takes", "status", status, "location_id", locationID, "limit", limit, "offset", offset)
+
+	takes, total, err := s.StockTakeRepo.Search(ctx, status, locationID, limit, offset)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when searching stock takes", err)
+		return nil, 0, err
+	}
+
+	return takes, total, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+type SupplierService struct {
+	SupplierRepo *postgres.SupplierRepo
+}
+
+func NewSupplierService(supplierRepo *postgres.SupplierRepo) *SupplierService {
+	return &SupplierService{
+		SupplierRepo: supplierRepo,
+	}
+}
+
+// SupplierUpdate holds the supplier fields that may change, nil leaves a field as is
+type SupplierUpdate struct {
+	Name         *string
+	Email        *string
+	Phone        *string
+	LeadTimeDays *int32
+	IsActive     *bool
+}
+
+func (s *SupplierService) CreateSupplier(ctx context.Context, supplier *types.Supplier) (*types.Supplier, error) {
+	logTag := "[SupplierService][CreateSupplier]"
+	log.InfofWithContext(ctx, logTag+" creating supplier", "code", supplier.Code)
+
+	supplier.Code = strings.ToUpper(strings.TrimSpace(supplier.Code))
+	supplier.IsActive = true
+	supplier.CreatedAt = time.Now()
+	supplier.UpdatedAt = time.Now()
+
+	created, err := s.SupplierRepo.Create(ctx, supplier)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating supplier", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *SupplierService) GetSupplier(ctx context.Context, id int64) (*types.Supplier, error) {
+	logTag := "[SupplierService][GetSupplier]"
+	log.InfofWithContext(ctx, logTag+" getting supplier", "supplier_id", id)
+
+	supplier, err := s.SupplierRepo.SearchByID(ctx, id)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting supplier", err)
+		return nil, err
+	}
+
+	return supplier, nil
+}
+
+func (s *SupplierService) GetSuppliers(ctx context.Context) ([]types.Supplier, error) {
+	logTag := "[SupplierService][GetSuppliers]"
+	log.InfofWithContext(ctx, logTag+" getting suppliers")
+
+	suppliers, err := s.SupplierRepo.GetAll(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting suppliers", err)
+		return nil, err
+	}
+
+	return suppliers, nil
+}
+
+// an inactive supplier keeps its purchase orders but no new ones can be raised against it
+func (s *SupplierService) UpdateSupplier(ctx context.Context, id int64, update SupplierUpdate) (*types.Supplier, error) {
+	logTag := "[SupplierService][UpdateSupplier]"
+	log.InfofWithContext(ctx, logTag+" updating supplier", "supplier_id", id)
+
+	supplier, err := s.SupplierRepo.SearchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		supplier.Name = *update.Name
+	}
+	if update.Email != nil {
+		supplier.Email = *update.Email
+	}
+	if update.Phone != nil {
+		supplier.Phone = *update.Phone
+	}
+	if update.LeadTimeDays != nil {
+		supplier.LeadTimeDays = *update.LeadTimeDays
+	}
+	if update.IsActive != nil {
+		supplier.IsActive = *update.IsActive
+	}
+	supplier.UpdatedAt = time.Now()
+
+	if err := s.SupplierRepo.Update(ctx, supplier); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when updating supplier", err)
+		return nil, err
+	}
+
+	return supplier, nil
+}
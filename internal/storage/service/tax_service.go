@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omniful/go_commons/log"
+	"github.com/si/internal/storage/postgres"
+	"github.com/si/internal/types"
+)
+
+const (
+	TaxRuleSourceConfig = "config"
+	TaxRuleSourceDB     = "db"
+)
+
+var ErrTaxRulesReadOnly = errors.New("tax rules are loaded from config")
+
+type TaxService struct {
+	TaxRuleRepo  *postgres.TaxRuleRepo
+	CategoryRepo *postgres.CategoryRepo
+	Source       string
+	ConfigRules  []types.TaxRule
+}
+
+func NewTaxService(taxRuleRepo *postgres.TaxRuleRepo, categoryRepo *postgres.CategoryRepo, source string, configRules []types.TaxRule) *TaxService {
+	return &TaxService{
+		TaxRuleRepo:  taxRuleRepo,
+		CategoryRepo: categoryRepo,
+		Source:       source,
+		ConfigRules:  configRules,
+	}
+}
+
+// returns the rules orders are taxed with, either from config.yaml or the tax_rules table
+func (s *TaxService) Rules(ctx context.Context) ([]types.TaxRule, error) {
+	if s.Source != TaxRuleSourceDB {
+		return s.ConfigRules, nil
+	}
+
+	rules, err := s.TaxRuleRepo.GetActive(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, "[TaxService][Rules] error when loading tax rules", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *TaxService) GetRules(ctx context.Context) ([]types.TaxRule, error) {
+	logTag := "[TaxService][GetRules]"
+	log.InfofWithContext(ctx, logTag+" getting tax rules", "source", s.Source)
+
+	if s.Source != TaxRuleSourceDB {
+		return s.ConfigRules, nil
+	}
+
+	rules, err := s.TaxRuleRepo.GetAll(ctx)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when getting tax rules", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *TaxService) CreateRule(ctx context.Context, rule *types.TaxRule) (*types.TaxRule, error) {
+	logTag := "[TaxService][CreateRule]"
+	log.InfofWithContext(ctx, logTag+" creating tax rule", "name", rule.Name)
+
+	if s.Source != TaxRuleSourceDB {
+		return nil, fmt.Errorf("%w: edit configs/config.yaml instead", ErrTaxRulesReadOnly)
+	}
+	if err := ruleCategoryExists(ctx, s.CategoryRepo, rule.CategoryID); err != nil {
+		return nil, err
+	}
+
+	rule.IsActive = true
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+
+	created, err := s.TaxRuleRepo.Create(ctx, rule)
+	if err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when creating tax rule", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// the category a coupon or tax rule is limited to has to exist, no category is fine
+func ruleCategoryExists(ctx context.Context, repo *postgres.CategoryRepo, categoryID *int64) error {
+	if categoryID == nil {
+		return nil
+	}
+	if _, err := repo.SearchByID(ctx, *categoryID); err != nil {
+		if err.Error() == "category not found" {
+			return fmt.Errorf("%w: category %d does not exist", ErrInvalidCategory, *categoryID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *TaxService) DeleteRule(ctx context.Context, id int64) error {
+	logTag := "[TaxService][DeleteRule]"
+	log.InfofWithContext(ctx, logTag+" deleting tax rule", "tax_rule_id", id)
+
+	if s.Source != TaxRuleSourceDB {
+		return fmt.Errorf("%w: edit configs/config.yaml instead", ErrTaxRulesReadOnly)
+	}
+
+	if err := s.TaxRuleRepo.Delete(ctx, id); err != nil {
+		log.ErrorfWithContext(ctx, logTag+" error when deleting tax rule", err)
+		return err
+	}
+
+	return nil
+}
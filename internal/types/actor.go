@@ -0,0 +1,20 @@
+package types
+
+import "context"
+
+type actorKey struct{}
+
+// actor recorded when a change is made without one in the context
+const SystemActor = "system"
+
+// returns a copy of ctx carrying who is making the change, used for audit records such as the stock ledger
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
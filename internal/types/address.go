@@ -0,0 +1,53 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// OrderAddress is the copy of a user address taken when an order is placed, stored as jsonb on the order
+type OrderAddress struct {
+	AddressID     int64  `json:"address_id"`
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone,omitempty"`
+	Line1         string `json:"line1"`
+	Line2         string `json:"line2,omitempty"`
+	City          string `json:"city"`
+	State         string `json:"state,omitempty"`
+	PostalCode    string `json:"postal_code,omitempty"`
+	Country       string `json:"country"`
+}
+
+func (a *UserAddress) Snapshot() *OrderAddress {
+	return &OrderAddress{
+		AddressID:     a.ID,
+		RecipientName: a.RecipientName,
+		Phone:         a.Phone,
+		Line1:         a.Line1,
+		Line2:         a.Line2,
+		City:          a.City,
+		State:         a.State,
+		PostalCode:    a.PostalCode,
+		Country:       a.Country,
+	}
+}
+
+func (a OrderAddress) Value() (driver.Value, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *OrderAddress) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into OrderAddress", src)
+	}
+}
@@ -0,0 +1,243 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// enum type AttributeType
+type AttributeType string
+
+const (
+	AttributeTypeString  AttributeType = "string"
+	AttributeTypeNumber  AttributeType = "number"
+	AttributeTypeBoolean AttributeType = "boolean"
+	AttributeTypeEnum    AttributeType = "enum"
+)
+
+func (t AttributeType) IsValid() bool {
+	switch t {
+	case AttributeTypeString, AttributeTypeNumber, AttributeTypeBoolean, AttributeTypeEnum:
+		return true
+	}
+	return false
+}
+
+// AttributeDefinition describes one attribute products of a category carry, an enum only takes one of Values
+type AttributeDefinition struct {
+	Name     string        `json:"name"`
+	Type     AttributeType `json:"type"`
+	Required bool          `json:"required,omitempty"`
+	Unit     string        `json:"unit,omitempty"`
+	Values   []string      `json:"values,omitempty"`
+}
+
+// AttributeSchema lists the attributes of a category, stored as a jsonb array
+type AttributeSchema []AttributeDefinition
+
+// ProductAttributes are the attribute values of a product keyed by name, stored as a jsonb object. Numbers
+// decode as float64
+type ProductAttributes map[string]interface{}
+
+// enum type AttributeFilterOp
+type AttributeFilterOp string
+
+const (
+	AttributeFilterOpEq    AttributeFilterOp = "eq"
+	AttributeFilterOpIn    AttributeFilterOp = "in"
+	AttributeFilterOpRange AttributeFilterOp = "range"
+)
+
+// AttributeFilter narrows a product search down on one attribute. eq compares with Value, in with any of
+// Values and range takes numbers between Min and Max, either bound may be left out
+type AttributeFilter struct {
+	Name   string            `json:"name"`
+	Op     AttributeFilterOp `json:"op"`
+	Value  interface{}       `json:"value,omitempty"`
+	Values []interface{}     `json:"values,omitempty"`
+	Min    *float64          `json:"min,omitempty"`
+	Max    *float64          `json:"max,omitempty"`
+}
+
+// Validate checks a category's own schema, names have to be set and distinct and an enum needs its values
+func (s AttributeSchema) Validate() error {
+	seen := make(map[string]bool, len(s))
+	for _, def := range s {
+		if strings.TrimSpace(def.Name) == "" {
+			return fmt.Errorf("attribute names cannot be blank")
+		}
+		if seen[def.Name] {
+			return fmt.Errorf("attribute %s is defined more than once", def.Name)
+		}
+		seen[def.Name] = true
+
+		if !def.Type.IsValid() {
+			return fmt.Errorf("attribute %s has unknown type %s", def.Name, def.Type)
+		}
+		if def.Type == AttributeTypeEnum && len(def.Values) == 0 {
+			return fmt.Errorf("enum attribute %s needs a list of values", def.Name)
+		}
+		if def.Type != AttributeTypeEnum && len(def.Values) > 0 {
+			return fmt.Errorf("only enum attributes take a list of values, %s is a %s", def.Name, def.Type)
+		}
+	}
+	return nil
+}
+
+// EffectiveAttributeSchema merges the schemas along a path of the category tree given root first, a category
+// inherits the attributes of its ancestors and its own definition of a name replaces an inherited one
+func EffectiveAttributeSchema(path []Category) AttributeSchema {
+	var schema AttributeSchema
+	index := make(map[string]int)
+	for _, category := range path {
+		for _, def := range category.AttributeSchema {
+			if i, ok := index[def.Name]; ok {
+				schema[i] = def
+				continue
+			}
+			index[def.Name] = len(schema)
+			schema = append(schema, def)
+		}
+	}
+	return schema
+}
+
+// Check validates a product's attributes against the schema, every attribute has to be defined with a value
+// of its type and the required ones have to be there
+func (s AttributeSchema) Check(attributes ProductAttributes) error {
+	defs := make(map[string]AttributeDefinition, len(s))
+	for _, def := range s {
+		defs[def.Name] = def
+	}
+
+	for name, value := range attributes {
+		def, ok := defs[name]
+		if !ok {
+			return fmt.Errorf("attribute %s is not defined for the category", name)
+		}
+		if err := def.check(value); err != nil {
+			return err
+		}
+	}
+
+	for _, def := range s {
+		if _, ok := attributes[def.Name]; def.Required && !ok {
+			return fmt.Errorf("attribute %s is required", def.Name)
+		}
+	}
+	return nil
+}
+
+func (d AttributeDefinition) check(value interface{}) error {
+	switch d.Type {
+	case AttributeTypeString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("attribute %s must be a string", d.Name)
+		}
+	case AttributeTypeNumber:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("attribute %s must be a number", d.Name)
+		}
+	case AttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("attribute %s must be true or false", d.Name)
+		}
+	case AttributeTypeEnum:
+		if str, ok := value.(string); !ok || !slices.Contains(d.Values, str) {
+			return fmt.Errorf("attribute %s must be one of %s", d.Name, strings.Join(d.Values, ", "))
+		}
+	}
+	return nil
+}
+
+func (f AttributeFilter) Validate() error {
+	if strings.TrimSpace(f.Name) == "" {
+		return fmt.Errorf("attribute filters need a name")
+	}
+
+	switch f.Op {
+	case AttributeFilterOpEq:
+		if f.Value == nil {
+			return fmt.Errorf("the eq filter on %s needs a value", f.Name)
+		}
+	case AttributeFilterOpIn:
+		if len(f.Values) == 0 {
+			return fmt.Errorf("the in filter on %s needs a list of values", f.Name)
+		}
+		if slices.Contains(f.Values, nil) {
+			return fmt.Errorf("the in filter on %s cannot match null", f.Name)
+		}
+	case AttributeFilterOpRange:
+		if f.Min == nil && f.Max == nil {
+			return fmt.Errorf("the range filter on %s needs a min or a max", f.Name)
+		}
+		if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+			return fmt.Errorf("the range filter on %s has min above max", f.Name)
+		}
+	default:
+		return fmt.Errorf("unknown filter op %s, use eq, in or range", f.Op)
+	}
+	return nil
+}
+
+func (s AttributeSchema) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]AttributeDefinition(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (s *AttributeSchema) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into AttributeSchema", src)
+	}
+}
+
+func (a ProductAttributes) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]interface{}(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *ProductAttributes) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into ProductAttributes", src)
+	}
+}
+
+// MergeAttributes lays a variant's own attributes over the ones of its parent, the variant's values win
+func MergeAttributes(parent, own ProductAttributes) ProductAttributes {
+	merged := make(ProductAttributes, len(parent)+len(own))
+	maps.Copy(merged, parent)
+	maps.Copy(merged, own)
+	return merged
+}
@@ -0,0 +1,44 @@
+package types
+
+import "time"
+
+// a node of the category tree, a category without a parent is a root
+type Category struct {
+	ID          int64  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ParentID    *int64 `json:"parent_id,omitempty" gorm:"column:parent_id"`
+	Name        string `json:"name" gorm:"column:name;not null"`
+	Description string `json:"description,omitempty" gorm:"column:description;default:null"`
+
+	// the attributes this category adds to those inherited from its ancestors
+	AttributeSchema AttributeSchema `json:"attribute_schema,omitempty" gorm:"column:attribute_schema;type:jsonb"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children,omitempty"`
+}
+
+// BuildCategoryTree nests the categories under their parents and returns the roots, children keep the order
+// they come in
+func BuildCategoryTree(categories []Category) []*CategoryNode {
+	nodes := make(map[int64]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category}
+	}
+
+	var roots []*CategoryNode
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+// DiscountableLine is an order line as seen by the discount engine
+type DiscountableLine struct {
+	ProductID int64
+	// the ids of the product's category and its ancestors, the root first
+	CategoryPath []int64
+	Quantity     int32
+	Price        Amount
+}
+
+// reports whether the coupon can be redeemed at the given time, usage limits are checked separately
+func (c *Coupon) IsRedeemableAt(now time.Time) bool {
+	if !c.IsActive {
+		return false
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return false
+	}
+	return true
+}
+
+func (c *Coupon) appliesTo(line DiscountableLine) bool {
+	return c.CategoryID == nil || slices.Contains(line.CategoryPath, *c.CategoryID)
+}
+
+// computes what the coupon takes off the lines of an order in the given currency,
+// the result never exceeds the value of the lines the coupon applies to
+func (c *Coupon) Discount(currency string, lines []DiscountableLine) (Amount, error) {
+	var eligible Amount
+	var discount Amount
+
+	for _, line := range lines {
+		if !c.appliesTo(line) {
+			continue
+		}
+		total, err := line.Total()
+		if err != nil {
+			return 0, err
+		}
+		eligible = eligible.Add(total)
+
+		// every full group of buy+get units of the same product gets the last get units free
+		if c.Type == CouponTypeBuyXGetY && c.BuyQuantity > 0 && c.GetQuantity > 0 {
+			groups := line.Quantity / (c.BuyQuantity + c.GetQuantity)
+			free, err := line.Price.Mul(int64(groups * c.GetQuantity))
+			if err != nil {
+				return 0, err
+			}
+			discount = discount.Add(free)
+		}
+	}
+
+	switch c.Type {
+	case CouponTypePercentage:
+		var err error
+		if discount, err = eligible.MulRat(int64(c.PercentOff), 100); err != nil {
+			return 0, err
+		}
+	case CouponTypeFixed:
+		if c.Currency != currency {
+			return 0, nil
+		}
+		discount = c.AmountOff
+	}
+
+	if discount > eligible {
+		return eligible, nil
+	}
+	return discount, nil
+}
+
+// the value of the line before discounts and tax
+func (l DiscountableLine) Total() (Amount, error) {
+	return l.Price.Mul(int64(l.Quantity))
+}
+
+// applies the coupons in order and spreads each discount over the lines it applies to. A coupon only
+// takes what the coupons before it left on those lines, so no line and no order goes below zero.
+// Returns the discount of each coupon and the total discount of each line
+func ApplyDiscounts(coupons []Coupon, currency string, lines []DiscountableLine) ([]Amount, []Amount, error) {
+	left := make([]Amount, len(lines))
+	for i, line := range lines {
+		total, err := line.Total()
+		if err != nil {
+			return nil, nil, err
+		}
+		left[i] = total
+	}
+
+	amounts := make([]Amount, len(coupons))
+	lineDiscounts := make([]Amount, len(lines))
+	for i := range coupons {
+		amount, err := coupons[i].Discount(currency, lines)
+		if err != nil {
+			return nil, nil, err
+		}
+		if amounts[i], err = allocateDiscount(&coupons[i], amount, lines, left, lineDiscounts); err != nil {
+			return nil, nil, err
+		}
+	}
+	return amounts, lineDiscounts, nil
+}
+
+// spreads a coupon discount over the lines it applies to in proportion to what is left on each, every
+// share is worked out from what the lines after it still have so the shares never exceed a line and add
+// up exactly. Returns the amount allocated, less than asked when the lines had less left
+func allocateDiscount(coupon *Coupon, amount Amount, lines []DiscountableLine, left, out []Amount) (Amount, error) {
+	var available Amount
+	for i, line := range lines {
+		if coupon.appliesTo(line) {
+			available = available.Add(left[i])
+		}
+	}
+	if amount > available {
+		amount = available
+	}
+	if amount <= 0 {
+		return 0, nil
+	}
+
+	remaining := amount
+	for i, line := range lines {
+		if !coupon.appliesTo(line) || left[i] == 0 {
+			continue
+		}
+
+		share, err := remaining.MulRat(int64(left[i]), int64(available))
+		if err != nil {
+			return 0, err
+		}
+		available = available.Sub(left[i])
+		left[i] = left[i].Sub(share)
+		out[i] = out[i].Add(share)
+		remaining = remaining.Sub(share)
+	}
+	return amount, nil
+}
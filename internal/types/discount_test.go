@@ -0,0 +1,128 @@
+package types
+
+import "testing"
+
+// category ids of the test tree: electronics > phones, and books
+const (
+	electronics int64 = iota + 1
+	phones
+	books
+)
+
+func TestApplyDiscountsStacked(t *testing.T) {
+	lines := []DiscountableLine{
+		{ProductID: 1, CategoryPath: []int64{electronics, phones}, Quantity: 1, Price: 1000},
+		{ProductID: 2, CategoryPath: []int64{books}, Quantity: 3, Price: 3000},
+	}
+
+	tests := []struct {
+		name              string
+		coupons           []Coupon
+		wantAmounts       []Amount
+		wantLineDiscounts []Amount
+	}{
+		{
+			name: "later coupon skips a line the earlier one used up",
+			coupons: []Coupon{
+				{Code: "TECH20", Type: CouponTypeFixed, AmountOff: 2000, Currency: "AED", CategoryID: ptr(electronics)},
+				{Code: "HALF", Type: CouponTypePercentage, PercentOff: 50},
+			},
+			wantAmounts:       []Amount{1000, 5000},
+			wantLineDiscounts: []Amount{1000, 5000},
+		},
+		{
+			name: "later coupon is cut to what is left on its lines",
+			coupons: []Coupon{
+				{Code: "TECH5", Type: CouponTypeFixed, AmountOff: 500, Currency: "AED", CategoryID: ptr(electronics)},
+				{Code: "ALL", Type: CouponTypeFixed, AmountOff: 20000, Currency: "AED"},
+			},
+			wantAmounts:       []Amount{500, 9500},
+			wantLineDiscounts: []Amount{1000, 9000},
+		},
+		{
+			name: "shares follow what is left on each line",
+			coupons: []Coupon{
+				{Code: "TECH5", Type: CouponTypeFixed, AmountOff: 500, Currency: "AED", CategoryID: ptr(electronics)},
+				{Code: "TEN", Type: CouponTypeFixed, AmountOff: 1000, Currency: "AED"},
+			},
+			wantAmounts:       []Amount{500, 1000},
+			wantLineDiscounts: []Amount{553, 947},
+		},
+		{
+			name: "coupon with nothing left on its lines gives no discount",
+			coupons: []Coupon{
+				{Code: "TECH20", Type: CouponTypeFixed, AmountOff: 2000, Currency: "AED", CategoryID: ptr(electronics)},
+				{Code: "TECH10", Type: CouponTypePercentage, PercentOff: 10, CategoryID: ptr(electronics)},
+			},
+			wantAmounts:       []Amount{1000, 0},
+			wantLineDiscounts: []Amount{1000, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amounts, lineDiscounts, err := ApplyDiscounts(tt.coupons, "AED", lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var sumAmounts, sumLines Amount
+			for i, amount := range amounts {
+				if amount != tt.wantAmounts[i] {
+					t.Errorf("coupon %s discount = %s, want %s", tt.coupons[i].Code, amount, tt.wantAmounts[i])
+				}
+				sumAmounts = sumAmounts.Add(amount)
+			}
+			for i, discount := range lineDiscounts {
+				if discount != tt.wantLineDiscounts[i] {
+					t.Errorf("line %d discount = %s, want %s", i, discount, tt.wantLineDiscounts[i])
+				}
+				total, _ := lines[i].Total()
+				if discount > total {
+					t.Errorf("line %d discount %s exceeds the line value %s", i, discount, total)
+				}
+				sumLines = sumLines.Add(discount)
+			}
+			if sumAmounts != sumLines {
+				t.Errorf("coupon discounts add up to %s but line discounts to %s", sumAmounts, sumLines)
+			}
+		})
+	}
+}
+
+func TestCouponDiscountMatchesCategoryPath(t *testing.T) {
+	lines := []DiscountableLine{
+		{ProductID: 1, CategoryPath: []int64{electronics, phones}, Quantity: 1, Price: 1000},
+		{ProductID: 2, CategoryPath: []int64{books}, Quantity: 1, Price: 3000},
+		{ProductID: 3, Quantity: 1, Price: 500},
+	}
+
+	tests := []struct {
+		name       string
+		categoryID *int64
+		want       Amount
+	}{
+		{name: "no category covers every line", want: 450},
+		{name: "parent category covers its subcategories", categoryID: ptr(electronics), want: 100},
+		{name: "leaf category", categoryID: ptr(phones), want: 100},
+		{name: "other branch", categoryID: ptr(books), want: 300},
+		{name: "unknown category", categoryID: ptr(int64(99)), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coupon := Coupon{Code: "TEN", Type: CouponTypePercentage, PercentOff: 10, CategoryID: tt.categoryID}
+			got, err := coupon.Discount("AED", lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("discount = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// how CreateOrder picks the location an order line is fulfilled from
+type AllocationStrategy string
+
+const (
+	AllocationStrategyNearest      AllocationStrategy = "nearest"
+	AllocationStrategyHighestStock AllocationStrategy = "highest_stock"
+	AllocationStrategyPriority     AllocationStrategy = "priority"
+)
+
+func (s AllocationStrategy) IsValid() bool {
+	switch s {
+	case AllocationStrategyNearest, AllocationStrategyHighestStock, AllocationStrategyPriority:
+		return true
+	}
+	return false
+}
+
+// an active location holding stock of the product being allocated
+type AllocationCandidate struct {
+	LocationID int64
+	Country    string
+	State      string
+	PostalCode string
+	Priority   int32
+	Available  int64
+}
+
+// orders the candidates best first, ties always fall back to the location priority and then the id
+// so the same stock picture allocates the same way every time
+func (s AllocationStrategy) Rank(candidates []AllocationCandidate, destination *OrderAddress) []AllocationCandidate {
+	ranked := slices.Clone(candidates)
+	slices.SortStableFunc(ranked, func(a, b AllocationCandidate) int {
+		var c int
+		switch s {
+		case AllocationStrategyNearest:
+			c = cmp.Compare(distance(a, destination), distance(b, destination))
+		case AllocationStrategyHighestStock:
+			c = cmp.Compare(b.Available, a.Available)
+		}
+		if c != 0 {
+			return c
+		}
+		if c = cmp.Compare(a.Priority, b.Priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.LocationID, b.LocationID)
+	})
+	return ranked
+}
+
+// addresses carry no coordinates, so nearness is how much of the destination the location shares:
+// 0 for the same postal code, 1 for the same state, 2 for the same country and 3 for anything else
+func distance(location AllocationCandidate, destination *OrderAddress) int {
+	if destination == nil || location.Country == "" || !strings.EqualFold(location.Country, destination.Country) {
+		return 3
+	}
+	if location.State == "" || !strings.EqualFold(location.State, destination.State) {
+		return 2
+	}
+	if location.PostalCode == "" || !strings.EqualFold(location.PostalCode, destination.PostalCode) {
+		return 1
+	}
+	return 0
+}
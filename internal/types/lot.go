@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// lots expire by calendar day, ExpiryDate drops the time of day so dates from requests compare equal to the
+// DATE columns they are stored in
+func ExpiryDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SameExpiry reports whether the lot expires on expiresAt, nil meaning never
+func (l *StockLot) SameExpiry(expiresAt *time.Time) bool {
+	if l.ExpiresAt == nil || expiresAt == nil {
+		return l.ExpiresAt == nil && expiresAt == nil
+	}
+	return ExpiryDate(*l.ExpiresAt).Equal(ExpiryDate(*expiresAt))
+}
@@ -0,0 +1,335 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount is an exact monetary value stored as an integer number of minor units (cents),
+// matching the NUMERIC(12,2) columns in postgres.
+//
+// Rounding rules:
+//   - sums and integer multiples are exact
+//   - parsed input must have at most two decimal places, anything finer is rejected
+//   - operations producing fractions of a cent (percentages, rates) round half away from zero
+//   - multiplications that do not fit in an int64 fail with ErrAmountOverflow instead of wrapping
+type Amount int64
+
+const amountScale = 100
+
+var ErrAmountOverflow = errors.New("amount out of range")
+
+func AmountFromMinorUnits(cents int64) Amount {
+	return Amount(cents)
+}
+
+// parses a decimal string such as "12", "12.5" or "-0.75"
+func ParseAmount(s string) (Amount, error) {
+	v, err := parseFixed(s, 2)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %w", err)
+	}
+	return Amount(v), nil
+}
+
+// parses a decimal string into an integer scaled by 10^places, rejecting extra precision
+func parseFixed(s string, places int) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty value")
+	}
+
+	negative := false
+	if s[0] == '-' || s[0] == '+' {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if hasFrac && len(frac) > places {
+		return 0, fmt.Errorf("%q has more than %d decimal places", s, places)
+	}
+	for len(frac) < places {
+		frac += "0"
+	}
+
+	if whole == "" {
+		whole = "0"
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	var fraction int64
+	if places > 0 {
+		fraction, err = strconv.ParseInt(frac, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", s)
+		}
+	}
+
+	scale := int64(1)
+	for i := 0; i < places; i++ {
+		scale *= 10
+	}
+	if units > (math.MaxInt64-fraction)/scale {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+
+	total := units*scale + fraction
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+func (a Amount) MinorUnits() int64 {
+	return int64(a)
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return a + b
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return a - b
+}
+
+func (a Amount) Mul(quantity int64) (Amount, error) {
+	product := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(quantity))
+	if !product.IsInt64() {
+		return 0, fmt.Errorf("%w: %s x %d", ErrAmountOverflow, a, quantity)
+	}
+	return Amount(product.Int64()), nil
+}
+
+// multiplies by numerator/denominator, rounding half away from zero to the cent
+func (a Amount) MulRat(numerator, denominator int64) (Amount, error) {
+	product := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(numerator))
+	den := big.NewInt(denominator)
+
+	quotient, remainder := new(big.Int).QuoRem(product, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(new(big.Int).Abs(den)) >= 0 {
+		if product.Sign()*den.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	if !quotient.IsInt64() {
+		return 0, fmt.Errorf("%w: %s x %d/%d", ErrAmountOverflow, a, numerator, denominator)
+	}
+	return Amount(quotient.Int64()), nil
+}
+
+// converts the amount with an exchange rate, rounding half away from zero to the cent
+func (a Amount) Convert(rate Rate) (Amount, error) {
+	return a.MulRat(int64(rate), rateScale)
+}
+
+func (a Amount) IsNegative() bool {
+	return a < 0
+}
+
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/amountScale, v%amountScale)
+}
+
+// amounts are written as JSON strings so clients never round-trip them through floats
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// accepts both "12.50" and 12.50, the number form is parsed from its literal text
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case int64:
+		scaled, ok := scaleWhole(v, amountScale)
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrAmountOverflow, v)
+		}
+		*a = Amount(scaled)
+		return nil
+	case float64:
+		// numeric columns never hit this path with pgx, kept for drivers that send floats
+		parsed, err := ParseAmount(strconv.FormatFloat(v, 'f', 2, 64))
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Amount", src)
+	}
+}
+
+// the database may return more than two decimals for computed values, round those half away from zero
+func (a *Amount) scanString(s string) error {
+	whole, frac, ok := strings.Cut(strings.TrimSpace(s), ".")
+	if ok && len(frac) > 2 {
+		roundUp := frac[2] >= '5'
+		parsed, err := ParseAmount(whole + "." + frac[:2])
+		if err != nil {
+			return err
+		}
+		if roundUp {
+			if strings.HasPrefix(whole, "-") {
+				parsed--
+			} else {
+				parsed++
+			}
+		}
+		*a = parsed
+		return nil
+	}
+
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Rate is an exchange rate with eight decimal places, matching NUMERIC(18,8)
+type Rate int64
+
+const rateScale = 100000000
+
+func ParseRate(s string) (Rate, error) {
+	v, err := parseFixed(s, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate: %w", err)
+	}
+	return Rate(v), nil
+}
+
+func (r Rate) String() string {
+	sign := ""
+	v := int64(r)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, v/rateScale, v%rateScale)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r *Rate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = 0
+		return nil
+	case []byte:
+		return r.scanString(string(v))
+	case string:
+		return r.scanString(v)
+	case int64:
+		scaled, ok := scaleWhole(v, rateScale)
+		if !ok {
+			return fmt.Errorf("%w: rate %d", ErrAmountOverflow, v)
+		}
+		*r = Rate(scaled)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Rate", src)
+	}
+}
+
+func (r *Rate) scanString(s string) error {
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// scales a whole number read from the database to fixed point, ok is false when the result does not fit an int64
+func scaleWhole(v, scale int64) (int64, bool) {
+	product := new(big.Int).Mul(big.NewInt(v), big.NewInt(scale))
+	return product.Int64(), product.IsInt64()
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,356 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Amount
+		wantErr bool
+	}{
+		{name: "whole", input: "12", want: 1200},
+		{name: "one decimal", input: "12.5", want: 1250},
+		{name: "two decimals", input: "12.34", want: 1234},
+		{name: "leading dot", input: ".75", want: 75},
+		{name: "trailing dot", input: "3.", want: 300},
+		{name: "negative", input: "-0.75", want: -75},
+		{name: "explicit plus", input: "+1.01", want: 101},
+		{name: "surrounding space", input: " 4.20 ", want: 420},
+		{name: "zero", input: "0.00", want: 0},
+		{name: "more than two decimals", input: "1.005", wantErr: true},
+		{name: "trailing zero past two decimals", input: "1.000", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "sign only", input: "-", wantErr: true},
+		{name: "dot only", input: ".", wantErr: true},
+		{name: "letters", input: "1a.00", wantErr: true},
+		{name: "exponent", input: "1e3", wantErr: true},
+		{name: "double sign", input: "--1", wantErr: true},
+		{name: "largest", input: "92233720368547758.07", want: math.MaxInt64},
+		{name: "out of range", input: "92233720368547758.08", wantErr: true},
+		{name: "whole out of range", input: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAmount(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseAmount(%q) = %s, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAmount(%q) returned %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAmount(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{amount: 0, want: "0.00"},
+		{amount: 5, want: "0.05"},
+		{amount: 1250, want: "12.50"},
+		{amount: -75, want: "-0.75"},
+		{amount: -1234, want: "-12.34"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(tt.amount), got, tt.want)
+		}
+	}
+}
+
+func TestAmountMul(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		quantity int64
+		want     Amount
+		overflow bool
+	}{
+		{name: "zero quantity", amount: 1999, quantity: 0, want: 0},
+		{name: "units", amount: 1999, quantity: 3, want: 5997},
+		{name: "negative amount", amount: -250, quantity: 4, want: -1000},
+		{name: "negative quantity", amount: 250, quantity: -4, want: -1000},
+		{name: "largest", amount: math.MaxInt64 / 2, quantity: 2, want: math.MaxInt64 - 1},
+		{name: "overflow", amount: math.MaxInt64 / 2, quantity: 3, overflow: true},
+		{name: "negative overflow", amount: math.MinInt64 / 2, quantity: 3, overflow: true},
+		{name: "max price times max quantity", amount: 999999999999, quantity: math.MaxInt32, overflow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.amount.Mul(tt.quantity)
+			if tt.overflow {
+				if !errors.Is(err, ErrAmountOverflow) {
+					t.Fatalf("Mul(%d) = %d, %v, want ErrAmountOverflow", tt.quantity, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Mul(%d) returned %v", tt.quantity, err)
+			}
+			if got != tt.want {
+				t.Errorf("Mul(%d) = %d, want %d", tt.quantity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountMulRat(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      Amount
+		numerator   int64
+		denominator int64
+		want        Amount
+		overflow    bool
+	}{
+		{name: "exact", amount: 1000, numerator: 15, denominator: 100, want: 150},
+		{name: "rounds down below half", amount: 333, numerator: 10, denominator: 100, want: 33},
+		{name: "rounds half up", amount: 5, numerator: 1, denominator: 2, want: 3},
+		{name: "rounds above half up", amount: 999, numerator: 1, denominator: 3, want: 333},
+		{name: "two thirds", amount: 100, numerator: 2, denominator: 3, want: 67},
+		{name: "negative rounds half away from zero", amount: -5, numerator: 1, denominator: 2, want: -3},
+		{name: "negative below half", amount: -333, numerator: 10, denominator: 100, want: -33},
+		{name: "negative denominator", amount: 5, numerator: 1, denominator: -2, want: -3},
+		{name: "intermediate beyond int64", amount: math.MaxInt64, numerator: 3, denominator: 4, want: 6917529027641081855},
+		{name: "overflow", amount: math.MaxInt64, numerator: 3, denominator: 2, overflow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.amount.MulRat(tt.numerator, tt.denominator)
+			if tt.overflow {
+				if !errors.Is(err, ErrAmountOverflow) {
+					t.Fatalf("MulRat(%d, %d) = %d, %v, want ErrAmountOverflow", tt.numerator, tt.denominator, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MulRat(%d, %d) returned %v", tt.numerator, tt.denominator, err)
+			}
+			if got != tt.want {
+				t.Errorf("MulRat(%d, %d) = %d, want %d", tt.numerator, tt.denominator, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		rate     string
+		want     Amount
+		overflow bool
+	}{
+		{name: "identity", amount: "10.00", rate: "1", want: 1000},
+		{name: "usd to aed", amount: "10.00", rate: "3.6725", want: 3673},
+		{name: "rounds half up", amount: "1.00", rate: "0.125", want: 13},
+		{name: "rounds down below half", amount: "1.00", rate: "0.12499999", want: 12},
+		{name: "eight decimal rate", amount: "100.00", rate: "0.27229408", want: 2723},
+		{name: "negative", amount: "-1.00", rate: "0.125", want: -13},
+		{name: "overflow", amount: "90000000000000000.00", rate: "2", overflow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := ParseAmount(tt.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rate, err := ParseRate(tt.rate)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := amount.Convert(rate)
+			if tt.overflow {
+				if !errors.Is(err, ErrAmountOverflow) {
+					t.Fatalf("%s.Convert(%s) = %s, %v, want ErrAmountOverflow", tt.amount, tt.rate, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s.Convert(%s) returned %v", tt.amount, tt.rate, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s.Convert(%s) = %s, want %s", tt.amount, tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Amount
+		wantErr bool
+	}{
+		{name: "nil", src: nil, want: 0},
+		{name: "numeric text", src: []byte("12.34"), want: 1234},
+		{name: "string", src: "12.3", want: 1230},
+		{name: "whole", src: "7", want: 700},
+		{name: "int64", src: int64(7), want: 700},
+		{name: "int64 overflow", src: int64(math.MaxInt64/100 + 1), wantErr: true},
+		{name: "negative int64 overflow", src: int64(math.MinInt64 / 10), wantErr: true},
+		{name: "float64", src: float64(12.34), want: 1234},
+		{name: "extra decimals round down", src: "1.234", want: 123},
+		{name: "extra decimals round half up", src: "1.235", want: 124},
+		{name: "extra decimals carry", src: "1.995", want: 200},
+		{name: "negative extra decimals round away from zero", src: "-1.235", want: -124},
+		{name: "negative extra decimals round down", src: "-1.234", want: -123},
+		{name: "computed average", src: []byte("33.3333333333333333"), want: 3333},
+		{name: "not a number", src: "abc", wantErr: true},
+		{name: "unsupported type", src: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Amount
+			err := got.Scan(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) = %s, want an error", tt.src, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v) returned %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Amount
+		wantErr bool
+	}{
+		{name: "string", input: `"12.50"`, want: 1250},
+		{name: "number", input: `12.50`, want: 1250},
+		{name: "whole number", input: `12`, want: 1200},
+		{name: "negative number", input: `-0.75`, want: -75},
+		{name: "negative string", input: `"-0.75"`, want: -75},
+		{name: "number with more than two decimals", input: `12.345`, wantErr: true},
+		{name: "string with more than two decimals", input: `"12.345"`, wantErr: true},
+		{name: "exponent number", input: `1e2`, wantErr: true},
+		{name: "boolean", input: `true`, wantErr: true},
+		{name: "null keeps the value", input: `null`, want: 99},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Amount(99)
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %s, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) returned %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	data, err := json.Marshal(struct {
+		Price Amount `json:"price"`
+	}{Price: -1205})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"price":"-12.05"}` {
+		t.Errorf("Marshal = %s, want the amount as a string", data)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Rate
+		wantErr bool
+	}{
+		{input: "1", want: 100000000},
+		{input: "3.6725", want: 367250000},
+		{input: "0.00000001", want: 1},
+		{input: "0.000000001", wantErr: true},
+		{input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRate(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRate(%q) = %s, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRate(%q) returned %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRateScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Rate
+		wantErr bool
+	}{
+		{name: "nil", src: nil, want: 0},
+		{name: "numeric text", src: []byte("3.6725"), want: 367250000},
+		{name: "int64", src: int64(4), want: 400000000},
+		{name: "int64 overflow", src: int64(math.MaxInt64/100000000 + 1), wantErr: true},
+		{name: "unsupported type", src: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Rate
+			err := got.Scan(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) = %s, want an error", tt.src, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v) returned %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package types
+
+import "time"
+
+// enum type PriceChangeStatus
+type PriceChangeStatus string
+
+const (
+	PriceChangeStatusScheduled PriceChangeStatus = "price.scheduled"
+	PriceChangeStatusApplied   PriceChangeStatus = "price.applied"
+	PriceChangeStatusCancelled PriceChangeStatus = "price.cancelled"
+	PriceChangeStatusFailed    PriceChangeStatus = "price.failed"
+)
+
+// PriceChange is a price a product had or will have in a currency. Applied changes make up the price history
+// and took effect at AppliedAt, a scheduled one is applied once EffectiveFrom passes. A nil Price removes the
+// explicit price of a currency other than the product's own, orders convert the base price again
+type PriceChange struct {
+	ID            int64             `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ProductID     int64             `json:"product_id" gorm:"column:product_id;not null"`
+	Currency      string            `json:"currency" gorm:"column:currency;not null"`
+	Price         *Amount           `json:"price" gorm:"column:price;type:numeric(12,2)"`
+	PreviousPrice *Amount           `json:"previous_price,omitempty" gorm:"column:previous_price;type:numeric(12,2)"`
+	Status        PriceChangeStatus `json:"status" gorm:"column:status;type:price_change_status;default:'price.scheduled'"`
+
+	// a sale ends at EndsAt, the price it replaced is scheduled back for then when it is applied
+	EffectiveFrom   time.Time  `json:"effective_from" gorm:"column:effective_from;not null"`
+	EndsAt          *time.Time `json:"ends_at,omitempty" gorm:"column:ends_at"`
+	RevertsChangeID *int64     `json:"reverts_change_id,omitempty" gorm:"column:reverts_change_id"`
+	AppliedAt       *time.Time `json:"applied_at,omitempty" gorm:"column:applied_at"`
+
+	Actor string `json:"actor" gorm:"column:actor;not null"`
+	Note  string `json:"note,omitempty" gorm:"column:note;default:null"`
+
+	// why a failed change could not be applied
+	Error string `json:"error,omitempty" gorm:"column:error;default:null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// AppliedPriceChange records a price that takes effect right away
+func AppliedPriceChange(productID int64, currency string, price, previousPrice *Amount, actor, note string) *PriceChange {
+	now := time.Now()
+	return &PriceChange{
+		ProductID:     productID,
+		Currency:      currency,
+		Price:         price,
+		PreviousPrice: previousPrice,
+		Status:        PriceChangeStatusApplied,
+		EffectiveFrom: now,
+		AppliedAt:     &now,
+		Actor:         actor,
+		Note:          note,
+	}
+}
@@ -0,0 +1,431 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// enum type ProductImportStatus
+type ProductImportStatus string
+
+const (
+	ProductImportStatusPending   ProductImportStatus = "import.pending"
+	ProductImportStatusRunning   ProductImportStatus = "import.running"
+	ProductImportStatusCompleted ProductImportStatus = "import.completed"
+	ProductImportStatusFailed    ProductImportStatus = "import.failed"
+)
+
+// enum type ProductFileFormat
+type ProductFileFormat string
+
+const (
+	ProductFileFormatCSV   ProductFileFormat = "csv"
+	ProductFileFormatJSONL ProductFileFormat = "jsonl"
+)
+
+func (f ProductFileFormat) IsValid() bool {
+	return f == ProductFileFormatCSV || f == ProductFileFormatJSONL
+}
+
+// an uploaded catalog file, every row is upserted by sku. Payload holds the file until the job finishes
+type ProductImport struct {
+	ID       int64               `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Format   ProductFileFormat   `json:"format" gorm:"column:format;type:product_file_format;not null"`
+	FileName string              `json:"file_name,omitempty" gorm:"column:file_name;default:null"`
+	Status   ProductImportStatus `json:"status" gorm:"column:status;type:product_import_status;default:'import.pending'"`
+	Payload  *string             `json:"-" gorm:"column:payload"`
+
+	TotalRows     int64  `json:"total_rows" gorm:"column:total_rows;not null;default:0"`
+	ProcessedRows int64  `json:"processed_rows" gorm:"column:processed_rows;not null;default:0"`
+	CreatedCount  int64  `json:"created_count" gorm:"column:created_count;not null;default:0"`
+	UpdatedCount  int64  `json:"updated_count" gorm:"column:updated_count;not null;default:0"`
+	FailedCount   int64  `json:"failed_count" gorm:"column:failed_count;not null;default:0"`
+	Error         string `json:"error,omitempty" gorm:"column:error;default:null"`
+	CreatedBy     string `json:"created_by" gorm:"column:created_by;not null"`
+
+	StartedAt  *time.Time `json:"started_at,omitempty" gorm:"column:started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" gorm:"column:finished_at"`
+	CreatedAt  time.Time  `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// a row of an import that could not be applied
+type ProductImportError struct {
+	ID        int64     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ImportID  int64     `json:"import_id" gorm:"column:import_id;not null"`
+	RowNumber int64     `json:"row_number" gorm:"column:row_number;not null"`
+	SKU       string    `json:"sku,omitempty" gorm:"column:sku;default:null"`
+	Message   string    `json:"message" gorm:"column:message;not null"`
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+}
+
+// ProductRecord is one row of a catalog file, the same shape is exported and imported. On import a missing
+// value leaves the product's value as is, a row with a parent sku is a variant whose price is its override.
+// Currency and category are only exported, products are filed by category id
+type ProductRecord struct {
+	SKU             string            `json:"sku"`
+	Name            *string           `json:"name,omitempty"`
+	Price           *Amount           `json:"price,omitempty"`
+	Currency        string            `json:"currency,omitempty"`
+	CategoryID      *int64            `json:"category_id,omitempty"`
+	Category        string            `json:"category,omitempty"`
+	StockQuantity   *int64            `json:"stock_quantity,omitempty"`
+	ReorderPoint    *int64            `json:"reorder_point,omitempty"`
+	ReorderQuantity *int64            `json:"reorder_quantity,omitempty"`
+	IsSerialized    *bool             `json:"is_serialized,omitempty"`
+	OptionAxes      []string          `json:"option_axes,omitempty"`
+	ParentSKU       string            `json:"parent_sku,omitempty"`
+	Options         ProductOptions    `json:"options,omitempty"`
+	Attributes      ProductAttributes `json:"attributes,omitempty"`
+}
+
+// the columns of a catalog csv, option_axes are separated by | and options and attributes are json objects
+var productRecordColumns = []string{
+	"sku", "name", "price", "currency", "category_id", "category", "stock_quantity", "reorder_point",
+	"reorder_quantity", "is_serialized", "option_axes", "parent_sku", "options", "attributes",
+}
+
+// NewProductRecord is the exported row of a product, parentSKU is set for a variant. A parent's stock is
+// left out as it holds none
+func NewProductRecord(product *Product, parentSKU string) ProductRecord {
+	record := ProductRecord{
+		SKU:             product.SKU,
+		Name:            &product.Name,
+		Price:           &product.Price,
+		Currency:        product.Currency,
+		CategoryID:      product.CategoryID,
+		Category:        product.Category,
+		StockQuantity:   &product.StockQuantity,
+		ReorderPoint:    &product.ReorderPoint,
+		ReorderQuantity: &product.ReorderQuantity,
+		IsSerialized:    &product.IsSerialized,
+		OptionAxes:      product.OptionAxes,
+		Attributes:      product.Attributes,
+	}
+	if product.HasVariants() {
+		record.StockQuantity = nil
+	}
+	if product.IsVariant() {
+		record.ParentSKU = parentSKU
+		record.Options = product.Options
+		record.Price = product.PriceOverride
+	}
+	return record
+}
+
+func (r ProductRecord) IsVariant() bool {
+	return r.ParentSKU != ""
+}
+
+// Validate checks a row on its own, whether it can be applied depends on the catalog
+func (r ProductRecord) Validate() error {
+	if r.SKU == "" {
+		return fmt.Errorf("sku is required")
+	}
+	for _, c := range r.SKU {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			return fmt.Errorf("sku %s must be alphanumeric", r.SKU)
+		}
+	}
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		return fmt.Errorf("name cannot be blank")
+	}
+	if r.Price != nil && r.Price.IsNegative() {
+		return fmt.Errorf("price cannot be negative")
+	}
+	if r.CategoryID != nil && *r.CategoryID < 1 {
+		return fmt.Errorf("category_id must be positive")
+	}
+	for name, quantity := range map[string]*int64{"stock_quantity": r.StockQuantity, "reorder_point": r.ReorderPoint, "reorder_quantity": r.ReorderQuantity} {
+		if quantity != nil && *quantity < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if r.IsVariant() && len(r.OptionAxes) > 0 {
+		return fmt.Errorf("a variant cannot have option axes of its own")
+	}
+	return nil
+}
+
+// ReadProductRecords calls fn with every row of a catalog file in order, rowNumber counts the data rows
+// from 1. A row that can't be decoded is passed on with its error, a file whose header can't be read fails
+// as a whole. Reading stops at the first error fn returns
+func ReadProductRecords(format ProductFileFormat, r io.Reader, fn func(rowNumber int64, record ProductRecord, err error) error) error {
+	switch format {
+	case ProductFileFormatCSV:
+		return readProductCSV(r, fn)
+	case ProductFileFormatJSONL:
+		return readProductJSONL(r, fn)
+	default:
+		return fmt.Errorf("unknown file format %s", format)
+	}
+}
+
+func readProductCSV(r io.Reader, fn func(int64, ProductRecord, error) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the header %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["sku"]; !ok {
+		return fmt.Errorf("the header has no sku column")
+	}
+
+	var rowNumber int64
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		rowNumber++
+
+		var record ProductRecord
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			err = fmt.Errorf("malformed row %v", parseErr.Err)
+		} else if err != nil {
+			return err
+		} else {
+			record, err = decodeProductCSV(columns, row)
+		}
+
+		if err := fn(rowNumber, record, err); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeProductCSV(columns map[string]int, row []string) (ProductRecord, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	integer := func(name string) (*int64, error) {
+		if cell(name) == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseInt(cell(name), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number", name)
+		}
+		return &v, nil
+	}
+
+	record := ProductRecord{
+		SKU:       cell("sku"),
+		Currency:  cell("currency"),
+		Category:  cell("category"),
+		ParentSKU: cell("parent_sku"),
+	}
+
+	if name := cell("name"); name != "" {
+		record.Name = &name
+	}
+	if cell("price") != "" {
+		price, err := ParseAmount(cell("price"))
+		if err != nil {
+			return record, fmt.Errorf("price must be a decimal amount")
+		}
+		record.Price = &price
+	}
+
+	var err error
+	if record.CategoryID, err = integer("category_id"); err != nil {
+		return record, err
+	}
+	if record.StockQuantity, err = integer("stock_quantity"); err != nil {
+		return record, err
+	}
+	if record.ReorderPoint, err = integer("reorder_point"); err != nil {
+		return record, err
+	}
+	if record.ReorderQuantity, err = integer("reorder_quantity"); err != nil {
+		return record, err
+	}
+
+	if cell("is_serialized") != "" {
+		serialized, err := strconv.ParseBool(cell("is_serialized"))
+		if err != nil {
+			return record, fmt.Errorf("is_serialized must be true or false")
+		}
+		record.IsSerialized = &serialized
+	}
+	if cell("option_axes") != "" {
+		for _, axis := range strings.Split(cell("option_axes"), "|") {
+			record.OptionAxes = append(record.OptionAxes, strings.TrimSpace(axis))
+		}
+	}
+	if cell("options") != "" {
+		if err := json.Unmarshal([]byte(cell("options")), &record.Options); err != nil {
+			return record, fmt.Errorf("options must be a json object of strings")
+		}
+	}
+	if cell("attributes") != "" {
+		if err := json.Unmarshal([]byte(cell("attributes")), &record.Attributes); err != nil {
+			return record, fmt.Errorf("attributes must be a json object")
+		}
+	}
+
+	return record, nil
+}
+
+// every non blank line is one row, so a malformed line fails on its own
+func readProductJSONL(r io.Reader, fn func(int64, ProductRecord, error) error) error {
+	reader := bufio.NewReader(r)
+
+	var rowNumber int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			rowNumber++
+
+			var record ProductRecord
+			err := json.Unmarshal(line, &record)
+			if err != nil {
+				err = fmt.Errorf("malformed row %v", err)
+			}
+			if err := fn(rowNumber, record, err); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// ProductRecordWriter writes catalog rows as csv, header first, or as json lines
+type ProductRecordWriter struct {
+	format  ProductFileFormat
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+}
+
+func NewProductRecordWriter(format ProductFileFormat, w io.Writer) *ProductRecordWriter {
+	writer := &ProductRecordWriter{format: format}
+	if format == ProductFileFormatCSV {
+		writer.csv = csv.NewWriter(w)
+	} else {
+		writer.json = json.NewEncoder(w)
+	}
+	return writer
+}
+
+func (w *ProductRecordWriter) Write(record ProductRecord) error {
+	if w.json != nil {
+		return w.json.Encode(record)
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	var options, attributes string
+	if len(record.Options) > 0 {
+		data, err := json.Marshal(record.Options)
+		if err != nil {
+			return err
+		}
+		options = string(data)
+	}
+	if len(record.Attributes) > 0 {
+		data, err := json.Marshal(record.Attributes)
+		if err != nil {
+			return err
+		}
+		attributes = string(data)
+	}
+
+	return w.csv.Write([]string{
+		record.SKU,
+		stringCell(record.Name),
+		amountCell(record.Price),
+		record.Currency,
+		intCell(record.CategoryID),
+		record.Category,
+		intCell(record.StockQuantity),
+		intCell(record.ReorderPoint),
+		intCell(record.ReorderQuantity),
+		boolCell(record.IsSerialized),
+		strings.Join(record.OptionAxes, "|"),
+		record.ParentSKU,
+		options,
+		attributes,
+	})
+}
+
+// Flush writes out what is buffered, a csv without rows still gets its header
+func (w *ProductRecordWriter) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *ProductRecordWriter) writeHeader() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.csv.Write(productRecordColumns)
+}
+
+func stringCell(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func amountCell(v *Amount) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+func intCell(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func boolCell(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
@@ -0,0 +1,38 @@
+package types
+
+// what the customer paid for the line: the units less their discount, plus the tax when it was charged on
+// top of the price
+func (i OrderItem) PaidTotal() (Amount, error) {
+	total, err := i.Price.Mul(int64(i.Quantity))
+	if err != nil {
+		return 0, err
+	}
+	total = total.Sub(i.DiscountAmount)
+	if !i.TaxInclusive {
+		total = total.Add(i.TaxAmount)
+	}
+	return total, nil
+}
+
+// the refund for returning quantity units of the line after alreadyReturned units were returned before.
+// Each refund is the difference of the prorated totals before and after it, so partial returns add up to
+// exactly the paid total once every unit is back and never to more
+func (i OrderItem) RefundFor(alreadyReturned, quantity int32) (Amount, error) {
+	if i.Quantity == 0 {
+		return 0, nil
+	}
+
+	paid, err := i.PaidTotal()
+	if err != nil {
+		return 0, err
+	}
+	before, err := paid.MulRat(int64(alreadyReturned), int64(i.Quantity))
+	if err != nil {
+		return 0, err
+	}
+	after, err := paid.MulRat(int64(alreadyReturned+quantity), int64(i.Quantity))
+	if err != nil {
+		return 0, err
+	}
+	return after.Sub(before), nil
+}
@@ -0,0 +1,64 @@
+package types
+
+import "testing"
+
+func TestOrderItemRefundFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    OrderItem
+		returns []int32
+		want    []Amount
+	}{
+		{
+			name:    "discount and exclusive tax",
+			item:    OrderItem{Quantity: 2, Price: 5000, DiscountAmount: 1000, TaxAmount: 450},
+			returns: []int32{1, 1},
+			want:    []Amount{4725, 4725},
+		},
+		{
+			name:    "inclusive tax is already in the price",
+			item:    OrderItem{Quantity: 2, Price: 5000, DiscountAmount: 1000, TaxAmount: 429, TaxInclusive: true},
+			returns: []int32{2},
+			want:    []Amount{9000},
+		},
+		{
+			name:    "thirds add up to the paid total",
+			item:    OrderItem{Quantity: 3, Price: 1000, DiscountAmount: 1},
+			returns: []int32{1, 1, 1},
+			want:    []Amount{1000, 999, 1000},
+		},
+		{
+			name:    "uneven split",
+			item:    OrderItem{Quantity: 3, Price: 333, DiscountAmount: 0, TaxAmount: 50},
+			returns: []int32{2, 1},
+			want:    []Amount{699, 350},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paid, err := tt.item.PaidTotal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var returned int32
+			var refunded Amount
+			for i, quantity := range tt.returns {
+				got, err := tt.item.RefundFor(returned, quantity)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want[i] {
+					t.Errorf("return %d of %d units refunds %s, want %s", i, quantity, got, tt.want[i])
+				}
+				returned += quantity
+				refunded = refunded.Add(got)
+			}
+
+			if refunded != paid {
+				t.Errorf("refunds add up to %s, want the paid total %s", refunded, paid)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package types
+
+type ProductSearchParams struct {
+	// free text matched against name, sku, category and attribute values, every word as a prefix
+	Query       string            `json:"query"`
+	CategoryIDs []int64           `json:"category_ids"`
+	Attributes  []AttributeFilter `json:"attributes"`
+	Limit       int               `json:"limit"`
+	Offset      int               `json:"offset"`
+
+	// matches names by trigram similarity instead of full text, for queries with typos
+	Fuzzy          bool    `json:"fuzzy"`
+	FuzzyThreshold float64 `json:"fuzzy_threshold"`
+
+	// the currency the price bands are counted in
+	Currency string `json:"currency"`
+}
+
+// ProductFacets count the products of a search result by category and by price band, over the whole
+// result and not only the page returned. Products are put in a price band by what they cost in
+// PriceBandCurrency the way an order prices them: their own price, a price list entry or a conversion at the
+// configured rate. Unpriced counts the products that have none of these and are in no band
+type ProductFacets struct {
+	Categories        []CategoryFacet  `json:"categories"`
+	PriceBands        []PriceBandFacet `json:"price_bands"`
+	PriceBandCurrency string           `json:"price_band_currency,omitempty"`
+	Unpriced          int64            `json:"unpriced,omitempty"`
+}
+
+type CategoryFacet struct {
+	CategoryID *int64 `json:"category_id"`
+	Name       string `json:"name"`
+	Count      int64  `json:"count"`
+}
+
+// a band takes prices from Min up to but not including Max, the last band has no Max
+type PriceBandFacet struct {
+	Min   Amount  `json:"min"`
+	Max   *Amount `json:"max,omitempty"`
+	Count int64   `json:"count"`
+}
+
+type ProductSearchResult struct {
+	Groups []ProductGroup `json:"products"`
+	Total  int64          `json:"total"`
+	Facets ProductFacets  `json:"facets"`
+
+	// set when nothing matched the full text query and names were matched by similarity instead
+	Fuzzy bool `json:"fuzzy"`
+}
+
+// NewPriceBandFacets lays the counts per band over the bands the bounds make up, bounds ascend and counts are
+// keyed by band index: 0 is below the first bound and len(bounds) at or above the last one. Empty bands are
+// listed too
+func NewPriceBandFacets(bounds []Amount, counts map[int]int64) []PriceBandFacet {
+	if len(bounds) == 0 {
+		return nil
+	}
+
+	facets := make([]PriceBandFacet, 0, len(bounds)+1)
+	var lower Amount
+	for i, bound := range bounds {
+		upper := bound
+		facets = append(facets, PriceBandFacet{Min: lower, Max: &upper, Count: counts[i]})
+		lower = bound
+	}
+	return append(facets, PriceBandFacet{Min: lower, Count: counts[len(bounds)]})
+}
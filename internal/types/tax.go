@@ -0,0 +1,174 @@
+package types
+
+import (
+	"slices"
+	"strings"
+)
+
+// the discount share and tax worked out for one order line
+type LinePricing struct {
+	Discount  Amount
+	RuleName  string
+	Rate      Rate
+	Inclusive bool
+	Tax       Amount
+}
+
+// the result of pricing an order, Discounts is aligned with the coupons and Lines with the order lines
+type OrderPricing struct {
+	Subtotal  Amount
+	Discounts []Amount
+	Discount  Amount
+	Lines     []LinePricing
+	Tax       Amount
+	Total     Amount
+}
+
+// picks the most specific active rule for a line: a longer region wins, then a deeper category. A rule for
+// "AE" also covers the subdivisions "AE-DU" and "AE-AZ", a rule for a category also covers the categories
+// below it. categoryPath holds the ids of the line's category and its ancestors, the root first
+func MatchTaxRule(rules []TaxRule, region string, categoryPath []int64) *TaxRule {
+	var best *TaxRule
+	bestRegion, bestDepth := -1, -1
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.IsActive {
+			continue
+		}
+		if rule.Region != "" && !regionMatches(rule.Region, region) {
+			continue
+		}
+
+		depth := 0
+		if rule.CategoryID != nil {
+			at := slices.Index(categoryPath, *rule.CategoryID)
+			if at < 0 {
+				continue
+			}
+			depth = at + 1
+		}
+
+		if len(rule.Region) > bestRegion || (len(rule.Region) == bestRegion && depth > bestDepth) {
+			best = rule
+			bestRegion, bestDepth = len(rule.Region), depth
+		}
+	}
+
+	return best
+}
+
+func regionMatches(ruleRegion, region string) bool {
+	if strings.EqualFold(ruleRegion, region) {
+		return true
+	}
+	return len(region) > len(ruleRegion) && strings.EqualFold(region[:len(ruleRegion)+1], ruleRegion+"-")
+}
+
+// prices an order: coupons are applied first, their discounts are spread over the lines they
+// apply to, and tax is worked out per line on the discounted amount
+func PriceOrder(lines []DiscountableLine, coupons []Coupon, currency, region string, rules []TaxRule) (OrderPricing, error) {
+	pricing := OrderPricing{
+		Lines: make([]LinePricing, len(lines)),
+	}
+
+	totals := make([]Amount, len(lines))
+	for i, line := range lines {
+		total, err := line.Total()
+		if err != nil {
+			return OrderPricing{}, err
+		}
+		totals[i] = total
+		pricing.Subtotal = pricing.Subtotal.Add(total)
+	}
+
+	discounts, lineDiscounts, err := ApplyDiscounts(coupons, currency, lines)
+	if err != nil {
+		return OrderPricing{}, err
+	}
+	pricing.Discounts = discounts
+	for i := range coupons {
+		pricing.Discount = pricing.Discount.Add(pricing.Discounts[i])
+	}
+	for i := range lines {
+		pricing.Lines[i].Discount = lineDiscounts[i]
+	}
+
+	var exclusiveTax Amount
+	for i, line := range lines {
+		rule := MatchTaxRule(rules, region, line.CategoryPath)
+		if rule == nil {
+			continue
+		}
+
+		taxable := totals[i].Sub(pricing.Lines[i].Discount)
+		if taxable.IsNegative() {
+			taxable = 0
+		}
+
+		var tax Amount
+		var err error
+		if rule.Inclusive {
+			// the price already contains the tax: tax = gross * rate / (1 + rate)
+			tax, err = taxable.MulRat(int64(rule.Rate), rateScale+int64(rule.Rate))
+		} else {
+			tax, err = taxable.Convert(rule.Rate)
+		}
+		if err != nil {
+			return OrderPricing{}, err
+		}
+		if !rule.Inclusive {
+			exclusiveTax = exclusiveTax.Add(tax)
+		}
+
+		pricing.Lines[i].RuleName = rule.Name
+		pricing.Lines[i].Rate = rule.Rate
+		pricing.Lines[i].Inclusive = rule.Inclusive
+		pricing.Lines[i].Tax = tax
+		pricing.Tax = pricing.Tax.Add(tax)
+	}
+
+	pricing.Total = pricing.Subtotal.Sub(pricing.Discount).Add(exclusiveTax)
+	return pricing, nil
+}
+
+// groups the per line tax of an order by rule for display
+func BuildTaxBreakdown(items []OrderItem) ([]TaxBreakdownLine, error) {
+	var breakdown []TaxBreakdownLine
+	index := make(map[string]int)
+
+	for _, item := range items {
+		if item.TaxRuleName == "" {
+			continue
+		}
+
+		total, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return nil, err
+		}
+		taxable := total.Sub(item.DiscountAmount)
+		if item.TaxInclusive {
+			taxable = taxable.Sub(item.TaxAmount)
+		}
+
+		key := item.TaxRuleName + "|" + item.TaxRate.String()
+		if item.TaxInclusive {
+			key += "|inclusive"
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(breakdown)
+			index[key] = i
+			breakdown = append(breakdown, TaxBreakdownLine{
+				RuleName:  item.TaxRuleName,
+				Rate:      item.TaxRate,
+				Inclusive: item.TaxInclusive,
+			})
+		}
+		breakdown[i].TaxableAmount = breakdown[i].TaxableAmount.Add(taxable)
+		breakdown[i].TaxAmount = breakdown[i].TaxAmount.Add(item.TaxAmount)
+	}
+
+	return breakdown, nil
+}
@@ -0,0 +1,39 @@
+package types
+
+import "testing"
+
+func TestMatchTaxRule(t *testing.T) {
+	rules := []TaxRule{
+		{Name: "AE standard", Region: "AE", Rate: 5000000, IsActive: true},
+		{Name: "AE electronics", Region: "AE", CategoryID: ptr(electronics), Rate: 7000000, IsActive: true},
+		{Name: "AE phones", Region: "AE", CategoryID: ptr(phones), Rate: 9000000, IsActive: true},
+		{Name: "Dubai", Region: "AE-DU", Rate: 6000000, IsActive: true},
+		{Name: "Books inactive", Region: "AE", CategoryID: ptr(books), Rate: 0, IsActive: false},
+	}
+
+	tests := []struct {
+		name         string
+		region       string
+		categoryPath []int64
+		want         string
+	}{
+		{name: "no category", region: "AE", want: "AE standard"},
+		{name: "category rule covers its subcategories", region: "AE", categoryPath: []int64{electronics, 4}, want: "AE electronics"},
+		{name: "deepest category wins", region: "AE", categoryPath: []int64{electronics, phones}, want: "AE phones"},
+		{name: "inactive rule is skipped", region: "AE", categoryPath: []int64{books}, want: "AE standard"},
+		{name: "longer region wins over a category", region: "AE-DU", categoryPath: []int64{electronics, phones}, want: "Dubai"},
+		{name: "other region", region: "SA", categoryPath: []int64{electronics}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if rule := MatchTaxRule(rules, tt.region, tt.categoryPath); rule != nil {
+				got = rule.Name
+			}
+			if got != tt.want {
+				t.Errorf("MatchTaxRule(%q, %v) = %q, want %q", tt.region, tt.categoryPath, got, tt.want)
+			}
+		})
+	}
+}
@@ -19,10 +19,40 @@ type OrderItemRequest struct {
     Quantity  int32 `json:"quantity"`
 }
 
+// optional settings for a new order, zero values fall back to the defaults
+type CreateOrderOptions struct {
+	Currency    string
+	TaxRegion   string
+	CouponCodes []string
+
+	// address book entries of the user, the default shipping and billing addresses are used when zero
+	ShippingAddressID int64
+	BillingAddressID  int64
+}
+
 type OrderWithDetails struct {
 	Order Order `json:"order,omitempty"`
 	Items []OrderItem `json:"items,omitempty"`
 	User *User	`json:"user,omitempty"`
+	Returns []OrderReturnWithItems `json:"returns,omitempty"`
+	Discounts []OrderDiscount `json:"discounts,omitempty"`
+	TaxBreakdown []TaxBreakdownLine `json:"tax_breakdown,omitempty"`
+}
+
+type ReturnItemRequest struct {
+	OrderItemID int64 `json:"order_item_id"`
+	Quantity    int32 `json:"quantity"`
+}
+
+// the serial numbers of the units of an order item that came back, required to restock serialized products
+type ReturnReceiptItem struct {
+	OrderItemID   int64    `json:"order_item_id"`
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+type OrderReturnWithItems struct {
+	Return OrderReturn       `json:"return"`
+	Items  []OrderReturnItem `json:"items,omitempty"`
 }
 
 type OrderSearchParams struct {
@@ -31,6 +61,7 @@ type OrderSearchParams struct {
     CustomerName string      `json:"customer_name"`
     ItemName     string      `json:"item_name"`
     Status       OrderStatus `json:"status"`
+    ReturnStatus ReturnStatus `json:"return_status"`
     Limit        int         `json:"limit"`
     Offset       int         `json:"offset"`
 }
@@ -50,14 +81,58 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
 }
 
+type UserAddress struct {
+	ID     int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	UserID int64 `json:"user_id" gorm:"column:user_id;not null;index"`
+
+	Label         string `json:"label,omitempty" gorm:"column:label;default:null"`
+	RecipientName string `json:"recipient_name" gorm:"column:recipient_name;not null"`
+	Phone         string `json:"phone,omitempty" gorm:"column:phone;default:null"`
+	Line1         string `json:"line1" gorm:"column:line1;not null"`
+	Line2         string `json:"line2,omitempty" gorm:"column:line2;default:null"`
+	City          string `json:"city" gorm:"column:city;not null"`
+	State         string `json:"state,omitempty" gorm:"column:state;default:null"`
+	PostalCode    string `json:"postal_code,omitempty" gorm:"column:postal_code;default:null"`
+	Country       string `json:"country" gorm:"column:country;not null"`
+
+	IsDefaultShipping bool `json:"is_default_shipping" gorm:"column:is_default_shipping;not null;default:false"`
+	IsDefaultBilling  bool `json:"is_default_billing" gorm:"column:is_default_billing;not null;default:false"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
 type Product struct {
 	ID int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
 
 	Name          string  `json:"name" gorm:"column:name;not null"`
 	SKU           string  `json:"sku" gorm:"column:sku;unique;not null"`
-	Price         float64 `json:"price" gorm:"column:price;not null"`
-	Category      string  `json:"category" gorm:"column:category"`
-	StockQuantity int64   `json:"stock_quantity" gorm:"column:stock_quantity;default:0"`
+	Price         Amount `json:"price" gorm:"column:price;type:numeric(12,2);not null"`
+	Currency      string `json:"currency" gorm:"column:currency;not null"`
+	Category      string `json:"category" gorm:"column:category"`
+	CategoryID    *int64 `json:"category_id,omitempty" gorm:"column:category_id"`
+	StockQuantity int64  `json:"stock_quantity" gorm:"column:stock_quantity;default:0"`
+
+	// maintained by the reservation queries only, available_quantity is a generated column
+	ReservedQuantity  int64 `json:"reserved_quantity" gorm:"column:reserved_quantity;->"`
+	AvailableQuantity int64 `json:"available_quantity" gorm:"column:available_quantity;->"`
+
+	// an alert is raised when the available quantity drops below ReorderPoint, 0 turns alerts off
+	ReorderPoint    int64 `json:"reorder_point" gorm:"column:reorder_point;not null;default:0"`
+	ReorderQuantity int64 `json:"reorder_quantity" gorm:"column:reorder_quantity;not null;default:0"`
+
+	// every unit of a serialized product carries a serial number that is assigned to the order item it ships with
+	IsSerialized bool `json:"is_serialized" gorm:"column:is_serialized;not null;default:false"`
+
+	// a parent lists the option axes of its variants, a variant points at its parent and has a value on
+	// every axis. Name, category and, without an override, price of a variant follow the parent
+	ParentID      *int64         `json:"parent_id,omitempty" gorm:"column:parent_id"`
+	OptionAxes    OptionAxes     `json:"option_axes,omitempty" gorm:"column:option_axes;type:jsonb"`
+	Options       ProductOptions `json:"options,omitempty" gorm:"column:options;type:jsonb"`
+	PriceOverride *Amount        `json:"price_override,omitempty" gorm:"column:price_override;type:numeric(12,2)"`
+
+	// checked against the attribute schema of the category on every write
+	Attributes ProductAttributes `json:"attributes,omitempty" gorm:"column:attributes;type:jsonb"`
 
 	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
@@ -67,17 +142,100 @@ type Product struct {
 type OrderStatus string
 
 const (
-	OrderStatusPending   OrderStatus = "order.pending"
-	OrderStatusShipped   OrderStatus = "order.shipped"
-	OrderStatusCancelled OrderStatus = "order.cancelled"
-	OrderStatusDelivered OrderStatus = "order.delivered"
+	OrderStatusPending          OrderStatus = "order.pending"
+	OrderStatusPartiallyShipped OrderStatus = "order.partially_shipped"
+	OrderStatusShipped          OrderStatus = "order.shipped"
+	OrderStatusCancelled        OrderStatus = "order.cancelled"
+	OrderStatusDelivered        OrderStatus = "order.delivered"
+)
+
+// allowed lifecycle transitions, terminal statuses map to an empty list
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:          {OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusPartiallyShipped: {OrderStatusShipped},
+	OrderStatusShipped:          {OrderStatusDelivered},
+	OrderStatusDelivered:        {},
+	OrderStatusCancelled:        {},
+}
+
+// fulfilment statuses are derived from shipments and cannot be set by hand
+func (s OrderStatus) IsFulfilmentStatus() bool {
+	return s == OrderStatusPartiallyShipped || s == OrderStatusShipped || s == OrderStatusDelivered
+}
+
+// returns the statuses an order in status s may be moved to by hand, the fulfilment statuses it reaches through
+// shipments are left out
+func (s OrderStatus) NextStatuses() []OrderStatus {
+	statuses := make([]OrderStatus, 0, len(orderStatusTransitions[s]))
+	for _, status := range orderStatusTransitions[s] {
+		if !status.IsFulfilmentStatus() {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderStatusTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderTransitions struct {
+	OrderID       int64         `json:"order_id"`
+	CurrentStatus OrderStatus   `json:"current_status"`
+	NextStatuses  []OrderStatus `json:"next_statuses"`
+
+	// a partially shipped order cannot be cancelled, only the part that has not shipped yet
+	CanCancelRemainder bool `json:"can_cancel_remainder"`
+}
+
+// enum type CancellationReason
+type CancellationReason string
+
+const (
+	CancellationReasonCustomerRequest CancellationReason = "customer_request"
+	CancellationReasonOutOfStock      CancellationReason = "out_of_stock"
+	CancellationReasonPaymentFailed   CancellationReason = "payment_failed"
+	CancellationReasonFraudSuspected  CancellationReason = "fraud_suspected"
+	CancellationReasonDuplicateOrder  CancellationReason = "duplicate_order"
+	CancellationReasonOther           CancellationReason = "other"
+
+	// set by the reservation sweeper when an unpaid order outlives its reservation
+	CancellationReasonReservationExpired CancellationReason = "reservation_expired"
 )
 
 type Order struct {
 	ID          int64       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
 	UserID      int64       `json:"user_id" gorm:"column:user_id;not null;index"`
 	Status      OrderStatus `json:"status" gorm:"column:status;type:order_status;default:'order.pending'"`
-	TotalAmount float64     `json:"total_amount" gorm:"column:total_amount;not null;default:0"`
+	TotalAmount Amount      `json:"total_amount" gorm:"column:total_amount;type:numeric(12,2);not null;default:0"`
+	Currency    string      `json:"currency" gorm:"column:currency;not null"`
+
+	// total_amount is subtotal_amount minus discount_amount plus the tax charged on top of exclusive prices,
+	// tax_amount also includes the tax already contained in inclusive prices
+	SubtotalAmount Amount `json:"subtotal_amount" gorm:"column:subtotal_amount;type:numeric(12,2);not null;default:0"`
+	DiscountAmount Amount `json:"discount_amount" gorm:"column:discount_amount;type:numeric(12,2);not null;default:0"`
+	TaxAmount      Amount `json:"tax_amount" gorm:"column:tax_amount;type:numeric(12,2);not null;default:0"`
+	TaxRegion      string `json:"tax_region,omitempty" gorm:"column:tax_region;default:null"`
+
+	ShippingAddress *OrderAddress `json:"shipping_address,omitempty" gorm:"column:shipping_address;type:jsonb"`
+	BillingAddress  *OrderAddress `json:"billing_address,omitempty" gorm:"column:billing_address;type:jsonb"`
+
+	// set only when line prices were converted from the base currency
+	FxBaseCurrency string `json:"fx_base_currency,omitempty" gorm:"column:fx_base_currency;default:null"`
+	FxRate         *Rate  `json:"fx_rate,omitempty" gorm:"column:fx_rate;type:numeric(18,8)"`
+
+	// on a shipped or delivered order they tell why the part that never shipped was cancelled
+	CancellationReason CancellationReason `json:"cancellation_reason,omitempty" gorm:"column:cancellation_reason;default:null"`
+	CancellationNote   string             `json:"cancellation_note,omitempty" gorm:"column:cancellation_note;default:null"`
+	CancelledAt        *time.Time         `json:"cancelled_at,omitempty" gorm:"column:cancelled_at"`
+
+	// paid orders keep their stock reservations until fulfilment
+	PaidAt *time.Time `json:"paid_at,omitempty" gorm:"column:paid_at"`
 
 	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
@@ -88,7 +246,641 @@ type OrderItem struct {
 	OrderID   int64 `json:"order_id" gorm:"column:order_id;not null;index"`
 	ProductID int64 `json:"product_id" gorm:"column:product_id;not null;index"`
 
-	Name     string  `json:"name" gorm:"column:name;not null"`
-	Quantity int32   `json:"quantity" gorm:"column:quantity;not null"`
-	Price    float64 `json:"price" gorm:"column:price;not null"`
-}
\ No newline at end of file
+	Name     string `json:"name" gorm:"column:name;not null"`
+	Quantity int32  `json:"quantity" gorm:"column:quantity;not null"`
+	Price    Amount `json:"price" gorm:"column:price;type:numeric(12,2);not null"`
+
+	// the share of the order discounts and the tax worked out for this line
+	DiscountAmount Amount `json:"discount_amount" gorm:"column:discount_amount;type:numeric(12,2);not null;default:0"`
+	TaxRuleName    string `json:"tax_rule_name,omitempty" gorm:"column:tax_rule_name;default:null"`
+	TaxRate        Rate   `json:"tax_rate" gorm:"column:tax_rate;type:numeric(18,8);not null;default:0"`
+	TaxAmount      Amount `json:"tax_amount" gorm:"column:tax_amount;type:numeric(12,2);not null;default:0"`
+	TaxInclusive   bool   `json:"tax_inclusive" gorm:"column:tax_inclusive;not null;default:false"`
+
+	// the warehouse the line is held and shipped from, chosen when the line is allocated
+	LocationID int64 `json:"location_id" gorm:"column:location_id;not null"`
+}
+
+// enum type ReturnStatus
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "return.requested"
+	ReturnStatusApproved  ReturnStatus = "return.approved"
+	ReturnStatusRejected  ReturnStatus = "return.rejected"
+	ReturnStatusReceived  ReturnStatus = "return.received"
+)
+
+var returnStatusTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnStatusRequested: {ReturnStatusApproved, ReturnStatusRejected},
+	ReturnStatusApproved:  {ReturnStatusReceived},
+	ReturnStatusRejected:  {},
+	ReturnStatusReceived:  {},
+}
+
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, status := range returnStatusTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderReturn struct {
+	ID      int64        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	OrderID int64        `json:"order_id" gorm:"column:order_id;not null;index"`
+	UserID  int64        `json:"user_id" gorm:"column:user_id;not null"`
+	Status  ReturnStatus `json:"status" gorm:"column:status;type:return_status;default:'return.requested'"`
+
+	Reason         string  `json:"reason" gorm:"column:reason;not null"`
+	ResolutionNote string  `json:"resolution_note,omitempty" gorm:"column:resolution_note;default:null"`
+	RefundAmount   Amount  `json:"refund_amount" gorm:"column:refund_amount;type:numeric(12,2);not null;default:0"`
+	Restocked      bool    `json:"restocked" gorm:"column:restocked;not null;default:false"`
+
+	ApprovedAt *time.Time `json:"approved_at,omitempty" gorm:"column:approved_at"`
+	RejectedAt *time.Time `json:"rejected_at,omitempty" gorm:"column:rejected_at"`
+	ReceivedAt *time.Time `json:"received_at,omitempty" gorm:"column:received_at"`
+	CreatedAt  time.Time  `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type OrderReturnItem struct {
+	ID          int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ReturnID    int64 `json:"return_id" gorm:"column:return_id;not null;index"`
+	OrderItemID int64 `json:"order_item_id" gorm:"column:order_item_id;not null;index"`
+	ProductID   int64 `json:"product_id" gorm:"column:product_id;not null"`
+
+	Quantity int32  `json:"quantity" gorm:"column:quantity;not null"`
+	Price    Amount `json:"price" gorm:"column:price;type:numeric(12,2);not null"`
+}
+
+type ShipmentItemRequest struct {
+	OrderItemID   int64    `json:"order_item_id"`
+	Quantity      int32    `json:"quantity"`
+	SerialNumbers []string `json:"serial_numbers,omitempty"`
+}
+
+type ShipmentWithItems struct {
+	Shipment Shipment       `json:"shipment"`
+	Items    []ShipmentItem `json:"items,omitempty"`
+}
+
+// enum type ShipmentStatus
+type ShipmentStatus string
+
+const (
+	ShipmentStatusInTransit ShipmentStatus = "shipment.in_transit"
+	ShipmentStatusDelivered ShipmentStatus = "shipment.delivered"
+)
+
+type Shipment struct {
+	ID             int64          `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	OrderID        int64          `json:"order_id" gorm:"column:order_id;not null;index"`
+	Carrier        string         `json:"carrier" gorm:"column:carrier;not null"`
+	TrackingNumber string         `json:"tracking_number" gorm:"column:tracking_number;not null"`
+	Status         ShipmentStatus `json:"status" gorm:"column:status;type:shipment_status;default:'shipment.in_transit'"`
+
+	ShippedAt   time.Time  `json:"shipped_at" gorm:"column:shipped_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" gorm:"column:delivered_at"`
+	CreatedAt   time.Time  `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type ShipmentItem struct {
+	ID          int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ShipmentID  int64 `json:"shipment_id" gorm:"column:shipment_id;not null;index"`
+	OrderItemID int64 `json:"order_item_id" gorm:"column:order_item_id;not null;index"`
+	Quantity    int32 `json:"quantity" gorm:"column:quantity;not null"`
+}
+type IdempotencyKey struct {
+	ID                 int64  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Scope              string `json:"scope" gorm:"column:scope;not null"`
+	Key                string `json:"idempotency_key" gorm:"column:idempotency_key;not null"`
+	RequestFingerprint string `json:"request_fingerprint" gorm:"column:request_fingerprint;not null"`
+	Completed          bool   `json:"completed" gorm:"column:completed;not null;default:false"`
+	ResponseStatus     int    `json:"response_status" gorm:"column:response_status;default:null"`
+	ResponseBody       string `json:"response_body" gorm:"column:response_body;type:jsonb;default:null"`
+
+	CreatedAt   time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"column:expires_at;not null"`
+	LockedUntil time.Time `json:"locked_until" gorm:"column:locked_until;not null"`
+}
+
+
+type ProductPrice struct {
+	ID        int64  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ProductID int64  `json:"product_id" gorm:"column:product_id;not null;index"`
+	Currency  string `json:"currency" gorm:"column:currency;not null"`
+	Price     Amount `json:"price" gorm:"column:price;type:numeric(12,2);not null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type ExchangeRate struct {
+	ID            int64  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	BaseCurrency  string `json:"base_currency" gorm:"column:base_currency;not null"`
+	QuoteCurrency string `json:"quote_currency" gorm:"column:quote_currency;not null"`
+	Rate          Rate   `json:"rate" gorm:"column:rate;type:numeric(18,8);not null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// enum type CouponType
+type CouponType string
+
+const (
+	CouponTypePercentage CouponType = "coupon.percentage"
+	CouponTypeFixed      CouponType = "coupon.fixed"
+	CouponTypeBuyXGetY   CouponType = "coupon.buy_x_get_y"
+)
+
+// the discount terms (type, values, category) are fixed once created so that
+// recalculating an order always reapplies the coupon the customer redeemed
+type Coupon struct {
+	ID          int64      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Code        string     `json:"code" gorm:"column:code;unique;not null"`
+	Type        CouponType `json:"type" gorm:"column:type;type:coupon_type;not null"`
+	Description string     `json:"description,omitempty" gorm:"column:description;default:null"`
+
+	PercentOff  int32  `json:"percent_off,omitempty" gorm:"column:percent_off;default:null"`
+	AmountOff   Amount `json:"amount_off,omitempty" gorm:"column:amount_off;type:numeric(12,2);default:null"`
+	Currency    string `json:"currency,omitempty" gorm:"column:currency;default:null"`
+	BuyQuantity int32  `json:"buy_quantity,omitempty" gorm:"column:buy_quantity;default:null"`
+	GetQuantity int32  `json:"get_quantity,omitempty" gorm:"column:get_quantity;default:null"`
+	// limits the coupon to products filed under the category or below it
+	CategoryID *int64 `json:"category_id,omitempty" gorm:"column:category_id"`
+
+	// zero means unlimited
+	MaxUses        int32      `json:"max_uses,omitempty" gorm:"column:max_uses;default:null"`
+	MaxUsesPerUser int32      `json:"max_uses_per_user,omitempty" gorm:"column:max_uses_per_user;default:null"`
+	StartsAt       *time.Time `json:"starts_at,omitempty" gorm:"column:starts_at"`
+	EndsAt         *time.Time `json:"ends_at,omitempty" gorm:"column:ends_at"`
+	IsActive       bool       `json:"is_active" gorm:"column:is_active;not null;default:true"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type OrderDiscount struct {
+	ID       int64  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	OrderID  int64  `json:"order_id" gorm:"column:order_id;not null;index"`
+	CouponID int64  `json:"coupon_id" gorm:"column:coupon_id;not null"`
+	Code     string `json:"code" gorm:"column:code;not null"`
+	Amount   Amount `json:"amount" gorm:"column:amount;type:numeric(12,2);not null;default:0"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+}
+
+// an empty region or category matches every order line, a category also covers the categories below it
+type TaxRule struct {
+	ID         int64  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name       string `json:"name" gorm:"column:name;not null"`
+	Region     string `json:"region,omitempty" gorm:"column:region;default:null"`
+	CategoryID *int64 `json:"category_id,omitempty" gorm:"column:category_id"`
+	Rate       Rate   `json:"rate" gorm:"column:rate;type:numeric(18,8);not null"`
+	Inclusive  bool   `json:"inclusive" gorm:"column:inclusive;not null;default:false"`
+	IsActive   bool   `json:"is_active" gorm:"column:is_active;not null;default:true"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type TaxBreakdownLine struct {
+	RuleName      string `json:"rule_name"`
+	Rate          Rate   `json:"rate"`
+	Inclusive     bool   `json:"inclusive"`
+	TaxableAmount Amount `json:"taxable_amount"`
+	TaxAmount     Amount `json:"tax_amount"`
+}
+
+// enum type ReservationStatus
+type ReservationStatus string
+
+const (
+	ReservationStatusActive    ReservationStatus = "reservation.active"
+	ReservationStatusFulfilled ReservationStatus = "reservation.fulfilled"
+	ReservationStatusReleased  ReservationStatus = "reservation.released"
+	ReservationStatusExpired   ReservationStatus = "reservation.expired"
+)
+
+// stock held for one order item, quantity minus fulfilled_quantity is still counted as reserved on the product
+type StockReservation struct {
+	ID                int64             `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	OrderID           int64             `json:"order_id" gorm:"column:order_id;not null;index"`
+	OrderItemID       int64             `json:"order_item_id" gorm:"column:order_item_id;not null;unique"`
+	ProductID         int64             `json:"product_id" gorm:"column:product_id;not null"`
+	LocationID        int64             `json:"location_id" gorm:"column:location_id;not null"`
+	Quantity          int32             `json:"quantity" gorm:"column:quantity;not null"`
+	FulfilledQuantity int32             `json:"fulfilled_quantity" gorm:"column:fulfilled_quantity;not null;default:0"`
+	Status            ReservationStatus `json:"status" gorm:"column:status;type:reservation_status;default:'reservation.active'"`
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at"`
+	CreatedAt time.Time  `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (r *StockReservation) OpenQuantity() int32 {
+	return r.Quantity - r.FulfilledQuantity
+}
+
+type StockMovementReason string
+
+const (
+	StockMovementReasonOpeningBalance     StockMovementReason = "opening_balance"
+	StockMovementReasonOrder              StockMovementReason = "order"
+	StockMovementReasonCancellation       StockMovementReason = "cancellation"
+	StockMovementReasonReservationExpired StockMovementReason = "reservation_expired"
+	StockMovementReasonFulfilment         StockMovementReason = "fulfilment"
+	StockMovementReasonManualAdjustment   StockMovementReason = "manual_adjustment"
+	StockMovementReasonReturn             StockMovementReason = "return"
+	StockMovementReasonReceipt            StockMovementReason = "receipt"
+	StockMovementReasonTransfer           StockMovementReason = "transfer"
+	StockMovementReasonStockTake          StockMovementReason = "stock_take"
+)
+
+// one append-only ledger row, delta changes stock_quantity and reserved_delta changes reserved_quantity
+type StockMovement struct {
+	ID int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	ProductID         int64               `json:"product_id" gorm:"column:product_id;not null"`
+	LocationID        int64               `json:"location_id" gorm:"column:location_id;not null"`
+	Delta             int64               `json:"delta" gorm:"column:delta;not null"`
+	ReservedDelta     int64               `json:"reserved_delta" gorm:"column:reserved_delta;not null"`
+	ResultingQuantity int64               `json:"resulting_quantity" gorm:"column:resulting_quantity;not null"`
+	ResultingReserved int64               `json:"resulting_reserved" gorm:"column:resulting_reserved;not null"`
+	Reason            StockMovementReason `json:"reason" gorm:"column:reason;not null"`
+
+	OrderID       *int64 `json:"order_id,omitempty" gorm:"column:order_id"`
+	ReferenceType string `json:"reference_type,omitempty" gorm:"column:reference_type;default:null"`
+	ReferenceID   *int64 `json:"reference_id,omitempty" gorm:"column:reference_id"`
+	Actor         string `json:"actor" gorm:"column:actor;not null"`
+	Note          string `json:"note,omitempty" gorm:"column:note;default:null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// compares a product's stored quantities with the ones replayed from its ledger and with the sum over its locations
+type StockReconciliation struct {
+	ProductID        int64 `json:"product_id"`
+	StockQuantity    int64 `json:"stock_quantity"`
+	LedgerQuantity   int64 `json:"ledger_quantity"`
+	LocationQuantity int64 `json:"location_quantity"`
+	ReservedQuantity int64 `json:"reserved_quantity"`
+	LedgerReserved   int64 `json:"ledger_reserved"`
+	LocationReserved int64 `json:"location_reserved"`
+	MovementCount    int64 `json:"movement_count"`
+	InSync           bool  `json:"in_sync"`
+}
+
+// a warehouse stock is held at, exactly one location is the default for changes that don't name one
+type Location struct {
+	ID int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	Code       string `json:"code" gorm:"column:code;unique;not null"`
+	Name       string `json:"name" gorm:"column:name;not null"`
+	Country    string `json:"country,omitempty" gorm:"column:country;default:null"`
+	State      string `json:"state,omitempty" gorm:"column:state;default:null"`
+	PostalCode string `json:"postal_code,omitempty" gorm:"column:postal_code;default:null"`
+	Priority   int32  `json:"priority" gorm:"column:priority;not null;default:0"`
+	IsDefault  bool   `json:"is_default" gorm:"column:is_default;->"`
+	IsActive   bool   `json:"is_active" gorm:"column:is_active;not null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// stock of one product at one location, written only by the stock queries of ProductRepo
+type ProductStock struct {
+	ProductID         int64 `json:"product_id" gorm:"column:product_id;primaryKey"`
+	LocationID        int64 `json:"location_id" gorm:"column:location_id;primaryKey"`
+	StockQuantity     int64 `json:"stock_quantity" gorm:"column:stock_quantity;->"`
+	ReservedQuantity  int64 `json:"reserved_quantity" gorm:"column:reserved_quantity;->"`
+	AvailableQuantity int64 `json:"available_quantity" gorm:"column:available_quantity;->"`
+
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;->"`
+}
+
+// stock moved from one location to another, recorded in the ledger as a pair of transfer movements
+type StockTransfer struct {
+	ID int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	ProductID      int64  `json:"product_id" gorm:"column:product_id;not null"`
+	FromLocationID int64  `json:"from_location_id" gorm:"column:from_location_id;not null"`
+	ToLocationID   int64  `json:"to_location_id" gorm:"column:to_location_id;not null"`
+	Quantity       int64  `json:"quantity" gorm:"column:quantity;not null"`
+	Actor          string `json:"actor" gorm:"column:actor;not null"`
+	Note           string `json:"note,omitempty" gorm:"column:note;default:null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// enum type StockAlertStatus
+type StockAlertStatus string
+
+const (
+	StockAlertStatusOpen     StockAlertStatus = "alert.open"
+	StockAlertStatusResolved StockAlertStatus = "alert.resolved"
+)
+
+// a product whose available quantity fell below its reorder point, the quantities are the ones seen
+// when the alert was raised
+type StockAlert struct {
+	ID int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	ProductID         int64            `json:"product_id" gorm:"column:product_id;not null"`
+	Status            StockAlertStatus `json:"status" gorm:"column:status;type:stock_alert_status;default:'alert.open'"`
+	ReorderPoint      int64            `json:"reorder_point" gorm:"column:reorder_point;not null"`
+	ReorderQuantity   int64            `json:"reorder_quantity" gorm:"column:reorder_quantity;not null"`
+	AvailableQuantity int64            `json:"available_quantity" gorm:"column:available_quantity;not null"`
+	StockQuantity     int64            `json:"stock_quantity" gorm:"column:stock_quantity;not null"`
+
+	NotifiedAt *time.Time `json:"notified_at,omitempty" gorm:"column:notified_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty" gorm:"column:resolved_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// a vendor stock is bought from
+type Supplier struct {
+	ID int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	Code         string `json:"code" gorm:"column:code;unique;not null"`
+	Name         string `json:"name" gorm:"column:name;not null"`
+	Email        string `json:"email,omitempty" gorm:"column:email;default:null"`
+	Phone        string `json:"phone,omitempty" gorm:"column:phone;default:null"`
+	LeadTimeDays int32  `json:"lead_time_days" gorm:"column:lead_time_days;not null;default:0"`
+	IsActive     bool   `json:"is_active" gorm:"column:is_active;not null"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type PurchaseOrderLineRequest struct {
+	ProductID  int64      `json:"product_id"`
+	Quantity   int64      `json:"quantity"`
+	UnitCost   Amount     `json:"unit_cost"`
+	ExpectedAt *time.Time `json:"expected_at,omitempty"`
+}
+
+type GoodsReceiptLineRequest struct {
+	ProductID int64      `json:"product_id"`
+	Quantity  int64      `json:"quantity"`
+	LotNumber string     `json:"lot_number"`
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+type PurchaseOrderWithLines struct {
+	PurchaseOrder PurchaseOrder           `json:"purchase_order"`
+	Lines         []PurchaseOrderLine     `json:"lines,omitempty"`
+	Receipts      []GoodsReceiptWithLines `json:"receipts,omitempty"`
+}
+
+type GoodsReceiptWithLines struct {
+	Receipt GoodsReceipt       `json:"receipt"`
+	Lines   []GoodsReceiptLine `json:"lines,omitempty"`
+}
+
+// enum type PurchaseOrderDelivery
+type PurchaseOrderDelivery string
+
+const (
+	// at least one line received more than was ordered
+	PurchaseOrderDeliveryOver PurchaseOrderDelivery = "over"
+	// closed while at least one line was still outstanding
+	PurchaseOrderDeliveryUnder PurchaseOrderDelivery = "under"
+	// still open with outstanding lines past their expected date
+	PurchaseOrderDeliveryOverdue PurchaseOrderDelivery = "overdue"
+)
+
+type PurchaseOrderSearchParams struct {
+	Status     PurchaseOrderStatus   `json:"status"`
+	SupplierID int64                 `json:"supplier_id"`
+	ProductID  int64                 `json:"product_id"`
+	Delivery   PurchaseOrderDelivery `json:"delivery"`
+	Limit      int                   `json:"limit"`
+	Offset     int                   `json:"offset"`
+}
+
+// enum type PurchaseOrderStatus
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderStatusDraft             PurchaseOrderStatus = "purchase_order.draft"
+	PurchaseOrderStatusSubmitted         PurchaseOrderStatus = "purchase_order.submitted"
+	PurchaseOrderStatusPartiallyReceived PurchaseOrderStatus = "purchase_order.partially_received"
+	PurchaseOrderStatusReceived          PurchaseOrderStatus = "purchase_order.received"
+	PurchaseOrderStatusClosed            PurchaseOrderStatus = "purchase_order.closed"
+	PurchaseOrderStatusCancelled         PurchaseOrderStatus = "purchase_order.cancelled"
+)
+
+// receipts move a submitted order to partially received or received, a partially received order can
+// also be closed short when the supplier won't deliver the rest
+var purchaseOrderStatusTransitions = map[PurchaseOrderStatus][]PurchaseOrderStatus{
+	PurchaseOrderStatusDraft:             {PurchaseOrderStatusSubmitted, PurchaseOrderStatusCancelled},
+	PurchaseOrderStatusSubmitted:         {PurchaseOrderStatusPartiallyReceived, PurchaseOrderStatusReceived, PurchaseOrderStatusCancelled},
+	PurchaseOrderStatusPartiallyReceived: {PurchaseOrderStatusPartiallyReceived, PurchaseOrderStatusReceived, PurchaseOrderStatusClosed},
+	PurchaseOrderStatusReceived:          {},
+	PurchaseOrderStatusClosed:            {},
+	PurchaseOrderStatusCancelled:         {},
+}
+
+func (s PurchaseOrderStatus) CanTransitionTo(next PurchaseOrderStatus) bool {
+	for _, status := range purchaseOrderStatusTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s PurchaseOrderStatus) IsValid() bool {
+	_, ok := purchaseOrderStatusTransitions[s]
+	return ok
+}
+
+type PurchaseOrder struct {
+	ID         int64               `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	SupplierID int64               `json:"supplier_id" gorm:"column:supplier_id;not null;index"`
+	LocationID int64               `json:"location_id" gorm:"column:location_id;not null"`
+	Status     PurchaseOrderStatus `json:"status" gorm:"column:status;type:purchase_order_status;default:'purchase_order.draft'"`
+
+	Currency   string     `json:"currency" gorm:"column:currency;not null"`
+	TotalCost  Amount     `json:"total_cost" gorm:"column:total_cost;type:numeric(12,2);not null;default:0"`
+	ExpectedAt *time.Time `json:"expected_at,omitempty" gorm:"column:expected_at;type:date"`
+	Note       string     `json:"note,omitempty" gorm:"column:note;default:null"`
+
+	SubmittedAt *time.Time `json:"submitted_at,omitempty" gorm:"column:submitted_at"`
+	ReceivedAt  *time.Time `json:"received_at,omitempty" gorm:"column:received_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty" gorm:"column:closed_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty" gorm:"column:cancelled_at"`
+	CreatedAt   time.Time  `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// outstanding and over received quantities are generated from the ordered and received ones
+type PurchaseOrderLine struct {
+	ID              int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	PurchaseOrderID int64 `json:"purchase_order_id" gorm:"column:purchase_order_id;not null;index"`
+	ProductID       int64 `json:"product_id" gorm:"column:product_id;not null"`
+
+	OrderedQuantity      int64      `json:"ordered_quantity" gorm:"column:ordered_quantity;not null"`
+	ReceivedQuantity     int64      `json:"received_quantity" gorm:"column:received_quantity;not null;default:0"`
+	OutstandingQuantity  int64      `json:"outstanding_quantity" gorm:"column:outstanding_quantity;->"`
+	OverReceivedQuantity int64      `json:"over_received_quantity" gorm:"column:over_received_quantity;->"`
+	UnitCost             Amount     `json:"unit_cost" gorm:"column:unit_cost;type:numeric(12,2);not null"`
+	ExpectedAt           *time.Time `json:"expected_at,omitempty" gorm:"column:expected_at;type:date"`
+}
+
+type GoodsReceipt struct {
+	ID              int64  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	PurchaseOrderID int64  `json:"purchase_order_id" gorm:"column:purchase_order_id;not null;index"`
+	LocationID      int64  `json:"location_id" gorm:"column:location_id;not null"`
+	Actor           string `json:"actor" gorm:"column:actor;not null"`
+	Note            string `json:"note,omitempty" gorm:"column:note;default:null"`
+
+	ReceivedAt time.Time `json:"received_at" gorm:"column:received_at"`
+}
+
+type GoodsReceiptLine struct {
+	ID                  int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ReceiptID           int64 `json:"receipt_id" gorm:"column:receipt_id;not null;index"`
+	PurchaseOrderLineID int64 `json:"purchase_order_line_id" gorm:"column:purchase_order_line_id;not null"`
+	ProductID           int64 `json:"product_id" gorm:"column:product_id;not null"`
+	Quantity            int64 `json:"quantity" gorm:"column:quantity;not null"`
+	OverQuantity        int64 `json:"over_quantity" gorm:"column:over_quantity;not null;default:0"`
+}
+
+type StockTakeCountRequest struct {
+	ProductID       int64 `json:"product_id"`
+	CountedQuantity int64 `json:"counted_quantity"`
+}
+
+type StockTakeWithLines struct {
+	StockTake StockTake       `json:"stock_take"`
+	Lines     []StockTakeLine `json:"lines,omitempty"`
+}
+
+// enum type StockTakeStatus
+type StockTakeStatus string
+
+const (
+	StockTakeStatusOpen      StockTakeStatus = "stock_take.open"
+	StockTakeStatusPosted    StockTakeStatus = "stock_take.posted"
+	StockTakeStatusCancelled StockTakeStatus = "stock_take.cancelled"
+)
+
+func (s StockTakeStatus) IsValid() bool {
+	return s == StockTakeStatusOpen || s == StockTakeStatusPosted || s == StockTakeStatusCancelled
+}
+
+// a count session for a set of products at one location
+type StockTake struct {
+	ID         int64           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	LocationID int64           `json:"location_id" gorm:"column:location_id;not null"`
+	Status     StockTakeStatus `json:"status" gorm:"column:status;type:stock_take_status;default:'stock_take.open'"`
+	Note       string          `json:"note,omitempty" gorm:"column:note;default:null"`
+	OpenedBy   string          `json:"opened_by" gorm:"column:opened_by;not null"`
+	PostedBy   string          `json:"posted_by,omitempty" gorm:"column:posted_by;default:null"`
+
+	PostedAt    *time.Time `json:"posted_at,omitempty" gorm:"column:posted_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty" gorm:"column:cancelled_at"`
+	CreatedAt   time.Time  `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// expected is snapshotted when the session opens, counted and the generated variance stay nil until counted
+type StockTakeLine struct {
+	ID          int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	StockTakeID int64 `json:"stock_take_id" gorm:"column:stock_take_id;not null;index"`
+	ProductID   int64 `json:"product_id" gorm:"column:product_id;not null"`
+
+	ExpectedQuantity int64      `json:"expected_quantity" gorm:"column:expected_quantity;not null"`
+	CountedQuantity  *int64     `json:"counted_quantity,omitempty" gorm:"column:counted_quantity"`
+	Variance         *int64     `json:"variance,omitempty" gorm:"column:variance;->"`
+	CountedBy        string     `json:"counted_by,omitempty" gorm:"column:counted_by;default:null"`
+	CountedAt        *time.Time `json:"counted_at,omitempty" gorm:"column:counted_at"`
+
+	// reserved units left without stock when the count was posted, the orders holding them cannot all ship
+	OverReservedQuantity int64 `json:"over_reserved_quantity,omitempty" gorm:"column:over_reserved_quantity;not null;default:0"`
+}
+
+// part of a location's stock with a lot number and expiry date, written only by StockLotRepo while the
+// product row is locked
+type StockLot struct {
+	ID         int64      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ProductID  int64      `json:"product_id" gorm:"column:product_id;not null"`
+	LocationID int64      `json:"location_id" gorm:"column:location_id;not null"`
+	LotNumber  string     `json:"lot_number" gorm:"column:lot_number;not null"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at;type:date"`
+
+	Quantity          int64 `json:"quantity" gorm:"column:quantity;not null;default:0"`
+	ReservedQuantity  int64 `json:"reserved_quantity" gorm:"column:reserved_quantity;not null;default:0"`
+	AvailableQuantity int64 `json:"available_quantity" gorm:"column:available_quantity;->"`
+
+	CreatedAt time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// units of one lot allocated to an order item, lot number and expiry are read from the lot
+type OrderItemLot struct {
+	ID                int64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	OrderItemID       int64 `json:"order_item_id" gorm:"column:order_item_id;not null"`
+	LotID             int64 `json:"lot_id" gorm:"column:lot_id;not null"`
+	Quantity          int64 `json:"quantity" gorm:"column:quantity;not null"`
+	FulfilledQuantity int64 `json:"fulfilled_quantity" gorm:"column:fulfilled_quantity;not null;default:0"`
+
+	LotNumber string     `json:"lot_number,omitempty" gorm:"column:lot_number;->"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at;->"`
+}
+
+// enum type SerialNumberStatus
+type SerialNumberStatus string
+
+const (
+	SerialNumberStatusInStock SerialNumberStatus = "serial.in_stock"
+	SerialNumberStatusShipped SerialNumberStatus = "serial.shipped"
+)
+
+func (s SerialNumberStatus) IsValid() bool {
+	return s == SerialNumberStatusInStock || s == SerialNumberStatusShipped
+}
+
+// one unit of a serialized product, order item and shipment are set once it has shipped
+type SerialNumber struct {
+	ID             int64              `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ProductID      int64              `json:"product_id" gorm:"column:product_id;not null"`
+	SerialNumber   string             `json:"serial_number" gorm:"column:serial_number;not null"`
+	Status         SerialNumberStatus `json:"status" gorm:"column:status;type:serial_number_status;default:'serial.in_stock'"`
+	LocationID     int64              `json:"location_id" gorm:"column:location_id;not null"`
+	GoodsReceiptID *int64             `json:"goods_receipt_id,omitempty" gorm:"column:goods_receipt_id"`
+	OrderItemID    *int64             `json:"order_item_id,omitempty" gorm:"column:order_item_id"`
+	ShipmentID     *int64             `json:"shipment_id,omitempty" gorm:"column:shipment_id"`
+
+	ReceivedAt time.Time  `json:"received_at" gorm:"column:received_at;not null"`
+	ShippedAt  *time.Time `json:"shipped_at,omitempty" gorm:"column:shipped_at"`
+	CreatedAt  time.Time  `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// what a warranty claim needs to know about a serial number, the order fields are empty while the unit
+// is still in stock
+type SerialNumberLookup struct {
+	SerialNumber string             `json:"serial_number"`
+	Status       SerialNumberStatus `json:"status"`
+	ProductID    int64              `json:"product_id"`
+	ProductSKU   string             `json:"product_sku"`
+	ProductName  string             `json:"product_name"`
+	ReceivedAt   time.Time          `json:"received_at"`
+
+	OrderID       *int64     `json:"order_id,omitempty"`
+	OrderItemID   *int64     `json:"order_item_id,omitempty"`
+	ShipmentID    *int64     `json:"shipment_id,omitempty"`
+	ShippedAt     *time.Time `json:"shipped_at,omitempty"`
+	UserID        *int64     `json:"user_id,omitempty"`
+	CustomerName  *string    `json:"customer_name,omitempty"`
+	CustomerEmail *string    `json:"customer_email,omitempty"`
+}
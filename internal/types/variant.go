@@ -0,0 +1,138 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OptionAxes are the names of the options a parent product's variants differ on, such as size and color,
+// stored as a jsonb array
+type OptionAxes []string
+
+// ProductOptions are a variant's values on its parent's option axes, stored as a jsonb object
+type ProductOptions map[string]string
+
+// ProductGroup is a search result, a standalone product or a parent with all of its variants
+type ProductGroup struct {
+	Product  *Product   `json:"product"`
+	Variants []*Product `json:"variants,omitempty"`
+
+	// summed over the variants for a parent, the product's own quantity otherwise
+	AvailableQuantity int64 `json:"available_quantity"`
+	InStockVariants   int   `json:"in_stock_variants"`
+}
+
+func (p *Product) HasVariants() bool {
+	return len(p.OptionAxes) > 0
+}
+
+func (p *Product) IsVariant() bool {
+	return p.ParentID != nil
+}
+
+// NewProductGroup groups a product with its variants and sums up what they have available
+func NewProductGroup(product *Product, variants []*Product) ProductGroup {
+	group := ProductGroup{Product: product, Variants: variants}
+	if !product.HasVariants() {
+		group.AvailableQuantity = product.AvailableQuantity
+		return group
+	}
+
+	for _, variant := range variants {
+		group.AvailableQuantity += variant.AvailableQuantity
+		if variant.AvailableQuantity > 0 {
+			group.InStockVariants++
+		}
+	}
+	return group
+}
+
+// Validate checks the axes of a new parent product, names have to be set and distinct
+func (a OptionAxes) Validate() error {
+	seen := make(map[string]bool, len(a))
+	for _, axis := range a {
+		if strings.TrimSpace(axis) == "" {
+			return fmt.Errorf("option axes cannot be blank")
+		}
+		if seen[axis] {
+			return fmt.Errorf("option axis %s is listed more than once", axis)
+		}
+		seen[axis] = true
+	}
+	return nil
+}
+
+// Match checks that options has a value on every axis and nothing else
+func (a OptionAxes) Match(options ProductOptions) error {
+	if len(options) != len(a) {
+		return fmt.Errorf("a variant needs a value on each of the axes %s", strings.Join(a, ", "))
+	}
+	for _, axis := range a {
+		if strings.TrimSpace(options[axis]) == "" {
+			return fmt.Errorf("a variant needs a value on the %s axis", axis)
+		}
+	}
+	return nil
+}
+
+// VariantName is the parent's name followed by the variant's option values in axis order,
+// like "Oxford Shirt (M / blue)"
+func VariantName(parentName string, axes OptionAxes, options ProductOptions) string {
+	values := make([]string, 0, len(axes))
+	for _, axis := range axes {
+		values = append(values, options[axis])
+	}
+	return fmt.Sprintf("%s (%s)", parentName, strings.Join(values, " / "))
+}
+
+func (a OptionAxes) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *OptionAxes) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into OptionAxes", src)
+	}
+}
+
+func (o ProductOptions) Value() (driver.Value, error) {
+	if len(o) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]string(o))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (o *ProductOptions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	default:
+		return fmt.Errorf("cannot scan %T into ProductOptions", src)
+	}
+}
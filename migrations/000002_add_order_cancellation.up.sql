@@ -0,0 +1,4 @@
+ALTER TABLE orders
+    ADD COLUMN cancellation_reason VARCHAR(50),
+    ADD COLUMN cancellation_note TEXT,
+    ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE;
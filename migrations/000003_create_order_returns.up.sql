@@ -0,0 +1,43 @@
+CREATE TYPE return_status AS ENUM (
+    'return.requested',
+    'return.approved',
+    'return.rejected',
+    'return.received'
+);
+
+CREATE TABLE order_returns (
+    id BIGSERIAL PRIMARY KEY,
+
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status return_status NOT NULL DEFAULT 'return.requested',
+    reason TEXT NOT NULL,
+    resolution_note TEXT,
+    refund_amount NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (refund_amount >= 0),
+    restocked BOOLEAN NOT NULL DEFAULT FALSE,
+
+    approved_at TIMESTAMP WITH TIME ZONE,
+    rejected_at TIMESTAMP WITH TIME ZONE,
+    received_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_order_returns_order_id ON order_returns (order_id);
+CREATE INDEX idx_order_returns_status ON order_returns (status);
+
+
+CREATE TABLE order_return_items (
+    id BIGSERIAL PRIMARY KEY,
+
+    return_id BIGINT NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    price NUMERIC(12,2) NOT NULL CHECK (price >= 0)
+);
+
+
+CREATE INDEX idx_order_return_items_return_id ON order_return_items (return_id);
+CREATE INDEX idx_order_return_items_order_item_id ON order_return_items (order_item_id);
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
+DROP TYPE IF EXISTS shipment_status;
+
+-- postgres cannot drop a single enum value, so the type is rebuilt without it
+UPDATE orders SET status = 'order.shipped' WHERE status = 'order.partially_shipped';
+ALTER TABLE orders ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE order_status RENAME TO order_status_old;
+CREATE TYPE order_status AS ENUM (
+    'order.pending',
+    'order.shipped',
+    'order.cancelled',
+    'order.delivered'
+);
+ALTER TABLE orders ALTER COLUMN status TYPE order_status USING status::text::order_status;
+ALTER TABLE orders ALTER COLUMN status SET DEFAULT 'order.pending';
+DROP TYPE order_status_old;
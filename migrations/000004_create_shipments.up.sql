@@ -0,0 +1,38 @@
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'order.partially_shipped' AFTER 'order.pending';
+
+CREATE TYPE shipment_status AS ENUM (
+    'shipment.in_transit',
+    'shipment.delivered'
+);
+
+CREATE TABLE shipments (
+    id BIGSERIAL PRIMARY KEY,
+
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    carrier VARCHAR(100) NOT NULL,
+    tracking_number VARCHAR(255) NOT NULL,
+    status shipment_status NOT NULL DEFAULT 'shipment.in_transit',
+
+    shipped_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (carrier, tracking_number)
+);
+
+
+CREATE INDEX idx_shipments_order_id ON shipments (order_id);
+
+
+CREATE TABLE shipment_items (
+    id BIGSERIAL PRIMARY KEY,
+
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0)
+);
+
+
+CREATE INDEX idx_shipment_items_shipment_id ON shipment_items (shipment_id);
+CREATE INDEX idx_shipment_items_order_item_id ON shipment_items (order_item_id);
@@ -0,0 +1,15 @@
+CREATE TABLE idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+
+    idempotency_key VARCHAR(255) UNIQUE NOT NULL,
+    request_fingerprint CHAR(64) NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    response_status INT,
+    response_body JSONB,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
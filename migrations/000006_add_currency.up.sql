@@ -0,0 +1,6 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS price NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (price >= 0),
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'AED';
+
+ALTER TABLE orders
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'AED';
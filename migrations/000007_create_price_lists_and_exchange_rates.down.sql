@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS fx_base_currency;
+
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS product_prices;
@@ -0,0 +1,31 @@
+CREATE TABLE product_prices (
+    id BIGSERIAL PRIMARY KEY,
+
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    price NUMERIC(12,2) NOT NULL CHECK (price >= 0),
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (product_id, currency)
+);
+
+
+CREATE TABLE exchange_rates (
+    id BIGSERIAL PRIMARY KEY,
+
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate NUMERIC(18,8) NOT NULL CHECK (rate > 0),
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (base_currency, quote_currency)
+);
+
+
+ALTER TABLE orders
+    ADD COLUMN fx_base_currency CHAR(3),
+    ADD COLUMN fx_rate NUMERIC(18,8);
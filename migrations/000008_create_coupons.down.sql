@@ -0,0 +1,7 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS discount_amount,
+    DROP COLUMN IF EXISTS subtotal_amount;
+
+DROP TABLE IF EXISTS order_discounts;
+DROP TABLE IF EXISTS coupons;
+DROP TYPE IF EXISTS coupon_type;
@@ -0,0 +1,60 @@
+CREATE TYPE coupon_type AS ENUM (
+    'coupon.percentage',
+    'coupon.fixed',
+    'coupon.buy_x_get_y'
+);
+
+CREATE TABLE coupons (
+    id BIGSERIAL PRIMARY KEY,
+
+    code VARCHAR(64) UNIQUE NOT NULL,
+    type coupon_type NOT NULL,
+    description TEXT,
+
+    percent_off INT CHECK (percent_off BETWEEN 1 AND 100),
+    amount_off NUMERIC(12,2) CHECK (amount_off > 0),
+    currency CHAR(3),
+    buy_quantity INT CHECK (buy_quantity > 0),
+    get_quantity INT CHECK (get_quantity > 0),
+    category VARCHAR(100),
+
+    max_uses INT CHECK (max_uses > 0),
+    max_uses_per_user INT CHECK (max_uses_per_user > 0),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CHECK (
+        (type = 'coupon.percentage' AND percent_off IS NOT NULL) OR
+        (type = 'coupon.fixed' AND amount_off IS NOT NULL AND currency IS NOT NULL) OR
+        (type = 'coupon.buy_x_get_y' AND buy_quantity IS NOT NULL AND get_quantity IS NOT NULL)
+    ),
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+
+CREATE TABLE order_discounts (
+    id BIGSERIAL PRIMARY KEY,
+
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    coupon_id BIGINT NOT NULL REFERENCES coupons(id),
+    code VARCHAR(64) NOT NULL,
+    amount NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (order_id, coupon_id)
+);
+
+
+CREATE INDEX idx_order_discounts_coupon_id ON order_discounts (coupon_id);
+
+
+ALTER TABLE orders
+    ADD COLUMN subtotal_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    ADD COLUMN discount_amount NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+UPDATE orders SET subtotal_amount = total_amount;
@@ -0,0 +1,12 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS tax_region;
+
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS tax_inclusive,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS tax_rate,
+    DROP COLUMN IF EXISTS tax_rule_name,
+    DROP COLUMN IF EXISTS discount_amount;
+
+DROP TABLE IF EXISTS tax_rules;
@@ -0,0 +1,28 @@
+CREATE TABLE tax_rules (
+    id BIGSERIAL PRIMARY KEY,
+
+    name VARCHAR(100) NOT NULL,
+    region VARCHAR(10),
+    category VARCHAR(100),
+    rate NUMERIC(18,8) NOT NULL CHECK (rate >= 0 AND rate < 1),
+    inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_tax_rules_region ON tax_rules (region);
+
+
+ALTER TABLE order_items
+    ADD COLUMN discount_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_rule_name VARCHAR(100),
+    ADD COLUMN tax_rate NUMERIC(18,8) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE orders
+    ADD COLUMN tax_region VARCHAR(10),
+    ADD COLUMN tax_amount NUMERIC(12,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS billing_address,
+    DROP COLUMN IF EXISTS shipping_address;
+
+DROP TABLE IF EXISTS user_addresses;
@@ -0,0 +1,30 @@
+CREATE TABLE user_addresses (
+    id BIGSERIAL PRIMARY KEY,
+
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label VARCHAR(50),
+    recipient_name VARCHAR(255) NOT NULL,
+    phone VARCHAR(20),
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255),
+    city VARCHAR(100) NOT NULL,
+    state VARCHAR(100),
+    postal_code VARCHAR(20),
+    country CHAR(2) NOT NULL,
+    is_default_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default_billing BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_user_addresses_user_id ON user_addresses (user_id);
+CREATE UNIQUE INDEX idx_user_addresses_default_shipping ON user_addresses (user_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX idx_user_addresses_default_billing ON user_addresses (user_id) WHERE is_default_billing;
+
+
+-- orders keep a copy of the addresses so later edits to the address book don't rewrite history
+ALTER TABLE orders
+    ADD COLUMN shipping_address JSONB,
+    ADD COLUMN billing_address JSONB;
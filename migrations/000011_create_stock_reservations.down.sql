@@ -0,0 +1,20 @@
+-- hand the open reservations back to the old deduct-on-order model
+UPDATE products p
+SET stock_quantity = p.stock_quantity - r.open_quantity
+FROM (
+    SELECT product_id, SUM(quantity - fulfilled_quantity) AS open_quantity
+    FROM stock_reservations
+    WHERE status = 'reservation.active'
+    GROUP BY product_id
+) r
+WHERE r.product_id = p.id;
+
+DROP TABLE IF EXISTS stock_reservations;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS paid_at;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS available_quantity,
+    DROP COLUMN IF EXISTS reserved_quantity;
+
+DROP TYPE IF EXISTS reservation_status;
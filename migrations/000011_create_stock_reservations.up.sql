@@ -0,0 +1,58 @@
+CREATE TYPE reservation_status AS ENUM (
+    'reservation.active',
+    'reservation.fulfilled',
+    'reservation.released',
+    'reservation.expired'
+);
+
+-- stock_quantity stays the on-hand count, reserved stock is only deducted when it ships
+ALTER TABLE products
+    ADD COLUMN reserved_quantity BIGINT NOT NULL DEFAULT 0 CHECK (reserved_quantity >= 0),
+    ADD COLUMN available_quantity BIGINT GENERATED ALWAYS AS (stock_quantity - reserved_quantity) STORED;
+
+ALTER TABLE orders
+    ADD COLUMN paid_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE stock_reservations (
+    id BIGSERIAL PRIMARY KEY,
+
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    fulfilled_quantity INT NOT NULL DEFAULT 0 CHECK (fulfilled_quantity >= 0 AND fulfilled_quantity <= quantity),
+    status reservation_status NOT NULL DEFAULT 'reservation.active',
+
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (order_item_id)
+);
+
+
+CREATE INDEX idx_stock_reservations_order_id ON stock_reservations (order_id);
+CREATE INDEX idx_stock_reservations_expires_at ON stock_reservations (expires_at) WHERE status = 'reservation.active';
+
+
+-- open orders were placed under the old model and already had their stock deducted, move the
+-- unshipped part back on hand as a non expiring reservation so both models agree
+INSERT INTO stock_reservations (order_id, order_item_id, product_id, quantity, fulfilled_quantity, status)
+SELECT oi.order_id, oi.id, oi.product_id, oi.quantity, COALESCE(s.shipped, 0), 'reservation.active'
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+LEFT JOIN (
+    SELECT order_item_id, SUM(quantity) AS shipped FROM shipment_items GROUP BY order_item_id
+) s ON s.order_item_id = oi.id
+WHERE o.status IN ('order.pending', 'order.partially_shipped')
+  AND oi.quantity > COALESCE(s.shipped, 0);
+
+UPDATE products p
+SET stock_quantity = p.stock_quantity + r.open_quantity,
+    reserved_quantity = r.open_quantity
+FROM (
+    SELECT product_id, SUM(quantity - fulfilled_quantity) AS open_quantity
+    FROM stock_reservations
+    GROUP BY product_id
+) r
+WHERE r.product_id = p.id;
@@ -0,0 +1,4 @@
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS chk_products_reserved_within_stock,
+    DROP CONSTRAINT IF EXISTS chk_products_stock_non_negative,
+    ALTER COLUMN stock_quantity DROP NOT NULL;
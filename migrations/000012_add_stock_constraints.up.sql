@@ -0,0 +1,8 @@
+-- last line of defence against overselling, a racing update that slips past the service checks fails
+-- here instead of leaving negative stock behind
+UPDATE products SET stock_quantity = 0 WHERE stock_quantity IS NULL;
+
+ALTER TABLE products
+    ALTER COLUMN stock_quantity SET NOT NULL,
+    ADD CONSTRAINT chk_products_stock_non_negative CHECK (stock_quantity >= 0),
+    ADD CONSTRAINT chk_products_reserved_within_stock CHECK (reserved_quantity <= stock_quantity);
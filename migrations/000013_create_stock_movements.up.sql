@@ -0,0 +1,45 @@
+-- append-only ledger of every change to products.stock_quantity (delta) and products.reserved_quantity
+-- (reserved_delta), replaying it from the first row gives the current quantities
+CREATE TABLE stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    delta BIGINT NOT NULL DEFAULT 0,
+    reserved_delta BIGINT NOT NULL DEFAULT 0,
+    resulting_quantity BIGINT NOT NULL,
+    resulting_reserved BIGINT NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+
+    order_id BIGINT,
+    reference_type VARCHAR(50),
+    reference_id BIGINT,
+    actor VARCHAR(255) NOT NULL,
+    note TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_stock_movements_product_id ON stock_movements (product_id, id);
+CREATE INDEX idx_stock_movements_order_id ON stock_movements (order_id) WHERE order_id IS NOT NULL;
+
+
+-- rows can only go away together with their product, the foreign key cascade runs one trigger level deeper
+CREATE FUNCTION stock_movements_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND pg_trigger_depth() > 1 THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_stock_movements_append_only
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+
+-- existing stock has no history, open the ledger with the current quantities
+INSERT INTO stock_movements (product_id, delta, reserved_delta, resulting_quantity, resulting_reserved, reason, actor)
+SELECT id, stock_quantity, reserved_quantity, stock_quantity, reserved_quantity, 'opening_balance', 'migration'
+FROM products;
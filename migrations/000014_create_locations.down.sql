@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS stock_transfers;
+
+DROP INDEX IF EXISTS idx_stock_movements_location_id;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS location_id;
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS location_id;
+ALTER TABLE order_items DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS product_stocks;
+DROP TABLE IF EXISTS locations;
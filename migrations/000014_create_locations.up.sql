@@ -0,0 +1,82 @@
+CREATE TABLE locations (
+    id BIGSERIAL PRIMARY KEY,
+
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    country CHAR(2),
+    state VARCHAR(100),
+    postal_code VARCHAR(20),
+    -- lower numbers are allocated first by the priority strategy and break ties in the others
+    priority INT NOT NULL DEFAULT 0,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+-- stock changes that don't name a location go to the default one, there is exactly one
+CREATE UNIQUE INDEX idx_locations_default ON locations ((TRUE)) WHERE is_default;
+
+INSERT INTO locations (code, name, is_default) VALUES ('DEFAULT', 'Default warehouse', TRUE);
+
+
+-- stock per product per location, products.stock_quantity and reserved_quantity stay the totals over all rows
+CREATE TABLE product_stocks (
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    location_id BIGINT NOT NULL REFERENCES locations(id),
+    stock_quantity BIGINT NOT NULL DEFAULT 0 CHECK (stock_quantity >= 0),
+    reserved_quantity BIGINT NOT NULL DEFAULT 0 CHECK (reserved_quantity >= 0 AND reserved_quantity <= stock_quantity),
+    available_quantity BIGINT GENERATED ALWAYS AS (stock_quantity - reserved_quantity) STORED,
+
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (product_id, location_id)
+);
+
+
+CREATE INDEX idx_product_stocks_location_id ON product_stocks (location_id);
+
+INSERT INTO product_stocks (product_id, location_id, stock_quantity, reserved_quantity)
+SELECT p.id, l.id, p.stock_quantity, p.reserved_quantity
+FROM products p
+CROSS JOIN locations l
+WHERE l.is_default;
+
+
+-- everything that existed so far was held and shipped from the default location
+ALTER TABLE order_items ADD COLUMN location_id BIGINT REFERENCES locations(id);
+UPDATE order_items SET location_id = (SELECT id FROM locations WHERE is_default);
+ALTER TABLE order_items ALTER COLUMN location_id SET NOT NULL;
+
+ALTER TABLE stock_reservations ADD COLUMN location_id BIGINT REFERENCES locations(id);
+UPDATE stock_reservations SET location_id = (SELECT id FROM locations WHERE is_default);
+ALTER TABLE stock_reservations ALTER COLUMN location_id SET NOT NULL;
+
+ALTER TABLE stock_movements ADD COLUMN location_id BIGINT REFERENCES locations(id);
+ALTER TABLE stock_movements DISABLE TRIGGER trg_stock_movements_append_only;
+UPDATE stock_movements SET location_id = (SELECT id FROM locations WHERE is_default);
+ALTER TABLE stock_movements ENABLE TRIGGER trg_stock_movements_append_only;
+ALTER TABLE stock_movements ALTER COLUMN location_id SET NOT NULL;
+
+CREATE INDEX idx_stock_movements_location_id ON stock_movements (location_id, id);
+
+
+CREATE TABLE stock_transfers (
+    id BIGSERIAL PRIMARY KEY,
+
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    from_location_id BIGINT NOT NULL REFERENCES locations(id),
+    to_location_id BIGINT NOT NULL REFERENCES locations(id),
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    actor VARCHAR(255) NOT NULL,
+    note TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CHECK (from_location_id <> to_location_id)
+);
+
+
+CREATE INDEX idx_stock_transfers_product_id ON stock_transfers (product_id, id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS stock_alerts;
+DROP TYPE IF EXISTS stock_alert_status;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS reorder_point,
+    DROP COLUMN IF EXISTS reorder_quantity;
@@ -0,0 +1,32 @@
+-- a reorder point of 0 turns alerts off for the product
+ALTER TABLE products
+    ADD COLUMN reorder_point BIGINT NOT NULL DEFAULT 0 CHECK (reorder_point >= 0),
+    ADD COLUMN reorder_quantity BIGINT NOT NULL DEFAULT 0 CHECK (reorder_quantity >= 0);
+
+CREATE TYPE stock_alert_status AS ENUM (
+    'alert.open',
+    'alert.resolved'
+);
+
+-- raised when the available quantity of a product drops below its reorder point and resolved once it is
+-- back at or above it, notified_at is set when the notifier has pushed the alert out
+CREATE TABLE stock_alerts (
+    id BIGSERIAL PRIMARY KEY,
+
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    status stock_alert_status NOT NULL DEFAULT 'alert.open',
+    reorder_point BIGINT NOT NULL,
+    reorder_quantity BIGINT NOT NULL,
+    available_quantity BIGINT NOT NULL,
+    stock_quantity BIGINT NOT NULL,
+
+    notified_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+-- a product has at most one open alert, staying low doesn't raise it again
+CREATE UNIQUE INDEX idx_stock_alerts_open ON stock_alerts (product_id) WHERE status = 'alert.open';
+CREATE INDEX idx_stock_alerts_status ON stock_alerts (status, id);
+CREATE INDEX idx_stock_alerts_unnotified ON stock_alerts (id) WHERE notified_at IS NULL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS goods_receipt_lines;
+DROP TABLE IF EXISTS goods_receipts;
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TYPE IF EXISTS purchase_order_status;
+DROP TABLE IF EXISTS suppliers;
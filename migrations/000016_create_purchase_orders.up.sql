@@ -0,0 +1,100 @@
+CREATE TABLE suppliers (
+    id BIGSERIAL PRIMARY KEY,
+
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50),
+    -- days between submitting a purchase order and the goods arriving, used when an order has no expected date
+    lead_time_days INT NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE TYPE purchase_order_status AS ENUM (
+    'purchase_order.draft',
+    'purchase_order.submitted',
+    'purchase_order.partially_received',
+    'purchase_order.received',
+    'purchase_order.closed',
+    'purchase_order.cancelled'
+);
+
+-- goods are received into location_id, closed means the supplier won't deliver what is still outstanding
+CREATE TABLE purchase_orders (
+    id BIGSERIAL PRIMARY KEY,
+
+    supplier_id BIGINT NOT NULL REFERENCES suppliers(id),
+    location_id BIGINT NOT NULL REFERENCES locations(id),
+    status purchase_order_status NOT NULL DEFAULT 'purchase_order.draft',
+    currency CHAR(3) NOT NULL,
+    total_cost NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (total_cost >= 0),
+    expected_at DATE,
+    note TEXT,
+
+    submitted_at TIMESTAMP WITH TIME ZONE,
+    received_at TIMESTAMP WITH TIME ZONE,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_purchase_orders_supplier_id ON purchase_orders (supplier_id);
+CREATE INDEX idx_purchase_orders_status ON purchase_orders (status, expected_at);
+
+
+-- received_quantity may end up above ordered_quantity when the supplier over-delivers,
+-- the generated columns keep both sides of the difference queryable
+CREATE TABLE purchase_order_lines (
+    id BIGSERIAL PRIMARY KEY,
+
+    purchase_order_id BIGINT NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    ordered_quantity BIGINT NOT NULL CHECK (ordered_quantity > 0),
+    received_quantity BIGINT NOT NULL DEFAULT 0 CHECK (received_quantity >= 0),
+    outstanding_quantity BIGINT GENERATED ALWAYS AS (GREATEST(ordered_quantity - received_quantity, 0)) STORED,
+    over_received_quantity BIGINT GENERATED ALWAYS AS (GREATEST(received_quantity - ordered_quantity, 0)) STORED,
+    unit_cost NUMERIC(12,2) NOT NULL CHECK (unit_cost >= 0),
+    expected_at DATE,
+
+    UNIQUE (purchase_order_id, product_id)
+);
+
+
+CREATE INDEX idx_purchase_order_lines_product_id ON purchase_order_lines (product_id);
+
+
+-- one delivery against a purchase order, each line is also a receipt movement in the stock ledger
+CREATE TABLE goods_receipts (
+    id BIGSERIAL PRIMARY KEY,
+
+    purchase_order_id BIGINT NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    location_id BIGINT NOT NULL REFERENCES locations(id),
+    actor VARCHAR(255) NOT NULL,
+    note TEXT,
+
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_goods_receipts_purchase_order_id ON goods_receipts (purchase_order_id);
+
+
+-- over_quantity is the part of the delivery that went beyond what was still outstanding on the line
+CREATE TABLE goods_receipt_lines (
+    id BIGSERIAL PRIMARY KEY,
+
+    receipt_id BIGINT NOT NULL REFERENCES goods_receipts(id) ON DELETE CASCADE,
+    purchase_order_line_id BIGINT NOT NULL REFERENCES purchase_order_lines(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    over_quantity BIGINT NOT NULL DEFAULT 0 CHECK (over_quantity >= 0 AND over_quantity <= quantity)
+);
+
+
+CREATE INDEX idx_goods_receipt_lines_receipt_id ON goods_receipt_lines (receipt_id);
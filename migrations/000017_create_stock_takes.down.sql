@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stock_take_lines;
+DROP TABLE IF EXISTS stock_takes;
+DROP TYPE IF EXISTS stock_take_status;
@@ -0,0 +1,46 @@
+CREATE TYPE stock_take_status AS ENUM (
+    'stock_take.open',
+    'stock_take.posted',
+    'stock_take.cancelled'
+);
+
+-- a count of some products at one location, posting it books the variances as stock_take movements
+CREATE TABLE stock_takes (
+    id BIGSERIAL PRIMARY KEY,
+
+    location_id BIGINT NOT NULL REFERENCES locations(id),
+    status stock_take_status NOT NULL DEFAULT 'stock_take.open',
+    note TEXT,
+    opened_by VARCHAR(255) NOT NULL,
+    posted_by VARCHAR(255),
+
+    posted_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_stock_takes_status ON stock_takes (status, id);
+CREATE INDEX idx_stock_takes_location_id ON stock_takes (location_id);
+
+
+-- expected_quantity is the on hand stock when the session was opened, the variance is applied on top of
+-- whatever the stock is when the session is posted so changes made meanwhile are kept
+CREATE TABLE stock_take_lines (
+    id BIGSERIAL PRIMARY KEY,
+
+    stock_take_id BIGINT NOT NULL REFERENCES stock_takes(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    expected_quantity BIGINT NOT NULL CHECK (expected_quantity >= 0),
+    counted_quantity BIGINT CHECK (counted_quantity >= 0),
+    variance BIGINT GENERATED ALWAYS AS (counted_quantity - expected_quantity) STORED,
+    counted_by VARCHAR(255),
+
+    counted_at TIMESTAMP WITH TIME ZONE,
+
+    UNIQUE (stock_take_id, product_id)
+);
+
+
+CREATE INDEX idx_stock_take_lines_product_id ON stock_take_lines (product_id);
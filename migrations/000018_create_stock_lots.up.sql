@@ -0,0 +1,40 @@
+-- lots break down part of a location's stock by lot number and expiry, stock received without a lot stays
+-- untracked. The free quantity of the lots never exceeds the free quantity of the location, so untracked
+-- reservations are always covered by untracked stock
+CREATE TABLE stock_lots (
+    id BIGSERIAL PRIMARY KEY,
+
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    location_id BIGINT NOT NULL REFERENCES locations(id),
+    lot_number VARCHAR(100) NOT NULL,
+    -- a lot without an expiry date never expires and is allocated last
+    expires_at DATE,
+    quantity BIGINT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    reserved_quantity BIGINT NOT NULL DEFAULT 0 CHECK (reserved_quantity >= 0 AND reserved_quantity <= quantity),
+    available_quantity BIGINT GENERATED ALWAYS AS (quantity - reserved_quantity) STORED,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (product_id, location_id, lot_number)
+);
+
+
+CREATE INDEX idx_stock_lots_fefo ON stock_lots (product_id, location_id, expires_at, id);
+CREATE INDEX idx_stock_lots_expires_at ON stock_lots (expires_at) WHERE quantity > 0;
+
+
+-- the lots an order item was allocated from, fulfilled_quantity of them has shipped
+CREATE TABLE order_item_lots (
+    id BIGSERIAL PRIMARY KEY,
+
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    lot_id BIGINT NOT NULL REFERENCES stock_lots(id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    fulfilled_quantity BIGINT NOT NULL DEFAULT 0 CHECK (fulfilled_quantity >= 0 AND fulfilled_quantity <= quantity),
+
+    UNIQUE (order_item_id, lot_id)
+);
+
+
+CREATE INDEX idx_order_item_lots_lot_id ON order_item_lots (lot_id);
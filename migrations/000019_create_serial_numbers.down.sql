@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS serial_numbers;
+DROP TYPE IF EXISTS serial_number_status;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS is_serialized;
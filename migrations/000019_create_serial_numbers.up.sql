@@ -0,0 +1,35 @@
+-- serial numbers are registered when stock of a serialized product comes in and assigned to the order item
+-- they leave with when the item ships
+ALTER TABLE products
+    ADD COLUMN is_serialized BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TYPE serial_number_status AS ENUM (
+    'serial.in_stock',
+    'serial.shipped'
+);
+
+CREATE TABLE serial_numbers (
+    id BIGSERIAL PRIMARY KEY,
+
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    serial_number VARCHAR(100) NOT NULL,
+    status serial_number_status NOT NULL DEFAULT 'serial.in_stock',
+    -- where the unit was received, goods_receipt_id is empty for units registered against stock on hand
+    location_id BIGINT NOT NULL REFERENCES locations(id),
+    goods_receipt_id BIGINT REFERENCES goods_receipts(id),
+    order_item_id BIGINT REFERENCES order_items(id),
+    shipment_id BIGINT REFERENCES shipments(id),
+
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    shipped_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (product_id, serial_number),
+    CHECK ((status = 'serial.shipped') = (order_item_id IS NOT NULL AND shipment_id IS NOT NULL))
+);
+
+
+-- warranty lookups come in by serial number alone
+CREATE INDEX idx_serial_numbers_serial_number ON serial_numbers (serial_number);
+CREATE INDEX idx_serial_numbers_order_item_id ON serial_numbers (order_item_id) WHERE order_item_id IS NOT NULL;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_products_variant_options;
+DROP INDEX IF EXISTS idx_products_parent_id;
+
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_parent_stock_check,
+    DROP CONSTRAINT IF EXISTS products_variant_check,
+    DROP COLUMN IF EXISTS price_override,
+    DROP COLUMN IF EXISTS options,
+    DROP COLUMN IF EXISTS option_axes,
+    DROP COLUMN IF EXISTS parent_id;
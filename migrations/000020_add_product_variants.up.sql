@@ -0,0 +1,19 @@
+-- a parent product names the option axes its variants differ on and holds no stock itself, every variant
+-- is a product of its own with a SKU, stock and the option values it has on each axis. A variant without a
+-- price override sells at the parent's price
+ALTER TABLE products
+    ADD COLUMN parent_id BIGINT REFERENCES products(id) ON DELETE CASCADE,
+    ADD COLUMN option_axes JSONB,
+    ADD COLUMN options JSONB,
+    ADD COLUMN price_override NUMERIC(12,2) CHECK (price_override >= 0),
+    ADD CONSTRAINT products_variant_check CHECK (
+        (parent_id IS NULL AND options IS NULL AND price_override IS NULL)
+        OR (parent_id IS NOT NULL AND options IS NOT NULL AND option_axes IS NULL)
+    ),
+    ADD CONSTRAINT products_parent_stock_check CHECK (option_axes IS NULL OR stock_quantity = 0);
+
+
+CREATE INDEX idx_products_parent_id ON products (parent_id) WHERE parent_id IS NOT NULL;
+
+-- two variants of a parent never have the same option values
+CREATE UNIQUE INDEX idx_products_variant_options ON products (parent_id, options) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,38 @@
+-- categories form a tree, a product links to one node by id. products.category keeps a copy of the node's
+-- name because coupons and tax rules still match on it
+CREATE TABLE categories (
+    id BIGSERIAL PRIMARY KEY,
+
+    parent_id BIGINT REFERENCES categories(id),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CHECK (parent_id <> id)
+);
+
+
+-- siblings have distinct names regardless of case, the roots count as siblings of each other
+CREATE UNIQUE INDEX idx_categories_sibling_name ON categories (COALESCE(parent_id, 0), LOWER(name));
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+
+ALTER TABLE products
+    ADD COLUMN category_id BIGINT REFERENCES categories(id);
+
+CREATE INDEX idx_products_category_id ON products (category_id);
+
+
+-- every distinct free-text category becomes a root node, spellings that only differ in case or surrounding
+-- spaces end up in the same node
+INSERT INTO categories (name)
+SELECT MIN(TRIM(category))
+FROM products
+WHERE TRIM(COALESCE(category, '')) <> ''
+GROUP BY LOWER(TRIM(category));
+
+UPDATE products p
+SET category_id = c.id, category = c.name
+FROM categories c
+WHERE c.parent_id IS NULL AND LOWER(c.name) = LOWER(TRIM(p.category));
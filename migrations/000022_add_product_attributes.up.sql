@@ -0,0 +1,11 @@
+-- a category defines the attributes its products carry as a jsonb array of {name, type, required, unit,
+-- values}, subcategories inherit the attributes of their ancestors
+ALTER TABLE categories
+    ADD COLUMN attribute_schema JSONB;
+
+-- the attribute values of a product keyed by name
+ALTER TABLE products
+    ADD COLUMN attributes JSONB;
+
+-- serves the equality and in-list filters of the product search
+CREATE INDEX idx_products_attributes ON products USING GIN (attributes jsonb_path_ops);
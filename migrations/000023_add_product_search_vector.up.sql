@@ -0,0 +1,17 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- the full text search document of a product, name and sku rank above the category and the category above
+-- the attribute values. The simple config keeps skus and brand names intact, prefix queries stand in for
+-- stemming
+ALTER TABLE products
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(sku, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(category, '')), 'B') ||
+        setweight(jsonb_to_tsvector('simple', COALESCE(attributes, '{}'), '["string", "numeric"]'), 'C')
+    ) STORED;
+
+CREATE INDEX idx_products_search_vector ON products USING GIN (search_vector);
+
+-- serves the fuzzy fallback when a query has no full text match
+CREATE INDEX idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
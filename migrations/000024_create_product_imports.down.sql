@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS product_import_errors;
+DROP TABLE IF EXISTS product_imports;
+
+DROP TYPE IF EXISTS product_file_format;
+DROP TYPE IF EXISTS product_import_status;
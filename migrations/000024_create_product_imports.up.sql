@@ -0,0 +1,54 @@
+CREATE TYPE product_import_status AS ENUM (
+    'import.pending',
+    'import.running',
+    'import.completed',
+    'import.failed'
+);
+
+CREATE TYPE product_file_format AS ENUM (
+    'csv',
+    'jsonl'
+);
+
+-- an uploaded catalog file waiting for or going through the import worker, the file is kept until the job
+-- finishes. updated_at moves with the progress, a running job that stops moving is picked up again
+CREATE TABLE product_imports (
+    id BIGSERIAL PRIMARY KEY,
+
+    format product_file_format NOT NULL,
+    file_name VARCHAR(255),
+    status product_import_status NOT NULL DEFAULT 'import.pending',
+    payload TEXT,
+
+    total_rows BIGINT NOT NULL DEFAULT 0,
+    processed_rows BIGINT NOT NULL DEFAULT 0,
+    created_count BIGINT NOT NULL DEFAULT 0,
+    updated_count BIGINT NOT NULL DEFAULT 0,
+    failed_count BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_by VARCHAR(255) NOT NULL,
+
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_product_imports_status ON product_imports (status, id);
+
+
+-- the rows of an import that could not be applied, row_number counts the data rows of the file from 1
+CREATE TABLE product_import_errors (
+    id BIGSERIAL PRIMARY KEY,
+
+    import_id BIGINT NOT NULL REFERENCES product_imports(id) ON DELETE CASCADE,
+    row_number BIGINT NOT NULL,
+    sku VARCHAR(255),
+    message TEXT NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE UNIQUE INDEX idx_product_import_errors_row ON product_import_errors (import_id, row_number);
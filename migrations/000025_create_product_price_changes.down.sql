@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_price_changes;
+
+DROP TYPE IF EXISTS price_change_status;
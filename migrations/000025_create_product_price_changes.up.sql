@@ -0,0 +1,46 @@
+CREATE TYPE price_change_status AS ENUM (
+    'price.scheduled',
+    'price.applied',
+    'price.cancelled'
+);
+
+-- every price a product had or will have in a currency. Applied rows are the price history, applied_at is
+-- when the price actually took effect. Scheduled rows are applied by the price scheduler once effective_from
+-- passes. A null price removes the explicit price of a non base currency, it is converted from the base
+-- price again
+CREATE TABLE product_price_changes (
+    id BIGSERIAL PRIMARY KEY,
+
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    price NUMERIC(12,2) CHECK (price >= 0),
+    previous_price NUMERIC(12,2),
+    status price_change_status NOT NULL DEFAULT 'price.scheduled',
+
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE CHECK (ends_at > effective_from),
+    reverts_change_id BIGINT REFERENCES product_price_changes(id) ON DELETE SET NULL,
+    applied_at TIMESTAMP WITH TIME ZONE,
+
+    actor VARCHAR(255) NOT NULL,
+    note TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+
+CREATE INDEX idx_product_price_changes_history ON product_price_changes (product_id, currency, applied_at)
+    WHERE status = 'price.applied';
+CREATE INDEX idx_product_price_changes_due ON product_price_changes (effective_from, id)
+    WHERE status = 'price.scheduled';
+
+
+-- earlier changes were never recorded, the history opens with the current prices
+INSERT INTO product_price_changes (product_id, currency, price, status, effective_from, applied_at, actor, note)
+SELECT id, currency, price, 'price.applied', NOW(), NOW(), 'migration', 'opening price'
+FROM products;
+
+INSERT INTO product_price_changes (product_id, currency, price, status, effective_from, applied_at, actor, note)
+SELECT product_id, currency, price, 'price.applied', NOW(), NOW(), 'migration', 'opening price'
+FROM product_prices;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_idempotency_keys_scope_key;
+
+-- keys shared between scopes cannot be kept under a global unique constraint
+DELETE FROM idempotency_keys a USING idempotency_keys b
+WHERE a.idempotency_key = b.idempotency_key AND a.id < b.id;
+
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_idempotency_key_key UNIQUE (idempotency_key);
+
+ALTER TABLE idempotency_keys
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS scope;
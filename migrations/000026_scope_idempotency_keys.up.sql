@@ -0,0 +1,9 @@
+-- keys are unique per caller and route instead of globally, and an in-progress key is held for a lease
+-- after which a retry can take it over
+ALTER TABLE idempotency_keys
+    ADD COLUMN scope TEXT NOT NULL DEFAULT '',
+    ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_idempotency_key_key;
+
+CREATE UNIQUE INDEX idx_idempotency_keys_scope_key ON idempotency_keys (scope, idempotency_key);
@@ -0,0 +1,7 @@
+ALTER TABLE stock_take_lines DROP COLUMN IF EXISTS over_reserved_quantity;
+
+-- fails while a location is still over-reserved
+ALTER TABLE product_stocks DROP CONSTRAINT IF EXISTS product_stocks_reserved_quantity_check;
+ALTER TABLE product_stocks ADD CONSTRAINT product_stocks_reserved_quantity_check CHECK (reserved_quantity >= 0 AND reserved_quantity <= stock_quantity);
+
+ALTER TABLE products ADD CONSTRAINT chk_products_reserved_within_stock CHECK (reserved_quantity <= stock_quantity);
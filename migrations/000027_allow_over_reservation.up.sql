@@ -0,0 +1,11 @@
+-- a stock take can find fewer units than orders have reserved, the count is posted anyway and the location is
+-- left over-reserved with a stock alert open until the shortfall is restocked or the orders are cancelled.
+-- Stock itself still never goes below zero
+ALTER TABLE products DROP CONSTRAINT IF EXISTS chk_products_reserved_within_stock;
+
+ALTER TABLE product_stocks DROP CONSTRAINT IF EXISTS product_stocks_reserved_quantity_check;
+ALTER TABLE product_stocks ADD CONSTRAINT product_stocks_reserved_quantity_check CHECK (reserved_quantity >= 0);
+
+-- reserved units the posted count left without stock at the location
+ALTER TABLE stock_take_lines
+    ADD COLUMN over_reserved_quantity BIGINT NOT NULL DEFAULT 0;
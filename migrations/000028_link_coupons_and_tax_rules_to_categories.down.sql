@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_tax_rules_category_id;
+DROP INDEX IF EXISTS idx_coupons_category_id;
+
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS category VARCHAR(100);
+ALTER TABLE tax_rules ADD COLUMN IF NOT EXISTS category VARCHAR(100);
+
+UPDATE coupons SET category = c.name FROM categories c WHERE c.id = coupons.category_id;
+UPDATE tax_rules SET category = c.name FROM categories c WHERE c.id = tax_rules.category_id;
+
+ALTER TABLE coupons DROP COLUMN IF EXISTS category_id;
+ALTER TABLE tax_rules DROP COLUMN IF EXISTS category_id;
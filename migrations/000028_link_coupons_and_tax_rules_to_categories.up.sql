@@ -0,0 +1,46 @@
+-- coupons and tax rules point at a category node instead of carrying its name, they cover the node and
+-- everything below it. A category still in use by either cannot be deleted
+ALTER TABLE coupons
+    ADD COLUMN category_id BIGINT REFERENCES categories(id);
+
+ALTER TABLE tax_rules
+    ADD COLUMN category_id BIGINT REFERENCES categories(id);
+
+
+-- names no category carries yet become root nodes, so those coupons and rules keep matching nothing
+-- instead of starting to match everything
+INSERT INTO categories (name)
+SELECT MIN(TRIM(n.category))
+FROM (
+    SELECT category FROM coupons
+    UNION ALL
+    SELECT category FROM tax_rules
+) n
+WHERE TRIM(COALESCE(n.category, '')) <> ''
+  AND NOT EXISTS (SELECT 1 FROM categories c WHERE LOWER(c.name) = LOWER(TRIM(n.category)))
+GROUP BY LOWER(TRIM(n.category));
+
+-- a name carried by several nodes goes to the root one, otherwise to the oldest
+UPDATE coupons
+SET category_id = (
+    SELECT c.id FROM categories c
+    WHERE LOWER(c.name) = LOWER(TRIM(coupons.category))
+    ORDER BY c.parent_id IS NOT NULL, c.id
+    LIMIT 1
+)
+WHERE TRIM(COALESCE(category, '')) <> '';
+
+UPDATE tax_rules
+SET category_id = (
+    SELECT c.id FROM categories c
+    WHERE LOWER(c.name) = LOWER(TRIM(tax_rules.category))
+    ORDER BY c.parent_id IS NOT NULL, c.id
+    LIMIT 1
+)
+WHERE TRIM(COALESCE(category, '')) <> '';
+
+ALTER TABLE coupons DROP COLUMN category;
+ALTER TABLE tax_rules DROP COLUMN category;
+
+CREATE INDEX idx_coupons_category_id ON coupons (category_id);
+CREATE INDEX idx_tax_rules_category_id ON tax_rules (category_id);
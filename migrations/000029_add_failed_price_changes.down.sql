@@ -0,0 +1,22 @@
+ALTER TABLE product_price_changes DROP COLUMN IF EXISTS error;
+
+-- postgres cannot drop a single enum value, so the type is rebuilt without it. Failed changes go back to
+-- the queue
+UPDATE product_price_changes SET status = 'price.scheduled' WHERE status = 'price.failed';
+DROP INDEX IF EXISTS idx_product_price_changes_history;
+DROP INDEX IF EXISTS idx_product_price_changes_due;
+ALTER TABLE product_price_changes ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE price_change_status RENAME TO price_change_status_old;
+CREATE TYPE price_change_status AS ENUM (
+    'price.scheduled',
+    'price.applied',
+    'price.cancelled'
+);
+ALTER TABLE product_price_changes ALTER COLUMN status TYPE price_change_status USING status::text::price_change_status;
+ALTER TABLE product_price_changes ALTER COLUMN status SET DEFAULT 'price.scheduled';
+DROP TYPE price_change_status_old;
+
+CREATE INDEX idx_product_price_changes_history ON product_price_changes (product_id, currency, applied_at)
+    WHERE status = 'price.applied';
+CREATE INDEX idx_product_price_changes_due ON product_price_changes (effective_from, id)
+    WHERE status = 'price.scheduled';
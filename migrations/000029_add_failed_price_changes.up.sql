@@ -0,0 +1,6 @@
+-- a scheduled change that cannot be applied is marked failed with the reason instead of holding up the
+-- changes due after it
+ALTER TYPE price_change_status ADD VALUE IF NOT EXISTS 'price.failed';
+
+ALTER TABLE product_price_changes
+    ADD COLUMN error TEXT;